@@ -0,0 +1,152 @@
+package main
+
+// Load benchmark of password hashing, run inline on every request goroutine and through the bounded hashing pool.
+// Clients hash passwords as fast as they can while a health endpoint is polled, for each mode it prints
+// how many hashes completed or were refused, their latency, and the latency of the health checks.
+//
+//	go run ./cmd/hashbench
+//	go run ./cmd/hashbench -algorithm bcrypt -clients 64 -duration 10s
+//
+// Refused clients wait for the Retry-After duration like a well behaved client would.
+// The throughput of the pool alone is measured by go test -bench WorkerPool ./pkg/utils
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordhash"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+type result struct {
+	hashes   []time.Duration
+	health   []time.Duration
+	refused  int
+	failures int
+}
+
+func main() {
+	algorithm := flag.String("algorithm", passwordhash.AlgorithmArgon2id, "argon2id or bcrypt")
+	clients := flag.Int("clients", 4*runtime.GOMAXPROCS(0), "clients hashing at the same time")
+	duration := flag.Duration("duration", 5*time.Second, "duration of each run")
+	concurrency := flag.Int("concurrency", max(runtime.GOMAXPROCS(0)-1, 1), "hashes running at once in the pool")
+	queueDepth := flag.Int("queue", 0, "hashes waiting in the pool, defaults to 4 per concurrent hash")
+	flag.Parse()
+	if *queueDepth <= 0 {
+		*queueDepth = 4 * *concurrency
+	}
+
+	hasher, err := passwordhash.NewFromConfig(passwordhash.Config{Algorithm: *algorithm})
+	if err != nil {
+		log.Fatalf("configure hasher: %v", err)
+	}
+
+	health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer health.Close()
+
+	fmt.Printf("%s, %d CPUs, %d clients, %s per run, pool of %d with a queue of %d\n\n",
+		*algorithm, runtime.GOMAXPROCS(0), *clients, *duration, *concurrency, *queueDepth)
+	fmt.Printf("%-7s %8s %8s %10s %10s %10s %10s %10s\n",
+		"mode", "hashes", "refused", "hash p50", "hash p99", "health p50", "health p99", "health max")
+
+	inline := func(ctx context.Context, job func()) error {
+		job()
+		return nil
+	}
+	pool := utils.NewWorkerPool(*concurrency, *queueDepth, utils.DefaultWorkerPoolRetryAfter)
+
+	for _, mode := range []struct {
+		name string
+		run  func(ctx context.Context, job func()) error
+	}{
+		{"inline", inline},
+		{"pool", pool.Do},
+	} {
+		r := benchmark(hasher, mode.run, health.URL, *clients, *duration)
+		fmt.Printf("%-7s %8d %8d %10s %10s %10s %10s %10s\n", mode.name, len(r.hashes), r.refused,
+			percentile(r.hashes, 50), percentile(r.hashes, 99),
+			percentile(r.health, 50), percentile(r.health, 99), percentile(r.health, 100))
+		if r.failures > 0 {
+			fmt.Printf("        %d health checks failed\n", r.failures)
+		}
+	}
+}
+
+// benchmark runs clients hashing through run for the duration while polling the health endpoint
+func benchmark(hasher *passwordhash.Hasher, run func(ctx context.Context, job func()) error, healthURL string, clients int, duration time.Duration) *result {
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	r := &result{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				start := time.Now()
+				err := run(ctx, func() { _, _ = hasher.Hash("correct horse battery staple") })
+				elapsed := time.Since(start)
+
+				var ce *utils.CustomError
+				switch {
+				case errors.As(err, &ce):
+					mu.Lock()
+					r.refused++
+					mu.Unlock()
+					select {
+					case <-time.After(ce.RetryAfter()):
+					case <-ctx.Done():
+					}
+				case err == nil:
+					mu.Lock()
+					r.hashes = append(r.hashes, elapsed)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		<-ticker.C
+		start := time.Now()
+		resp, err := client.Get(healthURL)
+		if err != nil {
+			r.failures++
+			continue
+		}
+		resp.Body.Close()
+		mu.Lock()
+		r.health = append(r.health, time.Since(start))
+		mu.Unlock()
+	}
+
+	wg.Wait()
+	return r
+}
+
+func percentile(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := (len(sorted)*p+99)/100 - 1
+	return sorted[max(index, 0)].Round(time.Microsecond)
+}
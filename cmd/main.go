@@ -7,8 +7,15 @@ package main
 // @description     Backend API for AI Security project.
 // @BasePath        /api/v1
 // @schemes         http
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and the access token.
 
 import (
+	"context"
+	"runtime"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,8 +28,17 @@ import (
 
 	// Auth
 	authHttp "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/delivery/http"
+	authRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
 	authUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	authzHttp "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/delivery/http"
+	authzRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/repository"
+	authzUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/usecase"
 	appLogger "github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/logger"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/mailer"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordhash"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordpolicy"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
@@ -61,11 +77,25 @@ func main() {
 
 	// Close the database connection when the application exits
 	defer cfg.Database.Close()
+	if cfg.Redis != nil {
+		defer cfg.Redis.Close()
+	}
 
 	// Create a new Gin instance
 	r := gin.New()
 	// Use the Gin logger and recovery middleware
 	r.Use(gin.Logger(), gin.Recovery())
+	// Only trust X-Forwarded-For from known proxies, otherwise clients could spoof their IP
+	// and escape the per-IP login limits
+	var trustedProxies []string
+	for _, proxy := range strings.Split(cfg.Env.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		zap.L().Fatal("invalid TRUSTED_PROXIES", zap.Error(err))
+	}
 
 	// Basic health endpoint
 	r.GET("/health", healthHandler)
@@ -73,17 +103,271 @@ func main() {
 	// Collections
 	userCollection := cfg.Database.Database.Collection("users")
 
-	// User routes
-	api := r.Group("/api/v1")
 	// User routes
 	mongoUserRepository := userRepository.NewMongoUserRepository(userCollection)
-	userService := userUseCase.NewUserService(mongoUserRepository, cfg.Env.PasswordHashSaltRounds)
-	userHttp.RegisterUserRoutes(api, userService)
+	// Existing users that differ only by case block the unique indexes until they are resolved
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := mongoUserRepository.EnsureIndexes(indexCtx); err != nil {
+		zap.L().Error("user indexes are missing, run go run ./cmd/normalizeusers to report colliding users", zap.Error(err))
+	}
+	cancelIndexes()
+	passwordHasher, err := passwordhash.NewFromConfig(passwordhash.Config{
+		Algorithm: cfg.Env.PasswordHashAlgorithm,
+		Argon2id: passwordhash.Argon2idParams{
+			Memory:      uint32(cfg.Env.PasswordHashArgon2Memory),
+			Iterations:  uint32(cfg.Env.PasswordHashArgon2Iterations),
+			Parallelism: uint8(cfg.Env.PasswordHashArgon2Parallelism),
+		},
+		BcryptCost: cfg.Env.PasswordHashSaltRounds,
+	})
+	if err != nil {
+		zap.L().Fatal("failed to configure password hashing", zap.Error(err))
+	}
+	passwordPolicy, err := passwordpolicy.NewFromConfig(passwordpolicy.Config{
+		MinLength:        cfg.Env.PasswordMinLength,
+		MaxLength:        cfg.Env.PasswordMaxLength,
+		MaxBytes:         passwordHasher.MaxPasswordBytes(),
+		MinCharClasses:   cfg.Env.PasswordMinCharacterClasses,
+		MinStrength:      cfg.Env.PasswordMinStrength,
+		BreachedListFile: cfg.Env.PasswordBreachedListFile,
+	})
+	if err != nil {
+		zap.L().Fatal("failed to load password policy", zap.Error(err))
+	}
+	// One CPU is left for everything else by default, so a login burst cannot starve health checks
+	hashConcurrency := cfg.Env.PasswordHashConcurrency
+	if hashConcurrency <= 0 {
+		hashConcurrency = max(runtime.GOMAXPROCS(0)-1, 1)
+	}
+	hashQueueDepth := cfg.Env.PasswordHashQueueDepth
+	if hashQueueDepth <= 0 {
+		hashQueueDepth = 4 * hashConcurrency
+	}
+	hashingPool := utils.NewWorkerPool(hashConcurrency, hashQueueDepth, utils.DefaultWorkerPoolRetryAfter)
+	userService := userUseCase.NewUserService(mongoUserRepository, passwordHasher, hashingPool, passwordPolicy)
+
+	// Audit trail of impersonation. The middleware is registered before the route groups are created:
+	// a group copies the engine middlewares when it is created, later ones would not wrap its routes.
+	var auditRepo authRepository.AuditRepository
+	if cfg.Env.AuthRepository == "memory" {
+		auditRepo = authRepository.NewMemoryAuditRepository()
+	} else {
+		auditRepo = authRepository.NewMongoAuditRepository(cfg.Database.Database)
+	}
+	auditService := authUseCase.NewAuditService(auditRepo, userService)
+	r.Use(authHttp.AuditImpersonatedRequests(auditService))
+
+	api := r.Group("/api/v1")
+
+	// Mailer used for password reset and email verification links
+	var mailSender mailer.Mailer
+	switch cfg.Env.Mailer {
+	case "console":
+		mailSender = mailer.NewConsoleMailer(cfg.Env.EmailFrom)
+	case "file":
+		mailSender, err = mailer.NewFileMailer(cfg.Env.EmailFrom, cfg.Env.MailerFileDir)
+		if err != nil {
+			zap.L().Fatal("failed to create file mailer", zap.Error(err))
+		}
+	default:
+		if cfg.Env.EmailResendAPIKey == "" {
+			zap.L().Fatal("EMAIL_RESEND_API_KEY is required by the resend mailer")
+		}
+		mailSender = mailer.NewResendMailer(cfg.Env.EmailResendAPIKey, cfg.Env.EmailFrom)
+	}
+	emailVerificationKey := cfg.Env.EmailVerificationKey
+	if emailVerificationKey == "" {
+		zap.L().Warn("EMAIL_VERIFICATION_KEY is not set, falling back to JWT_SECRET to sign verification links")
+		emailVerificationKey = cfg.Env.JWTSecret
+	}
+	// Counters of the login attempts, also limiting the emails sent on request
+	var loginAttemptRepo authRepository.LoginAttemptRepository
+	if cfg.Redis != nil {
+		loginAttemptRepo = authRepository.NewRedisLoginAttemptRepository(cfg.Redis)
+	} else {
+		zap.L().Warn("using in-memory login attempt counters, limits are per instance")
+		loginAttemptRepo = authRepository.NewMemoryLoginAttemptRepository()
+	}
+	emailRequestThrottler := authUseCase.NewEmailRequestThrottler(loginAttemptRepo, authUseCase.DefaultEmailRequestLimits)
+	emailVerificationService := userUseCase.NewEmailVerificationService(userService, emailRequestThrottler, mailSender, emailVerificationKey, cfg.Env.EmailVerificationURL)
+	registrationService := userUseCase.NewRegistrationService(userService, emailVerificationService, mailSender, cfg.Env.RegistrationHideExistingEmails)
+
+	// Auth service is needed before registering routes because it backs the auth middleware
+	var keyManager authUseCase.KeyManager
+	if cfg.Env.JWTSigningKeys != "" {
+		signingKeys, err := authUseCase.LoadSigningKeys(cfg.Env.JWTSigningKeys)
+		if err != nil {
+			zap.L().Fatal("failed to load jwt signing keys", zap.Error(err))
+		}
+		keyManager, err = authUseCase.NewKeyManager(signingKeys, authUseCase.SignedTokenLifetime(time.Duration(cfg.Env.JWTExpiresIn)*time.Second))
+		if err != nil {
+			zap.L().Fatal("failed to create jwt key manager", zap.Error(err))
+		}
+	}
+	accessTokenClaims, err := authUseCase.ParseAccessTokenClaims(cfg.Env.AccessTokenClaims)
+	if err != nil {
+		zap.L().Fatal("invalid ACCESS_TOKEN_CLAIMS", zap.Error(err))
+	}
+	jwtRefreshSecret := cfg.Env.JWTRefreshSecret
+	if jwtRefreshSecret == "" {
+		zap.L().Warn("JWT_REFRESH_SECRET is not set, falling back to JWT_SECRET to sign refresh tokens")
+		jwtRefreshSecret = cfg.Env.JWTSecret
+	}
+	jwtIssuer := cfg.Env.JWTIssuer
+	if jwtIssuer == "" {
+		jwtIssuer = cfg.Env.OAuthIssuer
+	}
+	if jwtIssuer == "" {
+		jwtIssuer = cfg.Env.AppName
+	}
+	jwtAudience := cfg.Env.JWTAudience
+	if jwtAudience == "" {
+		jwtAudience = jwtIssuer
+	}
+	jwtService := authUseCase.NewJWTService(authUseCase.JWTConfig{
+		Secret:            cfg.Env.JWTSecret,
+		ExpiresIn:         time.Duration(cfg.Env.JWTExpiresIn) * time.Second,
+		KeyManager:        keyManager,
+		RefreshSecret:     jwtRefreshSecret,
+		RefreshExpiresIn:  time.Duration(cfg.Env.JWTRefreshExpiresIn) * time.Second,
+		Issuer:            jwtIssuer,
+		Audience:          jwtAudience,
+		AccessTokenClaims: accessTokenClaims,
+	})
+	var authRepo authRepository.AuthRepository
+	if cfg.Env.AuthRepository == "memory" {
+		zap.L().Warn("using in-memory auth repository, tokens are lost on restart")
+		authRepo = authRepository.NewMemoryAuthRepository()
+	} else {
+		authRepo = authRepository.NewMongoAuthRepository(cfg.Database.Database)
+		indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := authRepo.EnsureIndexes(indexCtx); err != nil {
+			zap.L().Error("failed to create auth token indexes", zap.Error(err))
+		}
+		cancelIndexes()
+	}
+	switch cfg.Env.AccessTokenFormat {
+	case "", authUseCase.AccessTokenFormatJWT:
+	case authUseCase.AccessTokenFormatReference:
+		jwtService = authUseCase.NewReferenceTokenService(jwtService, authRepo)
+	default:
+		zap.L().Fatal("invalid ACCESS_TOKEN_FORMAT, expected jwt or reference", zap.String("format", cfg.Env.AccessTokenFormat))
+	}
+	mfaIssuer := cfg.Env.MFAIssuer
+	if mfaIssuer == "" {
+		mfaIssuer = cfg.Env.AppName
+	}
+	mfaEncryptionKey := cfg.Env.MFAEncryptionKey
+	if mfaEncryptionKey == "" {
+		zap.L().Warn("MFA_ENCRYPTION_KEY is not set, falling back to JWT_SECRET to encrypt totp secrets")
+		mfaEncryptionKey = cfg.Env.JWTSecret
+	}
+	loginThrottler := authUseCase.NewLoginThrottler(loginAttemptRepo,
+		authUseCase.DefaultUsernameThrottleLimits,
+		authUseCase.DefaultIPThrottleLimits,
+	)
+	mfaService := authUseCase.NewMFAService(userService, jwtService, authRepo, loginThrottler, mfaIssuer, mfaEncryptionKey)
+	// Custom roles, the permissions of every authenticated request are resolved from them
+	var roleRepo authzRepository.RoleRepository
+	if cfg.Env.AuthRepository == "memory" {
+		roleRepo = authzRepository.NewMemoryRoleRepository()
+	} else {
+		roleRepo = authzRepository.NewMongoRoleRepository(cfg.Database.Database)
+		indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := roleRepo.EnsureIndexes(indexCtx); err != nil {
+			zap.L().Error("failed to create role assignment indexes", zap.Error(err))
+		}
+		cancelIndexes()
+	}
+	roleService := authzUseCase.NewRoleService(roleRepo, userService)
+	authService := authUseCase.NewAuthService(userService, jwtService, authRepo, mfaService, loginThrottler, roleService, cfg.Env.RequireVerifiedEmail)
+	authMiddleware := middleware.RequireAuth(authService)
+	var oidcProviders []*authUseCase.OIDCProviderConfig
+	if cfg.Env.OIDCProviders != "" {
+		oidcProviders, err = authUseCase.LoadOIDCProviders(cfg.Env.OIDCProviders)
+		if err != nil {
+			zap.L().Fatal("failed to load oidc providers", zap.Error(err))
+		}
+	}
+	oidcService := authUseCase.NewOIDCService(userService, authService, authRepo, oidcProviders, nil)
+	passwordResetService := authUseCase.NewPasswordResetService(userService, authRepo, emailRequestThrottler, mailSender, cfg.Env.PasswordResetURL)
+
+	var apiKeyRepo authRepository.APIKeyRepository
+	if cfg.Env.AuthRepository == "memory" {
+		apiKeyRepo = authRepository.NewMemoryAPIKeyRepository()
+	} else {
+		apiKeyRepo = authRepository.NewMongoAPIKeyRepository(cfg.Database.Database)
+	}
+	apiKeyService := authUseCase.NewAPIKeyService(apiKeyRepo, authRepo, userService, roleService)
+	apiKeyAuth := func(scope string) gin.HandlerFunc {
+		return middleware.RequireAuthOrAPIKey(authService, apiKeyService, scope)
+	}
+
+	// Access policies and the decision API. The user management routes enforce them once a policy targets
+	// their action on users, until then their permission checks decide alone.
+	var policyRepo authzRepository.PolicyRepository
+	var policyDecisionRepo authzRepository.PolicyDecisionRepository
+	if cfg.Env.AuthRepository == "memory" {
+		policyRepo = authzRepository.NewMemoryPolicyRepository()
+		policyDecisionRepo = authzRepository.NewMemoryPolicyDecisionRepository()
+	} else {
+		policyRepo = authzRepository.NewMongoPolicyRepository(cfg.Database.Database)
+		policyDecisionRepo = authzRepository.NewMongoPolicyDecisionRepository(cfg.Database.Database)
+		indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := policyRepo.EnsureIndexes(indexCtx); err != nil {
+			zap.L().Error("failed to create policy version indexes", zap.Error(err))
+		}
+		if err := policyDecisionRepo.EnsureIndexes(indexCtx); err != nil {
+			zap.L().Error("failed to create policy decision indexes", zap.Error(err))
+		}
+		cancelIndexes()
+	}
+	policyService := authzUseCase.NewPolicyService(policyRepo, policyDecisionRepo, roleService, userService)
+	userPolicy := func(action, idParam string) gin.HandlerFunc {
+		return middleware.RequirePolicy(policyService, action, authzUseCase.ResourceTypeUser, idParam)
+	}
+
+	userHttp.RegisterUserRoutes(api, userService, registrationService, emailVerificationService, authMiddleware, apiKeyAuth, userPolicy)
 
 	// Auth routes
-	jwtService := authUseCase.NewJWTService(cfg.Env.JWTSecret, time.Duration(cfg.Env.JWTExpiresIn)*time.Second)
-	authService := authUseCase.NewAuthService(userService, jwtService)
-	authHttp.RegisterAuthRoutes(api, authService)
+	authHttp.RegisterAuthRoutes(api, authService, mfaService, passwordResetService, oidcService, authMiddleware)
+	authHttp.RegisterWellKnownRoutes(r, authService)
+	authHttp.RegisterAPIKeyRoutes(api, apiKeyService, authMiddleware)
+	authHttp.RegisterSessionRoutes(api, authUseCase.NewSessionService(authRepo), authMiddleware)
+	impersonationService := authUseCase.NewImpersonationService(userService, jwtService, authRepo, auditService, roleService,
+		time.Duration(cfg.Env.ImpersonationExpiresIn)*time.Second)
+	authHttp.RegisterImpersonationRoutes(api, impersonationService, auditService, authMiddleware, userPolicy)
+
+	authzHttp.RegisterRoleRoutes(api, roleService, policyService, authMiddleware)
+	authzHttp.RegisterPolicyRoutes(api, policyService, authMiddleware)
+
+	// Service account routes
+	var serviceAccountRepo authRepository.ServiceAccountRepository
+	if cfg.Env.AuthRepository == "memory" {
+		serviceAccountRepo = authRepository.NewMemoryServiceAccountRepository()
+	} else {
+		serviceAccountRepo = authRepository.NewMongoServiceAccountRepository(cfg.Database.Database)
+	}
+	serviceAccountService := authUseCase.NewServiceAccountService(serviceAccountRepo, authRepo, jwtService)
+	authHttp.RegisterServiceAccountRoutes(api, serviceAccountService, authMiddleware)
+
+	// OAuth authorization server routes
+	var oauthService authUseCase.OAuthService
+	if cfg.Env.OAuthIssuer != "" {
+		var oauthRepo authRepository.OAuthRepository
+		if cfg.Env.AuthRepository == "memory" {
+			oauthRepo = authRepository.NewMemoryOAuthRepository()
+		} else {
+			oauthRepo = authRepository.NewMongoOAuthRepository(cfg.Database.Database)
+		}
+		oauthService = authUseCase.NewOAuthService(oauthRepo, authService, userService, jwtService, cfg.Env.OAuthIssuer)
+		authHttp.RegisterOAuthRoutes(r, api, oauthService, authService, mfaService, authMiddleware)
+	} else {
+		zap.L().Info("OAUTH_ISSUER is not set, the oauth authorization server is disabled")
+	}
+	authHttp.RegisterUserInfoRoutes(r, authUseCase.NewUserInfoService(userService), authMiddleware)
+	tokenIntrospectionService := authUseCase.NewTokenIntrospectionService(authService, userService, authRepo, jwtService, serviceAccountService, oauthService)
+	authHttp.RegisterTokenIntrospectionRoutes(r, tokenIntrospectionService)
 
 	// Swagger UI Route (use local generated spec)
 	r.Static("/docs", "./docs") // or: r.StaticFile("/docs/swagger.json", "./docs/swagger.json")
@@ -0,0 +1,155 @@
+package main
+
+// One-off migration to case-insensitive usernames and emails.
+// It reports users whose usernames or emails only differ by case, those must be merged or renamed by hand.
+// With -apply it also lowercases the other users and creates the unique indexes once nothing collides.
+//
+//	go run ./cmd/normalizeusers          # report only
+//	go run ./cmd/normalizeusers -apply
+//
+// It reads MONGO_URI and MONGO_DATABASE like the API and exits with status 1 while collisions remain.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	config "github.com/luannguyenthanh-ba-dev/go-ai-security/config"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	userRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type storedUser struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Username  string             `bson:"username"`
+	Email     string             `bson:"email"`
+	CreatedAt int64              `bson:"created_at"`
+}
+
+func main() {
+	apply := flag.Bool("apply", false, "lowercase the users that do not collide and create the unique indexes")
+	flag.Parse()
+
+	env, err := config.LoadEnv()
+	if err != nil {
+		log.Fatalf("load env: %v", err)
+	}
+	database, err := config.NewMongoDatabase(config.MongoDBConfig{URI: env.MongoURI, Database: env.MongoDatabase})
+	if err != nil {
+		log.Fatalf("connect to mongo: %v", err)
+	}
+	defer database.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	collection := database.Database.Collection("users")
+
+	users, err := loadUsers(ctx, collection)
+	if err != nil {
+		log.Fatalf("load users: %v", err)
+	}
+
+	byUsername := groupBy(users, func(u *storedUser) string { return domain.NormalizeUsername(u.Username) })
+	byEmail := groupBy(users, func(u *storedUser) string { return domain.NormalizeEmail(u.Email) })
+	colliding := map[primitive.ObjectID]bool{}
+	collisions := report("username", byUsername, colliding) + report("email", byEmail, colliding)
+
+	for _, u := range users {
+		if strings.Contains(u.Username, "@") {
+			fmt.Printf("username %q of %s contains \"@\", it is only used at login when no email matches\n", u.Username, u.ID.Hex())
+		}
+	}
+
+	pending := 0
+	for _, u := range users {
+		if colliding[u.ID] || (u.Username == domain.NormalizeUsername(u.Username) && u.Email == domain.NormalizeEmail(u.Email)) {
+			continue
+		}
+		pending++
+		if !*apply {
+			continue
+		}
+		_, err := collection.UpdateOne(ctx,
+			primitive.D{{Key: "_id", Value: u.ID}},
+			primitive.D{{Key: "$set", Value: primitive.D{
+				{Key: "username", Value: domain.NormalizeUsername(u.Username)},
+				{Key: "email", Value: domain.NormalizeEmail(u.Email)},
+				{Key: "updated_at", Value: time.Now().UnixMilli()},
+			}}},
+		)
+		if err != nil {
+			log.Fatalf("normalize user %s: %v", u.ID.Hex(), err)
+		}
+	}
+
+	fmt.Printf("%d users, %d colliding groups, %d users to lowercase\n", len(users), collisions, pending)
+	if !*apply {
+		if pending > 0 || collisions > 0 {
+			fmt.Println("run again with -apply to lowercase the users that do not collide")
+		}
+	} else if collisions == 0 {
+		if err := userRepository.NewMongoUserRepository(collection).EnsureIndexes(ctx); err != nil {
+			log.Fatalf("create indexes: %v", err)
+		}
+		fmt.Println("users normalized, unique indexes created")
+	}
+	if collisions > 0 {
+		fmt.Println("resolve the colliding users by hand, then run again")
+		database.Close()
+		os.Exit(1)
+	}
+}
+
+func loadUsers(ctx context.Context, collection *mongo.Collection) ([]*storedUser, error) {
+	projection := primitive.D{
+		{Key: "username", Value: 1},
+		{Key: "email", Value: 1},
+		{Key: "created_at", Value: 1},
+	}
+	cursor, err := collection.Find(ctx, primitive.D{}, options.Find().SetProjection(projection).SetSort(primitive.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	users := []*storedUser{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func groupBy(users []*storedUser, key func(*storedUser) string) map[string][]*storedUser {
+	groups := map[string][]*storedUser{}
+	for _, u := range users {
+		groups[key(u)] = append(groups[key(u)], u)
+	}
+	return groups
+}
+
+// report prints the groups with more than one user, marks them as colliding and returns how many there are
+func report(field string, groups map[string][]*storedUser, colliding map[primitive.ObjectID]bool) int {
+	keys := make([]string, 0, len(groups))
+	for key, group := range groups {
+		if len(group) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Printf("%s %q is shared by:\n", field, key)
+		for _, u := range groups[key] {
+			colliding[u.ID] = true
+			fmt.Printf("  %s username=%q email=%q created_at=%s\n",
+				u.ID.Hex(), u.Username, u.Email, time.UnixMilli(u.CreatedAt).UTC().Format(time.RFC3339))
+		}
+	}
+	return len(keys)
+}
@@ -1,12 +1,18 @@
 package config
 
-import "time"
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
 
 // Configuration management
 
 type Config struct {
-	Env      *Env      // Environment variables configuration
-	Database *Database // Database configuration
+	Env      *Env          // Environment variables configuration
+	Database *Database     // Database configuration
+	Redis    *redis.Client // Redis client, nil when REDIS_URL is not set or Redis is unreachable
 }
 
 func LoadConfig() (*Config, error) {
@@ -27,8 +33,19 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	// Redis is optional, features relying on it fall back to in-memory storage
+	var redisClient *redis.Client
+	if env.RedisURL != "" {
+		redisClient, err = NewRedisClient(env.RedisURL)
+		if err != nil {
+			zap.L().Warn("Redis is unavailable, falling back to in-memory storage", zap.Error(err))
+			redisClient = nil
+		}
+	}
+
 	return &Config{
-		Env: env,
+		Env:      env,
 		Database: database,
+		Redis:    redisClient,
 	}, nil
 }
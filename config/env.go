@@ -10,16 +10,49 @@ import (
 // Struct is as class in other languages like Python, Java, etc.
 // Tags are used to map the environment variables to the struct fields
 type Env struct {
-	AppName                string `mapstructure:"APP_NAME"`
-	Port                   string `mapstructure:"PORT"`
-	AppEnv                 string `mapstructure:"APP_ENV"`
-	MongoURI               string `mapstructure:"MONGO_URI"`
-	MongoDatabase          string `mapstructure:"MONGO_DATABASE"`
-	RedisURL               string `mapstructure:"REDIS_URL"`
-	EmailResendAPIKey      string `mapstructure:"EMAIL_RESEND_API_KEY"`
-	PasswordHashSaltRounds int    `mapstructure:"PASSWORD_HASH_SALT_ROUNDS"`
-	JWTSecret              string `mapstructure:"JWT_SECRET"`
-	JWTExpiresIn           int    `mapstructure:"JWT_EXPIRES_IN"`
+	AppName                        string `mapstructure:"APP_NAME"`
+	Port                           string `mapstructure:"PORT"`
+	AppEnv                         string `mapstructure:"APP_ENV"`
+	MongoURI                       string `mapstructure:"MONGO_URI"`
+	MongoDatabase                  string `mapstructure:"MONGO_DATABASE"`
+	RedisURL                       string `mapstructure:"REDIS_URL"`
+	EmailResendAPIKey              string `mapstructure:"EMAIL_RESEND_API_KEY"`
+	EmailFrom                      string `mapstructure:"EMAIL_FROM"`
+	Mailer                         string `mapstructure:"MAILER"`                            // "resend" (default), "console" or "file"
+	MailerFileDir                  string `mapstructure:"MAILER_FILE_DIR"`                   // Output directory of the file mailer
+	PasswordResetURL               string `mapstructure:"PASSWORD_RESET_URL"`                // Client page that receives the reset token
+	EmailVerificationURL           string `mapstructure:"EMAIL_VERIFICATION_URL"`            // Client page that receives the verification token
+	EmailVerificationKey           string `mapstructure:"EMAIL_VERIFICATION_KEY"`            // Signs verification links, defaults to JWT_SECRET
+	RequireVerifiedEmail           bool   `mapstructure:"REQUIRE_VERIFIED_EMAIL"`            // Reject logins of users with an unverified email
+	RegistrationHideExistingEmails bool   `mapstructure:"REGISTRATION_HIDE_EXISTING_EMAILS"` // Same registration response for taken emails, the owner is notified by mail
+	PasswordHashAlgorithm          string `mapstructure:"PASSWORD_HASH_ALGORITHM"`           // "argon2id" (default) or "bcrypt"
+	PasswordHashSaltRounds         int    `mapstructure:"PASSWORD_HASH_SALT_ROUNDS"`         // bcrypt cost
+	PasswordHashArgon2Memory       int    `mapstructure:"PASSWORD_HASH_ARGON2_MEMORY"`       // KiB, defaults to 19456
+	PasswordHashArgon2Iterations   int    `mapstructure:"PASSWORD_HASH_ARGON2_ITERATIONS"`   // Defaults to 2
+	PasswordHashArgon2Parallelism  int    `mapstructure:"PASSWORD_HASH_ARGON2_PARALLELISM"`  // Defaults to 1
+	PasswordHashConcurrency        int    `mapstructure:"PASSWORD_HASH_CONCURRENCY"`         // Passwords hashed at once, defaults to the CPUs minus one
+	PasswordHashQueueDepth         int    `mapstructure:"PASSWORD_HASH_QUEUE_DEPTH"`         // Requests waiting to hash before 503, defaults to 4 per concurrent hash
+	PasswordMinLength              int    `mapstructure:"PASSWORD_MIN_LENGTH"`               // Defaults to 10 characters
+	PasswordMaxLength              int    `mapstructure:"PASSWORD_MAX_LENGTH"`               // Defaults to 128 characters
+	PasswordMinCharacterClasses    int    `mapstructure:"PASSWORD_MIN_CHARACTER_CLASSES"`    // Lowercase, uppercase, digits, symbols, 0 disables the rule
+	PasswordMinStrength            int    `mapstructure:"PASSWORD_MIN_STRENGTH"`             // Estimated strength score 0-4, 0 disables the rule
+	PasswordBreachedListFile       string `mapstructure:"PASSWORD_BREACHED_LIST_FILE"`       // Sorted SHA-1 list of breached passwords, empty disables the check
+	JWTSecret                      string `mapstructure:"JWT_SECRET"`
+	JWTExpiresIn                   int    `mapstructure:"JWT_EXPIRES_IN"`
+	JWTRefreshSecret               string `mapstructure:"JWT_REFRESH_SECRET"`     // Signs refresh tokens, defaults to JWT_SECRET
+	JWTRefreshExpiresIn            int    `mapstructure:"JWT_REFRESH_EXPIRES_IN"` // Seconds, defaults to 7 days
+	JWTIssuer                      string `mapstructure:"JWT_ISSUER"`             // iss of the tokens, defaults to OAUTH_ISSUER or APP_NAME
+	JWTAudience                    string `mapstructure:"JWT_AUDIENCE"`           // aud of the tokens, defaults to the issuer
+	ImpersonationExpiresIn         int    `mapstructure:"IMPERSONATION_EXPIRES_IN"`
+	JWTSigningKeys                 string `mapstructure:"JWT_SIGNING_KEYS"`    // kid:path.pem[@activation[/retirement]],... enables asymmetric signing
+	AccessTokenClaims              string `mapstructure:"ACCESS_TOKEN_CLAIMS"` // Comma separated user claims copied into access tokens, none by default
+	AccessTokenFormat              string `mapstructure:"ACCESS_TOKEN_FORMAT"` // jwt (default) or reference for opaque tokens resolved by the API
+	MFAIssuer                      string `mapstructure:"MFA_ISSUER"`          // Name shown in authenticator apps, defaults to APP_NAME
+	MFAEncryptionKey               string `mapstructure:"MFA_ENCRYPTION_KEY"`  // Encrypts TOTP secrets at rest
+	OIDCProviders                  string `mapstructure:"OIDC_PROVIDERS"`      // JSON array of external identity providers
+	OAuthIssuer                    string `mapstructure:"OAUTH_ISSUER"`        // Public base URL of this API, enables the OAuth authorization server
+	TrustedProxies                 string `mapstructure:"TRUSTED_PROXIES"`     // Comma separated proxy IPs/CIDRs allowed to set X-Forwarded-For
+	AuthRepository                 string `mapstructure:"AUTH_REPOSITORY"`     // "mongo" (default) or "memory"
 }
 
 // Return *Env and error: *Env is the environment variables configuration, error is the error if any
@@ -0,0 +1,32 @@
+package config
+
+// Redis configuration
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// NewRedisClient creates a Redis client from a redis:// URL and verifies the connection
+func NewRedisClient(url string) (*redis.Client, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		zap.L().Error("invalid Redis URL", zap.Error(err))
+		return nil, err
+	}
+
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		zap.L().Warn("failed to connect to Redis", zap.String("addr", options.Addr), zap.Error(err))
+		_ = client.Close()
+		return nil, err
+	}
+
+	zap.L().Info("successfully connected to Redis", zap.String("addr", options.Addr))
+	return client, nil
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// HTTP handlers for personal API keys
+
+type APIKeyHandler struct {
+	apiKeyService usecase.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService usecase.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// CreateAPIKey handles POST /users/me/api-keys request
+// @Summary Create an API key
+// @Description Create a personal API key for scripts, sent as "Authorization: ApiKey <key>". The key is only returned once.
+// @Tags API keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.CreateAPIKeyRequest true "API key"
+// @Success 201 {object} domain.CreatedAPIKeyEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /users/me/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+
+	created, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), principal, &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, created)
+}
+
+// ListAPIKeys handles GET /users/me/api-keys request
+// @Summary List API keys
+// @Description List the API keys of the current user with their last use, revoked and expired keys included
+// @Tags API keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.APIKeyEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /users/me/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(c.Request.Context(), principal)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles DELETE /users/me/api-keys/:id request
+// @Summary Revoke an API key
+// @Tags API keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key id"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/me/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), principal, c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, nil)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
 	"go.uber.org/zap"
 )
@@ -23,15 +24,19 @@ func NewAuthHandler(service usecase.AuthService) *AuthHandler {
 
 // Login handles POST /auth/login request
 // @Summary Login
-// @Description Login with username and password
+// @Description Login with a username or an email, compared ignoring case, and a password. Users with MFA enabled receive an MFA challenge instead of tokens.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param body body dto.LoginRequest true "Login request"
 // @Success 201 {object} domain.JWTAuthEntity
+// @Success 200 {object} domain.MFAChallengeEntity
 // @Failure 401 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 423 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var data dto.LoginRequest
@@ -40,9 +45,44 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	zap.L().Info("Login request received", zap.Any("data", data))
+	data.ClientIP = c.ClientIP()
+	data.UserAgent = c.Request.UserAgent()
+	// Never log the password
+	zap.L().Info("Login request received", zap.String("username", data.Username), zap.String("client_ip", data.ClientIP))
 
-	auth, err := h.service.Login(c.Request.Context(), &data)
+	auth, challenge, err := h.service.Login(c.Request.Context(), &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if challenge != nil {
+		utils.SuccessResponse(c, http.StatusOK, challenge)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, auth)
+}
+
+// RefreshToken handles POST /auth/refresh request
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token pair. Each refresh token can be used only once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body dto.RefreshTokenRequest true "Refresh token request"
+// @Success 201 {object} domain.JWTAuthEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/refresh [post]
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var data dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+	data.ClientIP = c.ClientIP()
+
+	auth, err := h.service.RefreshToken(c.Request.Context(), &data)
 	if err != nil {
 		if ce, ok := err.(*utils.CustomError); ok {
 			utils.ErrorResponse(c, ce.HTTPStatus(), ce.Code(), ce.Error())
@@ -56,3 +96,84 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 	utils.SuccessResponse(c, http.StatusCreated, auth)
 }
+
+// Logout handles POST /auth/logout request
+// @Summary Logout
+// @Description Revoke the current access token and the refresh token issued with it
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c,
+			middleware.ErrUnauthenticated.HTTPStatus(),
+			middleware.ErrUnauthenticated.Code(),
+			middleware.ErrUnauthenticated.Error())
+		return
+	}
+
+	if err := h.service.Logout(c.Request.Context(), principal); err != nil {
+		if ce, ok := err.(*utils.CustomError); ok {
+			utils.ErrorResponse(c, ce.HTTPStatus(), ce.Code(), ce.Error())
+			return
+		}
+		utils.ErrorResponse(c,
+			domain.ErrAuthInternalServerError.HTTPStatus(),
+			domain.ErrAuthInternalServerError.Code(),
+			domain.ErrAuthInternalServerError.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, nil)
+}
+
+// LogoutAll handles POST /auth/logout-all request
+// @Summary Logout everywhere
+// @Description Revoke every access and refresh token issued to the current user
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c,
+			middleware.ErrUnauthenticated.HTTPStatus(),
+			middleware.ErrUnauthenticated.Code(),
+			middleware.ErrUnauthenticated.Error())
+		return
+	}
+
+	if err := h.service.LogoutAll(c.Request.Context(), principal); err != nil {
+		if ce, ok := err.(*utils.CustomError); ok {
+			utils.ErrorResponse(c, ce.HTTPStatus(), ce.Code(), ce.Error())
+			return
+		}
+		utils.ErrorResponse(c,
+			domain.ErrAuthInternalServerError.HTTPStatus(),
+			domain.ErrAuthInternalServerError.Code(),
+			domain.ErrAuthInternalServerError.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, nil)
+}
+
+// JWKS handles GET /.well-known/jwks.json request
+// @Summary JSON Web Key Set
+// @Description Public keys used to verify the tokens issued by this service (RFC 7517)
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} domain.JWKSEntity
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	// Verifiers expect the bare key set, not the response envelope
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.service.GetJWKS(c.Request.Context()))
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// HTTP handlers for admin impersonation and the audit trail
+
+type ImpersonationHandler struct {
+	impersonationService usecase.ImpersonationService
+	auditService         usecase.AuditService
+}
+
+func NewImpersonationHandler(impersonationService usecase.ImpersonationService, auditService usecase.AuditService) *ImpersonationHandler {
+	return &ImpersonationHandler{impersonationService: impersonationService, auditService: auditService}
+}
+
+// Impersonate handles POST /auth/impersonate/:userId request
+// @Summary Impersonate a user
+// @Description Admins granted users:impersonate get a short-lived access token acting as the user, without refresh token. Changing credentials or MFA is not allowed with it, every request made with it is audited.
+// @Tags Impersonation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userId path string true "User id"
+// @Param body body dto.ImpersonateRequest true "Impersonation request"
+// @Success 201 {object} domain.JWTAuthEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /auth/impersonate/{userId} [post]
+func (h *ImpersonationHandler) Impersonate(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.ImpersonateRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+	data.ClientIP = c.ClientIP()
+	data.UserAgent = c.Request.UserAgent()
+
+	auth, err := h.impersonationService.Start(c.Request.Context(), principal, c.Param("userId"), &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, auth)
+}
+
+// StopImpersonation handles POST /auth/impersonate/stop request
+// @Summary Stop impersonating
+// @Description Revoke the impersonation token of the request
+// @Tags Impersonation
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/impersonate/stop [post]
+func (h *ImpersonationHandler) StopImpersonation(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+
+	device := dto.DeviceInfo{ClientIP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	if err := h.impersonationService.Stop(c.Request.Context(), principal, device); err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, nil)
+}
+
+// ListAuditEvents handles GET /auth/audit-events request
+// @Summary List audit events
+// @Description List the audit trail, newest first: impersonations and the requests made while impersonating
+// @Tags Impersonation
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "Event type, e.g. impersonation.started"
+// @Param actor_id query string false "User id of the admin"
+// @Param user_id query string false "User id of the user acted upon"
+// @Param limit query int false "Maximum number of events, defaults to 100"
+// @Success 200 {array} domain.AuditEventEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /auth/audit-events [get]
+func (h *ImpersonationHandler) ListAuditEvents(c *gin.Context) {
+	var query dto.AuditEventQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+
+	events, err := h.auditService.ListEvents(c.Request.Context(), &query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, events)
+}
+
+// AuditImpersonatedRequests records every request made with an impersonation token once it has been handled.
+// Register it on the engine before the route groups are created, groups only inherit the middlewares registered
+// before them. The principal is only known after the route's auth middleware ran.
+func AuditImpersonatedRequests(auditService usecase.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		principal, ok := middleware.GetPrincipal(c)
+		if !ok || !principal.IsImpersonated() {
+			return
+		}
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		// The response is written, the client may be gone already
+		ctx := context.WithoutCancel(c.Request.Context())
+		err := auditService.Record(ctx, &domain.AuditEventEntity{
+			Type:          domain.AuditEventImpersonatedRequest,
+			ActorID:       principal.ActorID,
+			ActorUsername: principal.ActorUsername,
+			UserID:        principal.UserID,
+			Username:      principal.Username,
+			TokenID:       principal.TokenID,
+			Method:        c.Request.Method,
+			Path:          path,
+			Status:        c.Writer.Status(),
+			IP:            c.ClientIP(),
+			UserAgent:     c.Request.UserAgent(),
+		})
+		if err != nil {
+			zap.L().Error("error recording impersonated request",
+				zap.String("actor_id", principal.ActorID),
+				zap.String("user_id", principal.UserID),
+				zap.String("path", path),
+				zap.Error(err),
+			)
+		}
+	}
+}
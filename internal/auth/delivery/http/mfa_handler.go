@@ -0,0 +1,162 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// HTTP handlers for MFA endpoints
+
+type MFAHandler struct {
+	authService usecase.AuthService
+	mfaService  usecase.MFAService
+}
+
+func NewMFAHandler(authService usecase.AuthService, mfaService usecase.MFAService) *MFAHandler {
+	return &MFAHandler{authService: authService, mfaService: mfaService}
+}
+
+// VerifyMFA handles POST /auth/mfa/verify request
+// @Summary Verify MFA
+// @Description Complete a login with the MFA token and a TOTP code or a recovery code
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param body body dto.MFAVerifyRequest true "MFA verification request"
+// @Success 201 {object} domain.JWTAuthEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/mfa/verify [post]
+func (h *MFAHandler) VerifyMFA(c *gin.Context) {
+	var data dto.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+
+	data.ClientIP = c.ClientIP()
+	data.UserAgent = c.Request.UserAgent()
+
+	auth, err := h.authService.VerifyMFA(c.Request.Context(), &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, auth)
+}
+
+// SetupTOTP handles POST /auth/mfa/totp/setup request
+// @Summary Start TOTP enrollment
+// @Description Generate a TOTP secret and otpauth URI, MFA is enabled once a code is confirmed
+// @Tags MFA
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} domain.TOTPSetupEntity
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/mfa/totp/setup [post]
+func (h *MFAHandler) SetupTOTP(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+
+	setup, err := h.mfaService.SetupTOTP(c.Request.Context(), principal)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, setup)
+}
+
+// EnableTOTP handles POST /auth/mfa/totp/enable request
+// @Summary Enable TOTP
+// @Description Confirm the TOTP enrollment with a code and receive one-time recovery codes
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.TOTPCodeRequest true "TOTP code"
+// @Success 200 {object} domain.RecoveryCodesEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/mfa/totp/enable [post]
+func (h *MFAHandler) EnableTOTP(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+	data.ClientIP = c.ClientIP()
+
+	codes, err := h.mfaService.EnableTOTP(c.Request.Context(), principal, &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, codes)
+}
+
+// DisableTOTP handles POST /auth/mfa/totp/disable request
+// @Summary Disable TOTP
+// @Description Disable MFA with a valid TOTP code, recovery codes are discarded
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.TOTPCodeRequest true "TOTP code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/mfa/totp/disable [post]
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+	data.ClientIP = c.ClientIP()
+
+	if err := h.mfaService.DisableTOTP(c.Request.Context(), principal, &data); err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, nil)
+}
+
+// respondError writes a domain error, unknown errors become internal server errors
+func respondError(c *gin.Context, err error) {
+	if ce, ok := err.(*utils.CustomError); ok {
+		utils.CustomErrorResponse(c, ce)
+		return
+	}
+	utils.ErrorResponse(c,
+		domain.ErrAuthInternalServerError.HTTPStatus(),
+		domain.ErrAuthInternalServerError.Code(),
+		domain.ErrAuthInternalServerError.Error())
+}
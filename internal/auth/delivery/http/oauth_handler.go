@@ -0,0 +1,367 @@
+package http
+
+import (
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// HTTP handlers for the OAuth 2.1 authorization server
+
+const (
+	// oauthCSRFCookie binds the login form to the browser that loaded it, this stops login CSRF
+	oauthCSRFCookie       = "oauth_csrf"
+	oauthCSRFCookieMaxAge = 15 * time.Minute
+)
+
+var scopeDescriptions = map[string]string{
+	usecase.ScopeOpenID:  "Know who you are",
+	usecase.ScopeProfile: "See your username, name and gender",
+	usecase.ScopeEmail:   "See your email address",
+	usecase.ScopePhone:   "See your phone number",
+	usecase.ScopeAddress: "See your address",
+}
+
+type OAuthHandler struct {
+	oauthService usecase.OAuthService
+	authService  usecase.AuthService
+	mfaService   usecase.MFAService
+}
+
+func NewOAuthHandler(oauthService usecase.OAuthService, authService usecase.AuthService, mfaService usecase.MFAService) *OAuthHandler {
+	return &OAuthHandler{oauthService: oauthService, authService: authService, mfaService: mfaService}
+}
+
+// Authorize handles GET /oauth/authorize request
+// @Summary Authorization endpoint
+// @Description Show the login and consent page for an authorization code request with PKCE
+// @Tags OAuth
+// @Produce html
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client id"
+// @Param redirect_uri query string false "Registered redirect uri"
+// @Param scope query string false "Space separated scopes"
+// @Param state query string false "Client state"
+// @Param nonce query string false "ID token nonce"
+// @Param code_challenge query string true "PKCE challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 200
+// @Success 303
+// @Failure 400
+// @Router /oauth/authorize [get]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var data dto.AuthorizationRequest
+	if err := c.ShouldBindQuery(&data); err != nil {
+		h.renderError(c, domain.ErrOAuthInvalidRequest)
+		return
+	}
+	client, ok := h.resolveRequest(c, &data)
+	if !ok {
+		return
+	}
+
+	csrfToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		h.renderError(c, domain.ErrOAuthServerError)
+		return
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(oauthCSRFCookie, csrfToken, int(oauthCSRFCookieMaxAge.Seconds()), "/oauth", "", isSecureRequest(c), true)
+
+	h.renderPage(c, http.StatusOK, client, &authorizePageData{Request: &data, CSRFToken: csrfToken})
+}
+
+// Decide handles POST /oauth/authorize request
+// @Summary Submit the login and consent form
+// @Description Authenticate the user, with the MFA step when enabled, and redirect to the client with a code or an error
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Success 200
+// @Success 303
+// @Failure 400
+// @Router /oauth/authorize [post]
+func (h *OAuthHandler) Decide(c *gin.Context) {
+	var data dto.AuthorizationDecision
+	if err := c.ShouldBind(&data); err != nil {
+		h.renderError(c, domain.ErrOAuthInvalidRequest)
+		return
+	}
+	csrfCookie, _ := c.Cookie(oauthCSRFCookie)
+	csrfToken := c.PostForm("csrf_token")
+	if csrfCookie == "" || subtle.ConstantTimeCompare([]byte(csrfCookie), []byte(csrfToken)) != 1 {
+		h.renderError(c, domain.ErrOAuthInvalidRequest)
+		return
+	}
+	client, ok := h.resolveRequest(c, &data.AuthorizationRequest)
+	if !ok {
+		return
+	}
+	if data.Action != "approve" {
+		c.Redirect(http.StatusSeeOther, h.oauthService.ErrorRedirectURL(&data.AuthorizationRequest, domain.ErrOAuthAccessDenied))
+		return
+	}
+
+	data.ClientIP = c.ClientIP()
+	data.UserAgent = c.Request.UserAgent()
+	page := &authorizePageData{Request: &data.AuthorizationRequest, CSRFToken: csrfToken, Username: data.Username}
+	ctx := c.Request.Context()
+
+	var user *userDomain.UserEntity
+	var err error
+	if data.MFAToken == "" {
+		user, err = h.authService.VerifyPassword(ctx, &dto.LoginRequest{
+			Username: data.Username,
+			Password: data.Password,
+			ClientIP: data.ClientIP,
+		})
+		if err == nil && user.MFAEnabled {
+			challenge, err := h.mfaService.CreateChallenge(ctx, user)
+			if err != nil {
+				h.renderPageError(c, client, page, err)
+				return
+			}
+			page.MFAToken = challenge.MFAToken
+			h.renderPage(c, http.StatusOK, client, page)
+			return
+		}
+	} else {
+		page.MFAToken = data.MFAToken
+		verify := &dto.MFAVerifyRequest{MFAToken: data.MFAToken, ClientIP: data.ClientIP}
+		// Authenticator codes are 6 digits, anything else is tried as a recovery code
+		if code := strings.TrimSpace(data.Code); len(code) == 6 && isDigits(code) {
+			verify.Code = code
+		} else {
+			verify.RecoveryCode = code
+		}
+		user, err = h.authService.VerifySecondFactor(ctx, verify)
+	}
+	if err != nil {
+		h.renderPageError(c, client, page, err)
+		return
+	}
+
+	device := dto.DeviceInfo{ClientIP: data.ClientIP, UserAgent: data.UserAgent}
+	redirectURL, err := h.oauthService.Authorize(ctx, client, &data.AuthorizationRequest, user, device)
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, h.oauthService.ErrorRedirectURL(&data.AuthorizationRequest, err))
+		return
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(oauthCSRFCookie, "", -1, "/oauth", "", isSecureRequest(c), true)
+	c.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+// Token handles POST /oauth/token request
+// @Summary Token endpoint
+// @Description Exchange an authorization code with its PKCE verifier, or a refresh token, for tokens
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Success 200 {object} domain.OAuthTokenEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var data dto.TokenRequest
+	if err := c.ShouldBind(&data); err != nil {
+		respondOAuthError(c, domain.ErrOAuthInvalidRequest)
+		return
+	}
+	// client_secret_basic, the credentials are form-encoded before being put in the header
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		if data.ClientID != "" || data.ClientSecret != "" {
+			respondOAuthError(c, domain.ErrOAuthInvalidRequest)
+			return
+		}
+		data.ClientID = queryUnescape(clientID)
+		data.ClientSecret = queryUnescape(clientSecret)
+	}
+
+	token, err := h.oauthService.Token(c.Request.Context(), &data)
+	if err != nil {
+		if err == domain.ErrOAuthInvalidClient {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		respondOAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+// Configuration handles GET /.well-known/openid-configuration request
+// @Summary OpenID Connect discovery
+// @Description Return the authorization server metadata
+// @Tags OAuth
+// @Produce json
+// @Success 200 {object} domain.OpenIDConfigurationEntity
+// @Router /.well-known/openid-configuration [get]
+func (h *OAuthHandler) Configuration(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.oauthService.Configuration())
+}
+
+// CreateClient handles POST /oauth/clients request
+// @Summary Register an OAuth client
+// @Description Register an application, the client secret of a confidential client is only returned once
+// @Tags OAuth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.CreateOAuthClientRequest true "Client"
+// @Success 201 {object} domain.OAuthClientEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /oauth/clients [post]
+func (h *OAuthHandler) CreateClient(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+
+	client, err := h.oauthService.RegisterClient(c.Request.Context(), principal, &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, client)
+}
+
+// ListClients handles GET /oauth/clients request
+// @Summary List OAuth clients
+// @Tags OAuth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.OAuthClientEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /oauth/clients [get]
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	clients, err := h.oauthService.ListClients(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, clients)
+}
+
+// resolveRequest validates the authorization request, errors about the client or the redirect uri are shown
+// on the page and the others are redirected to the client
+func (h *OAuthHandler) resolveRequest(c *gin.Context, data *dto.AuthorizationRequest) (*domain.OAuthClientEntity, bool) {
+	client, err := h.oauthService.ResolveClient(c.Request.Context(), data)
+	if err != nil {
+		h.renderError(c, err)
+		return nil, false
+	}
+	if err := h.oauthService.ValidateAuthorizationRequest(client, data); err != nil {
+		c.Redirect(http.StatusSeeOther, h.oauthService.ErrorRedirectURL(data, err))
+		return nil, false
+	}
+	return client, true
+}
+
+func (h *OAuthHandler) renderPageError(c *gin.Context, client *domain.OAuthClientEntity, page *authorizePageData, err error) {
+	ce, ok := err.(*utils.CustomError)
+	if !ok {
+		zap.L().Error("error authorizing oauth request", zap.String("client_id", client.ID), zap.Error(err))
+		ce = domain.ErrAuthInternalServerError
+	}
+	if ce.RetryAfter() > 0 {
+		c.Header("Retry-After", formatRetryAfter(ce.RetryAfter()))
+	}
+	page.Error = ce.Error()
+	h.renderPage(c, ce.HTTPStatus(), client, page)
+}
+
+func (h *OAuthHandler) renderPage(c *gin.Context, status int, client *domain.OAuthClientEntity, page *authorizePageData) {
+	page.ClientName = client.Name
+	page.Scopes = nil
+	for _, scope := range strings.Fields(page.Request.Scope) {
+		page.Scopes = append(page.Scopes, scopeDescriptions[scope])
+	}
+	setPageHeaders(c)
+	c.Status(status)
+	if err := authorizePage.Execute(c.Writer, page); err != nil {
+		zap.L().Error("error rendering authorize page", zap.Error(err))
+	}
+}
+
+func (h *OAuthHandler) renderError(c *gin.Context, err error) {
+	message := domain.ErrOAuthServerError.Error()
+	status := domain.ErrOAuthServerError.HTTPStatus()
+	if ce, ok := err.(*utils.CustomError); ok {
+		message = ce.Error()
+		status = ce.HTTPStatus()
+	}
+	setPageHeaders(c)
+	c.Status(status)
+	if err := authorizeErrorPage.Execute(c.Writer, message); err != nil {
+		zap.L().Error("error rendering authorize error page", zap.Error(err))
+	}
+}
+
+// setPageHeaders keeps the pages out of frames and caches, they handle passwords
+func setPageHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Header("Referrer-Policy", "no-referrer")
+}
+
+// respondOAuthError writes the error body defined by RFC 6749, {error, error_description}
+func respondOAuthError(c *gin.Context, err error) {
+	ce, ok := err.(*utils.CustomError)
+	if !ok {
+		zap.L().Error("oauth request failed", zap.Error(err))
+		ce = domain.ErrOAuthServerError
+	}
+	c.JSON(ce.HTTPStatus(), gin.H{
+		"error":             ce.Code(),
+		"error_description": ce.Error(),
+	})
+}
+
+// queryUnescape decodes a client credential of the basic authorization header, the raw value is kept when it is not encoded
+func queryUnescape(value string) string {
+	if unescaped, err := url.QueryUnescape(value); err == nil {
+		return unescaped
+	}
+	return value
+}
+
+func formatRetryAfter(retryAfter time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
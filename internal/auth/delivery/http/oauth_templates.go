@@ -0,0 +1,84 @@
+package http
+
+import (
+	"html/template"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+)
+
+// Pages of the authorization endpoint, rendered by the server since the client app must never see the password
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in to {{.ClientName}}</title>
+<style>
+body { font-family: system-ui, sans-serif; background: #f5f5f5; margin: 0; }
+main { max-width: 360px; margin: 64px auto; background: #fff; padding: 32px; border-radius: 8px; box-shadow: 0 1px 4px rgba(0,0,0,.1); }
+h1 { font-size: 20px; margin-top: 0; }
+label { display: block; margin: 12px 0 4px; font-size: 14px; }
+input[type=text], input[type=password] { width: 100%; box-sizing: border-box; padding: 8px; }
+ul { padding-left: 20px; font-size: 14px; }
+.error { color: #b00020; font-size: 14px; }
+.actions { display: flex; gap: 8px; margin-top: 20px; }
+button { flex: 1; padding: 10px; cursor: pointer; }
+</style>
+</head>
+<body>
+<main>
+<h1>Sign in to {{.ClientName}}</h1>
+{{if .Scopes}}<p>{{.ClientName}} will be able to:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="authorize">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+{{if .MFAToken}}<input type="hidden" name="mfa_token" value="{{.MFAToken}}">
+<label for="mfa_code">Authentication code</label>
+<input type="text" id="mfa_code" name="mfa_code" inputmode="numeric" autocomplete="one-time-code" autofocus required>
+{{else}}<label for="username">Username or email</label>
+<input type="text" id="username" name="username" value="{{.Username}}" autocomplete="username" autofocus required>
+<label for="password">Password</label>
+<input type="password" id="password" name="password" autocomplete="current-password" required>
+{{end}}<div class="actions">
+<button type="submit" name="action" value="deny" formnovalidate>Cancel</button>
+<button type="submit" name="action" value="approve">{{if .MFAToken}}Verify{{else}}Sign in and allow{{end}}</button>
+</div>
+</form>
+</main>
+</body>
+</html>
+`))
+
+var authorizeErrorPage = template.Must(template.New("authorize_error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Authorization error</title>
+</head>
+<body>
+<h1>Authorization error</h1>
+<p>{{.}}</p>
+</body>
+</html>
+`))
+
+// authorizePageData is rendered by authorizePage
+type authorizePageData struct {
+	ClientName string
+	Scopes     []string
+	Request    *dto.AuthorizationRequest
+	CSRFToken  string
+	Username   string
+	MFAToken   string
+	Error      string
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// HTTP handlers for login with external identity providers
+
+const (
+	// oidcStateCookie binds the login state to the browser that started the login
+	oidcStateCookie       = "oidc_state"
+	oidcStateCookieMaxAge = 10 * time.Minute
+)
+
+type OIDCHandler struct {
+	oidcService usecase.OIDCService
+}
+
+func NewOIDCHandler(oidcService usecase.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService}
+}
+
+// Providers handles GET /auth/oidc/providers request
+// @Summary List identity providers
+// @Description List the external identity providers available for login
+// @Tags OIDC
+// @Produce json
+// @Success 200 {array} domain.OIDCProviderEntity
+// @Router /auth/oidc/providers [get]
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, h.oidcService.Providers())
+}
+
+// Login handles GET /auth/oidc/:provider/login request
+// @Summary Login with an identity provider
+// @Description Redirect the browser to the identity provider to authenticate
+// @Tags OIDC
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authorizationURL, state, err := h.oidcService.StartLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Lax so the cookie comes back with the top-level redirect from the provider
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(oidcStateCookieMaxAge.Seconds()), "/", "", isSecureRequest(c), true)
+	c.Redirect(http.StatusFound, authorizationURL)
+}
+
+// Callback handles GET /auth/oidc/:provider/callback request
+// @Summary Identity provider callback
+// @Description Complete the login after the identity provider redirects back. Users with MFA enabled receive an MFA challenge instead of tokens.
+// @Tags OIDC
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string true "Login state"
+// @Success 201 {object} domain.JWTAuthEntity
+// @Success 200 {object} domain.MFAChallengeEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var data dto.OIDCCallbackRequest
+	if err := c.ShouldBindQuery(&data); err != nil {
+		respondError(c, domain.ErrOIDCStateInvalid)
+		return
+	}
+	data.BrowserState, _ = c.Cookie(oidcStateCookie)
+	data.ClientIP = c.ClientIP()
+	data.UserAgent = c.Request.UserAgent()
+
+	// The state is single use, drop the cookie whatever the outcome is
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/", "", isSecureRequest(c), true)
+
+	auth, challenge, err := h.oidcService.CompleteLogin(c.Request.Context(), c.Param("provider"), &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if challenge != nil {
+		utils.SuccessResponse(c, http.StatusOK, challenge)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, auth)
+}
+
+// isSecureRequest reports whether the client reached us over https, directly or through a proxy
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// HTTP handlers for password reset endpoints
+
+type PasswordHandler struct {
+	passwordResetService usecase.PasswordResetService
+}
+
+func NewPasswordHandler(passwordResetService usecase.PasswordResetService) *PasswordHandler {
+	return &PasswordHandler{passwordResetService: passwordResetService}
+}
+
+// ForgotPassword handles POST /auth/password/forgot request
+// @Summary Request a password reset
+// @Description Email a single-use reset link, the response is the same whether the email is registered or not
+// @Tags Password
+// @Accept json
+// @Produce json
+// @Param body body dto.ForgotPasswordRequest true "Forgot password request"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/password/forgot [post]
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var data dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+	data.ClientIP = c.ClientIP()
+
+	if err := h.passwordResetService.ForgotPassword(c.Request.Context(), &data); err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusAccepted, gin.H{
+		"message": "if the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword handles POST /auth/password/reset request
+// @Summary Reset password
+// @Description Set a new password with a reset token, every existing session of the user is ended
+// @Tags Password
+// @Accept json
+// @Produce json
+// @Param body body dto.ResetPasswordRequest true "Reset password request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/password/reset [post]
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var data dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.passwordResetService.ResetPassword(c.Request.Context(), &data); err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "password has been reset"})
+}
@@ -3,13 +3,147 @@ package http
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
 )
 
 // HTTP routes configuration
-func RegisterAuthRoutes(router *gin.RouterGroup, authService usecase.AuthService) {
+func RegisterAuthRoutes(router *gin.RouterGroup, authService usecase.AuthService, mfaService usecase.MFAService, passwordResetService usecase.PasswordResetService, oidcService usecase.OIDCService, authMiddleware gin.HandlerFunc) {
 	authHandler := NewAuthHandler(authService)
+	mfaHandler := NewMFAHandler(authService, mfaService)
+	passwordHandler := NewPasswordHandler(passwordResetService)
+	oidcHandler := NewOIDCHandler(oidcService)
 	auth := router.Group("/auth")
 	{
 		auth.POST("/login", authHandler.Login)
+		auth.POST("/refresh", authHandler.RefreshToken)
+		auth.POST("/logout", authMiddleware, authHandler.Logout)
+		auth.POST("/logout-all", authMiddleware, middleware.ForbidImpersonation(), authHandler.LogoutAll)
+	}
+	// Only the user may change their second factor, not an admin impersonating them
+	mfa := auth.Group("/mfa")
+	{
+		mfa.POST("/verify", mfaHandler.VerifyMFA)
+		mfa.POST("/totp/setup", authMiddleware, middleware.ForbidImpersonation(), mfaHandler.SetupTOTP)
+		mfa.POST("/totp/enable", authMiddleware, middleware.ForbidImpersonation(), mfaHandler.EnableTOTP)
+		mfa.POST("/totp/disable", authMiddleware, middleware.ForbidImpersonation(), mfaHandler.DisableTOTP)
+	}
+	password := auth.Group("/password")
+	{
+		password.POST("/forgot", passwordHandler.ForgotPassword)
+		password.POST("/reset", passwordHandler.ResetPassword)
+	}
+	oidc := auth.Group("/oidc")
+	{
+		oidc.GET("/providers", oidcHandler.Providers)
+		oidc.GET("/:provider/login", oidcHandler.Login)
+		oidc.GET("/:provider/callback", oidcHandler.Callback)
+	}
+}
+
+// RegisterWellKnownRoutes registers the discovery endpoints served from the root of the host
+func RegisterWellKnownRoutes(router *gin.Engine, authService usecase.AuthService) {
+	authHandler := NewAuthHandler(authService)
+	wellKnown := router.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", authHandler.JWKS)
+	}
+}
+
+// RegisterOAuthRoutes registers the authorization server, its endpoints live at the root of the host next to the discovery document
+func RegisterOAuthRoutes(router *gin.Engine, api *gin.RouterGroup, oauthService usecase.OAuthService, authService usecase.AuthService, mfaService usecase.MFAService, authMiddleware gin.HandlerFunc) {
+	oauthHandler := NewOAuthHandler(oauthService, authService, mfaService)
+	oauth := router.Group("/oauth")
+	{
+		oauth.GET("/authorize", oauthHandler.Authorize)
+		oauth.POST("/authorize", oauthHandler.Decide)
+		oauth.POST("/token", oauthHandler.Token)
+	}
+	router.GET("/.well-known/openid-configuration", oauthHandler.Configuration)
+
+	clients := api.Group("/oauth/clients", authMiddleware, middleware.RequireRole(shared.RoleAdmin), middleware.RequirePermission(shared.PermissionOAuthClientsManage))
+	{
+		clients.POST("", oauthHandler.CreateClient)
+		clients.GET("", oauthHandler.ListClients)
+	}
+}
+
+// RegisterUserInfoRoutes registers the userinfo endpoint next to the authorization server endpoints.
+// It is served even when the authorization server is disabled, access tokens of our own login read the profile there.
+func RegisterUserInfoRoutes(router *gin.Engine, userInfoService usecase.UserInfoService, authMiddleware gin.HandlerFunc) {
+	userInfoHandler := NewUserInfoHandler(userInfoService)
+	oauth := router.Group("/oauth")
+	{
+		oauth.GET("/userinfo", authMiddleware, userInfoHandler.UserInfo)
+		oauth.POST("/userinfo", authMiddleware, userInfoHandler.UserInfo)
+	}
+}
+
+// RegisterTokenIntrospectionRoutes registers token introspection and revocation next to the authorization server endpoints.
+// They are served even when the authorization server is disabled, service accounts can call them.
+func RegisterTokenIntrospectionRoutes(router *gin.Engine, tokenIntrospectionService usecase.TokenIntrospectionService) {
+	tokenIntrospectionHandler := NewTokenIntrospectionHandler(tokenIntrospectionService)
+	oauth := router.Group("/oauth")
+	{
+		oauth.POST("/introspect", tokenIntrospectionHandler.Introspect)
+		oauth.POST("/revoke", tokenIntrospectionHandler.Revoke)
+	}
+}
+
+// RegisterServiceAccountRoutes registers the client credentials token endpoint and the service account management,
+// which needs the admin role and the service_accounts:manage permission
+func RegisterServiceAccountRoutes(router *gin.RouterGroup, serviceAccountService usecase.ServiceAccountService, authMiddleware gin.HandlerFunc) {
+	serviceAccountHandler := NewServiceAccountHandler(serviceAccountService)
+	router.POST("/auth/token", serviceAccountHandler.Token)
+
+	accounts := router.Group("/auth/service-accounts", authMiddleware, middleware.RequireRole(shared.RoleAdmin), middleware.RequirePermission(shared.PermissionServiceAccountsManage))
+	{
+		accounts.POST("", serviceAccountHandler.CreateServiceAccount)
+		accounts.GET("", serviceAccountHandler.ListServiceAccounts)
+		accounts.GET("/:id", serviceAccountHandler.GetServiceAccount)
+		accounts.DELETE("/:id", serviceAccountHandler.DeleteServiceAccount)
+		accounts.POST("/:id/secrets", serviceAccountHandler.RotateSecret)
+		accounts.DELETE("/:id/secrets/:secretId", serviceAccountHandler.RevokeSecret)
+	}
+}
+
+// RegisterSessionRoutes registers the session management of the current user,
+// it needs a login session so authMiddleware must not accept API keys
+func RegisterSessionRoutes(router *gin.RouterGroup, sessionService usecase.SessionService, authMiddleware gin.HandlerFunc) {
+	sessionHandler := NewSessionHandler(sessionService)
+	sessions := router.Group("/users/me/sessions", authMiddleware)
+	{
+		sessions.GET("", sessionHandler.ListSessions)
+		sessions.DELETE("/:id", sessionHandler.RevokeSession)
+	}
+}
+
+// RegisterAPIKeyRoutes registers the personal API key management of the current user,
+// it needs a login session so authMiddleware must not accept API keys
+func RegisterAPIKeyRoutes(router *gin.RouterGroup, apiKeyService usecase.APIKeyService, authMiddleware gin.HandlerFunc) {
+	apiKeyHandler := NewAPIKeyHandler(apiKeyService)
+	apiKeys := router.Group("/users/me/api-keys", authMiddleware)
+	{
+		apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+		apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+		apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+	}
+}
+
+// RegisterImpersonationRoutes registers admin impersonation and the audit trail, they need the admin role and the
+// users:impersonate and audit:read permissions, which only super admins have unless granted by a custom role.
+// Stopping only needs the impersonation token. userPolicy enforces the access policies on the impersonated user.
+func RegisterImpersonationRoutes(router *gin.RouterGroup, impersonationService usecase.ImpersonationService, auditService usecase.AuditService, authMiddleware gin.HandlerFunc, userPolicy func(action, idParam string) gin.HandlerFunc) {
+	impersonationHandler := NewImpersonationHandler(impersonationService, auditService)
+	impersonate := router.Group("/auth/impersonate", authMiddleware)
+	{
+		impersonate.POST("/stop", impersonationHandler.StopImpersonation)
+		impersonate.POST("/:userId", middleware.ForbidImpersonation(), middleware.RequireRole(shared.RoleAdmin), middleware.RequirePermission(shared.PermissionUsersImpersonate),
+			userPolicy(string(shared.PermissionUsersImpersonate), "userId"), impersonationHandler.Impersonate)
+	}
+
+	auditEvents := router.Group("/auth/audit-events", authMiddleware, middleware.ForbidImpersonation(), middleware.RequireRole(shared.RoleAdmin), middleware.RequirePermission(shared.PermissionAuditRead))
+	{
+		auditEvents.GET("", impersonationHandler.ListAuditEvents)
 	}
 }
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// HTTP handlers for service accounts and the client credentials grant
+
+type ServiceAccountHandler struct {
+	serviceAccountService usecase.ServiceAccountService
+}
+
+func NewServiceAccountHandler(serviceAccountService usecase.ServiceAccountService) *ServiceAccountHandler {
+	return &ServiceAccountHandler{serviceAccountService: serviceAccountService}
+}
+
+// Token handles POST /auth/token request
+// @Summary Client credentials token
+// @Description Exchange the client id and secret of a service account for a scoped access token (RFC 6749 section 4.4). Credentials are sent with HTTP basic authentication or in the form.
+// @Tags Service accounts
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Must be client_credentials"
+// @Param scope formData string false "Space separated subset of the service account scopes"
+// @Success 200 {object} domain.OAuthTokenEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/token [post]
+func (h *ServiceAccountHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var data dto.TokenRequest
+	if err := c.ShouldBind(&data); err != nil {
+		respondOAuthError(c, domain.ErrOAuthInvalidRequest)
+		return
+	}
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		if data.ClientID != "" || data.ClientSecret != "" {
+			respondOAuthError(c, domain.ErrOAuthInvalidRequest)
+			return
+		}
+		data.ClientID = queryUnescape(clientID)
+		data.ClientSecret = queryUnescape(clientSecret)
+	}
+
+	token, err := h.serviceAccountService.Token(c.Request.Context(), &data)
+	if err != nil {
+		if err == domain.ErrOAuthInvalidClient {
+			c.Header("WWW-Authenticate", `Basic realm="service-accounts"`)
+		}
+		respondOAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+// CreateServiceAccount handles POST /auth/service-accounts request
+// @Summary Create a service account
+// @Description Create a machine identity with its scopes, the client secret is only returned once
+// @Tags Service accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.CreateServiceAccountRequest true "Service account"
+// @Success 201 {object} domain.ServiceAccountCredentialsEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /auth/service-accounts [post]
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+		return
+	}
+
+	credentials, err := h.serviceAccountService.CreateServiceAccount(c.Request.Context(), principal, &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, credentials)
+}
+
+// ListServiceAccounts handles GET /auth/service-accounts request
+// @Summary List service accounts
+// @Tags Service accounts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.ServiceAccountEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /auth/service-accounts [get]
+func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
+	accounts, err := h.serviceAccountService.ListServiceAccounts(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, accounts)
+}
+
+// GetServiceAccount handles GET /auth/service-accounts/:id request
+// @Summary Get a service account
+// @Tags Service accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Client id"
+// @Success 200 {object} domain.ServiceAccountEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /auth/service-accounts/{id} [get]
+func (h *ServiceAccountHandler) GetServiceAccount(c *gin.Context) {
+	account, err := h.serviceAccountService.GetServiceAccount(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, account)
+}
+
+// RotateSecret handles POST /auth/service-accounts/:id/secrets request
+// @Summary Rotate the secret of a service account
+// @Description Issue a new secret, the current secrets keep working during the overlap window
+// @Tags Service accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Client id"
+// @Param body body dto.RotateServiceAccountSecretRequest false "Overlap window"
+// @Success 201 {object} domain.ServiceAccountCredentialsEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /auth/service-accounts/{id}/secrets [post]
+func (h *ServiceAccountHandler) RotateSecret(c *gin.Context) {
+	var data dto.RotateServiceAccountSecretRequest
+	// The body is optional, the default overlap window applies without it
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&data); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "AUTH_INVALID_INPUT", err.Error())
+			return
+		}
+	}
+
+	credentials, err := h.serviceAccountService.RotateSecret(c.Request.Context(), c.Param("id"), &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, credentials)
+}
+
+// RevokeSecret handles DELETE /auth/service-accounts/:id/secrets/:secretId request
+// @Summary Revoke a secret of a service account
+// @Description End the overlap window of a secret early, tokens already issued with it stay valid until they expire
+// @Tags Service accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Client id"
+// @Param secretId path string true "Secret id"
+// @Success 200 {object} domain.ServiceAccountEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/service-accounts/{id}/secrets/{secretId} [delete]
+func (h *ServiceAccountHandler) RevokeSecret(c *gin.Context) {
+	account, err := h.serviceAccountService.RevokeSecret(c.Request.Context(), c.Param("id"), c.Param("secretId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, account)
+}
+
+// DeleteServiceAccount handles DELETE /auth/service-accounts/:id request
+// @Summary Delete a service account
+// @Description Delete the account, its secrets and tokens stop working immediately
+// @Tags Service accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Client id"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /auth/service-accounts/{id} [delete]
+func (h *ServiceAccountHandler) DeleteServiceAccount(c *gin.Context) {
+	if err := h.serviceAccountService.DeleteServiceAccount(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, nil)
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// HTTP handlers for the sessions (signed in devices) of the current user
+
+type SessionHandler struct {
+	sessionService usecase.SessionService
+}
+
+func NewSessionHandler(sessionService usecase.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// ListSessions handles GET /users/me/sessions request
+// @Summary List sessions
+// @Description List the devices the current user is signed in on, with their IP and last activity
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.SessionEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /users/me/sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+
+	sessions, err := h.sessionService.ListSessions(c.Request.Context(), principal)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE /users/me/sessions/:id request
+// @Summary Revoke a session
+// @Description Sign a device out, its refresh token and access tokens stop working immediately
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session id"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(c.Request.Context(), principal, c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, nil)
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+)
+
+// HTTP handlers for token introspection (RFC 7662) and revocation (RFC 7009)
+
+type TokenIntrospectionHandler struct {
+	tokenIntrospectionService usecase.TokenIntrospectionService
+}
+
+func NewTokenIntrospectionHandler(tokenIntrospectionService usecase.TokenIntrospectionService) *TokenIntrospectionHandler {
+	return &TokenIntrospectionHandler{tokenIntrospectionService: tokenIntrospectionService}
+}
+
+// Introspect handles POST /oauth/introspect request
+// @Summary Token introspection
+// @Description Tell whether an access or refresh token is active (RFC 7662). The caller authenticates with the credentials of a confidential OAuth client or a service account, with HTTP basic authentication or in the form. Service accounts with the tokens:introspect scope see every token, other clients only the tokens issued to them.
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 {object} domain.TokenIntrospectionEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/introspect [post]
+func (h *TokenIntrospectionHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	data, ok := bindTokenIntrospectionRequest(c)
+	if !ok {
+		return
+	}
+	introspection, err := h.tokenIntrospectionService.Introspect(c.Request.Context(), data)
+	if err != nil {
+		respondTokenIntrospectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, introspection)
+}
+
+// Revoke handles POST /oauth/revoke request
+// @Summary Token revocation
+// @Description Revoke an access token, or a refresh token with the session it belongs to (RFC 7009). Authenticated like introspection, public OAuth clients send their client id only. Invalid and unknown tokens are ignored.
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/revoke [post]
+func (h *TokenIntrospectionHandler) Revoke(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	data, ok := bindTokenIntrospectionRequest(c)
+	if !ok {
+		return
+	}
+	if err := h.tokenIntrospectionService.Revoke(c.Request.Context(), data); err != nil {
+		respondTokenIntrospectionError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// bindTokenIntrospectionRequest reads the form and the client credentials, sent with basic authentication or in the form
+func bindTokenIntrospectionRequest(c *gin.Context) (*dto.TokenIntrospectionRequest, bool) {
+	var data dto.TokenIntrospectionRequest
+	if err := c.ShouldBind(&data); err != nil {
+		respondOAuthError(c, domain.ErrOAuthInvalidRequest)
+		return nil, false
+	}
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		if data.ClientID != "" || data.ClientSecret != "" {
+			respondOAuthError(c, domain.ErrOAuthInvalidRequest)
+			return nil, false
+		}
+		data.ClientID = queryUnescape(clientID)
+		data.ClientSecret = queryUnescape(clientSecret)
+	}
+	return &data, true
+}
+
+func respondTokenIntrospectionError(c *gin.Context, err error) {
+	if err == domain.ErrOAuthInvalidClient {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	respondOAuthError(c, err)
+}
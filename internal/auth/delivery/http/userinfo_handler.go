@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+)
+
+// HTTP handler of the OpenID Connect userinfo endpoint
+
+type UserInfoHandler struct {
+	userInfoService usecase.UserInfoService
+}
+
+func NewUserInfoHandler(userInfoService usecase.UserInfoService) *UserInfoHandler {
+	return &UserInfoHandler{userInfoService: userInfoService}
+}
+
+// UserInfo handles GET and POST /oauth/userinfo request
+// @Summary UserInfo endpoint
+// @Description Return the profile of the user the access token was issued to. Tokens of OAuth clients require the openid scope and only get the claims of their scopes.
+// @Tags OAuth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.UserInfoEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /oauth/userinfo [get]
+func (h *UserInfoHandler) UserInfo(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+
+	userInfo, err := h.userInfoService.UserInfo(c.Request.Context(), principal)
+	if err != nil {
+		if err == domain.ErrOAuthInsufficientScope {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		}
+		respondOAuthError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, userInfo)
+}
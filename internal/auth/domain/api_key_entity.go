@@ -0,0 +1,31 @@
+package domain
+
+// Personal API key entities: long-lived credentials for scripts, acting as the user who created them
+
+// APIKeyEntity is a personal API key, only the hash of the key and its visible prefix are stored
+type APIKeyEntity struct {
+	ID     string   `bson:"_id" json:"id"`
+	UserID string   `bson:"user_id" json:"user_id"`
+	Name   string   `bson:"name" json:"name"`
+	Prefix string   `bson:"prefix" json:"prefix"` // First characters of the key, shown so users can recognise it
+	Hash   string   `bson:"hash" json:"-"`        // SHA-256 of the key
+	Scopes []string `bson:"scopes" json:"scopes"`
+	// TokenVersion is the user's token version at creation, logging out everywhere or resetting the password disables the key
+	TokenVersion int64  `bson:"token_version" json:"-"`
+	ExpiresAt    int64  `bson:"expires_at" json:"expires_at"`
+	LastUsedAt   int64  `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	LastUsedIP   string `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+	RevokedAt    int64  `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt    int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// IsActive reports whether the key can still authenticate at the given time, in unix milliseconds
+func (k *APIKeyEntity) IsActive(now int64) bool {
+	return k.RevokedAt == 0 && k.ExpiresAt > now
+}
+
+// CreatedAPIKeyEntity is returned when a key is created, the plaintext key is never shown again
+type CreatedAPIKeyEntity struct {
+	APIKey *APIKeyEntity `json:"api_key"`
+	Key    string        `json:"key"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+// Audit trail entities: security relevant actions, kept apart from the application logs
+
+// Audit event types
+const (
+	AuditEventImpersonationStarted = "impersonation.started"
+	AuditEventImpersonationStopped = "impersonation.stopped"
+	// AuditEventImpersonatedRequest is recorded for every request made with an impersonation token
+	AuditEventImpersonatedRequest = "impersonation.request"
+)
+
+// AuditEventEntity records who did what to which user. ActorID is the one acting,
+// UserID the user acted upon; they differ while a super admin impersonates a user.
+type AuditEventEntity struct {
+	ID            string `bson:"_id" json:"id"`
+	Type          string `bson:"type" json:"type"`
+	ActorID       string `bson:"actor_id" json:"actor_id"`
+	ActorUsername string `bson:"actor_username,omitempty" json:"actor_username,omitempty"`
+	UserID        string `bson:"user_id" json:"user_id"`
+	Username      string `bson:"username,omitempty" json:"username,omitempty"`
+	TokenID       string `bson:"token_id,omitempty" json:"token_id,omitempty"` // Access token the action was made with
+	Reason        string `bson:"reason,omitempty" json:"reason,omitempty"`
+	// Method, Path and Status describe the request of an impersonation.request event
+	Method    string `bson:"method,omitempty" json:"method,omitempty"`
+	Path      string `bson:"path,omitempty" json:"path,omitempty"`
+	Status    int    `bson:"status,omitempty" json:"status,omitempty"`
+	IP        string `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent string `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	CreatedAt int64  `bson:"created_at" json:"created_at"`
+}
@@ -6,4 +6,151 @@ type JWTAuthEntity struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 	ExpiredIn    int64  `json:"expired_in" binding:"required"`
 	TokenType    string `json:"token_type" binding:"required"`
+	Scope        string `json:"scope,omitempty"` // Granted scope, only for tokens issued to OAuth clients
+}
+
+// RefreshTokenFamilyEntity tracks the chain of refresh tokens rotated from a single login.
+// Only the latest token (CurrentJTI) of a family can be exchanged; presenting an older one
+// means the token was replayed and the whole family gets revoked.
+type RefreshTokenFamilyEntity struct {
+	ID         string `bson:"_id" json:"id"`
+	UserID     string `bson:"user_id" json:"user_id"`
+	ClientID   string `bson:"client_id,omitempty" json:"client_id,omitempty"` // OAuth client the family was issued to
+	Scope      string `bson:"scope,omitempty" json:"scope,omitempty"`
+	SessionID  string `bson:"session_id,omitempty" json:"session_id,omitempty"` // Session started with the family
+	CurrentJTI string `bson:"current_jti" json:"-"`
+	Revoked    bool   `bson:"revoked" json:"revoked"`
+	RevokedAt  int64  `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ExpiresAt  int64  `bson:"expires_at" json:"expires_at"`
+	CreatedAt  int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt  int64  `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// SessionEntity records a login and the device it came from, it lives as long as its refresh token family.
+// Revoking the family ends the session, and revoking the session revokes the family.
+type SessionEntity struct {
+	ID         string `bson:"_id" json:"id"`
+	UserID     string `bson:"user_id" json:"-"`
+	FamilyID   string `bson:"family_id" json:"-"`
+	ClientID   string `bson:"client_id,omitempty" json:"client_id,omitempty"` // OAuth client the session was started for
+	UserAgent  string `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	Device     string `bson:"device" json:"device"` // Readable summary of the user agent, e.g. "Chrome on macOS"
+	IP         string `bson:"ip,omitempty" json:"ip,omitempty"`
+	LastSeenIP string `bson:"last_seen_ip,omitempty" json:"last_seen_ip,omitempty"`
+	LastSeenAt int64  `bson:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt  int64  `bson:"expires_at" json:"expires_at"`
+	RevokedAt  int64  `bson:"revoked_at" json:"-"`
+	CreatedAt  int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	Current    bool   `bson:"-" json:"current"` // Set when listing, the session of the request
+}
+
+// IsActive reports whether the session is still usable at the given time, in unix milliseconds
+func (s *SessionEntity) IsActive(now int64) bool {
+	return s.RevokedAt == 0 && s.ExpiresAt > now
+}
+
+// IssuedTokenEntity records an access token (by its jti) issued to a user
+type IssuedTokenEntity struct {
+	ID        string `bson:"_id" json:"id"` // jti
+	UserID    string `bson:"user_id" json:"user_id"`
+	FamilyID  string `bson:"family_id,omitempty" json:"family_id,omitempty"`
+	ExpiresAt int64  `bson:"expires_at" json:"expires_at"`
+	CreatedAt int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// RevokedTokenEntity is an entry of the token denylist, it only needs to live until the token expires
+type RevokedTokenEntity struct {
+	ID        string `bson:"_id" json:"id"` // jti
+	UserID    string `bson:"user_id" json:"user_id"`
+	ExpiresAt int64  `bson:"expires_at" json:"expires_at"`
+	RevokedAt int64  `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// UserTokenVersionEntity holds the per-user token version.
+// Access tokens carry the version they were minted with, bumping it invalidates all of them at once.
+type UserTokenVersionEntity struct {
+	UserID    string `bson:"_id" json:"user_id"`
+	Version   int64  `bson:"version" json:"version"`
+	UpdatedAt int64  `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// JWKEntity is a public signing key in JSON Web Key format (RFC 7517)
+type JWKEntity struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // EC / OKP curve
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKSEntity is the JSON Web Key Set published at /.well-known/jwks.json
+type JWKSEntity struct {
+	Keys []JWKEntity `json:"keys"`
+}
+
+// MFAChallengeEntity is returned by login instead of tokens when the user has MFA enabled
+type MFAChallengeEntity struct {
+	MFARequired bool     `json:"mfa_required"`
+	MFAToken    string   `json:"mfa_token"`
+	ExpiredIn   int64    `json:"expired_in"`
+	Methods     []string `json:"methods"`
+}
+
+// PendingMFAChallengeEntity is an MFA challenge waiting for its second factor, looked up by the jti of the MFA token.
+// It is deleted by the first successful verification so one challenge starts one session.
+type PendingMFAChallengeEntity struct {
+	ID        string `bson:"_id" json:"-"` // jti of the MFA token
+	UserID    string `bson:"user_id" json:"user_id"`
+	ExpiresAt int64  `bson:"expires_at" json:"expires_at"`
+	CreatedAt int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// TOTPSetupEntity holds the secret to enroll in an authenticator app
+type TOTPSetupEntity struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesEntity holds the plaintext recovery codes, they are only shown once
+type RecoveryCodesEntity struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// PasswordResetTokenEntity is a single-use password reset token, only the hash of the token is stored
+type PasswordResetTokenEntity struct {
+	ID        string `bson:"_id" json:"-"` // SHA-256 of the token
+	UserID    string `bson:"user_id" json:"user_id"`
+	ExpiresAt int64  `bson:"expires_at" json:"expires_at"`
+	UsedAt    int64  `bson:"used_at" json:"used_at"` // 0 while unused
+	CreatedAt int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// ReferenceTokenEntity is the signed access token an opaque reference token stands for.
+// It is looked up by the hash of the reference token, which is never stored.
+type ReferenceTokenEntity struct {
+	ID        string `bson:"_id" json:"-"` // SHA-256 of the reference token
+	Token     string `bson:"token" json:"-"`
+	ExpiresAt int64  `bson:"expires_at" json:"expires_at"`
+	CreatedAt int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// OIDCLoginStateEntity is the pending state of an external login, kept until the provider redirects back.
+// It is looked up by the hash of the state parameter and can be used only once.
+type OIDCLoginStateEntity struct {
+	ID           string `bson:"_id" json:"-"` // SHA-256 of the state
+	Provider     string `bson:"provider" json:"provider"`
+	Nonce        string `bson:"nonce" json:"-"`
+	CodeVerifier string `bson:"code_verifier" json:"-"` // PKCE verifier, sent with the code exchange
+	ExpiresAt    int64  `bson:"expires_at" json:"expires_at"`
+	CreatedAt    int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// OIDCProviderEntity describes an external identity provider available for login
+type OIDCProviderEntity struct {
+	Name   string `json:"name"`
+	Issuer string `json:"issuer"`
 }
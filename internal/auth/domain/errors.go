@@ -16,6 +16,10 @@ var (
 		http.StatusUnauthorized,
 		"jwt token expired",
 	)
+	ErrJWTTokenRevoked = utils.NewCustomError("JWT_TOKEN_REVOKED",
+		http.StatusUnauthorized,
+		"jwt token has been revoked",
+	)
 	ErrJWTRefreshTokenInvalid = utils.NewCustomError("JWT_REFRESH_TOKEN_INVALID",
 		http.StatusUnauthorized,
 		"invalid jwt refresh token",
@@ -25,8 +29,214 @@ var (
 		"jwt refresh token expired",
 	)
 
+	ErrPasswordResetTokenInvalid = utils.NewCustomError("PASSWORD_RESET_TOKEN_INVALID",
+		http.StatusBadRequest,
+		"invalid or expired password reset token",
+	)
+
+	// MFA errors
+	ErrMFATokenInvalid = utils.NewCustomError("MFA_TOKEN_INVALID",
+		http.StatusUnauthorized,
+		"invalid mfa token",
+	)
+	ErrMFATokenExpired = utils.NewCustomError("MFA_TOKEN_EXPIRED",
+		http.StatusUnauthorized,
+		"mfa token expired",
+	)
+	ErrMFAInvalidCode = utils.NewCustomError("MFA_INVALID_CODE",
+		http.StatusUnauthorized,
+		"invalid mfa code",
+	)
+	ErrMFAAlreadyEnabled = utils.NewCustomError("MFA_ALREADY_ENABLED",
+		http.StatusConflict,
+		"mfa is already enabled",
+	)
+	ErrMFANotEnabled = utils.NewCustomError("MFA_NOT_ENABLED",
+		http.StatusBadRequest,
+		"mfa is not enabled",
+	)
+	ErrMFASetupRequired = utils.NewCustomError("MFA_SETUP_REQUIRED",
+		http.StatusBadRequest,
+		"totp setup must be started before enabling mfa",
+	)
+
+	// External identity provider errors
+	ErrOIDCProviderNotFound = utils.NewCustomError("OIDC_PROVIDER_NOT_FOUND",
+		http.StatusNotFound,
+		"identity provider not found",
+	)
+	ErrOIDCStateInvalid = utils.NewCustomError("OIDC_STATE_INVALID",
+		http.StatusBadRequest,
+		"invalid or expired login state",
+	)
+	ErrOIDCProviderUnavailable = utils.NewCustomError("OIDC_PROVIDER_UNAVAILABLE",
+		http.StatusBadGateway,
+		"identity provider is unavailable",
+	)
+	ErrOIDCLoginCancelled = utils.NewCustomError("OIDC_LOGIN_CANCELLED",
+		http.StatusUnauthorized,
+		"login was cancelled or denied at the identity provider",
+	)
+	ErrOIDCCodeExchangeFailed = utils.NewCustomError("OIDC_CODE_EXCHANGE_FAILED",
+		http.StatusUnauthorized,
+		"identity provider rejected the authorization code",
+	)
+	ErrOIDCIDTokenInvalid = utils.NewCustomError("OIDC_ID_TOKEN_INVALID",
+		http.StatusUnauthorized,
+		"invalid id token",
+	)
+	ErrOIDCEmailNotVerified = utils.NewCustomError("OIDC_EMAIL_NOT_VERIFIED",
+		http.StatusForbidden,
+		"identity provider did not return a verified email",
+	)
+
+	// OAuth client management errors
+	ErrOAuthClientNotFound = utils.NewCustomError("OAUTH_CLIENT_NOT_FOUND",
+		http.StatusNotFound,
+		"oauth client not found",
+	)
+	ErrOAuthClientInvalidRedirectURI = utils.NewCustomError("OAUTH_CLIENT_INVALID_REDIRECT_URI",
+		http.StatusBadRequest,
+		"redirect uris must be absolute, without fragment, and use https unless they point to this machine",
+	)
+
+	// OAuth protocol errors, the code is the error code of RFC 6749 returned to clients as is
+	ErrOAuthInvalidRequest = utils.NewCustomError("invalid_request",
+		http.StatusBadRequest,
+		"the request is missing a parameter or is malformed",
+	)
+	ErrOAuthInvalidClient = utils.NewCustomError("invalid_client",
+		http.StatusUnauthorized,
+		"client authentication failed",
+	)
+	ErrOAuthInvalidRedirectURI = utils.NewCustomError("invalid_request",
+		http.StatusBadRequest,
+		"the redirect_uri is not registered for this client",
+	)
+	ErrOAuthInvalidGrant = utils.NewCustomError("invalid_grant",
+		http.StatusBadRequest,
+		"the authorization grant is invalid, expired or was issued to another client",
+	)
+	ErrOAuthUnsupportedGrantType = utils.NewCustomError("unsupported_grant_type",
+		http.StatusBadRequest,
+		"the grant type is not supported",
+	)
+	ErrOAuthUnsupportedResponseType = utils.NewCustomError("unsupported_response_type",
+		http.StatusBadRequest,
+		"only the code response type is supported",
+	)
+	ErrOAuthPKCERequired = utils.NewCustomError("invalid_request",
+		http.StatusBadRequest,
+		"pkce with the S256 method is required",
+	)
+	ErrOAuthInvalidScope = utils.NewCustomError("invalid_scope",
+		http.StatusBadRequest,
+		"the requested scope is invalid or not allowed for this client",
+	)
+	ErrOAuthAccessDenied = utils.NewCustomError("access_denied",
+		http.StatusForbidden,
+		"the user denied the request",
+	)
+	ErrOAuthInsufficientScope = utils.NewCustomError("insufficient_scope",
+		http.StatusForbidden,
+		"the access token was not granted the openid scope",
+	)
+	ErrUserInfoUnavailable = utils.NewCustomError("insufficient_scope",
+		http.StatusForbidden,
+		"the access token was not issued to a user",
+	)
+	ErrOAuthServerError = utils.NewCustomError("server_error",
+		http.StatusInternalServerError,
+		"the authorization server encountered an error",
+	)
+
+	// Service account errors
+	ErrServiceAccountNotFound = utils.NewCustomError("SERVICE_ACCOUNT_NOT_FOUND",
+		http.StatusNotFound,
+		"service account not found",
+	)
+	ErrServiceAccountSecretNotFound = utils.NewCustomError("SERVICE_ACCOUNT_SECRET_NOT_FOUND",
+		http.StatusNotFound,
+		"service account secret not found",
+	)
+	ErrServiceAccountInvalidScope = utils.NewCustomError("SERVICE_ACCOUNT_INVALID_SCOPE",
+		http.StatusBadRequest,
+		"the scope cannot be granted to a service account",
+	)
+	ErrServiceAccountLastSecret = utils.NewCustomError("SERVICE_ACCOUNT_LAST_SECRET",
+		http.StatusConflict,
+		"the only active secret cannot be revoked, rotate it first or delete the service account",
+	)
+
+	// API key errors
+	// Unknown, expired and revoked keys get the same error
+	ErrAPIKeyInvalid = utils.NewCustomError("AUTH_API_KEY_INVALID",
+		http.StatusUnauthorized,
+		"invalid or expired api key",
+	)
+	ErrAPIKeyNotFound = utils.NewCustomError("AUTH_API_KEY_NOT_FOUND",
+		http.StatusNotFound,
+		"api key not found",
+	)
+	ErrAPIKeyInvalidScope = utils.NewCustomError("AUTH_API_KEY_INVALID_SCOPE",
+		http.StatusBadRequest,
+		"the scope cannot be granted to an api key",
+	)
+	ErrAPIKeyManagementForbidden = utils.NewCustomError("AUTH_API_KEY_MANAGEMENT_FORBIDDEN",
+		http.StatusForbidden,
+		"api keys can only be managed from a login session",
+	)
+	ErrAPIKeyLimitReached = utils.NewCustomError("AUTH_API_KEY_LIMIT_REACHED",
+		http.StatusConflict,
+		"too many active api keys, revoke one first",
+	)
+
+	// Session errors
+	ErrSessionNotFound = utils.NewCustomError("AUTH_SESSION_NOT_FOUND",
+		http.StatusNotFound,
+		"session not found",
+	)
+	ErrSessionManagementForbidden = utils.NewCustomError("AUTH_SESSION_MANAGEMENT_FORBIDDEN",
+		http.StatusForbidden,
+		"sessions can only be managed from a login session",
+	)
+
+	// Impersonation errors
+	ErrImpersonationNotAllowed = utils.NewCustomError("AUTH_IMPERSONATION_NOT_ALLOWED",
+		http.StatusForbidden,
+		"impersonation needs the login session of a user granted users:impersonate",
+	)
+	ErrImpersonationTargetForbidden = utils.NewCustomError("AUTH_IMPERSONATION_TARGET_FORBIDDEN",
+		http.StatusForbidden,
+		"super admins, yourself and users with permissions you do not have cannot be impersonated",
+	)
+	ErrNotImpersonating = utils.NewCustomError("AUTH_NOT_IMPERSONATING",
+		http.StatusBadRequest,
+		"the access token is not an impersonation token",
+	)
+
+	// Brute-force protection errors, returned with a Retry-After duration
+	ErrAuthTooManyAttempts = utils.NewCustomError("AUTH_TOO_MANY_ATTEMPTS",
+		http.StatusTooManyRequests,
+		"too many failed login attempts, try again later",
+	)
+	ErrAuthAccountTemporarilyLocked = utils.NewCustomError("AUTH_ACCOUNT_TEMPORARILY_LOCKED",
+		http.StatusLocked,
+		"login is temporarily locked after too many failed attempts",
+	)
+	ErrAuthTooManyEmailRequests = utils.NewCustomError("AUTH_TOO_MANY_EMAIL_REQUESTS",
+		http.StatusTooManyRequests,
+		"too many emails requested, try again later",
+	)
+
+	// Forbidden errors
+	ErrAuthEmailNotVerified = utils.NewCustomError("AUTH_EMAIL_NOT_VERIFIED",
+		http.StatusForbidden,
+		"email must be verified before logging in",
+	)
+
 	// Not found errors
-	ErrAuthUserNotFound = utils.NewCustomError("AUTH_USER_NOT_FOUND", http.StatusNotFound, "user not found")
+	ErrAuthTokenNotFound = utils.NewCustomError("AUTH_TOKEN_NOT_FOUND", http.StatusNotFound, "token not found")
 
 	// Internal server errors
 	ErrAuthInternalServerError = utils.NewCustomError("AUTH_INTERNAL_SERVER_ERROR",
@@ -41,7 +251,12 @@ var (
 		http.StatusInternalServerError,
 		"failed to sign refresh token",
 	)
+	ErrIDTokenSigningUnavailable = utils.NewCustomError("ID_TOKEN_SIGNING_UNAVAILABLE",
+		http.StatusInternalServerError,
+		"id tokens need asymmetric signing keys (JWT_SIGNING_KEYS)",
+	)
 
 	// Unauthorized errors
-	ErrInvalidPassword = utils.NewCustomError("INVALID_PASSWORD", http.StatusUnauthorized, "invalid password")
+	// Unknown usernames and wrong passwords get the same error so accounts cannot be enumerated
+	ErrAuthInvalidCredentials = utils.NewCustomError("AUTH_INVALID_CREDENTIALS", http.StatusUnauthorized, "invalid username or password")
 )
@@ -0,0 +1,111 @@
+package domain
+
+// OAuth 2.1 / OpenID Connect provider entities
+
+// OAuthClientEntity is an application registered to obtain tokens through the authorization code flow.
+// Public clients (SPA, mobile) have no secret, confidential clients authenticate with their secret.
+type OAuthClientEntity struct {
+	ID           string   `bson:"_id" json:"client_id"`
+	Name         string   `bson:"name" json:"name"`
+	Public       bool     `bson:"public" json:"public"`
+	SecretHash   string   `bson:"secret_hash,omitempty" json:"-"`                   // SHA-256 of the secret
+	ClientSecret string   `bson:"-" json:"client_secret,omitempty"`                 // Plaintext, only returned at registration
+	RedirectURIs []string `bson:"redirect_uris" json:"redirect_uris"`               // Matched exactly
+	Scopes       []string `bson:"scopes" json:"scopes"`                             // Scopes the client may request
+	CreatedBy    string   `bson:"created_by,omitempty" json:"created_by,omitempty"` // Admin who registered the client
+	CreatedAt    int64    `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt    int64    `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// OAuthAuthorizationCodeEntity is a single-use authorization code, only the hash of the code is stored
+type OAuthAuthorizationCodeEntity struct {
+	ID            string `bson:"_id" json:"-"` // SHA-256 of the code
+	ClientID      string `bson:"client_id" json:"client_id"`
+	UserID        string `bson:"user_id" json:"user_id"`
+	RedirectURI   string `bson:"redirect_uri" json:"redirect_uri"`
+	Scope         string `bson:"scope" json:"scope"`
+	Nonce         string `bson:"nonce,omitempty" json:"-"`
+	CodeChallenge string `bson:"code_challenge" json:"-"`    // PKCE S256 challenge
+	AuthTime      int64  `bson:"auth_time" json:"auth_time"` // When the user authenticated, unix seconds
+	ExpiresAt     int64  `bson:"expires_at" json:"expires_at"`
+	UsedAt        int64  `bson:"used_at" json:"used_at"` // 0 while unused
+	CreatedAt     int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	// Browser the user signed in from, recorded on the session started with the code
+	ClientIP  string `bson:"client_ip,omitempty" json:"-"`
+	UserAgent string `bson:"user_agent,omitempty" json:"-"`
+}
+
+// OAuthTokenEntity is the token endpoint response (RFC 6749 section 5.1)
+type OAuthTokenEntity struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// TokenIntrospectionEntity is the introspection response (RFC 7662 section 2.2), only Active is set for an inactive token.
+// Times are unix seconds.
+type TokenIntrospectionEntity struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	TokenUse  string   `json:"token_use,omitempty"` // access_token or refresh_token, a refresh token is no bearer credential
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+	// Actor is set on impersonation tokens, it is the super admin acting as the subject
+	Actor *TokenActorEntity `json:"act,omitempty"`
+}
+
+// TokenActorEntity identifies who is acting on behalf of the subject of a token (RFC 8693 "act" claim)
+type TokenActorEntity struct {
+	Subject  string `json:"sub"`
+	Username string `json:"username,omitempty"`
+}
+
+// UserInfoEntity is the OpenID Connect userinfo response, claims depend on the granted scopes.
+// Access tokens only identify the user, this is where clients read the profile.
+type UserInfoEntity struct {
+	Subject           string                 `json:"sub"`
+	PreferredUsername string                 `json:"preferred_username,omitempty"`
+	Name              string                 `json:"name,omitempty"`
+	Gender            string                 `json:"gender,omitempty"`
+	UpdatedAt         int64                  `json:"updated_at,omitempty"` // Unix seconds
+	Email             string                 `json:"email,omitempty"`
+	EmailVerified     *bool                  `json:"email_verified,omitempty"`
+	PhoneNumber       string                 `json:"phone_number,omitempty"`
+	Address           *UserInfoAddressEntity `json:"address,omitempty"`
+}
+
+// UserInfoAddressEntity is the OpenID Connect address claim, addresses are stored as free text
+type UserInfoAddressEntity struct {
+	Formatted string `json:"formatted"`
+}
+
+// OpenIDConfigurationEntity is the discovery document published at /.well-known/openid-configuration
+type OpenIDConfigurationEntity struct {
+	Issuer                                 string   `json:"issuer"`
+	AuthorizationEndpoint                  string   `json:"authorization_endpoint"`
+	TokenEndpoint                          string   `json:"token_endpoint"`
+	UserInfoEndpoint                       string   `json:"userinfo_endpoint"`
+	IntrospectionEndpoint                  string   `json:"introspection_endpoint"`
+	RevocationEndpoint                     string   `json:"revocation_endpoint"`
+	JWKSURI                                string   `json:"jwks_uri"`
+	ScopesSupported                        []string `json:"scopes_supported"`
+	ResponseTypesSupported                 []string `json:"response_types_supported"`
+	GrantTypesSupported                    []string `json:"grant_types_supported"`
+	SubjectTypesSupported                  []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported       []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported      []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported          []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                        []string `json:"claims_supported"`
+	AuthorizationResponseIssParamSupported bool     `json:"authorization_response_iss_parameter_supported"`
+}
@@ -0,0 +1,39 @@
+package domain
+
+// Service account entities: non-human principals authenticating with the client credentials grant
+
+// ServiceAccountEntity is a machine identity for backend workers. Its id is also its client id.
+// Service accounts have no user role, they are only granted the scopes listed here.
+type ServiceAccountEntity struct {
+	ID          string                       `bson:"_id" json:"client_id"`
+	Name        string                       `bson:"name" json:"name"`
+	Description string                       `bson:"description,omitempty" json:"description,omitempty"`
+	Scopes      []string                     `bson:"scopes" json:"scopes"`
+	Secrets     []ServiceAccountSecretEntity `bson:"secrets" json:"secrets"`
+	CreatedBy   string                       `bson:"created_by,omitempty" json:"created_by,omitempty"` // Admin who created the account
+	CreatedAt   int64                        `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt   int64                        `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// ServiceAccountSecretEntity is one client secret of a service account, only the hash of the secret is stored.
+// Several secrets are valid at once during a rotation, the previous one until its ExpiresAt.
+type ServiceAccountSecretEntity struct {
+	ID        string `bson:"id" json:"id"`
+	Hash      string `bson:"hash" json:"-"`                                    // SHA-256 of the secret
+	Hint      string `bson:"hint" json:"hint"`                                 // Last characters of the secret, to tell secrets apart
+	ExpiresAt int64  `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // 0 means the secret does not expire
+	CreatedAt int64  `bson:"created_at" json:"created_at"`
+}
+
+// IsActive reports whether the secret can still authenticate at the given time, in unix milliseconds
+func (s *ServiceAccountSecretEntity) IsActive(now int64) bool {
+	return s.ExpiresAt == 0 || s.ExpiresAt > now
+}
+
+// ServiceAccountCredentialsEntity is returned when a secret is issued, the plaintext secret is never shown again
+type ServiceAccountCredentialsEntity struct {
+	ServiceAccount *ServiceAccountEntity `json:"service_account"`
+	ClientID       string                `json:"client_id"`
+	ClientSecret   string                `json:"client_secret"`
+	SecretID       string                `json:"secret_id"`
+}
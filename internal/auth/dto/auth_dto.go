@@ -2,7 +2,134 @@ package dto
 
 // Auth DTOs for request/response
 type LoginRequest struct {
-	Username string `json:"username" binding:"required,min=5,max=20"`
-	Password string `json:"password" binding:"required,min=6,max=20"`
+	Username  string `json:"username" binding:"required,max=254"` // Username or email, compared ignoring case
+	Password  string `json:"password" binding:"required,max=1024"`
+	ClientIP  string `json:"-"` // Set by the handler, used by brute-force protection
+	UserAgent string `json:"-"` // Set by the handler, recorded on the session
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	ClientIP     string `json:"-"`
+}
+
+// DeviceInfo describes where a login comes from, it is recorded on the session
+type DeviceInfo struct {
+	ClientIP  string
+	UserAgent string
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code,omitempty,max=32"`
+	ClientIP     string `json:"-"`
+	UserAgent    string `json:"-"`
+}
+
+type TOTPCodeRequest struct {
+	Code     string `json:"code" binding:"required,len=6,numeric"`
+	ClientIP string `json:"-"` // Set by the handler, used by brute-force protection
+}
+
+type ForgotPasswordRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	ClientIP string `json:"-"` // Set by the handler, used by the rate limit
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"` // Checked against the password policy
+}
+
+// OIDCCallbackRequest is the query of the redirect back from an identity provider
+type OIDCCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+	BrowserState     string `form:"-"` // State from the cookie set when the login started
+	ClientIP         string `form:"-"`
+	UserAgent        string `form:"-"`
+}
+
+// AuthorizationRequest is the OAuth authorization request, sent as query (GET) or form (POST)
+type AuthorizationRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	Nonce               string `form:"nonce"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+// AuthorizationDecision is the login and consent form posted back to the authorization endpoint
+type AuthorizationDecision struct {
+	AuthorizationRequest
+	Action    string `form:"action"` // "approve" or "deny"
+	Username  string `form:"username"`
+	Password  string `form:"password"`
+	MFAToken  string `form:"mfa_token"` // Set on the second step for users with MFA enabled
+	Code      string `form:"mfa_code"`
+	ClientIP  string `form:"-"`
+	UserAgent string `form:"-"`
+}
+
+// TokenRequest is the form posted to the token endpoint
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"` // Client credentials grant, a subset of the service account scopes
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// TokenIntrospectionRequest is the form posted to the introspection (RFC 7662) and revocation (RFC 7009) endpoints
+type TokenIntrospectionRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"` // access_token or refresh_token, only an optimization the server may ignore
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,min=3,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,max=10,dive,required,max=2048"`
+	Public       bool     `json:"public"` // SPA and mobile apps, they cannot keep a secret
+	Scopes       []string `json:"scopes" binding:"omitempty,dive,oneof=openid profile email"`
+}
+
+type CreateServiceAccountRequest struct {
+	Name        string   `json:"name" binding:"required,min=3,max=100"`
+	Description string   `json:"description" binding:"max=500"`
+	Scopes      []string `json:"scopes" binding:"required,min=1,dive,required"`
+}
+
+type RotateServiceAccountSecretRequest struct {
+	// How long the current secrets keep working, defaults to 24 hours, capped at 30 days. 0 revokes them now.
+	OverlapSeconds *int64 `json:"overlap_seconds" binding:"omitempty,min=0"`
+}
+
+type ImpersonateRequest struct {
+	Reason    string `json:"reason" binding:"required,min=3,max=500"` // Why support needs the account, kept in the audit trail
+	ClientIP  string `json:"-"`                                       // Set by the handler, recorded in the audit trail
+	UserAgent string `json:"-"`                                       // Set by the handler, recorded in the audit trail
+}
+
+type AuditEventQuery struct {
+	Type    string `form:"type"`
+	ActorID string `form:"actor_id"`
+	UserID  string `form:"user_id"`
+	Limit   int    `form:"limit" binding:"omitempty,min=1,max=500"` // Defaults to 100
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,min=3,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresInDays *int     `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // Defaults to 90 days
+}
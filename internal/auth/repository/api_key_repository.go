@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+)
+
+// API key repository interface
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *domain.APIKeyEntity) (*domain.APIKeyEntity, error)
+	FindAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKeyEntity, error)
+	// ListAPIKeysByUserID returns the keys of the user, newest first, revoked keys included
+	ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKeyEntity, error)
+	// RevokeAPIKey revokes an active key of the user
+	RevokeAPIKey(ctx context.Context, userID, id string) error
+	UpdateAPIKeyLastUsed(ctx context.Context, id string, usedAt int64, ip string) error
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+)
+
+// Audit repository interface, events are append only
+
+type AuditRepository interface {
+	CreateAuditEvent(ctx context.Context, event *domain.AuditEventEntity) error
+	// ListAuditEvents returns the events matching the filters, newest first
+	ListAuditEvents(ctx context.Context, filters AuditEventFilters) ([]*domain.AuditEventEntity, error)
+}
+
+// AuditEventFilters selects audit events, empty fields match every event
+type AuditEventFilters struct {
+	Type    string
+	ActorID string
+	UserID  string
+	Limit   int
+}
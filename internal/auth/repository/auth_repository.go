@@ -1,3 +1,67 @@
 package repository
 
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+)
+
 // Auth repository interface
+
+type AuthRepository interface {
+	// EnsureIndexes creates the indexes on the user of the records and the TTL indexes removing them once expired
+	EnsureIndexes(ctx context.Context) error
+
+	// Refresh token families
+	CreateRefreshTokenFamily(ctx context.Context, family *domain.RefreshTokenFamilyEntity) (*domain.RefreshTokenFamilyEntity, error)
+	FindRefreshTokenFamilyByID(ctx context.Context, id string) (*domain.RefreshTokenFamilyEntity, error)
+	// RotateRefreshTokenFamily moves the family from currentJTI to nextJTI.
+	// It returns false when the family is revoked or currentJTI is no longer the latest token.
+	RotateRefreshTokenFamily(ctx context.Context, id, currentJTI, nextJTI string, expiresAt int64) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, id string) error
+	RevokeRefreshTokenFamiliesByUserID(ctx context.Context, userID string) error
+
+	// Sessions, revoking a refresh token family also revokes its session
+	CreateSession(ctx context.Context, session *domain.SessionEntity) error
+	FindSessionByID(ctx context.Context, id string) (*domain.SessionEntity, error)
+	// ListActiveSessionsByUserID returns the unrevoked, unexpired sessions of the user, most recently seen first
+	ListActiveSessionsByUserID(ctx context.Context, userID string) ([]*domain.SessionEntity, error)
+	// TouchSession records the last activity, ip and expiresAt are left unchanged when empty
+	TouchSession(ctx context.Context, id, ip string, seenAt, expiresAt int64) error
+	// RevokeSession revokes an active session of the user and its refresh token family
+	RevokeSession(ctx context.Context, userID, id string) error
+
+	// Issued access tokens and denylist
+	SaveIssuedToken(ctx context.Context, token *domain.IssuedTokenEntity) error
+	FindIssuedTokenByID(ctx context.Context, jti string) (*domain.IssuedTokenEntity, error)
+	RevokeToken(ctx context.Context, token *domain.RevokedTokenEntity) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+
+	// Reference access tokens
+	CreateReferenceToken(ctx context.Context, token *domain.ReferenceTokenEntity) error
+	// FindReferenceToken returns an unexpired reference token
+	FindReferenceToken(ctx context.Context, tokenHash string) (*domain.ReferenceTokenEntity, error)
+
+	// Password reset tokens
+	CreatePasswordResetToken(ctx context.Context, token *domain.PasswordResetTokenEntity) error
+	// FindPasswordResetToken returns an unused, unexpired token without using it
+	FindPasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetTokenEntity, error)
+	// ConsumePasswordResetToken marks an unused, unexpired token as used and returns it
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetTokenEntity, error)
+	// InvalidatePasswordResetTokens marks every unused token of the user as used
+	InvalidatePasswordResetTokens(ctx context.Context, userID string) error
+
+	// MFA challenges
+	CreateMFAChallenge(ctx context.Context, challenge *domain.PendingMFAChallengeEntity) error
+	// ConsumeMFAChallenge deletes an unexpired challenge and returns it
+	ConsumeMFAChallenge(ctx context.Context, id string) (*domain.PendingMFAChallengeEntity, error)
+
+	// External login states
+	CreateOIDCLoginState(ctx context.Context, state *domain.OIDCLoginStateEntity) error
+	// ConsumeOIDCLoginState deletes an unexpired state and returns it
+	ConsumeOIDCLoginState(ctx context.Context, stateHash string) (*domain.OIDCLoginStateEntity, error)
+
+	// Per-user token version
+	GetUserTokenVersion(ctx context.Context, userID string) (int64, error)
+	IncrementUserTokenVersion(ctx context.Context, userID string) (int64, error)
+}
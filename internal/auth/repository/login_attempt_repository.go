@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Login attempt repository interface, keys identify a username or a client IP
+
+type LoginAttemptRepository interface {
+	// IncrementFailedLogins adds a failure to the key and returns the failures within the window
+	IncrementFailedLogins(ctx context.Context, key string, window time.Duration) (int64, error)
+	LockLogin(ctx context.Context, key string, duration time.Duration) error
+	// GetLoginLock returns the remaining lock duration, 0 when the key is not locked
+	GetLoginLock(ctx context.Context, key string) (time.Duration, error)
+	ResetLoginAttempts(ctx context.Context, key string) error
+}
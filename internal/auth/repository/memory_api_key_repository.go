@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.uber.org/zap"
+)
+
+// In-memory implementation of API key repository, used for local development and tests.
+// Data is lost on restart and is not shared between instances.
+
+type memoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]domain.APIKeyEntity
+}
+
+func NewMemoryAPIKeyRepository() APIKeyRepository {
+	return &memoryAPIKeyRepository{
+		keys: make(map[string]domain.APIKeyEntity),
+	}
+}
+
+// Memory - CreateAPIKey stores a new API key
+func (r *memoryAPIKeyRepository) CreateAPIKey(ctx context.Context, key *domain.APIKeyEntity) (*domain.APIKeyEntity, error) {
+	if key == nil || key.ID == "" || key.Hash == "" {
+		zap.L().Error("api key is invalid")
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.CreatedAt = time.Now().UnixMilli()
+	stored := *key
+	stored.Scopes = append([]string(nil), key.Scopes...)
+	r.keys[key.ID] = stored
+
+	return key, nil
+}
+
+// Memory - FindAPIKeyByHash finds an API key by the hash of the key
+func (r *memoryAPIKeyRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKeyEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			return &key, nil
+		}
+	}
+	return nil, domain.ErrAPIKeyNotFound
+}
+
+// Memory - ListAPIKeysByUserID returns the keys of the user, newest first
+func (r *memoryAPIKeyRepository) ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKeyEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*domain.APIKeyEntity, 0)
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			key := key
+			keys = append(keys, &key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt > keys[j].CreatedAt
+	})
+	return keys, nil
+}
+
+// Memory - RevokeAPIKey revokes an active key of the user
+func (r *memoryAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	key.RevokedAt = time.Now().UnixMilli()
+	r.keys[id] = key
+	return nil
+}
+
+// Memory - UpdateAPIKeyLastUsed records when and from where the key was last used
+func (r *memoryAPIKeyRepository) UpdateAPIKeyLastUsed(ctx context.Context, id string, usedAt int64, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil
+	}
+	key.LastUsedAt = usedAt
+	key.LastUsedIP = ip
+	r.keys[id] = key
+	return nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.uber.org/zap"
+)
+
+// In-memory implementation of audit repository, used for local development and tests.
+// Data is lost on restart and is not shared between instances.
+
+type memoryAuditRepository struct {
+	mu     sync.RWMutex
+	events []domain.AuditEventEntity // Oldest first
+}
+
+func NewMemoryAuditRepository() AuditRepository {
+	return &memoryAuditRepository{}
+}
+
+// Memory - CreateAuditEvent stores an audit event
+func (r *memoryAuditRepository) CreateAuditEvent(ctx context.Context, event *domain.AuditEventEntity) error {
+	if event == nil || event.ID == "" || event.Type == "" {
+		zap.L().Error("audit event is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.CreatedAt == 0 {
+		event.CreatedAt = time.Now().UnixMilli()
+	}
+	r.events = append(r.events, *event)
+	return nil
+}
+
+// Memory - ListAuditEvents returns the events matching the filters, newest first
+func (r *memoryAuditRepository) ListAuditEvents(ctx context.Context, filters AuditEventFilters) ([]*domain.AuditEventEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make([]*domain.AuditEventEntity, 0)
+	for i := len(r.events) - 1; i >= 0; i-- {
+		event := r.events[i]
+		if (filters.Type != "" && event.Type != filters.Type) ||
+			(filters.ActorID != "" && event.ActorID != filters.ActorID) ||
+			(filters.UserID != "" && event.UserID != filters.UserID) {
+			continue
+		}
+		events = append(events, &event)
+		if filters.Limit > 0 && len(events) == filters.Limit {
+			break
+		}
+	}
+	return events, nil
+}
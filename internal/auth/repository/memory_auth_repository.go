@@ -0,0 +1,502 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.uber.org/zap"
+)
+
+// In-memory implementation of auth repository, used for local development and tests.
+// Data is lost on restart and is not shared between instances.
+
+type memoryAuthRepository struct {
+	mu                   sync.RWMutex
+	refreshTokenFamilies map[string]domain.RefreshTokenFamilyEntity
+	issuedTokens         map[string]domain.IssuedTokenEntity
+	revokedTokens        map[string]domain.RevokedTokenEntity
+	userTokenVersions    map[string]int64
+	passwordResetTokens  map[string]domain.PasswordResetTokenEntity
+	oidcLoginStates      map[string]domain.OIDCLoginStateEntity
+	mfaChallenges        map[string]domain.PendingMFAChallengeEntity
+	sessions             map[string]domain.SessionEntity
+	referenceTokens      map[string]domain.ReferenceTokenEntity
+}
+
+func NewMemoryAuthRepository() AuthRepository {
+	return &memoryAuthRepository{
+		refreshTokenFamilies: make(map[string]domain.RefreshTokenFamilyEntity),
+		issuedTokens:         make(map[string]domain.IssuedTokenEntity),
+		revokedTokens:        make(map[string]domain.RevokedTokenEntity),
+		userTokenVersions:    make(map[string]int64),
+		passwordResetTokens:  make(map[string]domain.PasswordResetTokenEntity),
+		oidcLoginStates:      make(map[string]domain.OIDCLoginStateEntity),
+		mfaChallenges:        make(map[string]domain.PendingMFAChallengeEntity),
+		sessions:             make(map[string]domain.SessionEntity),
+		referenceTokens:      make(map[string]domain.ReferenceTokenEntity),
+	}
+}
+
+// Memory - EnsureIndexes has nothing to create, expired records are pruned on writes
+func (r *memoryAuthRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Memory - CreateRefreshTokenFamily stores a new refresh token family
+func (r *memoryAuthRepository) CreateRefreshTokenFamily(ctx context.Context, family *domain.RefreshTokenFamilyEntity) (*domain.RefreshTokenFamilyEntity, error) {
+	if family == nil || family.ID == "" {
+		zap.L().Error("refresh token family is invalid", zap.Any("family", family))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	family.CreatedAt = time.Now().UnixMilli()
+	family.UpdatedAt = time.Now().UnixMilli()
+	r.refreshTokenFamilies[family.ID] = *family
+
+	return family, nil
+}
+
+// Memory - FindRefreshTokenFamilyByID finds a refresh token family by its id
+func (r *memoryAuthRepository) FindRefreshTokenFamilyByID(ctx context.Context, id string) (*domain.RefreshTokenFamilyEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	family, ok := r.refreshTokenFamilies[id]
+	if !ok {
+		return nil, domain.ErrJWTRefreshTokenInvalid
+	}
+
+	return &family, nil
+}
+
+// Memory - RotateRefreshTokenFamily swaps the current jti of a non-revoked family
+func (r *memoryAuthRepository) RotateRefreshTokenFamily(ctx context.Context, id, currentJTI, nextJTI string, expiresAt int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	family, ok := r.refreshTokenFamilies[id]
+	if !ok || family.Revoked || family.CurrentJTI != currentJTI {
+		return false, nil
+	}
+
+	family.CurrentJTI = nextJTI
+	family.ExpiresAt = expiresAt
+	family.UpdatedAt = time.Now().UnixMilli()
+	r.refreshTokenFamilies[id] = family
+
+	return true, nil
+}
+
+// Memory - RevokeRefreshTokenFamily marks a refresh token family as revoked
+func (r *memoryAuthRepository) RevokeRefreshTokenFamily(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if family, ok := r.refreshTokenFamilies[id]; ok {
+		r.refreshTokenFamilies[id] = revokeFamily(family)
+	}
+	for sessionID, session := range r.sessions {
+		if session.FamilyID == id && session.RevokedAt == 0 {
+			session.RevokedAt = time.Now().UnixMilli()
+			r.sessions[sessionID] = session
+		}
+	}
+
+	return nil
+}
+
+// Memory - RevokeRefreshTokenFamiliesByUserID revokes every active refresh token family of a user
+func (r *memoryAuthRepository) RevokeRefreshTokenFamiliesByUserID(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, family := range r.refreshTokenFamilies {
+		if family.UserID == userID && !family.Revoked {
+			r.refreshTokenFamilies[id] = revokeFamily(family)
+		}
+	}
+	for id, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == 0 {
+			session.RevokedAt = time.Now().UnixMilli()
+			r.sessions[id] = session
+		}
+	}
+
+	return nil
+}
+
+// Memory - SaveIssuedToken records an issued access token
+func (r *memoryAuthRepository) SaveIssuedToken(ctx context.Context, token *domain.IssuedTokenEntity) error {
+	if token == nil || token.ID == "" {
+		zap.L().Error("issued token is invalid", zap.Any("token", token))
+		return domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneExpired()
+	token.CreatedAt = time.Now().UnixMilli()
+	r.issuedTokens[token.ID] = *token
+
+	return nil
+}
+
+// Memory - FindIssuedTokenByID finds an issued access token by its jti
+func (r *memoryAuthRepository) FindIssuedTokenByID(ctx context.Context, jti string) (*domain.IssuedTokenEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, ok := r.issuedTokens[jti]
+	if !ok {
+		return nil, domain.ErrAuthTokenNotFound
+	}
+
+	return &token, nil
+}
+
+// Memory - RevokeToken adds a token to the denylist, revoking it twice is a no-op
+func (r *memoryAuthRepository) RevokeToken(ctx context.Context, token *domain.RevokedTokenEntity) error {
+	if token == nil || token.ID == "" {
+		zap.L().Error("revoked token is invalid", zap.Any("token", token))
+		return domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.revokedTokens[token.ID]; ok {
+		return nil
+	}
+	token.RevokedAt = time.Now().UnixMilli()
+	r.revokedTokens[token.ID] = *token
+
+	return nil
+}
+
+// Memory - IsTokenRevoked checks whether a token is in the denylist
+func (r *memoryAuthRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.revokedTokens[jti]
+	return ok, nil
+}
+
+// Memory - GetUserTokenVersion returns the current token version of a user, 0 when it was never bumped
+func (r *memoryAuthRepository) GetUserTokenVersion(ctx context.Context, userID string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.userTokenVersions[userID], nil
+}
+
+// Memory - IncrementUserTokenVersion bumps the token version of a user and returns the new version
+func (r *memoryAuthRepository) IncrementUserTokenVersion(ctx context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.userTokenVersions[userID]++
+	return r.userTokenVersions[userID], nil
+}
+
+// Memory - CreatePasswordResetToken stores a password reset token
+func (r *memoryAuthRepository) CreatePasswordResetToken(ctx context.Context, token *domain.PasswordResetTokenEntity) error {
+	if token == nil || token.ID == "" {
+		zap.L().Error("password reset token is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneExpired()
+	token.CreatedAt = time.Now().UnixMilli()
+	r.passwordResetTokens[token.ID] = *token
+
+	return nil
+}
+
+// Memory - FindPasswordResetToken returns an unused, unexpired token without using it
+func (r *memoryAuthRepository) FindPasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetTokenEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, ok := r.passwordResetTokens[tokenHash]
+	if !ok || token.UsedAt != 0 || token.ExpiresAt <= time.Now().UnixMilli() {
+		return nil, domain.ErrPasswordResetTokenInvalid
+	}
+
+	return &token, nil
+}
+
+// Memory - ConsumePasswordResetToken marks the token as used so it works only once
+func (r *memoryAuthRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetTokenEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.passwordResetTokens[tokenHash]
+	if !ok || token.UsedAt != 0 || token.ExpiresAt <= time.Now().UnixMilli() {
+		return nil, domain.ErrPasswordResetTokenInvalid
+	}
+	token.UsedAt = time.Now().UnixMilli()
+	r.passwordResetTokens[tokenHash] = token
+
+	return &token, nil
+}
+
+// Memory - InvalidatePasswordResetTokens marks every unused token of the user as used
+func (r *memoryAuthRepository) InvalidatePasswordResetTokens(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.passwordResetTokens {
+		if token.UserID == userID && token.UsedAt == 0 {
+			token.UsedAt = time.Now().UnixMilli()
+			r.passwordResetTokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+// Memory - CreateReferenceToken stores the access token of a reference token
+func (r *memoryAuthRepository) CreateReferenceToken(ctx context.Context, token *domain.ReferenceTokenEntity) error {
+	if token == nil || token.ID == "" {
+		zap.L().Error("reference token is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneExpired()
+	token.CreatedAt = time.Now().UnixMilli()
+	r.referenceTokens[token.ID] = *token
+
+	return nil
+}
+
+// Memory - FindReferenceToken returns an unexpired reference token
+func (r *memoryAuthRepository) FindReferenceToken(ctx context.Context, tokenHash string) (*domain.ReferenceTokenEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, ok := r.referenceTokens[tokenHash]
+	if !ok || token.ExpiresAt <= time.Now().UnixMilli() {
+		return nil, domain.ErrJWTTokenInvalid
+	}
+
+	return &token, nil
+}
+
+// Memory - CreateOIDCLoginState stores the state of an external login
+func (r *memoryAuthRepository) CreateOIDCLoginState(ctx context.Context, state *domain.OIDCLoginStateEntity) error {
+	if state == nil || state.ID == "" {
+		zap.L().Error("oidc login state is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneExpired()
+	state.CreatedAt = time.Now().UnixMilli()
+	r.oidcLoginStates[state.ID] = *state
+
+	return nil
+}
+
+// Memory - ConsumeOIDCLoginState deletes the state so a callback can be handled only once
+func (r *memoryAuthRepository) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (*domain.OIDCLoginStateEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.oidcLoginStates[stateHash]
+	if !ok {
+		return nil, domain.ErrOIDCStateInvalid
+	}
+	delete(r.oidcLoginStates, stateHash)
+	if state.ExpiresAt <= time.Now().UnixMilli() {
+		return nil, domain.ErrOIDCStateInvalid
+	}
+
+	return &state, nil
+}
+
+// Memory - CreateMFAChallenge stores a challenge waiting for its second factor
+func (r *memoryAuthRepository) CreateMFAChallenge(ctx context.Context, challenge *domain.PendingMFAChallengeEntity) error {
+	if challenge == nil || challenge.ID == "" {
+		zap.L().Error("mfa challenge is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneExpired()
+	challenge.CreatedAt = time.Now().UnixMilli()
+	r.mfaChallenges[challenge.ID] = *challenge
+
+	return nil
+}
+
+// Memory - ConsumeMFAChallenge deletes the challenge so it can be completed only once
+func (r *memoryAuthRepository) ConsumeMFAChallenge(ctx context.Context, id string) (*domain.PendingMFAChallengeEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.mfaChallenges[id]
+	if !ok {
+		return nil, domain.ErrMFATokenInvalid
+	}
+	delete(r.mfaChallenges, id)
+	if challenge.ExpiresAt <= time.Now().UnixMilli() {
+		return nil, domain.ErrMFATokenInvalid
+	}
+
+	return &challenge, nil
+}
+
+// Memory - CreateSession stores the session started by a login
+func (r *memoryAuthRepository) CreateSession(ctx context.Context, session *domain.SessionEntity) error {
+	if session == nil || session.ID == "" {
+		zap.L().Error("session is invalid", zap.Any("session", session))
+		return domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneExpired()
+	session.CreatedAt = time.Now().UnixMilli()
+	session.LastSeenAt = session.CreatedAt
+	r.sessions[session.ID] = *session
+
+	return nil
+}
+
+// Memory - FindSessionByID finds a session by its id
+func (r *memoryAuthRepository) FindSessionByID(ctx context.Context, id string) (*domain.SessionEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, domain.ErrSessionNotFound
+	}
+
+	return &session, nil
+}
+
+// Memory - ListActiveSessionsByUserID returns the unrevoked, unexpired sessions of the user, most recently seen first
+func (r *memoryAuthRepository) ListActiveSessionsByUserID(ctx context.Context, userID string) ([]*domain.SessionEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now().UnixMilli()
+	sessions := []*domain.SessionEntity{}
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.IsActive(now) {
+			session := session
+			sessions = append(sessions, &session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt > sessions[j].LastSeenAt
+	})
+
+	return sessions, nil
+}
+
+// Memory - TouchSession records the last activity, ip and expiresAt are left unchanged when empty
+func (r *memoryAuthRepository) TouchSession(ctx context.Context, id, ip string, seenAt, expiresAt int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil
+	}
+	session.LastSeenAt = seenAt
+	if ip != "" {
+		session.LastSeenIP = ip
+	}
+	if expiresAt != 0 {
+		session.ExpiresAt = expiresAt
+	}
+	r.sessions[id] = session
+
+	return nil
+}
+
+// Memory - RevokeSession revokes an active session of the user and its refresh token family
+func (r *memoryAuthRepository) RevokeSession(ctx context.Context, userID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.UserID != userID || !session.IsActive(time.Now().UnixMilli()) {
+		return domain.ErrSessionNotFound
+	}
+	session.RevokedAt = time.Now().UnixMilli()
+	r.sessions[id] = session
+	if family, ok := r.refreshTokenFamilies[session.FamilyID]; ok {
+		r.refreshTokenFamilies[session.FamilyID] = revokeFamily(family)
+	}
+
+	return nil
+}
+
+// pruneExpired drops tokens that are already expired, the caller must hold the lock
+func (r *memoryAuthRepository) pruneExpired() {
+	now := time.Now().UnixMilli()
+	for jti, token := range r.issuedTokens {
+		if token.ExpiresAt < now {
+			delete(r.issuedTokens, jti)
+		}
+	}
+	for jti, token := range r.revokedTokens {
+		if token.ExpiresAt < now {
+			delete(r.revokedTokens, jti)
+		}
+	}
+	for id, token := range r.passwordResetTokens {
+		if token.ExpiresAt < now {
+			delete(r.passwordResetTokens, id)
+		}
+	}
+	for id, state := range r.oidcLoginStates {
+		if state.ExpiresAt < now {
+			delete(r.oidcLoginStates, id)
+		}
+	}
+	for id, challenge := range r.mfaChallenges {
+		if challenge.ExpiresAt < now {
+			delete(r.mfaChallenges, id)
+		}
+	}
+	for id, session := range r.sessions {
+		if session.ExpiresAt < now {
+			delete(r.sessions, id)
+		}
+	}
+	for id, token := range r.referenceTokens {
+		if token.ExpiresAt < now {
+			delete(r.referenceTokens, id)
+		}
+	}
+}
+
+func revokeFamily(family domain.RefreshTokenFamilyEntity) domain.RefreshTokenFamilyEntity {
+	family.Revoked = true
+	family.RevokedAt = time.Now().UnixMilli()
+	family.UpdatedAt = time.Now().UnixMilli()
+	return family
+}
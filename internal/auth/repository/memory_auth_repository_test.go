@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+)
+
+func TestMemoryAuthRepositoryDenylist(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryAuthRepository()
+	expiresAt := time.Now().Add(time.Minute).UnixMilli()
+
+	if revoked, err := repo.IsTokenRevoked(ctx, "token-1"); err != nil || revoked {
+		t.Fatalf("IsTokenRevoked() before revoking = %v, %v", revoked, err)
+	}
+	for range 2 {
+		if err := repo.RevokeToken(ctx, &domain.RevokedTokenEntity{ID: "token-1", UserID: "user-1", ExpiresAt: expiresAt}); err != nil {
+			t.Fatalf("RevokeToken() = %v", err)
+		}
+	}
+	if revoked, err := repo.IsTokenRevoked(ctx, "token-1"); err != nil || !revoked {
+		t.Fatalf("IsTokenRevoked() = %v, %v, want revoked", revoked, err)
+	}
+	if err := repo.RevokeToken(ctx, &domain.RevokedTokenEntity{}); err == nil {
+		t.Fatal("RevokeToken() without jti = nil, want an error")
+	}
+}
+
+func TestMemoryAuthRepositoryTokenVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryAuthRepository()
+
+	if version, err := repo.GetUserTokenVersion(ctx, "user-1"); err != nil || version != 0 {
+		t.Fatalf("GetUserTokenVersion() of a new user = %d, %v, want 0", version, err)
+	}
+	for want := int64(1); want <= 3; want++ {
+		version, err := repo.IncrementUserTokenVersion(ctx, "user-1")
+		if err != nil || version != want {
+			t.Fatalf("IncrementUserTokenVersion() = %d, %v, want %d", version, err, want)
+		}
+	}
+	if version, _ := repo.GetUserTokenVersion(ctx, "user-2"); version != 0 {
+		t.Fatalf("GetUserTokenVersion() of another user = %d, want 0", version)
+	}
+}
+
+func TestMemoryAuthRepositoryRevokeFamilies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryAuthRepository()
+	expiresAt := time.Now().Add(time.Hour).UnixMilli()
+	for _, family := range []*domain.RefreshTokenFamilyEntity{
+		{ID: "family-1", UserID: "user-1", CurrentJTI: "a", ExpiresAt: expiresAt},
+		{ID: "family-2", UserID: "user-1", CurrentJTI: "b", ExpiresAt: expiresAt},
+		{ID: "family-3", UserID: "user-2", CurrentJTI: "c", ExpiresAt: expiresAt},
+	} {
+		if _, err := repo.CreateRefreshTokenFamily(ctx, family); err != nil {
+			t.Fatalf("CreateRefreshTokenFamily() = %v", err)
+		}
+	}
+
+	if err := repo.RevokeRefreshTokenFamiliesByUserID(ctx, "user-1"); err != nil {
+		t.Fatalf("RevokeRefreshTokenFamiliesByUserID() = %v", err)
+	}
+	for id, wantRevoked := range map[string]bool{"family-1": true, "family-2": true, "family-3": false} {
+		family, err := repo.FindRefreshTokenFamilyByID(ctx, id)
+		if err != nil {
+			t.Fatalf("FindRefreshTokenFamilyByID(%q) = %v", id, err)
+		}
+		if family.Revoked != wantRevoked {
+			t.Errorf("%s revoked = %v, want %v", id, family.Revoked, wantRevoked)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// In-memory implementation of login attempt repository, used when Redis is not available.
+// Counters are per instance, so the limits are multiplied by the number of instances.
+
+type memoryLoginAttempt struct {
+	failures    int64
+	expiresAt   time.Time
+	lockedUntil time.Time
+}
+
+type memoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]*memoryLoginAttempt
+}
+
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: make(map[string]*memoryLoginAttempt)}
+}
+
+// Memory - IncrementFailedLogins increments the failure counter, the window restarts on every failure
+func (r *memoryLoginAttemptRepository) IncrementFailedLogins(ctx context.Context, key string, window time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.pruneExpired(now)
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		attempt = &memoryLoginAttempt{}
+		r.attempts[key] = attempt
+	}
+	if attempt.expiresAt.Before(now) {
+		attempt.failures = 0
+	}
+	attempt.failures++
+	attempt.expiresAt = now.Add(window)
+
+	return attempt.failures, nil
+}
+
+// Memory - LockLogin locks the key until the duration elapses
+func (r *memoryLoginAttemptRepository) LockLogin(ctx context.Context, key string, duration time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		attempt = &memoryLoginAttempt{expiresAt: time.Now().Add(duration)}
+		r.attempts[key] = attempt
+	}
+	attempt.lockedUntil = time.Now().Add(duration)
+
+	return nil
+}
+
+// Memory - GetLoginLock returns the remaining lock duration
+func (r *memoryLoginAttemptRepository) GetLoginLock(ctx context.Context, key string) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return 0, nil
+	}
+	remaining := time.Until(attempt.lockedUntil)
+	if remaining < 0 {
+		return 0, nil
+	}
+
+	return remaining, nil
+}
+
+// Memory - ResetLoginAttempts clears the failures and the lock of the key
+func (r *memoryLoginAttemptRepository) ResetLoginAttempts(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+// pruneExpired drops keys whose window and lock are both over, the caller must hold the lock
+func (r *memoryLoginAttemptRepository) pruneExpired(now time.Time) {
+	for key, attempt := range r.attempts {
+		if attempt.expiresAt.Before(now) && attempt.lockedUntil.Before(now) {
+			delete(r.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.uber.org/zap"
+)
+
+// In-memory implementation of OAuth repository, used for local development and tests.
+// Data is lost on restart and is not shared between instances.
+
+type memoryOAuthRepository struct {
+	mu                 sync.RWMutex
+	clients            map[string]domain.OAuthClientEntity
+	authorizationCodes map[string]domain.OAuthAuthorizationCodeEntity
+}
+
+func NewMemoryOAuthRepository() OAuthRepository {
+	return &memoryOAuthRepository{
+		clients:            make(map[string]domain.OAuthClientEntity),
+		authorizationCodes: make(map[string]domain.OAuthAuthorizationCodeEntity),
+	}
+}
+
+// Memory - CreateClient stores a new OAuth client
+func (r *memoryOAuthRepository) CreateClient(ctx context.Context, client *domain.OAuthClientEntity) (*domain.OAuthClientEntity, error) {
+	if client == nil || client.ID == "" {
+		zap.L().Error("oauth client is invalid")
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	client.CreatedAt = time.Now().UnixMilli()
+	client.UpdatedAt = time.Now().UnixMilli()
+	stored := *client
+	stored.ClientSecret = ""
+	r.clients[client.ID] = stored
+
+	return client, nil
+}
+
+// Memory - FindClientByID finds an OAuth client by its client id
+func (r *memoryOAuthRepository) FindClientByID(ctx context.Context, id string) (*domain.OAuthClientEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	client, ok := r.clients[id]
+	if !ok {
+		return nil, domain.ErrOAuthClientNotFound
+	}
+	return &client, nil
+}
+
+// Memory - ListClients returns every OAuth client, newest first
+func (r *memoryOAuthRepository) ListClients(ctx context.Context) ([]*domain.OAuthClientEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clients := make([]*domain.OAuthClientEntity, 0, len(r.clients))
+	for _, client := range r.clients {
+		client := client
+		clients = append(clients, &client)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].CreatedAt > clients[j].CreatedAt
+	})
+	return clients, nil
+}
+
+// Memory - CreateAuthorizationCode stores an authorization code
+func (r *memoryOAuthRepository) CreateAuthorizationCode(ctx context.Context, code *domain.OAuthAuthorizationCodeEntity) error {
+	if code == nil || code.ID == "" {
+		zap.L().Error("authorization code is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Drop expired codes so the map does not grow forever
+	now := time.Now().UnixMilli()
+	for id, stored := range r.authorizationCodes {
+		if stored.ExpiresAt < now {
+			delete(r.authorizationCodes, id)
+		}
+	}
+
+	code.CreatedAt = now
+	r.authorizationCodes[code.ID] = *code
+	return nil
+}
+
+// Memory - ConsumeAuthorizationCode marks the code as used so it can be redeemed only once
+func (r *memoryOAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*domain.OAuthAuthorizationCodeEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, ok := r.authorizationCodes[codeHash]
+	if !ok || code.UsedAt != 0 || code.ExpiresAt <= time.Now().UnixMilli() {
+		return nil, domain.ErrOAuthInvalidGrant
+	}
+	code.UsedAt = time.Now().UnixMilli()
+	r.authorizationCodes[codeHash] = code
+
+	return &code, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.uber.org/zap"
+)
+
+// In-memory implementation of service account repository, used for local development and tests.
+// Data is lost on restart and is not shared between instances.
+
+type memoryServiceAccountRepository struct {
+	mu       sync.RWMutex
+	accounts map[string]domain.ServiceAccountEntity
+}
+
+func NewMemoryServiceAccountRepository() ServiceAccountRepository {
+	return &memoryServiceAccountRepository{
+		accounts: make(map[string]domain.ServiceAccountEntity),
+	}
+}
+
+// Memory - CreateServiceAccount stores a new service account
+func (r *memoryServiceAccountRepository) CreateServiceAccount(ctx context.Context, account *domain.ServiceAccountEntity) (*domain.ServiceAccountEntity, error) {
+	if account == nil || account.ID == "" {
+		zap.L().Error("service account is invalid")
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account.CreatedAt = time.Now().UnixMilli()
+	account.UpdatedAt = time.Now().UnixMilli()
+	r.accounts[account.ID] = copyServiceAccount(*account)
+
+	return account, nil
+}
+
+// Memory - FindServiceAccountByID finds a service account by its client id
+func (r *memoryServiceAccountRepository) FindServiceAccountByID(ctx context.Context, id string) (*domain.ServiceAccountEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, domain.ErrServiceAccountNotFound
+	}
+	account = copyServiceAccount(account)
+	return &account, nil
+}
+
+// Memory - ListServiceAccounts returns every service account, newest first
+func (r *memoryServiceAccountRepository) ListServiceAccounts(ctx context.Context) ([]*domain.ServiceAccountEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	accounts := make([]*domain.ServiceAccountEntity, 0, len(r.accounts))
+	for _, account := range r.accounts {
+		account := copyServiceAccount(account)
+		accounts = append(accounts, &account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].CreatedAt > accounts[j].CreatedAt
+	})
+	return accounts, nil
+}
+
+// Memory - UpdateServiceAccountSecrets replaces the secrets of the account
+func (r *memoryServiceAccountRepository) UpdateServiceAccountSecrets(ctx context.Context, id string, secrets []domain.ServiceAccountSecretEntity) (*domain.ServiceAccountEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, domain.ErrServiceAccountNotFound
+	}
+	account.Secrets = secrets
+	account.UpdatedAt = time.Now().UnixMilli()
+	r.accounts[id] = copyServiceAccount(account)
+
+	account = copyServiceAccount(account)
+	return &account, nil
+}
+
+// Memory - DeleteServiceAccount deletes a service account
+func (r *memoryServiceAccountRepository) DeleteServiceAccount(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[id]; !ok {
+		return domain.ErrServiceAccountNotFound
+	}
+	delete(r.accounts, id)
+	return nil
+}
+
+// copyServiceAccount copies the slices so callers cannot modify the stored account
+func copyServiceAccount(account domain.ServiceAccountEntity) domain.ServiceAccountEntity {
+	account.Scopes = append([]string(nil), account.Scopes...)
+	account.Secrets = append([]domain.ServiceAccountSecretEntity(nil), account.Secrets...)
+	return account
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// MongoDB implementation of API key repository
+
+const APIKeyCollection = "api_keys"
+
+type mongoAPIKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAPIKeyRepository(database *mongo.Database) APIKeyRepository {
+	return &mongoAPIKeyRepository{
+		collection: database.Collection(APIKeyCollection),
+	}
+}
+
+// Mongo - CreateAPIKey stores a new API key
+func (r *mongoAPIKeyRepository) CreateAPIKey(ctx context.Context, key *domain.APIKeyEntity) (*domain.APIKeyEntity, error) {
+	if key == nil || key.ID == "" || key.Hash == "" {
+		zap.L().Error("api key is invalid")
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	key.CreatedAt = time.Now().UnixMilli()
+	_, err := r.collection.InsertOne(ctx, key)
+	if err != nil {
+		zap.L().Error("error inserting api key", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return key, nil
+}
+
+// Mongo - FindAPIKeyByHash finds an API key by the hash of the key
+func (r *mongoAPIKeyRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKeyEntity, error) {
+	key := &domain.APIKeyEntity{}
+	err := r.collection.FindOne(ctx, primitive.D{{Key: "hash", Value: hash}}).Decode(key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrAPIKeyNotFound
+		}
+		zap.L().Error("error finding api key", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return key, nil
+}
+
+// Mongo - ListAPIKeysByUserID returns the keys of the user, newest first
+func (r *mongoAPIKeyRepository) ListAPIKeysByUserID(ctx context.Context, userID string) ([]*domain.APIKeyEntity, error) {
+	cursor, err := r.collection.Find(ctx, primitive.D{{Key: "user_id", Value: userID}},
+		options.Find().SetSort(primitive.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		zap.L().Error("error listing api keys", zap.String("user_id", userID), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+	defer cursor.Close(ctx)
+
+	keys := make([]*domain.APIKeyEntity, 0)
+	if err := cursor.All(ctx, &keys); err != nil {
+		zap.L().Error("error decoding api keys", zap.String("user_id", userID), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return keys, nil
+}
+
+// Mongo - RevokeAPIKey revokes an active key of the user
+func (r *mongoAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	filter := primitive.D{
+		{Key: "_id", Value: id},
+		{Key: "user_id", Value: userID},
+		{Key: "revoked_at", Value: primitive.D{{Key: "$exists", Value: false}}},
+	}
+	update := primitive.D{{Key: "$set", Value: primitive.D{{Key: "revoked_at", Value: time.Now().UnixMilli()}}}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		zap.L().Error("error revoking api key", zap.String("user_id", userID), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// Mongo - UpdateAPIKeyLastUsed records when and from where the key was last used
+func (r *mongoAPIKeyRepository) UpdateAPIKeyLastUsed(ctx context.Context, id string, usedAt int64, ip string) error {
+	update := primitive.D{{Key: "$set", Value: primitive.D{
+		{Key: "last_used_at", Value: usedAt},
+		{Key: "last_used_ip", Value: ip},
+	}}}
+
+	_, err := r.collection.UpdateOne(ctx, primitive.D{{Key: "_id", Value: id}}, update)
+	if err != nil {
+		zap.L().Error("error updating api key last use", zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
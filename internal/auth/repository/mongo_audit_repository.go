@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// MongoDB implementation of audit repository
+
+const AuditEventCollection = "audit_events"
+
+type mongoAuditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAuditRepository(database *mongo.Database) AuditRepository {
+	return &mongoAuditRepository{
+		collection: database.Collection(AuditEventCollection),
+	}
+}
+
+// Mongo - CreateAuditEvent stores an audit event
+func (r *mongoAuditRepository) CreateAuditEvent(ctx context.Context, event *domain.AuditEventEntity) error {
+	if event == nil || event.ID == "" || event.Type == "" {
+		zap.L().Error("audit event is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	if event.CreatedAt == 0 {
+		event.CreatedAt = time.Now().UnixMilli()
+	}
+	_, err := r.collection.InsertOne(ctx, event)
+	if err != nil {
+		zap.L().Error("error inserting audit event", zap.String("type", event.Type), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - ListAuditEvents returns the events matching the filters, newest first
+func (r *mongoAuditRepository) ListAuditEvents(ctx context.Context, filters AuditEventFilters) ([]*domain.AuditEventEntity, error) {
+	filter := primitive.D{}
+	if filters.Type != "" {
+		filter = append(filter, primitive.E{Key: "type", Value: filters.Type})
+	}
+	if filters.ActorID != "" {
+		filter = append(filter, primitive.E{Key: "actor_id", Value: filters.ActorID})
+	}
+	if filters.UserID != "" {
+		filter = append(filter, primitive.E{Key: "user_id", Value: filters.UserID})
+	}
+
+	opts := options.Find().SetSort(primitive.D{{Key: "created_at", Value: -1}})
+	if filters.Limit > 0 {
+		opts.SetLimit(int64(filters.Limit))
+	}
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		zap.L().Error("error listing audit events", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+	defer cursor.Close(ctx)
+
+	events := make([]*domain.AuditEventEntity, 0)
+	if err := cursor.All(ctx, &events); err != nil {
+		zap.L().Error("error decoding audit events", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return events, nil
+}
@@ -0,0 +1,650 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// MongoDB implementation of auth repository
+
+const (
+	RefreshTokenFamilyCollection = "refresh_token_families"
+	IssuedTokenCollection        = "issued_tokens"
+	RevokedTokenCollection       = "revoked_tokens"
+	UserTokenVersionCollection   = "user_token_versions"
+	PasswordResetTokenCollection = "password_reset_tokens"
+	OIDCLoginStateCollection     = "oidc_login_states"
+	MFAChallengeCollection       = "mfa_challenges"
+	SessionCollection            = "sessions"
+	ReferenceTokenCollection     = "reference_tokens"
+)
+
+// expireAtField holds the expiry as a date for the TTL indexes, which ignore the unix milliseconds of expires_at.
+// It is only written by this repository and is not part of the entities.
+const expireAtField = "expire_at"
+
+type mongoAuthRepository struct {
+	refreshTokenFamilies *mongo.Collection
+	issuedTokens         *mongo.Collection
+	revokedTokens        *mongo.Collection
+	userTokenVersions    *mongo.Collection
+	passwordResetTokens  *mongo.Collection
+	oidcLoginStates      *mongo.Collection
+	mfaChallenges        *mongo.Collection
+	sessions             *mongo.Collection
+	referenceTokens      *mongo.Collection
+}
+
+func NewMongoAuthRepository(database *mongo.Database) AuthRepository {
+	return &mongoAuthRepository{
+		refreshTokenFamilies: database.Collection(RefreshTokenFamilyCollection),
+		issuedTokens:         database.Collection(IssuedTokenCollection),
+		revokedTokens:        database.Collection(RevokedTokenCollection),
+		userTokenVersions:    database.Collection(UserTokenVersionCollection),
+		passwordResetTokens:  database.Collection(PasswordResetTokenCollection),
+		oidcLoginStates:      database.Collection(OIDCLoginStateCollection),
+		mfaChallenges:        database.Collection(MFAChallengeCollection),
+		sessions:             database.Collection(SessionCollection),
+		referenceTokens:      database.Collection(ReferenceTokenCollection),
+	}
+}
+
+// Mongo - EnsureIndexes creates the indexes on the user of the records and the TTL indexes removing them once expired.
+// Records written before the TTL indexes existed get their expiry date first.
+func (r *mongoAuthRepository) EnsureIndexes(ctx context.Context) error {
+	collections := []struct {
+		collection *mongo.Collection
+		indexes    []mongo.IndexModel
+	}{
+		{r.refreshTokenFamilies, []mongo.IndexModel{{Keys: primitive.D{{Key: "user_id", Value: 1}}}}},
+		{r.sessions, []mongo.IndexModel{
+			{Keys: primitive.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+			{Keys: primitive.D{{Key: "family_id", Value: 1}}},
+		}},
+		{r.issuedTokens, nil},
+		{r.revokedTokens, nil},
+		{r.passwordResetTokens, []mongo.IndexModel{{Keys: primitive.D{{Key: "user_id", Value: 1}}}}},
+		{r.oidcLoginStates, nil},
+		{r.mfaChallenges, nil},
+		{r.referenceTokens, nil},
+	}
+
+	for _, c := range collections {
+		_, err := c.collection.UpdateMany(ctx,
+			primitive.D{{Key: expireAtField, Value: primitive.D{{Key: "$exists", Value: false}}}},
+			mongo.Pipeline{{{Key: "$set", Value: primitive.D{{Key: expireAtField, Value: primitive.D{{Key: "$toDate", Value: "$expires_at"}}}}}}},
+		)
+		if err != nil {
+			zap.L().Error("error setting expiry dates", zap.String("collection", c.collection.Name()), zap.Error(err))
+			return domain.ErrAuthInternalServerError
+		}
+
+		indexes := append(c.indexes, mongo.IndexModel{
+			Keys:    primitive.D{{Key: expireAtField, Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if _, err := c.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+			zap.L().Error("error creating indexes", zap.String("collection", c.collection.Name()), zap.Error(err))
+			return domain.ErrAuthInternalServerError
+		}
+	}
+
+	return nil
+}
+
+// withExpireAt returns the document of the entity with the expiry date read by the TTL indexes
+func withExpireAt(entity interface{}, expiresAt int64) (primitive.D, error) {
+	data, err := bson.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	document := primitive.D{}
+	if err := bson.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return append(document, primitive.E{Key: expireAtField, Value: expireAt(expiresAt)}), nil
+}
+
+// expireAt converts an expiry in unix milliseconds to the date read by the TTL indexes
+func expireAt(expiresAt int64) primitive.DateTime {
+	return primitive.NewDateTimeFromTime(time.UnixMilli(expiresAt))
+}
+
+// Mongo - CreateRefreshTokenFamily stores a new refresh token family
+func (r *mongoAuthRepository) CreateRefreshTokenFamily(ctx context.Context, family *domain.RefreshTokenFamilyEntity) (*domain.RefreshTokenFamilyEntity, error) {
+	if family == nil || family.ID == "" {
+		zap.L().Error("refresh token family is invalid", zap.Any("family", family))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	family.CreatedAt = time.Now().UnixMilli()
+	family.UpdatedAt = time.Now().UnixMilli()
+
+	document, err := withExpireAt(family, family.ExpiresAt)
+	if err == nil {
+		_, err = r.refreshTokenFamilies.InsertOne(ctx, document)
+	}
+	if err != nil {
+		zap.L().Error("error inserting refresh token family", zap.Error(err))
+		// Wrap infra error before returning to usecase
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return family, nil
+}
+
+// Mongo - FindRefreshTokenFamilyByID finds a refresh token family by its id
+func (r *mongoAuthRepository) FindRefreshTokenFamilyByID(ctx context.Context, id string) (*domain.RefreshTokenFamilyEntity, error) {
+	family := &domain.RefreshTokenFamilyEntity{}
+	err := r.refreshTokenFamilies.FindOne(ctx, primitive.D{{Key: "_id", Value: id}}).Decode(family)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrJWTRefreshTokenInvalid
+		}
+		zap.L().Error("error finding refresh token family", zap.String("family_id", id), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return family, nil
+}
+
+// Mongo - RotateRefreshTokenFamily atomically swaps the current jti of a non-revoked family
+func (r *mongoAuthRepository) RotateRefreshTokenFamily(ctx context.Context, id, currentJTI, nextJTI string, expiresAt int64) (bool, error) {
+	filter := primitive.D{
+		{Key: "_id", Value: id},
+		{Key: "current_jti", Value: currentJTI},
+		{Key: "revoked", Value: false},
+	}
+	update := primitive.D{{Key: "$set", Value: primitive.D{
+		{Key: "current_jti", Value: nextJTI},
+		{Key: "expires_at", Value: expiresAt},
+		{Key: expireAtField, Value: expireAt(expiresAt)},
+		{Key: "updated_at", Value: time.Now().UnixMilli()},
+	}}}
+
+	result, err := r.refreshTokenFamilies.UpdateOne(ctx, filter, update)
+	if err != nil {
+		zap.L().Error("error rotating refresh token family", zap.String("family_id", id), zap.Error(err))
+		return false, domain.ErrAuthInternalServerError
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// Mongo - RevokeRefreshTokenFamily marks a refresh token family as revoked
+func (r *mongoAuthRepository) RevokeRefreshTokenFamily(ctx context.Context, id string) error {
+	update := primitive.D{{Key: "$set", Value: primitive.D{
+		{Key: "revoked", Value: true},
+		{Key: "revoked_at", Value: time.Now().UnixMilli()},
+		{Key: "updated_at", Value: time.Now().UnixMilli()},
+	}}}
+
+	_, err := r.refreshTokenFamilies.UpdateOne(ctx, primitive.D{{Key: "_id", Value: id}}, update)
+	if err != nil {
+		zap.L().Error("error revoking refresh token family", zap.String("family_id", id), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	filter := primitive.D{
+		{Key: "family_id", Value: id},
+		{Key: "revoked_at", Value: 0},
+	}
+	_, err = r.sessions.UpdateOne(ctx, filter, revokeSessionUpdate())
+	if err != nil {
+		zap.L().Error("error revoking session of refresh token family", zap.String("family_id", id), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - RevokeRefreshTokenFamiliesByUserID revokes every active refresh token family of a user
+func (r *mongoAuthRepository) RevokeRefreshTokenFamiliesByUserID(ctx context.Context, userID string) error {
+	filter := primitive.D{
+		{Key: "user_id", Value: userID},
+		{Key: "revoked", Value: false},
+	}
+	update := primitive.D{{Key: "$set", Value: primitive.D{
+		{Key: "revoked", Value: true},
+		{Key: "revoked_at", Value: time.Now().UnixMilli()},
+		{Key: "updated_at", Value: time.Now().UnixMilli()},
+	}}}
+
+	_, err := r.refreshTokenFamilies.UpdateMany(ctx, filter, update)
+	if err != nil {
+		zap.L().Error("error revoking refresh token families of user", zap.String("user_id", userID), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	filter = primitive.D{
+		{Key: "user_id", Value: userID},
+		{Key: "revoked_at", Value: 0},
+	}
+	_, err = r.sessions.UpdateMany(ctx, filter, revokeSessionUpdate())
+	if err != nil {
+		zap.L().Error("error revoking sessions of user", zap.String("user_id", userID), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - SaveIssuedToken records an issued access token
+func (r *mongoAuthRepository) SaveIssuedToken(ctx context.Context, token *domain.IssuedTokenEntity) error {
+	if token == nil || token.ID == "" {
+		zap.L().Error("issued token is invalid", zap.Any("token", token))
+		return domain.ErrAuthInternalServerError
+	}
+
+	token.CreatedAt = time.Now().UnixMilli()
+	document, err := withExpireAt(token, token.ExpiresAt)
+	if err == nil {
+		_, err = r.issuedTokens.InsertOne(ctx, document)
+	}
+	if err != nil {
+		zap.L().Error("error inserting issued token", zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - FindIssuedTokenByID finds an issued access token by its jti
+func (r *mongoAuthRepository) FindIssuedTokenByID(ctx context.Context, jti string) (*domain.IssuedTokenEntity, error) {
+	token := &domain.IssuedTokenEntity{}
+	err := r.issuedTokens.FindOne(ctx, primitive.D{{Key: "_id", Value: jti}}).Decode(token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrAuthTokenNotFound
+		}
+		zap.L().Error("error finding issued token", zap.String("jti", jti), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return token, nil
+}
+
+// Mongo - RevokeToken adds a token to the denylist, revoking it twice is a no-op
+func (r *mongoAuthRepository) RevokeToken(ctx context.Context, token *domain.RevokedTokenEntity) error {
+	if token == nil || token.ID == "" {
+		zap.L().Error("revoked token is invalid", zap.Any("token", token))
+		return domain.ErrAuthInternalServerError
+	}
+
+	token.RevokedAt = time.Now().UnixMilli()
+	document, err := withExpireAt(token, token.ExpiresAt)
+	if err == nil {
+		_, err = r.revokedTokens.UpdateOne(ctx,
+			primitive.D{{Key: "_id", Value: token.ID}},
+			primitive.D{{Key: "$setOnInsert", Value: document}},
+			options.Update().SetUpsert(true),
+		)
+	}
+	if err != nil {
+		zap.L().Error("error revoking token", zap.String("jti", token.ID), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - IsTokenRevoked checks whether a token is in the denylist
+func (r *mongoAuthRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	count, err := r.revokedTokens.CountDocuments(ctx, primitive.D{{Key: "_id", Value: jti}}, options.Count().SetLimit(1))
+	if err != nil {
+		zap.L().Error("error checking revoked token", zap.String("jti", jti), zap.Error(err))
+		return false, domain.ErrAuthInternalServerError
+	}
+
+	return count > 0, nil
+}
+
+// Mongo - GetUserTokenVersion returns the current token version of a user, 0 when it was never bumped
+func (r *mongoAuthRepository) GetUserTokenVersion(ctx context.Context, userID string) (int64, error) {
+	version := &domain.UserTokenVersionEntity{}
+	err := r.userTokenVersions.FindOne(ctx, primitive.D{{Key: "_id", Value: userID}}).Decode(version)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		zap.L().Error("error finding user token version", zap.String("user_id", userID), zap.Error(err))
+		return 0, domain.ErrAuthInternalServerError
+	}
+
+	return version.Version, nil
+}
+
+// Mongo - IncrementUserTokenVersion bumps the token version of a user and returns the new version
+func (r *mongoAuthRepository) IncrementUserTokenVersion(ctx context.Context, userID string) (int64, error) {
+	update := primitive.D{
+		{Key: "$inc", Value: primitive.D{{Key: "version", Value: int64(1)}}},
+		{Key: "$set", Value: primitive.D{{Key: "updated_at", Value: time.Now().UnixMilli()}}},
+	}
+
+	version := &domain.UserTokenVersionEntity{}
+	err := r.userTokenVersions.FindOneAndUpdate(ctx,
+		primitive.D{{Key: "_id", Value: userID}},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(version)
+	if err != nil {
+		zap.L().Error("error incrementing user token version", zap.String("user_id", userID), zap.Error(err))
+		return 0, domain.ErrAuthInternalServerError
+	}
+
+	return version.Version, nil
+}
+
+// Mongo - CreateReferenceToken stores the access token of a reference token
+func (r *mongoAuthRepository) CreateReferenceToken(ctx context.Context, token *domain.ReferenceTokenEntity) error {
+	if token == nil || token.ID == "" {
+		zap.L().Error("reference token is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	token.CreatedAt = time.Now().UnixMilli()
+	document, err := withExpireAt(token, token.ExpiresAt)
+	if err == nil {
+		_, err = r.referenceTokens.InsertOne(ctx, document)
+	}
+	if err != nil {
+		zap.L().Error("error inserting reference token", zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - FindReferenceToken returns an unexpired reference token, the TTL index removes expired ones late
+func (r *mongoAuthRepository) FindReferenceToken(ctx context.Context, tokenHash string) (*domain.ReferenceTokenEntity, error) {
+	filter := primitive.D{
+		{Key: "_id", Value: tokenHash},
+		{Key: "expires_at", Value: primitive.D{{Key: "$gt", Value: time.Now().UnixMilli()}}},
+	}
+
+	token := &domain.ReferenceTokenEntity{}
+	err := r.referenceTokens.FindOne(ctx, filter).Decode(token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrJWTTokenInvalid
+		}
+		zap.L().Error("error finding reference token", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return token, nil
+}
+
+// Mongo - CreatePasswordResetToken stores a password reset token
+func (r *mongoAuthRepository) CreatePasswordResetToken(ctx context.Context, token *domain.PasswordResetTokenEntity) error {
+	if token == nil || token.ID == "" {
+		zap.L().Error("password reset token is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	token.CreatedAt = time.Now().UnixMilli()
+	document, err := withExpireAt(token, token.ExpiresAt)
+	if err == nil {
+		_, err = r.passwordResetTokens.InsertOne(ctx, document)
+	}
+	if err != nil {
+		zap.L().Error("error inserting password reset token", zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - FindPasswordResetToken returns an unused, unexpired token without using it
+func (r *mongoAuthRepository) FindPasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetTokenEntity, error) {
+	filter := primitive.D{
+		{Key: "_id", Value: tokenHash},
+		{Key: "used_at", Value: int64(0)},
+		{Key: "expires_at", Value: primitive.D{{Key: "$gt", Value: time.Now().UnixMilli()}}},
+	}
+
+	token := &domain.PasswordResetTokenEntity{}
+	err := r.passwordResetTokens.FindOne(ctx, filter).Decode(token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrPasswordResetTokenInvalid
+		}
+		zap.L().Error("error finding password reset token", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return token, nil
+}
+
+// Mongo - ConsumePasswordResetToken atomically marks the token as used so it works only once
+func (r *mongoAuthRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetTokenEntity, error) {
+	filter := primitive.D{
+		{Key: "_id", Value: tokenHash},
+		{Key: "used_at", Value: int64(0)},
+		{Key: "expires_at", Value: primitive.D{{Key: "$gt", Value: time.Now().UnixMilli()}}},
+	}
+	update := primitive.D{{Key: "$set", Value: primitive.D{{Key: "used_at", Value: time.Now().UnixMilli()}}}}
+
+	token := &domain.PasswordResetTokenEntity{}
+	err := r.passwordResetTokens.FindOneAndUpdate(ctx, filter, update).Decode(token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrPasswordResetTokenInvalid
+		}
+		zap.L().Error("error consuming password reset token", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return token, nil
+}
+
+// Mongo - InvalidatePasswordResetTokens marks every unused token of the user as used
+func (r *mongoAuthRepository) InvalidatePasswordResetTokens(ctx context.Context, userID string) error {
+	filter := primitive.D{
+		{Key: "user_id", Value: userID},
+		{Key: "used_at", Value: int64(0)},
+	}
+	update := primitive.D{{Key: "$set", Value: primitive.D{{Key: "used_at", Value: time.Now().UnixMilli()}}}}
+
+	_, err := r.passwordResetTokens.UpdateMany(ctx, filter, update)
+	if err != nil {
+		zap.L().Error("error invalidating password reset tokens", zap.String("user_id", userID), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - CreateOIDCLoginState stores the state of an external login
+func (r *mongoAuthRepository) CreateOIDCLoginState(ctx context.Context, state *domain.OIDCLoginStateEntity) error {
+	if state == nil || state.ID == "" {
+		zap.L().Error("oidc login state is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	state.CreatedAt = time.Now().UnixMilli()
+	document, err := withExpireAt(state, state.ExpiresAt)
+	if err == nil {
+		_, err = r.oidcLoginStates.InsertOne(ctx, document)
+	}
+	if err != nil {
+		zap.L().Error("error inserting oidc login state", zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - ConsumeOIDCLoginState atomically deletes the state so a callback can be handled only once
+func (r *mongoAuthRepository) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (*domain.OIDCLoginStateEntity, error) {
+	filter := primitive.D{
+		{Key: "_id", Value: stateHash},
+		{Key: "expires_at", Value: primitive.D{{Key: "$gt", Value: time.Now().UnixMilli()}}},
+	}
+
+	state := &domain.OIDCLoginStateEntity{}
+	err := r.oidcLoginStates.FindOneAndDelete(ctx, filter).Decode(state)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrOIDCStateInvalid
+		}
+		zap.L().Error("error consuming oidc login state", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return state, nil
+}
+
+// Mongo - CreateMFAChallenge stores a challenge waiting for its second factor
+func (r *mongoAuthRepository) CreateMFAChallenge(ctx context.Context, challenge *domain.PendingMFAChallengeEntity) error {
+	if challenge == nil || challenge.ID == "" {
+		zap.L().Error("mfa challenge is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	challenge.CreatedAt = time.Now().UnixMilli()
+	document, err := withExpireAt(challenge, challenge.ExpiresAt)
+	if err == nil {
+		_, err = r.mfaChallenges.InsertOne(ctx, document)
+	}
+	if err != nil {
+		zap.L().Error("error inserting mfa challenge", zap.String("user_id", challenge.UserID), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - ConsumeMFAChallenge atomically deletes the challenge so it can be completed only once
+func (r *mongoAuthRepository) ConsumeMFAChallenge(ctx context.Context, id string) (*domain.PendingMFAChallengeEntity, error) {
+	filter := primitive.D{
+		{Key: "_id", Value: id},
+		{Key: "expires_at", Value: primitive.D{{Key: "$gt", Value: time.Now().UnixMilli()}}},
+	}
+
+	challenge := &domain.PendingMFAChallengeEntity{}
+	err := r.mfaChallenges.FindOneAndDelete(ctx, filter).Decode(challenge)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrMFATokenInvalid
+		}
+		zap.L().Error("error consuming mfa challenge", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return challenge, nil
+}
+
+// Mongo - CreateSession stores the session started by a login
+func (r *mongoAuthRepository) CreateSession(ctx context.Context, session *domain.SessionEntity) error {
+	if session == nil || session.ID == "" {
+		zap.L().Error("session is invalid", zap.Any("session", session))
+		return domain.ErrAuthInternalServerError
+	}
+
+	session.CreatedAt = time.Now().UnixMilli()
+	session.LastSeenAt = session.CreatedAt
+	document, err := withExpireAt(session, session.ExpiresAt)
+	if err == nil {
+		_, err = r.sessions.InsertOne(ctx, document)
+	}
+	if err != nil {
+		zap.L().Error("error inserting session", zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - FindSessionByID finds a session by its id
+func (r *mongoAuthRepository) FindSessionByID(ctx context.Context, id string) (*domain.SessionEntity, error) {
+	session := &domain.SessionEntity{}
+	err := r.sessions.FindOne(ctx, primitive.D{{Key: "_id", Value: id}}).Decode(session)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrSessionNotFound
+		}
+		zap.L().Error("error finding session", zap.String("session_id", id), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return session, nil
+}
+
+// Mongo - ListActiveSessionsByUserID returns the unrevoked, unexpired sessions of the user, most recently seen first
+func (r *mongoAuthRepository) ListActiveSessionsByUserID(ctx context.Context, userID string) ([]*domain.SessionEntity, error) {
+	filter := primitive.D{
+		{Key: "user_id", Value: userID},
+		{Key: "revoked_at", Value: 0},
+		{Key: "expires_at", Value: primitive.D{{Key: "$gt", Value: time.Now().UnixMilli()}}},
+	}
+	opts := options.Find().SetSort(primitive.D{{Key: "last_seen_at", Value: -1}})
+
+	cursor, err := r.sessions.Find(ctx, filter, opts)
+	if err != nil {
+		zap.L().Error("error listing sessions", zap.String("user_id", userID), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+	sessions := []*domain.SessionEntity{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		zap.L().Error("error decoding sessions", zap.String("user_id", userID), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return sessions, nil
+}
+
+// Mongo - TouchSession records the last activity, ip and expiresAt are left unchanged when empty
+func (r *mongoAuthRepository) TouchSession(ctx context.Context, id, ip string, seenAt, expiresAt int64) error {
+	set := primitive.D{{Key: "last_seen_at", Value: seenAt}}
+	if ip != "" {
+		set = append(set, primitive.E{Key: "last_seen_ip", Value: ip})
+	}
+	if expiresAt != 0 {
+		set = append(set, primitive.E{Key: "expires_at", Value: expiresAt}, primitive.E{Key: expireAtField, Value: expireAt(expiresAt)})
+	}
+
+	_, err := r.sessions.UpdateOne(ctx, primitive.D{{Key: "_id", Value: id}}, primitive.D{{Key: "$set", Value: set}})
+	if err != nil {
+		zap.L().Error("error touching session", zap.String("session_id", id), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - RevokeSession revokes an active session of the user and its refresh token family
+func (r *mongoAuthRepository) RevokeSession(ctx context.Context, userID, id string) error {
+	filter := primitive.D{
+		{Key: "_id", Value: id},
+		{Key: "user_id", Value: userID},
+		{Key: "revoked_at", Value: 0},
+		{Key: "expires_at", Value: primitive.D{{Key: "$gt", Value: time.Now().UnixMilli()}}},
+	}
+
+	session := &domain.SessionEntity{}
+	err := r.sessions.FindOneAndUpdate(ctx, filter, revokeSessionUpdate()).Decode(session)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.ErrSessionNotFound
+		}
+		zap.L().Error("error revoking session", zap.String("session_id", id), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return r.RevokeRefreshTokenFamily(ctx, session.FamilyID)
+}
+
+func revokeSessionUpdate() primitive.D {
+	return primitive.D{{Key: "$set", Value: primitive.D{
+		{Key: "revoked_at", Value: time.Now().UnixMilli()},
+	}}}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// MongoDB implementation of OAuth repository
+
+const (
+	OAuthClientCollection            = "oauth_clients"
+	OAuthAuthorizationCodeCollection = "oauth_authorization_codes"
+)
+
+type mongoOAuthRepository struct {
+	clients            *mongo.Collection
+	authorizationCodes *mongo.Collection
+}
+
+func NewMongoOAuthRepository(database *mongo.Database) OAuthRepository {
+	return &mongoOAuthRepository{
+		clients:            database.Collection(OAuthClientCollection),
+		authorizationCodes: database.Collection(OAuthAuthorizationCodeCollection),
+	}
+}
+
+// Mongo - CreateClient stores a new OAuth client
+func (r *mongoOAuthRepository) CreateClient(ctx context.Context, client *domain.OAuthClientEntity) (*domain.OAuthClientEntity, error) {
+	if client == nil || client.ID == "" {
+		zap.L().Error("oauth client is invalid")
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	client.CreatedAt = time.Now().UnixMilli()
+	client.UpdatedAt = time.Now().UnixMilli()
+	_, err := r.clients.InsertOne(ctx, client)
+	if err != nil {
+		zap.L().Error("error inserting oauth client", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return client, nil
+}
+
+// Mongo - FindClientByID finds an OAuth client by its client id
+func (r *mongoOAuthRepository) FindClientByID(ctx context.Context, id string) (*domain.OAuthClientEntity, error) {
+	client := &domain.OAuthClientEntity{}
+	err := r.clients.FindOne(ctx, primitive.D{{Key: "_id", Value: id}}).Decode(client)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrOAuthClientNotFound
+		}
+		zap.L().Error("error finding oauth client", zap.String("client_id", id), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return client, nil
+}
+
+// Mongo - ListClients returns every OAuth client, newest first
+func (r *mongoOAuthRepository) ListClients(ctx context.Context) ([]*domain.OAuthClientEntity, error) {
+	cursor, err := r.clients.Find(ctx, primitive.D{}, options.Find().SetSort(primitive.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		zap.L().Error("error listing oauth clients", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+	defer cursor.Close(ctx)
+
+	clients := make([]*domain.OAuthClientEntity, 0)
+	if err := cursor.All(ctx, &clients); err != nil {
+		zap.L().Error("error decoding oauth clients", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return clients, nil
+}
+
+// Mongo - CreateAuthorizationCode stores an authorization code
+func (r *mongoOAuthRepository) CreateAuthorizationCode(ctx context.Context, code *domain.OAuthAuthorizationCodeEntity) error {
+	if code == nil || code.ID == "" {
+		zap.L().Error("authorization code is invalid")
+		return domain.ErrAuthInternalServerError
+	}
+
+	code.CreatedAt = time.Now().UnixMilli()
+	_, err := r.authorizationCodes.InsertOne(ctx, code)
+	if err != nil {
+		zap.L().Error("error inserting authorization code", zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - ConsumeAuthorizationCode atomically marks the code as used so it can be redeemed only once
+func (r *mongoOAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*domain.OAuthAuthorizationCodeEntity, error) {
+	filter := primitive.D{
+		{Key: "_id", Value: codeHash},
+		{Key: "used_at", Value: int64(0)},
+		{Key: "expires_at", Value: primitive.D{{Key: "$gt", Value: time.Now().UnixMilli()}}},
+	}
+	update := primitive.D{{Key: "$set", Value: primitive.D{{Key: "used_at", Value: time.Now().UnixMilli()}}}}
+
+	code := &domain.OAuthAuthorizationCodeEntity{}
+	err := r.authorizationCodes.FindOneAndUpdate(ctx, filter, update).Decode(code)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrOAuthInvalidGrant
+		}
+		zap.L().Error("error consuming authorization code", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return code, nil
+}
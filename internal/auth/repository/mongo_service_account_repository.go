@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// MongoDB implementation of service account repository
+
+const ServiceAccountCollection = "service_accounts"
+
+type mongoServiceAccountRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoServiceAccountRepository(database *mongo.Database) ServiceAccountRepository {
+	return &mongoServiceAccountRepository{
+		collection: database.Collection(ServiceAccountCollection),
+	}
+}
+
+// Mongo - CreateServiceAccount stores a new service account
+func (r *mongoServiceAccountRepository) CreateServiceAccount(ctx context.Context, account *domain.ServiceAccountEntity) (*domain.ServiceAccountEntity, error) {
+	if account == nil || account.ID == "" {
+		zap.L().Error("service account is invalid")
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	account.CreatedAt = time.Now().UnixMilli()
+	account.UpdatedAt = time.Now().UnixMilli()
+	_, err := r.collection.InsertOne(ctx, account)
+	if err != nil {
+		zap.L().Error("error inserting service account", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return account, nil
+}
+
+// Mongo - FindServiceAccountByID finds a service account by its client id
+func (r *mongoServiceAccountRepository) FindServiceAccountByID(ctx context.Context, id string) (*domain.ServiceAccountEntity, error) {
+	account := &domain.ServiceAccountEntity{}
+	err := r.collection.FindOne(ctx, primitive.D{{Key: "_id", Value: id}}).Decode(account)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrServiceAccountNotFound
+		}
+		zap.L().Error("error finding service account", zap.String("client_id", id), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return account, nil
+}
+
+// Mongo - ListServiceAccounts returns every service account, newest first
+func (r *mongoServiceAccountRepository) ListServiceAccounts(ctx context.Context) ([]*domain.ServiceAccountEntity, error) {
+	cursor, err := r.collection.Find(ctx, primitive.D{}, options.Find().SetSort(primitive.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		zap.L().Error("error listing service accounts", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+	defer cursor.Close(ctx)
+
+	accounts := make([]*domain.ServiceAccountEntity, 0)
+	if err := cursor.All(ctx, &accounts); err != nil {
+		zap.L().Error("error decoding service accounts", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return accounts, nil
+}
+
+// Mongo - UpdateServiceAccountSecrets replaces the secrets of the account
+func (r *mongoServiceAccountRepository) UpdateServiceAccountSecrets(ctx context.Context, id string, secrets []domain.ServiceAccountSecretEntity) (*domain.ServiceAccountEntity, error) {
+	update := primitive.D{{Key: "$set", Value: primitive.D{
+		{Key: "secrets", Value: secrets},
+		{Key: "updated_at", Value: time.Now().UnixMilli()},
+	}}}
+
+	account := &domain.ServiceAccountEntity{}
+	err := r.collection.FindOneAndUpdate(ctx, primitive.D{{Key: "_id", Value: id}}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(account)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrServiceAccountNotFound
+		}
+		zap.L().Error("error updating service account secrets", zap.String("client_id", id), zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	return account, nil
+}
+
+// Mongo - DeleteServiceAccount deletes a service account
+func (r *mongoServiceAccountRepository) DeleteServiceAccount(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, primitive.D{{Key: "_id", Value: id}})
+	if err != nil {
+		zap.L().Error("error deleting service account", zap.String("client_id", id), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrServiceAccountNotFound
+	}
+
+	return nil
+}
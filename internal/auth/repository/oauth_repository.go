@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+)
+
+// OAuth repository interface: registered clients and authorization codes
+
+type OAuthRepository interface {
+	// Clients
+	CreateClient(ctx context.Context, client *domain.OAuthClientEntity) (*domain.OAuthClientEntity, error)
+	FindClientByID(ctx context.Context, id string) (*domain.OAuthClientEntity, error)
+	ListClients(ctx context.Context) ([]*domain.OAuthClientEntity, error)
+
+	// Authorization codes
+	CreateAuthorizationCode(ctx context.Context, code *domain.OAuthAuthorizationCodeEntity) error
+	// ConsumeAuthorizationCode marks an unused, unexpired code as used and returns it
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*domain.OAuthAuthorizationCodeEntity, error)
+}
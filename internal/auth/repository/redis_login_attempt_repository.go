@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Redis implementation of login attempt repository, shared by every instance of the service
+
+const (
+	loginAttemptKeyPrefix = "login_attempt:"
+	loginLockKeyPrefix    = "login_lock:"
+)
+
+type redisLoginAttemptRepository struct {
+	client *redis.Client
+}
+
+func NewRedisLoginAttemptRepository(client *redis.Client) LoginAttemptRepository {
+	return &redisLoginAttemptRepository{client: client}
+}
+
+// Redis - IncrementFailedLogins increments the failure counter, the window restarts on every failure
+func (r *redisLoginAttemptRepository) IncrementFailedLogins(ctx context.Context, key string, window time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, loginAttemptKeyPrefix+key)
+		pipe.PExpire(ctx, loginAttemptKeyPrefix+key, window)
+		return nil
+	})
+	if err != nil {
+		zap.L().Error("error incrementing failed logins", zap.String("key", key), zap.Error(err))
+		return 0, domain.ErrAuthInternalServerError
+	}
+
+	return incr.Val(), nil
+}
+
+// Redis - LockLogin locks the key, the lock expires by itself
+func (r *redisLoginAttemptRepository) LockLogin(ctx context.Context, key string, duration time.Duration) error {
+	if err := r.client.Set(ctx, loginLockKeyPrefix+key, 1, duration).Err(); err != nil {
+		zap.L().Error("error locking login", zap.String("key", key), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
+
+// Redis - GetLoginLock returns the remaining lock duration
+func (r *redisLoginAttemptRepository) GetLoginLock(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, loginLockKeyPrefix+key).Result()
+	if err != nil {
+		zap.L().Error("error getting login lock", zap.String("key", key), zap.Error(err))
+		return 0, domain.ErrAuthInternalServerError
+	}
+	// -2 means the key does not exist, -1 that it has no expiry (never set by us)
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Redis - ResetLoginAttempts clears the failures and the lock of the key
+func (r *redisLoginAttemptRepository) ResetLoginAttempts(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, loginAttemptKeyPrefix+key, loginLockKeyPrefix+key).Err(); err != nil {
+		zap.L().Error("error resetting login attempts", zap.String("key", key), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	return nil
+}
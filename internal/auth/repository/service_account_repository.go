@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+)
+
+// Service account repository interface
+
+type ServiceAccountRepository interface {
+	CreateServiceAccount(ctx context.Context, account *domain.ServiceAccountEntity) (*domain.ServiceAccountEntity, error)
+	FindServiceAccountByID(ctx context.Context, id string) (*domain.ServiceAccountEntity, error)
+	ListServiceAccounts(ctx context.Context) ([]*domain.ServiceAccountEntity, error)
+	// UpdateServiceAccountSecrets replaces the secrets of the account and returns the updated account
+	UpdateServiceAccountSecrets(ctx context.Context, id string, secrets []domain.ServiceAccountSecretEntity) (*domain.ServiceAccountEntity, error)
+	DeleteServiceAccount(ctx context.Context, id string) error
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+)
+
+// Optional access token claims describing the user, set in ACCESS_TOKEN_CLAIMS.
+// None is issued by default, clients read the profile from the userinfo endpoint instead.
+const (
+	AccessTokenClaimUsername = "username"
+	AccessTokenClaimEmail    = "email"
+	AccessTokenClaimPhone    = "phone"
+	AccessTokenClaimAddress  = "address"
+	AccessTokenClaimGender   = "gender"
+)
+
+// AccessTokenClaims lists the claims describing the user that are copied into user access tokens,
+// the zero value only keeps sub, role and jti. The username of the actor (act) of an impersonation token
+// is always kept, it names the admin in the audit trail of every impersonated request.
+type AccessTokenClaims struct {
+	Username bool
+	Email    bool
+	Phone    bool
+	Address  bool
+	Gender   bool
+}
+
+// ParseAccessTokenClaims parses a comma separated list of claim names, e.g. "username,email"
+func ParseAccessTokenClaims(spec string) (AccessTokenClaims, error) {
+	enabled := AccessTokenClaims{}
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case AccessTokenClaimUsername:
+			enabled.Username = true
+		case AccessTokenClaimEmail:
+			enabled.Email = true
+		case AccessTokenClaimPhone:
+			enabled.Phone = true
+		case AccessTokenClaimAddress:
+			enabled.Address = true
+		case AccessTokenClaimGender:
+			enabled.Gender = true
+		default:
+			return AccessTokenClaims{}, fmt.Errorf("unknown access token claim %q", strings.TrimSpace(name))
+		}
+	}
+	return enabled, nil
+}
+
+// userClaims returns the access token claims of the user at the given token version, with the enabled claims only
+func (enabled AccessTokenClaims) userClaims(user *userDomain.UserEntity, version int64) *Claims {
+	claims := &Claims{
+		UserID:       user.ID.Hex(),
+		Role:         user.Role,
+		TokenVersion: version,
+	}
+	if enabled.Username {
+		claims.Username = user.Username
+	}
+	if enabled.Email {
+		claims.Email = user.Email
+	}
+	if enabled.Phone {
+		claims.Phone = user.Phone
+	}
+	if enabled.Address {
+		claims.Address = user.Address
+	}
+	if enabled.Gender {
+		claims.Gender = user.Gender
+	}
+	return claims
+}
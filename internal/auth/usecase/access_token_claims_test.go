@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+func newTestUser() *userDomain.UserEntity {
+	return userDomain.NewUserEntity("alice", "alice@example.com", "correct horse battery", "Alice", "+84 123", "Hanoi", shared.RoleUser, shared.GenderFemale)
+}
+
+// issueAccessToken signs the access token of the user the way the login does, with the given claims enabled
+func issueAccessToken(t *testing.T, jwtService JWTService, user *userDomain.UserEntity) string {
+	t.Helper()
+	issued, err := jwtService.GenerateJWT(context.Background(), jwtService.NewUserClaims(user, 0), &RefreshClaims{FamilyID: "family-1"})
+	if err != nil {
+		t.Fatalf("GenerateJWT() = %v", err)
+	}
+	return issued.AccessToken
+}
+
+func TestAccessTokenClaims(t *testing.T) {
+	user := newFakeUserService().add(newTestUser())
+
+	tests := []struct {
+		spec string
+		want []string // claims describing the user found in the token
+	}{
+		{spec: "", want: nil},
+		{spec: "username", want: []string{"username"}},
+		{spec: "username,email", want: []string{"username", "email"}},
+		{spec: "username,email,phone,address,gender", want: []string{"username", "email", "phone", "address", "gender"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("claims "+tt.spec, func(t *testing.T) {
+			enabled, err := ParseAccessTokenClaims(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseAccessTokenClaims(%q) = %v", tt.spec, err)
+			}
+			jwtService := NewJWTService(JWTConfig{Secret: testSecret, ExpiresIn: time.Minute, Issuer: testIssuer, Audience: testAudience, AccessTokenClaims: enabled})
+
+			payload := jwt.MapClaims{}
+			if _, _, err := jwt.NewParser().ParseUnverified(issueAccessToken(t, jwtService, user), payload); err != nil {
+				t.Fatalf("decode token: %v", err)
+			}
+			if payload["sub"] != user.ID.Hex() || payload["role"] != string(shared.RoleUser) {
+				t.Fatalf("token = %v, want sub %s with role user", payload, user.ID.Hex())
+			}
+			want := map[string]bool{}
+			for _, name := range tt.want {
+				want[name] = true
+			}
+			for _, name := range []string{"username", "email", "phone", "address", "gender"} {
+				if _, found := payload[name]; found != want[name] {
+					t.Errorf("token has %s = %v, want %v", name, found, want[name])
+				}
+			}
+		})
+	}
+
+	if _, err := ParseAccessTokenClaims("username,password"); err == nil {
+		t.Fatal("ParseAccessTokenClaims() accepted an unknown claim")
+	}
+}
+
+// The username is no longer in the access token by default, it is read by the subject
+func TestAuthenticateReadsTheUsername(t *testing.T) {
+	users := newFakeUserService()
+	user := users.add(newTestUser())
+	jwtService := NewJWTService(JWTConfig{Secret: testSecret, ExpiresIn: time.Minute, Issuer: testIssuer, Audience: testAudience})
+	service := NewAuthService(users, jwtService, repository.NewMemoryAuthRepository(), nil, nil, builtInPermissions{}, false)
+	token := issueAccessToken(t, jwtService, user)
+
+	principal, err := service.Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	if principal.UserID != user.ID.Hex() || principal.Username != "alice" {
+		t.Fatalf("Authenticate() = %s %q, want %s \"alice\"", principal.UserID, principal.Username, user.ID.Hex())
+	}
+
+	users.remove(user.ID)
+	if _, err := service.Authenticate(context.Background(), token); !errors.Is(err, domain.ErrJWTTokenRevoked) {
+		t.Fatalf("Authenticate() of a deleted user = %v, want %v", err, domain.ErrJWTTokenRevoked)
+	}
+}
@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// API key use case: personal API keys for scripts
+
+const (
+	// apiKeyPrefix marks our keys so secret scanners and people can recognise a leaked key
+	apiKeyPrefix           = "gas_"
+	apiKeyBytes            = 32
+	apiKeyVisiblePrefix    = len(apiKeyPrefix) + 8
+	apiKeyDefaultExpiresIn = 90 * 24 * time.Hour
+	apiKeyMaxActivePerUser = 20
+	// apiKeyLastUsedInterval limits the writes of the last use to one per key and interval
+	apiKeyLastUsedInterval = time.Minute
+)
+
+type APIKeyService interface {
+	// CreateAPIKey creates a key acting as the user, limited to the scopes
+	CreateAPIKey(ctx context.Context, principal *shared.Principal, data *dto.CreateAPIKeyRequest) (*domain.CreatedAPIKeyEntity, error)
+	ListAPIKeys(ctx context.Context, principal *shared.Principal) ([]*domain.APIKeyEntity, error)
+	RevokeAPIKey(ctx context.Context, principal *shared.Principal, id string) error
+	// AuthenticateAPIKey resolves a key into the principal of its user and records the use.
+	// It satisfies middleware.APIKeyAuthenticator.
+	AuthenticateAPIKey(ctx context.Context, key, clientIP string) (*shared.Principal, error)
+}
+
+type apiKeyService struct {
+	repo        repository.APIKeyRepository
+	authRepo    repository.AuthRepository
+	userService userUseCase.UserService
+	permissions PermissionResolver
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, authRepo repository.AuthRepository, userService userUseCase.UserService, permissions PermissionResolver) APIKeyService {
+	return &apiKeyService{
+		repo:        repo,
+		authRepo:    authRepo,
+		userService: userService,
+		permissions: permissions,
+	}
+}
+
+func (service *apiKeyService) CreateAPIKey(ctx context.Context, principal *shared.Principal, data *dto.CreateAPIKeyRequest) (*domain.CreatedAPIKeyEntity, error) {
+	if !canManageAPIKeys(principal) {
+		return nil, domain.ErrAPIKeyManagementForbidden
+	}
+	for _, scope := range data.Scopes {
+		if !containsString(shared.APIKeyScopes, scope) {
+			return nil, domain.ErrAPIKeyInvalidScope
+		}
+	}
+
+	keys, err := service.repo.ListAPIKeysByUserID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	active := 0
+	for _, key := range keys {
+		if key.IsActive(now) {
+			active++
+		}
+	}
+	if active >= apiKeyMaxActivePerUser {
+		return nil, domain.ErrAPIKeyLimitReached
+	}
+
+	expiresIn := apiKeyDefaultExpiresIn
+	if data.ExpiresInDays != nil {
+		expiresIn = time.Duration(*data.ExpiresInDays) * 24 * time.Hour
+	}
+	version, err := service.authRepo.GetUserTokenVersion(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	id, errID := utils.GenerateRandomToken(16)
+	secret, errSecret := utils.GenerateRandomToken(apiKeyBytes)
+	if errID != nil || errSecret != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	plaintext := apiKeyPrefix + secret
+
+	key, err := service.repo.CreateAPIKey(ctx, &domain.APIKeyEntity{
+		ID:           id,
+		UserID:       principal.UserID,
+		Name:         data.Name,
+		Prefix:       plaintext[:apiKeyVisiblePrefix],
+		Hash:         utils.HashToken(plaintext),
+		Scopes:       uniqueStrings(data.Scopes),
+		TokenVersion: version,
+		ExpiresAt:    time.Now().Add(expiresIn).UnixMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("api key created",
+		zap.String("user_id", principal.UserID),
+		zap.String("api_key_id", key.ID),
+		zap.Strings("scopes", key.Scopes),
+	)
+
+	return &domain.CreatedAPIKeyEntity{APIKey: key, Key: plaintext}, nil
+}
+
+func (service *apiKeyService) ListAPIKeys(ctx context.Context, principal *shared.Principal) ([]*domain.APIKeyEntity, error) {
+	if !canManageAPIKeys(principal) {
+		return nil, domain.ErrAPIKeyManagementForbidden
+	}
+	return service.repo.ListAPIKeysByUserID(ctx, principal.UserID)
+}
+
+func (service *apiKeyService) RevokeAPIKey(ctx context.Context, principal *shared.Principal, id string) error {
+	if !canManageAPIKeys(principal) {
+		return domain.ErrAPIKeyManagementForbidden
+	}
+	if err := service.repo.RevokeAPIKey(ctx, principal.UserID, id); err != nil {
+		return err
+	}
+	zap.L().Info("api key revoked",
+		zap.String("user_id", principal.UserID),
+		zap.String("api_key_id", id),
+	)
+	return nil
+}
+
+func (service *apiKeyService) AuthenticateAPIKey(ctx context.Context, key, clientIP string) (*shared.Principal, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, domain.ErrAPIKeyInvalid
+	}
+	apiKey, err := service.repo.FindAPIKeyByHash(ctx, utils.HashToken(key))
+	if err != nil {
+		if err == domain.ErrAPIKeyNotFound {
+			return nil, domain.ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !apiKey.IsActive(now.UnixMilli()) {
+		return nil, domain.ErrAPIKeyInvalid
+	}
+
+	// Keys created before the user logged out everywhere or reset the password are disabled
+	version, err := service.authRepo.GetUserTokenVersion(ctx, apiKey.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.TokenVersion < version {
+		return nil, domain.ErrAPIKeyInvalid
+	}
+
+	// The key acts with the current role of the user, not the role at creation
+	user, err := service.findUser(ctx, apiKey.UserID)
+	if err != nil {
+		if err == userDomain.ErrUserNotFound {
+			return nil, domain.ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+
+	if now.Sub(time.UnixMilli(apiKey.LastUsedAt)) >= apiKeyLastUsedInterval || apiKey.LastUsedIP != clientIP {
+		if err := service.repo.UpdateAPIKeyLastUsed(ctx, apiKey.ID, now.UnixMilli(), clientIP); err != nil {
+			zap.L().Warn("error recording api key use", zap.String("api_key_id", apiKey.ID), zap.Error(err))
+		}
+	}
+
+	principal := &shared.Principal{
+		Type:      shared.PrincipalTypeUser,
+		UserID:    user.ID.Hex(),
+		Username:  user.Username,
+		Role:      user.Role,
+		ExpiresAt: apiKey.ExpiresAt,
+		Scopes:    apiKey.Scopes,
+		APIKeyID:  apiKey.ID,
+	}
+	// Limited to the permissions the scopes of the key cover
+	principal.Permissions, err = service.permissions.ResolvePermissions(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+func (service *apiKeyService) findUser(ctx context.Context, userID string) (*userDomain.UserEntity, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, userDomain.ErrUserNotFound
+	}
+	return service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &userObjectID})
+}
+
+// canManageAPIKeys allows users logged in with their own session only:
+// a key cannot mint keys, and neither can service accounts or tokens issued to OAuth clients
+func canManageAPIKeys(principal *shared.Principal) bool {
+	return principal != nil && principal.IsLoginSession()
+}
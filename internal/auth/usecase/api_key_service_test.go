@@ -0,0 +1,235 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// apiKeyFixture is the API key service sharing the token version store of authFixture,
+// with alice signed in with her own session
+type apiKeyFixture struct {
+	*authFixture
+	keys      repository.APIKeyRepository
+	service   APIKeyService
+	principal *shared.Principal
+}
+
+func newAPIKeyFixture(t *testing.T) *apiKeyFixture {
+	t.Helper()
+	auth := newAuthFixture(t)
+	keys := repository.NewMemoryAPIKeyRepository()
+	principal, err := auth.service.Authenticate(context.Background(), auth.login(t).AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	return &apiKeyFixture{
+		authFixture: auth,
+		keys:        keys,
+		service:     NewAPIKeyService(keys, auth.repo, auth.users, builtInPermissions{}),
+		principal:   principal,
+	}
+}
+
+func (f *apiKeyFixture) create(t *testing.T, scopes ...string) *domain.CreatedAPIKeyEntity {
+	t.Helper()
+	created, err := f.service.CreateAPIKey(context.Background(), f.principal, &dto.CreateAPIKeyRequest{Name: "backup script", Scopes: scopes})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() = %v", err)
+	}
+	return created
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	f := newAPIKeyFixture(t)
+	days := 7
+	created, err := f.service.CreateAPIKey(context.Background(), f.principal, &dto.CreateAPIKeyRequest{
+		Name:          "backup script",
+		Scopes:        []string{shared.ScopeProfileRead, shared.ScopeProfileRead},
+		ExpiresInDays: &days,
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() = %v", err)
+	}
+
+	key := created.APIKey
+	if !strings.HasPrefix(created.Key, apiKeyPrefix) || !strings.HasPrefix(created.Key, key.Prefix) || len(key.Prefix) != apiKeyVisiblePrefix {
+		t.Fatalf("CreateAPIKey() key = %q, prefix %q, want a %q key starting with its visible prefix", created.Key, key.Prefix, apiKeyPrefix)
+	}
+	if key.Hash != utils.HashToken(created.Key) || key.UserID != f.user.ID.Hex() {
+		t.Fatalf("CreateAPIKey() = %+v, want the hashed key of alice", key)
+	}
+	if !slices.Equal(key.Scopes, []string{shared.ScopeProfileRead}) {
+		t.Fatalf("CreateAPIKey() scopes = %v, want them without duplicates", key.Scopes)
+	}
+	if expiresIn := time.Until(time.UnixMilli(key.ExpiresAt)); expiresIn < 7*24*time.Hour-time.Minute || expiresIn > 7*24*time.Hour {
+		t.Fatalf("CreateAPIKey() expires in %v, want 7 days", expiresIn)
+	}
+
+	_, err = f.service.CreateAPIKey(context.Background(), f.principal, &dto.CreateAPIKeyRequest{Name: "gateway", Scopes: []string{shared.ScopeTokensIntrospect}})
+	if err != domain.ErrAPIKeyInvalidScope {
+		t.Fatalf("CreateAPIKey() with a service account scope = %v, want %v", err, domain.ErrAPIKeyInvalidScope)
+	}
+}
+
+func TestCreateAPIKeyLimit(t *testing.T) {
+	f := newAPIKeyFixture(t)
+	for range apiKeyMaxActivePerUser {
+		f.create(t, shared.ScopeProfileRead)
+	}
+	_, err := f.service.CreateAPIKey(context.Background(), f.principal, &dto.CreateAPIKeyRequest{Name: "one too many", Scopes: []string{shared.ScopeProfileRead}})
+	if err != domain.ErrAPIKeyLimitReached {
+		t.Fatalf("CreateAPIKey() over the limit = %v, want %v", err, domain.ErrAPIKeyLimitReached)
+	}
+
+	// Revoked keys do not count
+	keys, err := f.service.ListAPIKeys(context.Background(), f.principal)
+	if err != nil {
+		t.Fatalf("ListAPIKeys() = %v", err)
+	}
+	if err := f.service.RevokeAPIKey(context.Background(), f.principal, keys[0].ID); err != nil {
+		t.Fatalf("RevokeAPIKey() = %v", err)
+	}
+	f.create(t, shared.ScopeProfileRead)
+}
+
+// Keys can only be managed from a login session, not with a key, a service account or an OAuth client token
+func TestAPIKeyManagementForbidden(t *testing.T) {
+	f := newAPIKeyFixture(t)
+	ctx := context.Background()
+	created := f.create(t, shared.ScopeProfileRead)
+	withKey, err := f.service.AuthenticateAPIKey(ctx, created.Key, "203.0.113.7")
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey() = %v", err)
+	}
+
+	principals := map[string]*shared.Principal{
+		"api key":         withKey,
+		"service account": {Type: shared.PrincipalTypeServiceAccount, ClientID: "sa_gateway"},
+		"oauth client":    {Type: shared.PrincipalTypeUser, UserID: f.principal.UserID, ClientID: "client"},
+		"impersonation":   {Type: shared.PrincipalTypeUser, UserID: f.principal.UserID, ActorID: "admin"},
+		"anonymous":       nil,
+	}
+	for name, principal := range principals {
+		if _, err := f.service.CreateAPIKey(ctx, principal, &dto.CreateAPIKeyRequest{Name: "minted", Scopes: []string{shared.ScopeProfileRead}}); err != domain.ErrAPIKeyManagementForbidden {
+			t.Errorf("CreateAPIKey() by %s = %v, want %v", name, err, domain.ErrAPIKeyManagementForbidden)
+		}
+		if _, err := f.service.ListAPIKeys(ctx, principal); err != domain.ErrAPIKeyManagementForbidden {
+			t.Errorf("ListAPIKeys() by %s = %v, want %v", name, err, domain.ErrAPIKeyManagementForbidden)
+		}
+		if err := f.service.RevokeAPIKey(ctx, principal, created.APIKey.ID); err != domain.ErrAPIKeyManagementForbidden {
+			t.Errorf("RevokeAPIKey() by %s = %v, want %v", name, err, domain.ErrAPIKeyManagementForbidden)
+		}
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	f := newAPIKeyFixture(t)
+	ctx := context.Background()
+	created := f.create(t, shared.ScopeProfileRead)
+
+	principal, err := f.service.AuthenticateAPIKey(ctx, created.Key, "203.0.113.7")
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey() = %v", err)
+	}
+	if principal.UserID != f.user.ID.Hex() || principal.Username != f.user.Username || principal.Role != f.user.Role || principal.APIKeyID != created.APIKey.ID {
+		t.Fatalf("AuthenticateAPIKey() = %+v, want alice through the key", principal)
+	}
+	if !principal.HasScope(shared.ScopeProfileRead) || principal.HasScope(shared.ScopeUsersRead) || principal.IsLoginSession() {
+		t.Fatalf("AuthenticateAPIKey() = %+v, want the scopes of the key only", principal)
+	}
+
+	// The last use is recorded
+	keys, err := f.service.ListAPIKeys(ctx, f.principal)
+	if err != nil {
+		t.Fatalf("ListAPIKeys() = %v", err)
+	}
+	if len(keys) != 1 || keys[0].LastUsedAt == 0 || keys[0].LastUsedIP != "203.0.113.7" {
+		t.Fatalf("ListAPIKeys() = %+v, want the last use recorded", keys)
+	}
+
+	// The key acts with the current role of the user
+	f.user.Role = shared.RoleAdmin
+	f.users.add(f.user)
+	principal, err = f.service.AuthenticateAPIKey(ctx, created.Key, "203.0.113.7")
+	if err != nil || principal.Role != shared.RoleAdmin {
+		t.Fatalf("AuthenticateAPIKey() after a role change = %+v, %v, want the admin role", principal, err)
+	}
+}
+
+func TestAuthenticateAPIKeyRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		// key returns the key to present, after changing the state
+		key func(t *testing.T, f *apiKeyFixture) string
+	}{
+		{"unknown key", func(t *testing.T, f *apiKeyFixture) string { return apiKeyPrefix + "unknown" }},
+		{"without prefix", func(t *testing.T, f *apiKeyFixture) string {
+			return strings.TrimPrefix(f.create(t, shared.ScopeProfileRead).Key, apiKeyPrefix)
+		}},
+		{"revoked", func(t *testing.T, f *apiKeyFixture) string {
+			created := f.create(t, shared.ScopeProfileRead)
+			if err := f.service.RevokeAPIKey(context.Background(), f.principal, created.APIKey.ID); err != nil {
+				t.Fatalf("RevokeAPIKey() = %v", err)
+			}
+			return created.Key
+		}},
+		{"expired", func(t *testing.T, f *apiKeyFixture) string {
+			const key = apiKeyPrefix + "expired"
+			_, err := f.keys.CreateAPIKey(context.Background(), &domain.APIKeyEntity{
+				ID:        "expired",
+				UserID:    f.user.ID.Hex(),
+				Hash:      utils.HashToken(key),
+				Scopes:    []string{shared.ScopeProfileRead},
+				ExpiresAt: time.Now().Add(-time.Minute).UnixMilli(),
+			})
+			if err != nil {
+				t.Fatalf("CreateAPIKey() = %v", err)
+			}
+			return key
+		}},
+		{"after logging out everywhere", func(t *testing.T, f *apiKeyFixture) string {
+			created := f.create(t, shared.ScopeProfileRead)
+			if err := f.authFixture.service.LogoutAll(context.Background(), f.principal); err != nil {
+				t.Fatalf("LogoutAll() = %v", err)
+			}
+			return created.Key
+		}},
+		{"deleted user", func(t *testing.T, f *apiKeyFixture) string {
+			created := f.create(t, shared.ScopeProfileRead)
+			f.users.remove(f.user.ID)
+			return created.Key
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAPIKeyFixture(t)
+			if _, err := f.service.AuthenticateAPIKey(context.Background(), tt.key(t, f), "203.0.113.7"); err != domain.ErrAPIKeyInvalid {
+				t.Fatalf("AuthenticateAPIKey() = %v, want %v", err, domain.ErrAPIKeyInvalid)
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKeyOfAnotherUser(t *testing.T) {
+	f := newAPIKeyFixture(t)
+	created := f.create(t, shared.ScopeProfileRead)
+
+	bob := f.users.add(&userDomain.UserEntity{Username: "bob", Email: "bob@example.com", Role: shared.RoleUser})
+	other := &shared.Principal{Type: shared.PrincipalTypeUser, UserID: bob.ID.Hex()}
+	if err := f.service.RevokeAPIKey(context.Background(), other, created.APIKey.ID); err != domain.ErrAPIKeyNotFound {
+		t.Fatalf("RevokeAPIKey() of alice's key by bob = %v, want %v", err, domain.ErrAPIKeyNotFound)
+	}
+	if _, err := f.service.AuthenticateAPIKey(context.Background(), created.Key, "203.0.113.7"); err != nil {
+		t.Fatalf("AuthenticateAPIKey() = %v, want the key still active", err)
+	}
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Audit use case: the trail of security relevant actions, readable by super admins
+
+// defaultAuditEventLimit is the number of events listed when the query sets no limit
+const defaultAuditEventLimit = 100
+
+type AuditService interface {
+	// Record stores the event, its id and time are set here. Missing usernames are read from the user store,
+	// access tokens carry no username unless ACCESS_TOKEN_CLAIMS adds it.
+	Record(ctx context.Context, event *domain.AuditEventEntity) error
+	// ListEvents returns the events matching the query, newest first
+	ListEvents(ctx context.Context, query *dto.AuditEventQuery) ([]*domain.AuditEventEntity, error)
+}
+
+type auditService struct {
+	repo        repository.AuditRepository
+	userService userUseCase.UserService
+}
+
+func NewAuditService(repo repository.AuditRepository, userService userUseCase.UserService) AuditService {
+	return &auditService{repo: repo, userService: userService}
+}
+
+func (service *auditService) Record(ctx context.Context, event *domain.AuditEventEntity) error {
+	id, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return domain.ErrAuthInternalServerError
+	}
+	event.ID = id
+	event.CreatedAt = 0
+	event.UserAgent = truncate(event.UserAgent, maxUserAgentLength)
+	event.ActorUsername = service.username(ctx, event.ActorID, event.ActorUsername)
+	event.Username = service.username(ctx, event.UserID, event.Username)
+	return service.repo.CreateAuditEvent(ctx, event)
+}
+
+// username returns the given username, or reads it when missing. The event is still recorded when the user
+// cannot be read, the user id identifies them.
+func (service *auditService) username(ctx context.Context, userID, username string) string {
+	if username != "" || userID == "" {
+		return username
+	}
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ""
+	}
+	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &objectID})
+	if err != nil {
+		zap.L().Warn("audit event recorded without username", zap.String("user_id", userID), zap.Error(err))
+		return ""
+	}
+	return user.Username
+}
+
+func (service *auditService) ListEvents(ctx context.Context, query *dto.AuditEventQuery) ([]*domain.AuditEventEntity, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAuditEventLimit
+	}
+	return service.repo.ListAuditEvents(ctx, repository.AuditEventFilters{
+		Type:    query.Type,
+		ActorID: query.ActorID,
+		UserID:  query.UserID,
+		Limit:   limit,
+	})
+}
@@ -2,54 +2,538 @@ package usecase
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
 	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
 	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
 )
 
 // Auth use case (application service)
+
+// mfaThrottlePrefix separates second factor attempts from username attempts in the login throttler
+const mfaThrottlePrefix = "mfa:"
+
+// sessionTouchInterval limits how often requests update the last seen time of their session
+const sessionTouchInterval = time.Minute
+
+// PermissionResolver expands the roles and scopes of a principal into permissions, it is implemented by the authz module
+type PermissionResolver interface {
+	ResolvePermissions(ctx context.Context, principal *shared.Principal) ([]shared.Permission, error)
+}
+
 type AuthService interface {
-	Login(ctx context.Context, data *dto.LoginRequest) (*domain.JWTAuthEntity, error)
+	// Login returns the tokens, or an MFA challenge when the user has MFA enabled
+	Login(ctx context.Context, data *dto.LoginRequest) (*domain.JWTAuthEntity, *domain.MFAChallengeEntity, error)
+	VerifyMFA(ctx context.Context, data *dto.MFAVerifyRequest) (*domain.JWTAuthEntity, error)
+	// LoginExternalUser logs in a user already authenticated by an external identity provider
+	LoginExternalUser(ctx context.Context, user *userDomain.UserEntity, device dto.DeviceInfo) (*domain.JWTAuthEntity, *domain.MFAChallengeEntity, error)
+	// VerifyPassword checks the first factor with the same throttling as Login, without issuing tokens
+	VerifyPassword(ctx context.Context, data *dto.LoginRequest) (*userDomain.UserEntity, error)
+	// VerifySecondFactor checks an MFA challenge with the same throttling as VerifyMFA, without issuing tokens
+	VerifySecondFactor(ctx context.Context, data *dto.MFAVerifyRequest) (*userDomain.UserEntity, error)
+	// StartClientSession issues tokens to an OAuth client on behalf of the user, limited to the granted scope
+	StartClientSession(ctx context.Context, user *userDomain.UserEntity, clientID, scope string, device dto.DeviceInfo) (*domain.JWTAuthEntity, error)
+	RefreshToken(ctx context.Context, data *dto.RefreshTokenRequest) (*domain.JWTAuthEntity, error)
+	// RefreshClientToken rotates a refresh token that was issued to the OAuth client
+	RefreshClientToken(ctx context.Context, refreshToken, clientID string) (*domain.JWTAuthEntity, error)
+	Authenticate(ctx context.Context, token string) (*shared.Principal, error)
+	// ValidateAccessToken runs the checks of Authenticate and returns the claims of the token
+	ValidateAccessToken(ctx context.Context, token string) (*Claims, error)
+	Logout(ctx context.Context, principal *shared.Principal) error
+	LogoutAll(ctx context.Context, principal *shared.Principal) error
+	GetJWKS(ctx context.Context) *domain.JWKSEntity
 }
 
 type authService struct {
 	userService userUseCase.UserService
 	jwtService  JWTService
+	repo        repository.AuthRepository
+	mfaService  MFAService
+	throttler   LoginThrottler
+	permissions PermissionResolver
+
+	requireVerifiedEmail bool
 }
 
-func NewAuthService(userService userUseCase.UserService, jwtService JWTService) AuthService {
-	return &authService{userService: userService, jwtService: jwtService}
+// NewAuthService creates the auth service.
+// When requireVerifiedEmail is set, users must verify their email before they can log in.
+func NewAuthService(userService userUseCase.UserService, jwtService JWTService, repo repository.AuthRepository, mfaService MFAService, throttler LoginThrottler, permissions PermissionResolver, requireVerifiedEmail bool) AuthService {
+	return &authService{
+		userService:          userService,
+		jwtService:           jwtService,
+		repo:                 repo,
+		mfaService:           mfaService,
+		throttler:            throttler,
+		permissions:          permissions,
+		requireVerifiedEmail: requireVerifiedEmail,
+	}
 }
 
-func (service *authService) Login(ctx context.Context, data *dto.LoginRequest) (*domain.JWTAuthEntity, error) {
-	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{
-		Username: &data.Username,
-	})
+func (service *authService) Login(ctx context.Context, data *dto.LoginRequest) (*domain.JWTAuthEntity, *domain.MFAChallengeEntity, error) {
+	user, err := service.VerifyPassword(ctx, data)
 	if err != nil {
+		return nil, nil, err
+	}
+
+	return service.completeLogin(ctx, user, dto.DeviceInfo{ClientIP: data.ClientIP, UserAgent: data.UserAgent})
+}
+
+func (service *authService) VerifyPassword(ctx context.Context, data *dto.LoginRequest) (*userDomain.UserEntity, error) {
+	// Throttled by the normalized identifier so changing its case does not give more attempts
+	identifier := strings.ToLower(strings.TrimSpace(data.Username))
+
+	// Refuse while the identifier or the client IP is delayed or locked, before touching the password
+	if err := service.throttler.Check(ctx, identifier, data.ClientIP); err != nil {
+		return nil, err
+	}
+
+	user, err := service.findUserByIdentifier(ctx, identifier)
+	if err != nil && err != userDomain.ErrUserNotFound {
 		return nil, err
 	}
-	if user == nil {
-		return nil, domain.ErrAuthUserNotFound
+	// Unknown users pay for a password comparison too and get the same error as a wrong password
+	match, err := service.userService.ComparePassword(ctx, user, data.Password)
+	if err != nil {
+		// Busy, not a failed attempt
+		return nil, err
+	}
+	if !match {
+		service.throttler.RecordFailure(ctx, identifier, data.ClientIP)
+		return nil, domain.ErrAuthInvalidCredentials
+	}
+	service.throttler.Reset(ctx, identifier)
+
+	// The plain password is only known now, upgrade hashes made with an older algorithm or cost.
+	// The login goes on with the old hash if this fails, it is retried on the next one.
+	if service.userService.PasswordNeedsRehash(user) {
+		if err := service.userService.UpdatePassword(ctx, user.ID, data.Password); err != nil {
+			zap.L().Error("error rehashing password", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+		}
+	}
+
+	// Checked after the password so the verification state is not revealed to anyone without it
+	if service.requireVerifiedEmail && !user.EmailVerified {
+		return nil, domain.ErrAuthEmailNotVerified
+	}
+
+	return user, nil
+}
+
+func (service *authService) findUser(ctx context.Context, userID string) (*userDomain.UserEntity, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, userDomain.ErrUserNotFound
+	}
+	return service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &userObjectID})
+}
+
+// findUserByIdentifier looks the login identifier up as an email when it has an "@", as a username otherwise.
+// Usernames could contain "@" before it was forbidden, those are still found when no email matches.
+func (service *authService) findUserByIdentifier(ctx context.Context, identifier string) (*userDomain.UserEntity, error) {
+	if strings.Contains(identifier, "@") {
+		user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{Email: &identifier})
+		if err != userDomain.ErrUserNotFound {
+			return user, err
+		}
 	}
-	// Compare password
-	if !utils.ComparePassword(data.Password, user.Password) {
-		return nil, domain.ErrInvalidPassword
+	return service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{Username: &identifier})
+}
+
+func (service *authService) LoginExternalUser(ctx context.Context, user *userDomain.UserEntity, device dto.DeviceInfo) (*domain.JWTAuthEntity, *domain.MFAChallengeEntity, error) {
+	return service.completeLogin(ctx, user, device)
+}
+
+// completeLogin runs after the first factor: it returns an MFA challenge when MFA is enabled, the tokens otherwise
+func (service *authService) completeLogin(ctx context.Context, user *userDomain.UserEntity, device dto.DeviceInfo) (*domain.JWTAuthEntity, *domain.MFAChallengeEntity, error) {
+	// The first factor is not enough, tokens are issued once the second one is verified
+	if user.MFAEnabled {
+		challenge, err := service.mfaService.CreateChallenge(ctx, user)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
 	}
+
+	auth, err := service.startSession(ctx, user, device)
+	if err != nil {
+		return nil, nil, err
+	}
+	return auth, nil, nil
+}
+
+// VerifyMFA completes a login that was answered with an MFA challenge
+func (service *authService) VerifyMFA(ctx context.Context, data *dto.MFAVerifyRequest) (*domain.JWTAuthEntity, error) {
+	user, err := service.VerifySecondFactor(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.startSession(ctx, user, dto.DeviceInfo{ClientIP: data.ClientIP, UserAgent: data.UserAgent})
+}
+
+func (service *authService) VerifySecondFactor(ctx context.Context, data *dto.MFAVerifyRequest) (*userDomain.UserEntity, error) {
+	claims, err := service.jwtService.ParseMFAToken(data.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Second factor guesses are throttled per user like passwords
+	throttleKey := mfaThrottlePrefix + claims.UserID
+	if err := service.throttler.Check(ctx, throttleKey, data.ClientIP); err != nil {
+		return nil, err
+	}
+	user, err := service.mfaService.VerifyChallenge(ctx, data)
+	if err != nil {
+		if err == domain.ErrMFAInvalidCode {
+			service.throttler.RecordFailure(ctx, throttleKey, data.ClientIP)
+		}
+		return nil, err
+	}
+	service.throttler.Reset(ctx, throttleKey)
+
+	return user, nil
+}
+
+// startSession issues the tokens of a successful login in a new refresh token family
+func (service *authService) startSession(ctx context.Context, user *userDomain.UserEntity, device dto.DeviceInfo) (*domain.JWTAuthEntity, error) {
+	return service.startFamily(ctx, user, "", "", device)
+}
+
+func (service *authService) StartClientSession(ctx context.Context, user *userDomain.UserEntity, clientID, scope string, device dto.DeviceInfo) (*domain.JWTAuthEntity, error) {
+	return service.startFamily(ctx, user, clientID, scope, device)
+}
+
+// startFamily starts a new refresh token family and the session tracking it, bound to the OAuth client when clientID is set
+func (service *authService) startFamily(ctx context.Context, user *userDomain.UserEntity, clientID, scope string, device dto.DeviceInfo) (*domain.JWTAuthEntity, error) {
+	familyID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	sessionID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	family := &domain.RefreshTokenFamilyEntity{
+		ID:        familyID,
+		UserID:    user.ID.Hex(),
+		ClientID:  clientID,
+		Scope:     scope,
+		SessionID: sessionID,
+	}
+
 	// Generate JWT
-	auth, err := service.jwtService.GenerateJWT(&Claims{
-		UserID:   user.ID.Hex(),
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
-		Phone:    user.Phone,
-		Address:  user.Address,
-		Gender:   user.Gender,
+	auth, refreshClaims, err := service.generateTokens(ctx, user, family)
+	if err != nil {
+		return nil, err
+	}
+
+	family.CurrentJTI = refreshClaims.ID
+	family.ExpiresAt = refreshClaims.ExpiresAt.UnixMilli()
+	_, err = service.repo.CreateRefreshTokenFamily(ctx, family)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.repo.CreateSession(ctx, &domain.SessionEntity{
+		ID:         sessionID,
+		UserID:     family.UserID,
+		FamilyID:   familyID,
+		ClientID:   clientID,
+		UserAgent:  truncate(device.UserAgent, maxUserAgentLength),
+		Device:     describeUserAgent(device.UserAgent),
+		IP:         device.ClientIP,
+		LastSeenIP: device.ClientIP,
+		ExpiresAt:  family.ExpiresAt,
 	})
 	if err != nil {
 		return nil, err
 	}
 	return auth, nil
 }
+
+// RefreshToken exchanges a refresh token for a new access and refresh token pair.
+// Every refresh token can be used only once: replaying an already rotated token revokes the whole family.
+func (service *authService) RefreshToken(ctx context.Context, data *dto.RefreshTokenRequest) (*domain.JWTAuthEntity, error) {
+	return service.rotateRefreshToken(ctx, data.RefreshToken, "", data.ClientIP)
+}
+
+func (service *authService) RefreshClientToken(ctx context.Context, refreshToken, clientID string) (*domain.JWTAuthEntity, error) {
+	// The client refreshes from its own backend, its address says nothing about the user's device
+	return service.rotateRefreshToken(ctx, refreshToken, clientID, "")
+}
+
+// rotateRefreshToken rotates the refresh token, the family must belong to clientID (empty for our own login).
+// The session of the family is extended with it, clientIP is recorded as its last seen address when set.
+func (service *authService) rotateRefreshToken(ctx context.Context, refreshToken, clientID, clientIP string) (*domain.JWTAuthEntity, error) {
+	claims, err := service.jwtService.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	family, err := service.repo.FindRefreshTokenFamilyByID(ctx, claims.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if family.Revoked || family.UserID != claims.UserID || family.ExpiresAt < time.Now().UnixMilli() {
+		return nil, domain.ErrJWTRefreshTokenInvalid
+	}
+	// A token issued to an OAuth client can only be refreshed by that client
+	if family.ClientID != clientID {
+		return nil, domain.ErrJWTRefreshTokenInvalid
+	}
+	if family.CurrentJTI != claims.ID {
+		// The token was already rotated, someone is replaying it
+		zap.L().Warn("refresh token reuse detected, revoking family",
+			zap.String("family_id", family.ID),
+			zap.String("user_id", family.UserID),
+		)
+		if err := service.repo.RevokeRefreshTokenFamily(ctx, family.ID); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrJWTRefreshTokenInvalid
+	}
+
+	// Reload the user so that role or profile changes are reflected in the new access token
+	userObjectID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, domain.ErrJWTRefreshTokenInvalid
+	}
+	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &userObjectID})
+	if err != nil {
+		if err == userDomain.ErrUserNotFound {
+			_ = service.repo.RevokeRefreshTokenFamily(ctx, family.ID)
+			return nil, domain.ErrJWTRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	auth, refreshClaims, err := service.generateTokens(ctx, user, family)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := service.repo.RotateRefreshTokenFamily(ctx, family.ID, claims.ID, refreshClaims.ID, refreshClaims.ExpiresAt.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request rotated this token concurrently, treat it as a replay
+		zap.L().Warn("concurrent refresh token reuse detected, revoking family",
+			zap.String("family_id", family.ID),
+			zap.String("user_id", family.UserID),
+		)
+		if err := service.repo.RevokeRefreshTokenFamily(ctx, family.ID); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrJWTRefreshTokenInvalid
+	}
+
+	if family.SessionID != "" {
+		err = service.repo.TouchSession(ctx, family.SessionID, clientIP, time.Now().UnixMilli(), refreshClaims.ExpiresAt.UnixMilli())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return auth, nil
+}
+
+// Authenticate validates an access token and returns the principal it was issued to.
+// It satisfies middleware.Authenticator so other modules can protect their routes.
+func (service *authService) Authenticate(ctx context.Context, token string) (*shared.Principal, error) {
+	claims, err := service.ValidateAccessToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &shared.Principal{
+		Type:      shared.PrincipalTypeUser,
+		UserID:    claims.UserID,
+		Role:      claims.Role,
+		TokenID:   claims.ID,
+		ExpiresAt: claims.ExpiresAt.UnixMilli(),
+		ClientID:  claims.ClientID,
+		Scopes:    strings.Fields(claims.Scope),
+		SessionID: claims.SessionID,
+	}
+	if claims.PrincipalType == shared.PrincipalTypeServiceAccount {
+		// Service accounts have no user, their token carries the account name
+		principal.Type = shared.PrincipalTypeServiceAccount
+		principal.Username = claims.Username
+	} else {
+		// Access tokens do not carry the username by default, it is read by the subject
+		user, err := service.findUser(ctx, claims.UserID)
+		if err != nil {
+			if err == userDomain.ErrUserNotFound {
+				return nil, domain.ErrJWTTokenRevoked
+			}
+			return nil, err
+		}
+		principal.Username = user.Username
+	}
+	if claims.Actor != nil {
+		principal.ActorID = claims.Actor.Subject
+		principal.ActorUsername = claims.Actor.Username
+	}
+
+	// Resolved on every request so that role changes apply at once
+	principal.Permissions, err = service.permissions.ResolvePermissions(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+func (service *authService) ValidateAccessToken(ctx context.Context, token string) (*Claims, error) {
+	claims, err := service.jwtService.ParseAccessToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject tokens that were logged out
+	revoked, err := service.repo.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, domain.ErrJWTTokenRevoked
+	}
+
+	// Reject tokens minted before the user logged out everywhere, or before the service account was deleted
+	version, err := service.repo.GetUserTokenVersion(ctx, claims.SubjectID())
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenVersion < version {
+		return nil, domain.ErrJWTTokenRevoked
+	}
+
+	// Reject tokens of a session the user signed out from another device.
+	// Impersonation tokens carry the session of the admin, they stop working when the admin signs out.
+	if claims.SessionID != "" {
+		if err := service.checkSession(ctx, claims.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+// checkSession rejects revoked or expired sessions and records the activity of the active ones
+func (service *authService) checkSession(ctx context.Context, sessionID string) error {
+	session, err := service.repo.FindSessionByID(ctx, sessionID)
+	if err != nil {
+		if err == domain.ErrSessionNotFound {
+			return domain.ErrJWTTokenRevoked
+		}
+		return err
+	}
+	now := time.Now()
+	if !session.IsActive(now.UnixMilli()) {
+		return domain.ErrJWTTokenRevoked
+	}
+
+	if now.Sub(time.UnixMilli(session.LastSeenAt)) >= sessionTouchInterval {
+		if err := service.repo.TouchSession(ctx, session.ID, "", now.UnixMilli(), 0); err != nil {
+			zap.L().Warn("failed to update session last seen time", zap.String("session_id", session.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Logout revokes the access token of the current request and the refresh token family issued with it
+func (service *authService) Logout(ctx context.Context, principal *shared.Principal) error {
+	err := service.repo.RevokeToken(ctx, &domain.RevokedTokenEntity{
+		ID:        principal.TokenID,
+		UserID:    principal.SubjectID(),
+		ExpiresAt: principal.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	issuedToken, err := service.repo.FindIssuedTokenByID(ctx, principal.TokenID)
+	if err != nil {
+		if err == domain.ErrAuthTokenNotFound {
+			// Nothing else to revoke
+			return nil
+		}
+		return err
+	}
+	if issuedToken.FamilyID != "" {
+		return service.repo.RevokeRefreshTokenFamily(ctx, issuedToken.FamilyID)
+	}
+	return nil
+}
+
+// LogoutAll bumps the user's token version so every access token minted earlier is rejected,
+// and revokes all refresh token families of the user
+func (service *authService) LogoutAll(ctx context.Context, principal *shared.Principal) error {
+	version, err := service.repo.IncrementUserTokenVersion(ctx, principal.SubjectID())
+	if err != nil {
+		return err
+	}
+	zap.L().Info("user logged out everywhere",
+		zap.String("user_id", principal.UserID),
+		zap.Int64("token_version", version),
+	)
+
+	return service.repo.RevokeRefreshTokenFamiliesByUserID(ctx, principal.UserID)
+}
+
+// GetJWKS returns the public keys other services use to verify our tokens
+func (service *authService) GetJWKS(ctx context.Context) *domain.JWKSEntity {
+	return service.jwtService.JWKS()
+}
+
+// generateTokens builds the claims for the user, signs a new token pair in the given refresh token family
+// and records the issued access token
+func (service *authService) generateTokens(ctx context.Context, user *userDomain.UserEntity, family *domain.RefreshTokenFamilyEntity) (*domain.JWTAuthEntity, *RefreshClaims, error) {
+	accessJTI, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, nil, domain.ErrAuthInternalServerError
+	}
+	refreshJTI, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, nil, domain.ErrAuthInternalServerError
+	}
+	version, err := service.repo.GetUserTokenVersion(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims := service.jwtService.NewUserClaims(user, version)
+	claims.FamilyID = family.ID
+	claims.SessionID = family.SessionID
+	claims.ClientID = family.ClientID
+	claims.Scope = family.Scope
+	claims.ID = accessJTI
+	refreshClaims := &RefreshClaims{FamilyID: family.ID}
+	refreshClaims.ID = refreshJTI
+
+	auth, err := service.jwtService.GenerateJWT(ctx, claims, refreshClaims)
+	if err != nil {
+		return nil, nil, err
+	}
+	auth.Scope = family.Scope
+
+	err = service.repo.SaveIssuedToken(ctx, &domain.IssuedTokenEntity{
+		ID:        accessJTI,
+		UserID:    claims.UserID,
+		FamilyID:  family.ID,
+		ExpiresAt: claims.ExpiresAt.UnixMilli(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return auth, refreshClaims, nil
+}
@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+)
+
+// authFixture is an auth service over the memory repositories with one user, alice
+type authFixture struct {
+	users   *fakeUserService
+	repo    repository.AuthRepository
+	user    *userDomain.UserEntity
+	service AuthService
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+	users := newFakeUserService()
+	user := users.add(newTestUser())
+	repo := repository.NewMemoryAuthRepository()
+	jwtService := newTestJWTService()
+	throttler := NewLoginThrottler(repository.NewMemoryLoginAttemptRepository(), DefaultUsernameThrottleLimits, DefaultIPThrottleLimits)
+	mfaService := NewMFAService(users, jwtService, repo, throttler, "Test", testMFAKey)
+	return &authFixture{
+		users:   users,
+		repo:    repo,
+		user:    user,
+		service: NewAuthService(users, jwtService, repo, mfaService, throttler, builtInPermissions{}, false),
+	}
+}
+
+// login starts a new session of alice, as after her password was checked
+func (f *authFixture) login(t *testing.T) *domain.JWTAuthEntity {
+	t.Helper()
+	auth, challenge, err := f.service.LoginExternalUser(context.Background(), f.user, dto.DeviceInfo{ClientIP: "203.0.113.7", UserAgent: "test"})
+	if err != nil || challenge != nil {
+		t.Fatalf("LoginExternalUser() = %v, %v, want tokens", challenge, err)
+	}
+	return auth
+}
+
+func (f *authFixture) refresh(refreshToken string) (*domain.JWTAuthEntity, error) {
+	return f.service.RefreshToken(context.Background(), &dto.RefreshTokenRequest{RefreshToken: refreshToken, ClientIP: "203.0.113.7"})
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	f := newAuthFixture(t)
+	auth := f.login(t)
+
+	// Every refresh hands out a new pair and the new refresh token is the one to use next
+	for i := range 3 {
+		rotated, err := f.refresh(auth.RefreshToken)
+		if err != nil {
+			t.Fatalf("refresh #%d = %v", i, err)
+		}
+		if rotated.RefreshToken == auth.RefreshToken || rotated.AccessToken == auth.AccessToken {
+			t.Fatalf("refresh #%d returned the same tokens", i)
+		}
+		if _, err := f.service.Authenticate(context.Background(), rotated.AccessToken); err != nil {
+			t.Fatalf("Authenticate() with the refreshed access token = %v", err)
+		}
+		auth = rotated
+	}
+}
+
+func TestRefreshTokenReuseRevokesTheFamily(t *testing.T) {
+	f := newAuthFixture(t)
+	first := f.login(t)
+	second, err := f.refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh = %v", err)
+	}
+	other := f.login(t)
+
+	// Replaying the rotated token means it leaked, the legitimate holder loses the family too
+	if _, err := f.refresh(first.RefreshToken); !errors.Is(err, domain.ErrJWTRefreshTokenInvalid) {
+		t.Fatalf("replayed refresh = %v, want %v", err, domain.ErrJWTRefreshTokenInvalid)
+	}
+	if _, err := f.refresh(second.RefreshToken); !errors.Is(err, domain.ErrJWTRefreshTokenInvalid) {
+		t.Fatalf("refresh after the replay = %v, want %v", err, domain.ErrJWTRefreshTokenInvalid)
+	}
+	// Other logins of the user are separate families
+	if _, err := f.refresh(other.RefreshToken); err != nil {
+		t.Fatalf("refresh of another family = %v", err)
+	}
+}
+
+func TestRefreshTokenRejected(t *testing.T) {
+	f := newAuthFixture(t)
+	auth := f.login(t)
+
+	tests := map[string]string{
+		"access token": auth.AccessToken,
+		"garbage":      "not-a-token",
+		"unknown family": func() string {
+			issued, err := newTestJWTService().GenerateJWT(context.Background(), &Claims{UserID: f.user.ID.Hex()}, &RefreshClaims{FamilyID: "unknown"})
+			if err != nil {
+				t.Fatalf("GenerateJWT() = %v", err)
+			}
+			return issued.RefreshToken
+		}(),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := f.refresh(token); err == nil {
+				t.Fatal("refresh = nil, want an error")
+			}
+		})
+	}
+
+	// A deleted user cannot refresh, and the family goes with them
+	f.users.remove(f.user.ID)
+	if _, err := f.refresh(auth.RefreshToken); !errors.Is(err, domain.ErrJWTRefreshTokenInvalid) {
+		t.Fatalf("refresh of a deleted user = %v, want %v", err, domain.ErrJWTRefreshTokenInvalid)
+	}
+}
+
+// Two requests racing with the same refresh token cannot both get a new pair
+func TestRefreshTokenConcurrentRotation(t *testing.T) {
+	f := newAuthFixture(t)
+	auth := f.login(t)
+
+	const requests = 8
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.refresh(auth.RefreshToken); err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := succeeded.Load(); got > 1 {
+		t.Fatalf("%d of %d concurrent refreshes succeeded, want at most 1", got, requests)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	phone, laptop := f.login(t), f.login(t)
+
+	principal, err := f.service.Authenticate(ctx, phone.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	if err := f.service.Logout(ctx, principal); err != nil {
+		t.Fatalf("Logout() = %v", err)
+	}
+
+	if _, err := f.service.Authenticate(ctx, phone.AccessToken); !errors.Is(err, domain.ErrJWTTokenRevoked) {
+		t.Fatalf("Authenticate() after logout = %v, want %v", err, domain.ErrJWTTokenRevoked)
+	}
+	if _, err := f.refresh(phone.RefreshToken); !errors.Is(err, domain.ErrJWTRefreshTokenInvalid) {
+		t.Fatalf("refresh after logout = %v, want %v", err, domain.ErrJWTRefreshTokenInvalid)
+	}
+	// Logging out signs out of this session only
+	if _, err := f.service.Authenticate(ctx, laptop.AccessToken); err != nil {
+		t.Fatalf("Authenticate() of another session = %v", err)
+	}
+	if _, err := f.refresh(laptop.RefreshToken); err != nil {
+		t.Fatalf("refresh of another session = %v", err)
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	phone, laptop := f.login(t), f.login(t)
+
+	principal, err := f.service.Authenticate(ctx, laptop.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	if err := f.service.LogoutAll(ctx, principal); err != nil {
+		t.Fatalf("LogoutAll() = %v", err)
+	}
+
+	for name, auth := range map[string]*domain.JWTAuthEntity{"phone": phone, "laptop": laptop} {
+		if _, err := f.service.Authenticate(ctx, auth.AccessToken); !errors.Is(err, domain.ErrJWTTokenRevoked) {
+			t.Errorf("Authenticate() on the %s = %v, want %v", name, err, domain.ErrJWTTokenRevoked)
+		}
+		if _, err := f.refresh(auth.RefreshToken); !errors.Is(err, domain.ErrJWTRefreshTokenInvalid) {
+			t.Errorf("refresh on the %s = %v, want %v", name, err, domain.ErrJWTRefreshTokenInvalid)
+		}
+	}
+
+	// Tokens minted afterwards carry the new version
+	if _, err := f.service.Authenticate(ctx, f.login(t).AccessToken); err != nil {
+		t.Fatalf("Authenticate() after logging in again = %v", err)
+	}
+}
+
+// With MFA enabled the first factor only gets a challenge, the tokens come with the second one
+func TestLoginWithMFA(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	mfa := newMFAFixture(t)
+	f.users.add(mfa.user)
+
+	auth, challenge, err := f.service.LoginExternalUser(ctx, mfa.user, dto.DeviceInfo{})
+	if err != nil || auth != nil || challenge == nil {
+		t.Fatalf("LoginExternalUser() = %v, %v, %v, want a challenge only", auth, challenge, err)
+	}
+	if !challenge.MFARequired || challenge.ExpiredIn != int64(mfaTokenExpiresIn.Seconds()) {
+		t.Fatalf("LoginExternalUser() challenge = %+v", challenge)
+	}
+	// The challenge is not an access token
+	if _, err := f.service.Authenticate(ctx, challenge.MFAToken); !errors.Is(err, domain.ErrJWTTokenInvalid) {
+		t.Fatalf("Authenticate() with the MFA token = %v, want %v", err, domain.ErrJWTTokenInvalid)
+	}
+
+	auth, err = f.service.VerifyMFA(ctx, &dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: mfa.code(t, 0)})
+	if err != nil {
+		t.Fatalf("VerifyMFA() = %v", err)
+	}
+	principal, err := f.service.Authenticate(ctx, auth.AccessToken)
+	if err != nil || principal.UserID != mfa.user.ID.Hex() {
+		t.Fatalf("Authenticate() = %v, %v, want %s", principal, err, mfa.user.ID.Hex())
+	}
+}
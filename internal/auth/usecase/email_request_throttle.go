@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+)
+
+// Rate limiting of the emails sent on request, such as password reset links, so that the endpoints
+// cannot be used to flood an inbox or to send mail in bulk
+
+// EmailRequestLimits bounds the requests within the window for one email address and for one client IP
+type EmailRequestLimits struct {
+	PerEmail int64
+	PerIP    int64
+	Window   time.Duration
+}
+
+// DefaultEmailRequestLimits lets a user retry a few times, the IP limit is looser for users behind a NAT
+var DefaultEmailRequestLimits = EmailRequestLimits{
+	PerEmail: 3,
+	PerIP:    20,
+	Window:   time.Hour,
+}
+
+type EmailRequestThrottler interface {
+	// Allow counts a request for the email from the client IP and rejects it once either is over its limit.
+	// Requests are counted whether the email is registered or not, the answer reveals nothing.
+	Allow(ctx context.Context, email, clientIP string) error
+}
+
+type emailRequestThrottler struct {
+	repo   repository.LoginAttemptRepository
+	limits EmailRequestLimits
+}
+
+// NewEmailRequestThrottler stores its counters with the login attempts, under their own keys
+func NewEmailRequestThrottler(repo repository.LoginAttemptRepository, limits EmailRequestLimits) EmailRequestThrottler {
+	return &emailRequestThrottler{repo: repo, limits: limits}
+}
+
+func (throttler *emailRequestThrottler) Allow(ctx context.Context, email, clientIP string) error {
+	keys := throttler.keys(email, clientIP)
+	for _, key := range keys {
+		remaining, err := throttler.repo.GetLoginLock(ctx, key.name)
+		if err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return domain.ErrAuthTooManyEmailRequests.WithRetryAfter(remaining)
+		}
+	}
+
+	for _, key := range keys {
+		requests, err := throttler.repo.IncrementFailedLogins(ctx, key.name, throttler.limits.Window)
+		if err != nil {
+			return err
+		}
+		if requests > key.limit {
+			_ = throttler.repo.LockLogin(ctx, key.name, throttler.limits.Window)
+			return domain.ErrAuthTooManyEmailRequests.WithRetryAfter(throttler.limits.Window)
+		}
+	}
+	return nil
+}
+
+type emailRequestKey struct {
+	name  string
+	limit int64
+}
+
+func (throttler *emailRequestThrottler) keys(email, clientIP string) []emailRequestKey {
+	keys := []emailRequestKey{{name: "mail:email:" + strings.ToLower(strings.TrimSpace(email)), limit: throttler.limits.PerEmail}}
+	if clientIP != "" {
+		keys = append(keys, emailRequestKey{name: "mail:ip:" + clientIP, limit: throttler.limits.PerIP})
+	}
+	return keys
+}
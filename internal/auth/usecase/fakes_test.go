@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeUserService keeps the users in memory, the methods the tests do not need panic through the nil interface
+type fakeUserService struct {
+	userUseCase.UserService
+	mu    sync.Mutex
+	users map[primitive.ObjectID]*userDomain.UserEntity
+}
+
+func newFakeUserService(users ...*userDomain.UserEntity) *fakeUserService {
+	service := &fakeUserService{users: map[primitive.ObjectID]*userDomain.UserEntity{}}
+	for _, user := range users {
+		service.add(user)
+	}
+	return service
+}
+
+// add stores the user, with a new id when it has none
+func (service *fakeUserService) add(user *userDomain.UserEntity) *userDomain.UserEntity {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	service.users[user.ID] = user
+	return user
+}
+
+func (service *fakeUserService) remove(id primitive.ObjectID) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	delete(service.users, id)
+}
+
+func (service *fakeUserService) FindAUserByFilters(ctx context.Context, filters usersRepository.UserFilters) (*userDomain.UserEntity, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	for _, user := range service.users {
+		// Normalized like the user service does
+		if (filters.ID != nil && user.ID == *filters.ID) ||
+			(filters.Email != nil && user.Email == userDomain.NormalizeEmail(*filters.Email)) ||
+			(filters.Username != nil && user.Username == userDomain.NormalizeUsername(*filters.Username)) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, userDomain.ErrUserNotFound
+}
+
+// ComparePassword compares plain text, the fake users keep their password as is
+func (service *fakeUserService) ComparePassword(ctx context.Context, user *userDomain.UserEntity, password string) (bool, error) {
+	return user != nil && user.Password == password, nil
+}
+
+func (service *fakeUserService) PasswordNeedsRehash(user *userDomain.UserEntity) bool {
+	return false
+}
+
+// ValidatePassword only asks for 12 characters, the password policy has its own tests
+func (service *fakeUserService) ValidatePassword(user *userDomain.UserEntity, password string) error {
+	if len(password) < 12 {
+		return userDomain.ErrUserInvalidPassword
+	}
+	return nil
+}
+
+func (service *fakeUserService) UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	user, ok := service.users[id]
+	if !ok {
+		return userDomain.ErrUserNotFound
+	}
+	user.Password = password
+	return nil
+}
+
+func (service *fakeUserService) UpdateAUser(ctx context.Context, id primitive.ObjectID, updates usersRepository.UserUpdates) (*userDomain.UserEntity, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	user, ok := service.users[id]
+	if !ok {
+		return nil, userDomain.ErrUserNotFound
+	}
+	if updates.MFAEnabled != nil {
+		user.MFAEnabled = *updates.MFAEnabled
+	}
+	if updates.TOTPSecret != nil {
+		user.TOTPSecret = *updates.TOTPSecret
+	}
+	if updates.TOTPLastUsedStep != nil {
+		user.TOTPLastUsedStep = *updates.TOTPLastUsedStep
+	}
+	if updates.RecoveryCodes != nil {
+		user.RecoveryCodes = *updates.RecoveryCodes
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (service *fakeUserService) ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	user, ok := service.users[id]
+	if !ok || user.TOTPLastUsedStep >= step {
+		return false, nil
+	}
+	user.TOTPLastUsedStep = step
+	return true, nil
+}
+
+func (service *fakeUserService) ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	user, ok := service.users[id]
+	if !ok {
+		return false, nil
+	}
+	for i, hash := range user.RecoveryCodes {
+		if hash == codeHash {
+			user.RecoveryCodes = append(user.RecoveryCodes[:i:i], user.RecoveryCodes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// builtInPermissions resolves the permissions of the built-in roles and of the scopes, without custom roles
+type builtInPermissions struct{}
+
+func (builtInPermissions) ResolvePermissions(ctx context.Context, principal *shared.Principal) ([]shared.Permission, error) {
+	if principal.IsServiceAccount() {
+		return nil, nil
+	}
+	return principal.Role.Permissions(), nil
+}
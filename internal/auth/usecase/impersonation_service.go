@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Impersonation use case: admins granted users:impersonate act as a user to reproduce their issues.
+// The token carries the user and the admin ("act" claim), it has no refresh token and is bound to
+// the admin's session. Starting and stopping are recorded in the audit trail.
+
+// DefaultImpersonationExpiresIn is the lifetime of impersonation tokens when none is configured
+const DefaultImpersonationExpiresIn = 15 * time.Minute
+
+type ImpersonationService interface {
+	// Start issues a token acting as the user, the actor must be granted users:impersonate and be signed in with their own login.
+	// The user must not hold a permission the actor lacks.
+	Start(ctx context.Context, actor *shared.Principal, userID string, data *dto.ImpersonateRequest) (*domain.JWTAuthEntity, error)
+	// Stop revokes the impersonation token of the request
+	Stop(ctx context.Context, principal *shared.Principal, device dto.DeviceInfo) error
+}
+
+type impersonationService struct {
+	userService  userUseCase.UserService
+	jwtService   JWTService
+	authRepo     repository.AuthRepository
+	auditService AuditService
+	permissions  PermissionResolver
+	expiresIn    time.Duration
+}
+
+func NewImpersonationService(userService userUseCase.UserService, jwtService JWTService, authRepo repository.AuthRepository, auditService AuditService, permissions PermissionResolver, expiresIn time.Duration) ImpersonationService {
+	if expiresIn <= 0 {
+		expiresIn = DefaultImpersonationExpiresIn
+	}
+	return &impersonationService{
+		userService:  userService,
+		jwtService:   jwtService,
+		authRepo:     authRepo,
+		auditService: auditService,
+		permissions:  permissions,
+		expiresIn:    expiresIn,
+	}
+}
+
+func (service *impersonationService) Start(ctx context.Context, actor *shared.Principal, userID string, data *dto.ImpersonateRequest) (*domain.JWTAuthEntity, error) {
+	if actor == nil || !actor.IsLoginSession() || !actor.HasPermission(shared.PermissionUsersImpersonate) {
+		return nil, domain.ErrImpersonationNotAllowed
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, userDomain.ErrUserNotFound
+	}
+	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &objectID})
+	if err != nil {
+		return nil, err
+	}
+	// Also covers impersonating oneself
+	if user.Role == shared.RoleSuperAdmin || user.ID.Hex() == actor.UserID {
+		return nil, domain.ErrImpersonationTargetForbidden
+	}
+	// Impersonating must not grant more than the actor already has
+	targetPermissions, err := service.permissions.ResolvePermissions(ctx, &shared.Principal{
+		Type:   shared.PrincipalTypeUser,
+		UserID: user.ID.Hex(),
+		Role:   user.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range targetPermissions {
+		if !actor.HasPermission(permission) {
+			return nil, domain.ErrImpersonationTargetForbidden
+		}
+	}
+
+	jti, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	version, err := service.authRepo.GetUserTokenVersion(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	claims := service.jwtService.NewUserClaims(user, version)
+	claims.ID = jti
+	claims.SessionID = actor.SessionID
+	actorUsername, err := service.actorUsername(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+	claims.Actor = &ActorClaim{Subject: actor.UserID, Username: actorUsername}
+	auth, err := service.jwtService.GenerateImpersonationToken(ctx, claims, service.expiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	// No audit record, no token
+	err = service.auditService.Record(ctx, &domain.AuditEventEntity{
+		Type:          domain.AuditEventImpersonationStarted,
+		ActorID:       actor.UserID,
+		ActorUsername: actorUsername,
+		UserID:        claims.UserID,
+		Username:      user.Username,
+		TokenID:       jti,
+		Reason:        data.Reason,
+		IP:            data.ClientIP,
+		UserAgent:     data.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = service.authRepo.SaveIssuedToken(ctx, &domain.IssuedTokenEntity{
+		ID:        jti,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.UnixMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("impersonation started",
+		zap.String("actor_id", actor.UserID),
+		zap.String("user_id", claims.UserID),
+		zap.String("token_id", jti),
+	)
+	return auth, nil
+}
+
+func (service *impersonationService) Stop(ctx context.Context, principal *shared.Principal, device dto.DeviceInfo) error {
+	if principal == nil || !principal.IsImpersonated() {
+		return domain.ErrNotImpersonating
+	}
+
+	err := service.authRepo.RevokeToken(ctx, &domain.RevokedTokenEntity{
+		ID:        principal.TokenID,
+		UserID:    principal.UserID,
+		ExpiresAt: principal.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = service.auditService.Record(ctx, &domain.AuditEventEntity{
+		Type:          domain.AuditEventImpersonationStopped,
+		ActorID:       principal.ActorID,
+		ActorUsername: principal.ActorUsername,
+		UserID:        principal.UserID,
+		Username:      principal.Username,
+		TokenID:       principal.TokenID,
+		IP:            device.ClientIP,
+		UserAgent:     device.UserAgent,
+	})
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("impersonation stopped",
+		zap.String("actor_id", principal.ActorID),
+		zap.String("user_id", principal.UserID),
+		zap.String("token_id", principal.TokenID),
+	)
+	return nil
+}
+
+// actorUsername returns the username of the admin, read from the user store when their access token has none
+func (service *impersonationService) actorUsername(ctx context.Context, actor *shared.Principal) (string, error) {
+	if actor.Username != "" {
+		return actor.Username, nil
+	}
+	objectID, err := primitive.ObjectIDFromHex(actor.UserID)
+	if err != nil {
+		return "", userDomain.ErrUserNotFound
+	}
+	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &objectID})
+	if err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
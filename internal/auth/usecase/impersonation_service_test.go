@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testImpersonationReason = "ticket 4521, cannot see her invoices"
+
+// impersonationFixture lets root, a super admin signed in with their own login, impersonate the users of authFixture
+type impersonationFixture struct {
+	*authFixture
+	audit   AuditService
+	service ImpersonationService
+	root    *userDomain.UserEntity
+	actor   *shared.Principal
+}
+
+func newImpersonationFixture(t *testing.T) *impersonationFixture {
+	t.Helper()
+	auth := newAuthFixture(t)
+	audit := NewAuditService(repository.NewMemoryAuditRepository(), auth.users)
+	root := auth.users.add(&userDomain.UserEntity{Username: "root", Email: "root@example.com", Role: shared.RoleSuperAdmin})
+	rootAuth, _, err := auth.service.LoginExternalUser(context.Background(), root, dto.DeviceInfo{ClientIP: "198.51.100.1", UserAgent: testFirefoxOnLinux})
+	if err != nil {
+		t.Fatalf("LoginExternalUser(root) = %v", err)
+	}
+	actor, err := auth.service.Authenticate(context.Background(), rootAuth.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate(root) = %v", err)
+	}
+	return &impersonationFixture{
+		authFixture: auth,
+		audit:       audit,
+		service:     NewImpersonationService(auth.users, newTestJWTService(), auth.repo, audit, builtInPermissions{}, 5*time.Minute),
+		root:        root,
+		actor:       actor,
+	}
+}
+
+func (f *impersonationFixture) start(t *testing.T) (*domain.JWTAuthEntity, *shared.Principal) {
+	t.Helper()
+	auth, err := f.service.Start(context.Background(), f.actor, f.user.ID.Hex(), &dto.ImpersonateRequest{Reason: testImpersonationReason, ClientIP: "198.51.100.1"})
+	if err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	principal, err := f.authFixture.service.Authenticate(context.Background(), auth.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate() with the impersonation token = %v", err)
+	}
+	return auth, principal
+}
+
+func (f *impersonationFixture) events(t *testing.T, eventType string) []*domain.AuditEventEntity {
+	t.Helper()
+	events, err := f.audit.ListEvents(context.Background(), &dto.AuditEventQuery{Type: eventType})
+	if err != nil {
+		t.Fatalf("ListEvents() = %v", err)
+	}
+	return events
+}
+
+func TestImpersonation(t *testing.T) {
+	ctx := context.Background()
+	f := newImpersonationFixture(t)
+	auth, principal := f.start(t)
+
+	if auth.RefreshToken != "" || auth.ExpiredIn != int64((5*time.Minute).Seconds()) {
+		t.Fatalf("Start() = %+v, want a 5 minute token without refresh token", auth)
+	}
+	if principal.UserID != f.user.ID.Hex() || principal.Username != f.user.Username || principal.Role != f.user.Role {
+		t.Fatalf("Authenticate() = %+v, want alice", principal)
+	}
+	if !principal.IsImpersonated() || principal.IsLoginSession() || principal.ActorID != f.root.ID.Hex() || principal.ActorUsername != "root" {
+		t.Fatalf("Authenticate() = %+v, want root acting as alice", principal)
+	}
+
+	started := f.events(t, domain.AuditEventImpersonationStarted)
+	if len(started) != 1 || started[0].ActorID != f.root.ID.Hex() || started[0].UserID != f.user.ID.Hex() ||
+		started[0].Username != f.user.Username || started[0].TokenID != principal.TokenID || started[0].Reason != testImpersonationReason {
+		t.Fatalf("audit trail = %+v, want the start with the reason and the token id", started)
+	}
+
+	if err := f.service.Stop(ctx, principal, dto.DeviceInfo{ClientIP: "198.51.100.1"}); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+	if _, err := f.authFixture.service.Authenticate(ctx, auth.AccessToken); err != domain.ErrJWTTokenRevoked {
+		t.Fatalf("Authenticate() after Stop() = %v, want %v", err, domain.ErrJWTTokenRevoked)
+	}
+	stopped := f.events(t, domain.AuditEventImpersonationStopped)
+	if len(stopped) != 1 || stopped[0].ActorUsername != "root" || stopped[0].TokenID != principal.TokenID {
+		t.Fatalf("audit trail = %+v, want the stop", stopped)
+	}
+
+	// The admin keeps their own session
+	if err := f.service.Stop(ctx, f.actor, dto.DeviceInfo{}); err != domain.ErrNotImpersonating {
+		t.Fatalf("Stop() without impersonation = %v, want %v", err, domain.ErrNotImpersonating)
+	}
+}
+
+// The impersonation token is bound to the session of the admin
+func TestImpersonationEndsWithTheAdminSession(t *testing.T) {
+	ctx := context.Background()
+	f := newImpersonationFixture(t)
+	auth, _ := f.start(t)
+
+	if err := f.authFixture.service.Logout(ctx, f.actor); err != nil {
+		t.Fatalf("Logout() = %v", err)
+	}
+	if _, err := f.authFixture.service.Authenticate(ctx, auth.AccessToken); err != domain.ErrJWTTokenRevoked {
+		t.Fatalf("Authenticate() after the admin logged out = %v, want %v", err, domain.ErrJWTTokenRevoked)
+	}
+}
+
+func TestImpersonationNotAllowed(t *testing.T) {
+	f := newImpersonationFixture(t)
+	_, impersonated := f.start(t)
+	_, alice := signIn(t, f.authFixture, testChromeOnMacOS)
+	bob := f.users.add(&userDomain.UserEntity{Username: "bob", Email: "bob@example.com", Role: shared.RoleUser})
+
+	withKey := *f.actor
+	withKey.APIKeyID = "key"
+	throughClient := *f.actor
+	throughClient.ClientID = "client"
+
+	actors := map[string]*shared.Principal{
+		"user without the permission": alice,
+		"api key":                     &withKey,
+		"oauth client":                &throughClient,
+		"impersonation token":         impersonated,
+		"anonymous":                   nil,
+	}
+	for name, actor := range actors {
+		_, err := f.service.Start(context.Background(), actor, bob.ID.Hex(), &dto.ImpersonateRequest{Reason: testImpersonationReason})
+		if err != domain.ErrImpersonationNotAllowed {
+			t.Errorf("Start() by %s = %v, want %v", name, err, domain.ErrImpersonationNotAllowed)
+		}
+	}
+	if events := f.events(t, domain.AuditEventImpersonationStarted); len(events) != 1 {
+		t.Fatalf("audit trail has %d starts, want the fixture's only", len(events))
+	}
+}
+
+func TestImpersonationTargetForbidden(t *testing.T) {
+	f := newImpersonationFixture(t)
+	other := f.users.add(&userDomain.UserEntity{Username: "other-root", Email: "other-root@example.com", Role: shared.RoleSuperAdmin})
+	admin := f.users.add(&userDomain.UserEntity{Username: "carol", Email: "carol@example.com", Role: shared.RoleAdmin})
+
+	// A support agent granted users:impersonate only, through a custom role
+	support := &shared.Principal{
+		Type:        shared.PrincipalTypeUser,
+		UserID:      f.user.ID.Hex(),
+		Username:    f.user.Username,
+		Role:        shared.RoleUser,
+		Permissions: []shared.Permission{shared.PermissionUsersImpersonate},
+	}
+
+	tests := []struct {
+		name   string
+		actor  *shared.Principal
+		userID string
+		want   error
+	}{
+		{"another super admin", f.actor, other.ID.Hex(), domain.ErrImpersonationTargetForbidden},
+		{"oneself", f.actor, f.root.ID.Hex(), domain.ErrImpersonationTargetForbidden},
+		{"user with more permissions than the actor", support, admin.ID.Hex(), domain.ErrImpersonationTargetForbidden},
+		{"unknown user", f.actor, primitive.NewObjectID().Hex(), userDomain.ErrUserNotFound},
+		{"invalid id", f.actor, "alice", userDomain.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.service.Start(context.Background(), tt.actor, tt.userID, &dto.ImpersonateRequest{Reason: testImpersonationReason})
+			if err != tt.want {
+				t.Fatalf("Start() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// The support agent may act as a user holding no permission they lack
+	bob := f.users.add(&userDomain.UserEntity{Username: "bob", Email: "bob@example.com", Role: shared.RoleUser})
+	if _, err := f.service.Start(context.Background(), support, bob.ID.Hex(), &dto.ImpersonateRequest{Reason: testImpersonationReason}); err != nil {
+		t.Fatalf("Start() by the support agent = %v", err)
+	}
+}
@@ -1,69 +1,207 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
-	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/shared"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.uber.org/zap"
 )
 
 // JWT
 
+const (
+	// DefaultRefreshTokenExpiresIn is the refresh token lifetime when none is configured
+	DefaultRefreshTokenExpiresIn = 7 * 24 * time.Hour
+	// tokenLeeway tolerates the clock skew between instances when checking exp, nbf and iat
+	tokenLeeway = 30 * time.Second
+)
+
+// Token types, set in the typ header so that a token is only accepted where it was meant to be used
+const (
+	accessTokenType  = "at+jwt" // RFC 9068
+	refreshTokenType = "refresh+jwt"
+	mfaTokenType     = "mfa+jwt"
+	idTokenType      = "JWT"
+)
+
+var errUnexpectedTokenType = errors.New("unexpected token type")
+
+// Claims are the access token claims. Tokens are readable by whoever holds them, so a user token only carries
+// its subject (sub), role and id (jti) unless more claims are enabled with AccessTokenClaims.
 type Claims struct {
-	UserID   string        `json:"user_id" required:"true"`
-	Username string        `json:"username" required:"true"`
-	Email    string        `json:"email" required:"true"`
-	Role     shared.Role   `json:"role" required:"true"`
+	UserID   string        `json:"-"` // Carried in the sub claim
+	Username string        `json:"username,omitempty"`
+	Email    string        `json:"email,omitempty"`
+	Role     shared.Role   `json:"role,omitempty"`
 	Phone    string        `json:"phone,omitempty"`
 	Address  string        `json:"address,omitempty"`
 	Gender   shared.Gender `json:"gender,omitempty"`
+	// TokenVersion is the user's token version at issuance, tokens with an older version are rejected
+	TokenVersion int64  `json:"ver"`
+	FamilyID     string `json:"fid,omitempty"` // Refresh token family issued together with this access token
+	SessionID    string `json:"sid,omitempty"` // Session of the login, revoking it rejects the token
+	// Set when the token was issued to an OAuth client, Scope is space separated like in RFC 9068
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// PrincipalType is set to service_account for client credentials tokens, which carry no user, empty means a user
+	PrincipalType shared.PrincipalType `json:"principal_type,omitempty"`
+	// Actor is set on impersonation tokens, it is the super admin acting as the user (RFC 8693 "act" claim)
+	Actor *ActorClaim `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActorClaim identifies who is acting on behalf of the subject of the token
+type ActorClaim struct {
+	Subject  string `json:"sub"`
+	Username string `json:"username,omitempty"`
+}
+
+// SubjectID is the user the token was issued to, or the client id of a service account
+func (c *Claims) SubjectID() string {
+	if c.PrincipalType == shared.PrincipalTypeServiceAccount {
+		return c.ClientID
+	}
+	return c.UserID
+}
+
 type RefreshClaims struct {
-	UserID string `json:"user_id" required:"true"`
+	UserID   string `json:"-"`                   // Carried in the sub claim
+	FamilyID string `json:"fid" required:"true"` // Refresh token family, the jti is stored in RegisteredClaims.ID
+	jwt.RegisteredClaims
+}
+
+// MFAClaims is the short-lived challenge issued between the password and the second factor
+type MFAClaims struct {
+	UserID  string `json:"user_id" required:"true"`
+	Purpose string `json:"purpose" required:"true"`
+	jwt.RegisteredClaims
+}
+
+const mfaTokenPurpose = "mfa"
+
+// IDTokenClaims is an OpenID Connect ID token, derived from the access token Claims of the same user.
+// The profile and email claims are only set when the matching scope was granted.
+type IDTokenClaims struct {
+	Nonce             string `json:"nonce,omitempty"`
+	AuthTime          int64  `json:"auth_time,omitempty"`
+	AuthorizedParty   string `json:"azp,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type JWTService interface {
-	GenerateJWT(claims *Claims) (*domain.JWTAuthEntity, error)
+	// NewUserClaims returns the access token claims of the user at the given token version,
+	// the claims describing the user are only set when enabled in AccessTokenClaims
+	NewUserClaims(user *userDomain.UserEntity, version int64) *Claims
+	GenerateJWT(ctx context.Context, claims *Claims, refreshClaims *RefreshClaims) (*domain.JWTAuthEntity, error)
+	// GenerateAccessToken signs an access token without refresh token, for the client credentials grant
+	GenerateAccessToken(ctx context.Context, claims *Claims) (*domain.JWTAuthEntity, error)
+	// GenerateImpersonationToken signs an access token without refresh token that expires after expiresIn,
+	// at most after the usual access token lifetime
+	GenerateImpersonationToken(ctx context.Context, claims *Claims, expiresIn time.Duration) (*domain.JWTAuthEntity, error)
+	ParseAccessToken(ctx context.Context, tokenString string) (*Claims, error)
+	ParseRefreshToken(tokenString string) (*RefreshClaims, error)
+	// GenerateMFAToken signs an MFA challenge token with the jti chosen by the caller, which records the challenge
+	GenerateMFAToken(userID, jti string, expiresIn time.Duration) (string, error)
+	ParseMFAToken(tokenString string) (*MFAClaims, error)
+	// GenerateIDToken signs an ID token, it needs asymmetric keys so that clients can verify it
+	GenerateIDToken(claims *IDTokenClaims) (string, error)
+	// IDTokenAlgorithms returns the ID token signing algorithms, empty when tokens are signed with the shared secret
+	IDTokenAlgorithms() []string
+	JWKS() *domain.JWKSEntity
+}
+
+// JWTConfig configures the JWT service
+type JWTConfig struct {
+	// Secret signs access tokens with HS256 when KeyManager is nil
+	Secret    string
+	ExpiresIn time.Duration
+	// KeyManager signs access and ID tokens with the active asymmetric key, the Secret is then not accepted
+	KeyManager KeyManager
+	// RefreshSecret signs refresh tokens with HS256, only this API reads them. Defaults to Secret.
+	RefreshSecret string
+	// RefreshExpiresIn defaults to DefaultRefreshTokenExpiresIn
+	RefreshExpiresIn time.Duration
+	// Issuer (iss) and Audience (aud) of the access, refresh and MFA tokens, required on verify when set
+	Issuer   string
+	Audience string
+	// AccessTokenClaims lists the claims describing the user that are copied into user access tokens
+	AccessTokenClaims AccessTokenClaims
 }
 
 type jwtService struct {
-	secret    string
-	expiresIn time.Duration
+	secret            string
+	expiresIn         time.Duration
+	keyManager        KeyManager
+	refreshSecret     string
+	refreshExpiresIn  time.Duration
+	issuer            string
+	audience          string
+	accessTokenClaims AccessTokenClaims
 }
 
-func NewJWTService(secret string, expiresIn time.Duration) JWTService {
+// NewJWTService creates the JWT service.
+// When config.KeyManager is nil tokens are signed with HS256 and the shared secret,
+// otherwise they are signed with the active asymmetric key and the secret is not accepted.
+func NewJWTService(config JWTConfig) JWTService {
+	refreshSecret := config.RefreshSecret
+	if refreshSecret == "" {
+		refreshSecret = config.Secret
+	}
+	refreshExpiresIn := config.RefreshExpiresIn
+	if refreshExpiresIn <= 0 {
+		refreshExpiresIn = DefaultRefreshTokenExpiresIn
+	}
 	return &jwtService{
-		secret:    secret,
-		expiresIn: expiresIn,
+		secret:            config.Secret,
+		expiresIn:         config.ExpiresIn,
+		keyManager:        config.KeyManager,
+		refreshSecret:     refreshSecret,
+		refreshExpiresIn:  refreshExpiresIn,
+		issuer:            config.Issuer,
+		audience:          config.Audience,
+		accessTokenClaims: config.AccessTokenClaims,
 	}
 }
 
-func (jService *jwtService) GenerateJWT(claims *Claims) (*domain.JWTAuthEntity, error) {
-	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(jService.expiresIn))
-	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+func (jService *jwtService) NewUserClaims(user *userDomain.UserEntity, version int64) *Claims {
+	return jService.accessTokenClaims.userClaims(user, version)
+}
+
+// GenerateJWT signs the access token and the refresh token.
+// The caller owns the token identities (jti and family), the other registered claims are set here.
+func (jService *jwtService) GenerateJWT(ctx context.Context, claims *Claims, refreshClaims *RefreshClaims) (*domain.JWTAuthEntity, error) {
+	if err := jService.setRegisteredClaims(&claims.RegisteredClaims, jService.expiresIn); err != nil {
+		zap.L().Error("error generating access token id", zap.Error(err))
+		return nil, domain.ErrSigningAccessTokenFailed
+	}
 
 	// Generate access token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString([]byte(jService.secret))
+	accessToken, err := jService.signAccessToken(claims)
 	if err != nil {
+		zap.L().Error("error signing access token", zap.Error(err))
 		return nil, domain.ErrSigningAccessTokenFailed
 	}
 
 	// Generate refresh token
-	refreshTokenClaims := &RefreshClaims{
-		UserID: claims.UserID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(jService.secret))
+	refreshClaims.UserID = claims.UserID
+	refreshClaims.Subject = claims.UserID
+	if err := jService.setRegisteredClaims(&refreshClaims.RegisteredClaims, jService.refreshExpiresIn); err != nil {
+		zap.L().Error("error generating refresh token id", zap.Error(err))
+		return nil, domain.ErrSigningRefreshTokenFailed
+	}
+	refreshTokenString, err := jService.signRefreshToken(refreshClaims)
 	if err != nil {
+		zap.L().Error("error signing refresh token", zap.Error(err))
 		return nil, domain.ErrSigningRefreshTokenFailed
 	}
 
@@ -74,3 +212,294 @@ func (jService *jwtService) GenerateJWT(claims *Claims) (*domain.JWTAuthEntity,
 		TokenType:    "Bearer",
 	}, nil
 }
+
+func (jService *jwtService) GenerateAccessToken(ctx context.Context, claims *Claims) (*domain.JWTAuthEntity, error) {
+	if err := jService.setRegisteredClaims(&claims.RegisteredClaims, jService.expiresIn); err != nil {
+		zap.L().Error("error generating access token id", zap.Error(err))
+		return nil, domain.ErrSigningAccessTokenFailed
+	}
+
+	accessToken, err := jService.signAccessToken(claims)
+	if err != nil {
+		zap.L().Error("error signing access token", zap.Error(err))
+		return nil, domain.ErrSigningAccessTokenFailed
+	}
+
+	return &domain.JWTAuthEntity{
+		AccessToken: accessToken,
+		ExpiredIn:   int64(jService.expiresIn.Seconds()),
+		TokenType:   "Bearer",
+	}, nil
+}
+
+func (jService *jwtService) GenerateImpersonationToken(ctx context.Context, claims *Claims, expiresIn time.Duration) (*domain.JWTAuthEntity, error) {
+	if expiresIn <= 0 || expiresIn > jService.expiresIn {
+		expiresIn = jService.expiresIn
+	}
+	if err := jService.setRegisteredClaims(&claims.RegisteredClaims, expiresIn); err != nil {
+		zap.L().Error("error generating impersonation token id", zap.Error(err))
+		return nil, domain.ErrSigningAccessTokenFailed
+	}
+
+	accessToken, err := jService.signAccessToken(claims)
+	if err != nil {
+		zap.L().Error("error signing impersonation token", zap.Error(err))
+		return nil, domain.ErrSigningAccessTokenFailed
+	}
+
+	return &domain.JWTAuthEntity{
+		AccessToken: accessToken,
+		ExpiredIn:   int64(expiresIn.Seconds()),
+		TokenType:   "Bearer",
+	}, nil
+}
+
+// ParseAccessToken verifies the signature, type and registered claims of an access token and returns its claims
+func (jService *jwtService) ParseAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	err := jService.parse(tokenString, claims, accessTokenType, jService.keyFunc, jService.validMethods())
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, domain.ErrJWTTokenExpired
+		}
+		return nil, domain.ErrJWTTokenInvalid
+	}
+
+	if claims.ID == "" {
+		return nil, domain.ErrJWTTokenInvalid
+	}
+	switch claims.PrincipalType {
+	case shared.PrincipalTypeServiceAccount:
+		// Service accounts never carry a user or a role, and cannot be impersonated
+		if claims.ClientID == "" || claims.Subject != claims.ClientID || claims.Role != "" || claims.Actor != nil {
+			return nil, domain.ErrJWTTokenInvalid
+		}
+	case "":
+		if claims.Subject == "" || !claims.Role.IsValid() {
+			return nil, domain.ErrJWTTokenInvalid
+		}
+		claims.UserID = claims.Subject
+		// Nobody acts as themselves
+		if claims.Actor != nil && (claims.Actor.Subject == "" || claims.Actor.Subject == claims.UserID) {
+			return nil, domain.ErrJWTTokenInvalid
+		}
+	default:
+		return nil, domain.ErrJWTTokenInvalid
+	}
+
+	return claims, nil
+}
+
+// ParseRefreshToken verifies the signature, type and registered claims of a refresh token and returns its claims
+func (jService *jwtService) ParseRefreshToken(tokenString string) (*RefreshClaims, error) {
+	claims := &RefreshClaims{}
+	err := jService.parse(tokenString, claims, refreshTokenType, jService.refreshKeyFunc, []string{jwt.SigningMethodHS256.Alg()})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, domain.ErrJWTRefreshTokenExpired
+		}
+		return nil, domain.ErrJWTRefreshTokenInvalid
+	}
+
+	if claims.Subject == "" || claims.FamilyID == "" || claims.ID == "" {
+		return nil, domain.ErrJWTRefreshTokenInvalid
+	}
+	claims.UserID = claims.Subject
+
+	return claims, nil
+}
+
+// GenerateMFAToken signs an MFA challenge token, it can only be exchanged at the MFA verification endpoint
+func (jService *jwtService) GenerateMFAToken(userID, jti string, expiresIn time.Duration) (string, error) {
+	claims := &MFAClaims{
+		UserID:  userID,
+		Purpose: mfaTokenPurpose,
+	}
+	claims.ID = jti
+	if err := jService.setRegisteredClaims(&claims.RegisteredClaims, expiresIn); err != nil {
+		zap.L().Error("error generating mfa token id", zap.Error(err))
+		return "", domain.ErrSigningAccessTokenFailed
+	}
+	token, err := jService.sign(claims, mfaTokenType)
+	if err != nil {
+		zap.L().Error("error signing mfa token", zap.Error(err))
+		return "", domain.ErrSigningAccessTokenFailed
+	}
+	return token, nil
+}
+
+// ParseMFAToken verifies an MFA challenge token and returns its claims
+func (jService *jwtService) ParseMFAToken(tokenString string) (*MFAClaims, error) {
+	claims := &MFAClaims{}
+	err := jService.parse(tokenString, claims, mfaTokenType, jService.keyFunc, jService.validMethods())
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, domain.ErrMFATokenExpired
+		}
+		return nil, domain.ErrMFATokenInvalid
+	}
+
+	if claims.UserID == "" || claims.Purpose != mfaTokenPurpose || claims.ID == "" {
+		return nil, domain.ErrMFATokenInvalid
+	}
+
+	return claims, nil
+}
+
+func (jService *jwtService) GenerateIDToken(claims *IDTokenClaims) (string, error) {
+	// Clients would need our shared secret to verify an HS256 ID token
+	if jService.keyManager == nil {
+		return "", domain.ErrIDTokenSigningUnavailable
+	}
+
+	// The OAuth service sets the issuer and the audience (the client) of ID tokens
+	jti, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		zap.L().Error("error generating id token id", zap.Error(err))
+		return "", domain.ErrSigningAccessTokenFailed
+	}
+	now := time.Now()
+	claims.ID = jti
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(jService.expiresIn))
+	token, err := jService.sign(claims, idTokenType)
+	if err != nil {
+		zap.L().Error("error signing id token", zap.Error(err))
+		return "", domain.ErrSigningAccessTokenFailed
+	}
+	return token, nil
+}
+
+func (jService *jwtService) IDTokenAlgorithms() []string {
+	if jService.keyManager == nil {
+		return []string{}
+	}
+	return jService.keyManager.Algorithms()
+}
+
+// JWKS returns the public keys used to verify tokens, empty when tokens are signed with the shared secret
+func (jService *jwtService) JWKS() *domain.JWKSEntity {
+	if jService.keyManager == nil {
+		return &domain.JWKSEntity{Keys: []domain.JWKEntity{}}
+	}
+	return jService.keyManager.JWKS()
+}
+
+// signAccessToken signs the access token claims, the user id of user tokens goes into sub
+func (jService *jwtService) signAccessToken(claims *Claims) (string, error) {
+	if claims.PrincipalType == "" {
+		claims.Subject = claims.UserID
+	}
+	return jService.sign(claims, accessTokenType)
+}
+
+// signRefreshToken signs a refresh token with the refresh secret, never with the access token keys
+func (jService *jwtService) signRefreshToken(claims *RefreshClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["typ"] = refreshTokenType
+	return token.SignedString([]byte(jService.refreshSecret))
+}
+
+// sign signs the claims with the active key, the kid header identifies the key for verifiers
+// and the typ header tells the kinds of tokens apart
+func (jService *jwtService) sign(claims jwt.Claims, tokenType string) (string, error) {
+	if jService.keyManager == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["typ"] = tokenType
+		return token.SignedString([]byte(jService.secret))
+	}
+
+	key, err := jService.keyManager.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	token.Header["typ"] = tokenType
+	return token.SignedString(key.PrivateKey)
+}
+
+// setRegisteredClaims stamps a token issued now that expires after expiresIn with the configured issuer and audience.
+// The jti is kept when the caller owns it, a random one is generated otherwise.
+func (jService *jwtService) setRegisteredClaims(claims *jwt.RegisteredClaims, expiresIn time.Duration) error {
+	if claims.ID == "" {
+		jti, err := utils.GenerateRandomToken(16)
+		if err != nil {
+			return err
+		}
+		claims.ID = jti
+	}
+	now := time.Now()
+	claims.Issuer = jService.issuer
+	claims.Audience = nil
+	if jService.audience != "" {
+		claims.Audience = jwt.ClaimStrings{jService.audience}
+	}
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expiresIn))
+	return nil
+}
+
+// parse verifies the signature and the typ header of a token, then its registered claims:
+// exp, iat and nbf are required, iss and aud must match the configured ones
+func (jService *jwtService) parse(tokenString string, claims jwt.Claims, tokenType string, keyFunc jwt.Keyfunc, methods []string) error {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(tokenLeeway),
+	}
+	if jService.issuer != "" {
+		options = append(options, jwt.WithIssuer(jService.issuer))
+	}
+	if jService.audience != "" {
+		options = append(options, jwt.WithAudience(jService.audience))
+	}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, options...)
+	if err != nil {
+		return err
+	}
+
+	if tokenTypeHeader, _ := token.Header["typ"].(string); tokenTypeHeader != tokenType {
+		return errUnexpectedTokenType
+	}
+	if issuedAt, _ := claims.GetIssuedAt(); issuedAt == nil {
+		return jwt.ErrTokenRequiredClaimMissing
+	}
+	if notBefore, _ := claims.GetNotBefore(); notBefore == nil {
+		return jwt.ErrTokenRequiredClaimMissing
+	}
+	return nil
+}
+
+// keyFunc resolves the verification key of a token
+func (jService *jwtService) keyFunc(token *jwt.Token) (interface{}, error) {
+	if jService.keyManager == nil {
+		return []byte(jService.secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, err := jService.keyManager.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	// The algorithm is bound to the key, a token cannot pick another one
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("signing method does not match the key")
+	}
+	return key.PublicKey, nil
+}
+
+// refreshKeyFunc returns the refresh secret, refresh tokens are always HS256
+func (jService *jwtService) refreshKeyFunc(token *jwt.Token) (interface{}, error) {
+	return []byte(jService.refreshSecret), nil
+}
+
+func (jService *jwtService) validMethods() []string {
+	if jService.keyManager == nil {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+	return jService.keyManager.Algorithms()
+}
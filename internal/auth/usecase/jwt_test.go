@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+const (
+	testSecret   = "test-secret-of-at-least-32-bytes!"
+	testIssuer   = "https://auth.example.com"
+	testAudience = "example-api"
+)
+
+// newTestJWTService signs with HS256, refresh tokens share the secret so that only their type tells them apart
+func newTestJWTService() JWTService {
+	return NewJWTService(JWTConfig{
+		Secret:    testSecret,
+		ExpiresIn: 15 * time.Minute,
+		Issuer:    testIssuer,
+		Audience:  testAudience,
+	})
+}
+
+// signTestToken signs the claims of a valid user access token, edited by edit, with the typ header
+func signTestToken(t *testing.T, typ string, edit func(claims jwt.MapClaims)) string {
+	t.Helper()
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":  "user-1",
+		"role": string(shared.RoleUser),
+		"ver":  1,
+		"jti":  "token-1",
+		"iss":  testIssuer,
+		"aud":  testAudience,
+		"iat":  now.Unix(),
+		"nbf":  now.Unix(),
+		"exp":  now.Add(15 * time.Minute).Unix(),
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+	signed, err := token.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func TestParseAccessToken(t *testing.T) {
+	ctx := context.Background()
+	service := newTestJWTService()
+
+	issued, err := service.GenerateJWT(ctx, &Claims{UserID: "user-1", Role: shared.RoleUser}, &RefreshClaims{FamilyID: "family-1"})
+	if err != nil {
+		t.Fatalf("GenerateJWT() = %v", err)
+	}
+	mfaToken, err := service.GenerateMFAToken("user-1", "challenge-1", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateMFAToken() = %v", err)
+	}
+	inFuture := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "issued access token", token: issued.AccessToken},
+		{name: "valid token", token: signTestToken(t, accessTokenType, nil)},
+		{name: "audience in a list", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { c["aud"] = []string{"other-api", testAudience} })},
+
+		{name: "refresh token used as access token", token: issued.RefreshToken, wantErr: domain.ErrJWTTokenInvalid},
+		{name: "MFA token used as access token", token: mfaToken, wantErr: domain.ErrJWTTokenInvalid},
+		{name: "refresh typ", token: signTestToken(t, refreshTokenType, nil), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "ID token typ", token: signTestToken(t, idTokenType, nil), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "missing typ", token: signTestToken(t, "", nil), wantErr: domain.ErrJWTTokenInvalid},
+
+		{name: "wrong issuer", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "missing issuer", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { delete(c, "iss") }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "wrong audience", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { c["aud"] = "other-api" }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "missing audience", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { delete(c, "aud") }), wantErr: domain.ErrJWTTokenInvalid},
+
+		{name: "not valid yet", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { c["nbf"] = inFuture }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "missing nbf", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { delete(c, "nbf") }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "issued in the future", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { c["iat"] = inFuture }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "missing iat", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { delete(c, "iat") }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "missing exp", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { delete(c, "exp") }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "expired", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }), wantErr: domain.ErrJWTTokenExpired},
+		{name: "expired within the leeway", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-tokenLeeway / 2).Unix() })},
+
+		{name: "missing jti", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { delete(c, "jti") }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "missing subject", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { delete(c, "sub") }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "unknown role", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { c["role"] = "root" }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "acting as themselves", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) { c["act"] = map[string]any{"sub": "user-1"} }), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "service account with a role", token: signTestToken(t, accessTokenType, func(c jwt.MapClaims) {
+			c["principal_type"] = string(shared.PrincipalTypeServiceAccount)
+			c["client_id"] = "user-1"
+		}), wantErr: domain.ErrJWTTokenInvalid},
+
+		{name: "unsigned", token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "user-1", "role": "user", "jti": "token-1"})
+			token.Header["typ"] = accessTokenType
+			signed, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return signed
+		}(), wantErr: domain.ErrJWTTokenInvalid},
+		{name: "tampered signature", token: issued.AccessToken[:len(issued.AccessToken)-4] + "AAAA", wantErr: domain.ErrJWTTokenInvalid},
+		{name: "garbage", token: "not-a-token", wantErr: domain.ErrJWTTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := service.ParseAccessToken(ctx, tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseAccessToken() = %+v, %v, want %v", claims, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAccessToken() = %v", err)
+			}
+			if claims.UserID != "user-1" || claims.Role != shared.RoleUser {
+				t.Fatalf("ParseAccessToken() = %+v, want user-1 with role user", claims)
+			}
+		})
+	}
+}
+
+func TestParseRefreshToken(t *testing.T) {
+	service := newTestJWTService()
+	issued, err := service.GenerateJWT(context.Background(), &Claims{UserID: "user-1", Role: shared.RoleUser}, &RefreshClaims{FamilyID: "family-1"})
+	if err != nil {
+		t.Fatalf("GenerateJWT() = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "issued refresh token", token: issued.RefreshToken},
+		{name: "access token used as refresh token", token: issued.AccessToken, wantErr: domain.ErrJWTRefreshTokenInvalid},
+		{name: "refresh typ without family", token: signTestToken(t, refreshTokenType, nil), wantErr: domain.ErrJWTRefreshTokenInvalid},
+		{name: "wrong issuer", token: signTestToken(t, refreshTokenType, func(c jwt.MapClaims) {
+			c["fid"] = "family-1"
+			c["iss"] = "https://evil.example.com"
+		}), wantErr: domain.ErrJWTRefreshTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := service.ParseRefreshToken(tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseRefreshToken() = %+v, %v, want %v", claims, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRefreshToken() = %v", err)
+			}
+			if claims.UserID != "user-1" || claims.FamilyID != "family-1" {
+				t.Fatalf("ParseRefreshToken() = %+v, want user-1 in family-1", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	"go.uber.org/zap"
+)
+
+// Brute-force protection for login
+
+// LoginThrottleLimits configures the protection of one kind of key (username or client IP).
+// After FreeAttempts failures every further failure delays the next attempt by BaseDelay doubled each time,
+// up to MaxDelay. Reaching LockoutThreshold failures locks the key for LockoutDuration.
+type LoginThrottleLimits struct {
+	FreeAttempts     int64
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int64
+	LockoutDuration  time.Duration
+	Window           time.Duration // Failures older than the window are forgotten
+}
+
+var (
+	// DefaultUsernameThrottleLimits protects a single account
+	DefaultUsernameThrottleLimits = LoginThrottleLimits{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+		Window:           15 * time.Minute,
+	}
+	// DefaultIPThrottleLimits is looser because many users can share an IP behind a NAT
+	DefaultIPThrottleLimits = LoginThrottleLimits{
+		FreeAttempts:     10,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 50,
+		LockoutDuration:  15 * time.Minute,
+		Window:           15 * time.Minute,
+	}
+)
+
+type LoginThrottler interface {
+	// Check rejects the attempt while the account or the client IP is delayed or locked.
+	// The account is usually the username, any stable identifier of the target works.
+	Check(ctx context.Context, account, clientIP string) error
+	RecordFailure(ctx context.Context, account, clientIP string)
+	// Reset forgets the failures of the account after a success. The client IP keeps its failures until
+	// they leave the window, otherwise logging into one's own account between guesses would clear them.
+	Reset(ctx context.Context, account string)
+}
+
+type loginThrottler struct {
+	repo           repository.LoginAttemptRepository
+	usernameLimits LoginThrottleLimits
+	ipLimits       LoginThrottleLimits
+}
+
+func NewLoginThrottler(repo repository.LoginAttemptRepository, usernameLimits, ipLimits LoginThrottleLimits) LoginThrottler {
+	return &loginThrottler{repo: repo, usernameLimits: usernameLimits, ipLimits: ipLimits}
+}
+
+func (throttler *loginThrottler) Check(ctx context.Context, account, clientIP string) error {
+	for _, key := range throttler.keys(account, clientIP) {
+		remaining, err := throttler.repo.GetLoginLock(ctx, key.name)
+		if err != nil {
+			return err
+		}
+		if remaining <= 0 {
+			continue
+		}
+		if remaining > key.limits.MaxDelay {
+			return domain.ErrAuthAccountTemporarilyLocked.WithRetryAfter(remaining)
+		}
+		return domain.ErrAuthTooManyAttempts.WithRetryAfter(remaining)
+	}
+	return nil
+}
+
+// RecordFailure counts the failure and applies the progressive delay or the lockout.
+// Storage errors are logged only, a failing counter must not turn a wrong password into a 500.
+func (throttler *loginThrottler) RecordFailure(ctx context.Context, account, clientIP string) {
+	for _, key := range throttler.keys(account, clientIP) {
+		failures, err := throttler.repo.IncrementFailedLogins(ctx, key.name, key.limits.Window)
+		if err != nil {
+			continue
+		}
+
+		delay := key.limits.delay(failures)
+		if delay <= 0 {
+			continue
+		}
+		if failures >= key.limits.LockoutThreshold {
+			zap.L().Warn("login temporarily locked after too many failed attempts",
+				zap.String("key", key.name),
+				zap.Int64("failures", failures),
+				zap.Duration("duration", delay),
+			)
+		}
+		_ = throttler.repo.LockLogin(ctx, key.name, delay)
+	}
+}
+
+func (throttler *loginThrottler) Reset(ctx context.Context, account string) {
+	_ = throttler.repo.ResetLoginAttempts(ctx, throttler.keys(account, "")[0].name)
+}
+
+type throttleKey struct {
+	name   string
+	limits LoginThrottleLimits
+}
+
+func (throttler *loginThrottler) keys(account, clientIP string) []throttleKey {
+	keys := []throttleKey{{name: "account:" + strings.ToLower(account), limits: throttler.usernameLimits}}
+	if clientIP != "" {
+		keys = append(keys, throttleKey{name: "ip:" + clientIP, limits: throttler.ipLimits})
+	}
+	return keys
+}
+
+// delay returns how long the key must wait after the given number of failures
+func (limits LoginThrottleLimits) delay(failures int64) time.Duration {
+	if failures >= limits.LockoutThreshold {
+		return limits.LockoutDuration
+	}
+	if failures <= limits.FreeAttempts {
+		return 0
+	}
+	exponent := float64(failures - limits.FreeAttempts - 1)
+	delay := time.Duration(float64(limits.BaseDelay) * math.Pow(2, exponent))
+	if delay > limits.MaxDelay || delay <= 0 {
+		return limits.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// throttleError returns the custom error of a refused attempt, nil when the attempt is allowed
+func throttleError(t *testing.T, err error) *utils.CustomError {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	ce, ok := err.(*utils.CustomError)
+	if !ok {
+		t.Fatalf("error %v is not a custom error", err)
+	}
+	return ce
+}
+
+func TestLoginThrottleLimitsDelay(t *testing.T) {
+	limits := DefaultUsernameThrottleLimits
+	tests := []struct {
+		failures int64
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{9, 32 * time.Second},
+		{10, 15 * time.Minute},
+		{1000, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := limits.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	// The doubling stops at MaxDelay before the lockout
+	capped := LoginThrottleLimits{FreeAttempts: 0, BaseDelay: time.Second, MaxDelay: 5 * time.Second, LockoutThreshold: 100, LockoutDuration: time.Hour}
+	if got := capped.delay(50); got != 5*time.Second {
+		t.Errorf("delay(50) = %v, want the 5s cap", got)
+	}
+}
+
+func TestLoginThrottler(t *testing.T) {
+	ctx := context.Background()
+	limits := LoginThrottleLimits{FreeAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, LockoutThreshold: 4, LockoutDuration: 2 * time.Hour, Window: time.Hour}
+	throttler := NewLoginThrottler(repository.NewMemoryLoginAttemptRepository(), limits, DefaultIPThrottleLimits)
+
+	for range 2 {
+		throttler.RecordFailure(ctx, "Alice", "198.51.100.1")
+	}
+	if err := throttler.Check(ctx, "alice", "198.51.100.1"); err != nil {
+		t.Fatalf("Check() after the free attempts = %v", err)
+	}
+
+	throttler.RecordFailure(ctx, "alice", "198.51.100.1")
+	ce := throttleError(t, throttler.Check(ctx, "ALICE", "198.51.100.2"))
+	if ce == nil || ce.Code() != domain.ErrAuthTooManyAttempts.Code() || ce.RetryAfter() <= 0 || ce.RetryAfter() > time.Minute {
+		t.Fatalf("Check() after a delayed failure = %v, want %s with a retry after of at most a minute", ce, domain.ErrAuthTooManyAttempts.Code())
+	}
+	if err := throttler.Check(ctx, "bob", "198.51.100.2"); err != nil {
+		t.Fatalf("Check() of another account = %v", err)
+	}
+
+	throttler.RecordFailure(ctx, "alice", "198.51.100.1")
+	ce = throttleError(t, throttler.Check(ctx, "alice", ""))
+	if ce == nil || ce.Code() != domain.ErrAuthAccountTemporarilyLocked.Code() || ce.RetryAfter() <= time.Hour {
+		t.Fatalf("Check() at the lockout threshold = %v, want %s for the lockout duration", ce, domain.ErrAuthAccountTemporarilyLocked.Code())
+	}
+
+	throttler.Reset(ctx, "alice")
+	if err := throttler.Check(ctx, "alice", ""); err != nil {
+		t.Fatalf("Check() after Reset() = %v", err)
+	}
+}
+
+// The client IP is limited on its own, spreading guesses over many accounts does not escape it
+func TestLoginThrottlerClientIP(t *testing.T) {
+	ctx := context.Background()
+	ipLimits := LoginThrottleLimits{FreeAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, LockoutThreshold: 100, LockoutDuration: time.Hour, Window: time.Hour}
+	throttler := NewLoginThrottler(repository.NewMemoryLoginAttemptRepository(), DefaultUsernameThrottleLimits, ipLimits)
+
+	for _, account := range []string{"a", "b", "c", "d"} {
+		throttler.RecordFailure(ctx, account, "198.51.100.1")
+	}
+	ce := throttleError(t, throttler.Check(ctx, "e", "198.51.100.1"))
+	if ce == nil || ce.Code() != domain.ErrAuthTooManyAttempts.Code() {
+		t.Fatalf("Check() from the guessing IP = %v, want %s", ce, domain.ErrAuthTooManyAttempts.Code())
+	}
+	// Logging into one's own account does not clear the failures of the IP
+	throttler.Reset(ctx, "e")
+	if err := throttler.Check(ctx, "e", "198.51.100.1"); err == nil {
+		t.Fatal("Check() from the guessing IP after a success = nil, want an error")
+	}
+	if err := throttler.Check(ctx, "e", "198.51.100.9"); err != nil {
+		t.Fatalf("Check() from another IP = %v", err)
+	}
+}
+
+func TestLoginIsThrottled(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	login := func(password string) error {
+		_, _, err := f.service.Login(ctx, &dto.LoginRequest{Username: "Alice", Password: password, ClientIP: "198.51.100.1"})
+		return err
+	}
+
+	for i := int64(0); i < DefaultUsernameThrottleLimits.FreeAttempts; i++ {
+		if err := login("wrong password"); err != domain.ErrAuthInvalidCredentials {
+			t.Fatalf("Login() with a wrong password = %v, want %v", err, domain.ErrAuthInvalidCredentials)
+		}
+	}
+	if err := login("correct horse battery"); err != nil {
+		t.Fatalf("Login() within the free attempts = %v", err)
+	}
+
+	// The success forgot the failures, they start over
+	for i := int64(0); i <= DefaultUsernameThrottleLimits.FreeAttempts; i++ {
+		_ = login("wrong password")
+	}
+	ce := throttleError(t, login("correct horse battery"))
+	if ce == nil || ce.Code() != domain.ErrAuthTooManyAttempts.Code() {
+		t.Fatalf("Login() while delayed = %v, want %s even with the right password", ce, domain.ErrAuthTooManyAttempts.Code())
+	}
+}
@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// MFA use case: TOTP enrollment, recovery codes and the second login step
+
+const (
+	mfaTokenExpiresIn  = 5 * time.Minute
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	mfaMethodTOTP      = "totp"
+	mfaMethodRecovery  = "recovery_code"
+)
+
+type MFAService interface {
+	// SetupTOTP generates a new pending TOTP secret for the user
+	SetupTOTP(ctx context.Context, principal *shared.Principal) (*domain.TOTPSetupEntity, error)
+	// EnableTOTP confirms the pending secret with a code and returns the recovery codes
+	EnableTOTP(ctx context.Context, principal *shared.Principal, data *dto.TOTPCodeRequest) (*domain.RecoveryCodesEntity, error)
+	DisableTOTP(ctx context.Context, principal *shared.Principal, data *dto.TOTPCodeRequest) error
+	// CreateChallenge issues the challenge returned by login for users with MFA enabled
+	CreateChallenge(ctx context.Context, user *userDomain.UserEntity) (*domain.MFAChallengeEntity, error)
+	// VerifyChallenge checks the second factor and returns the user the challenge was issued to.
+	// A challenge can be completed once, a wrong code leaves it usable until it expires.
+	VerifyChallenge(ctx context.Context, data *dto.MFAVerifyRequest) (*userDomain.UserEntity, error)
+}
+
+type mfaService struct {
+	userService   userUseCase.UserService
+	jwtService    JWTService
+	repo          repository.AuthRepository
+	throttler     LoginThrottler
+	issuer        string
+	encryptionKey string
+}
+
+// NewMFAService creates the MFA service.
+// issuer is shown in authenticator apps, encryptionKey encrypts the TOTP secrets at rest.
+// throttler limits the codes guessed to enable or disable TOTP, like the ones of the second login step.
+func NewMFAService(userService userUseCase.UserService, jwtService JWTService, repo repository.AuthRepository, throttler LoginThrottler, issuer, encryptionKey string) MFAService {
+	return &mfaService{
+		userService:   userService,
+		jwtService:    jwtService,
+		repo:          repo,
+		throttler:     throttler,
+		issuer:        issuer,
+		encryptionKey: encryptionKey,
+	}
+}
+
+func (service *mfaService) SetupTOTP(ctx context.Context, principal *shared.Principal) (*domain.TOTPSetupEntity, error) {
+	user, err := service.findUser(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	encryptedSecret, err := utils.EncryptString(secret, service.encryptionKey)
+	if err != nil {
+		zap.L().Error("error encrypting totp secret", zap.Error(err))
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	// The secret stays pending until it is confirmed with a valid code
+	_, err = service.userService.UpdateAUser(ctx, user.ID, usersRepository.UserUpdates{
+		TOTPSecret: &encryptedSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TOTPSetupEntity{
+		Secret:     secret,
+		OTPAuthURI: utils.BuildTOTPURI(service.issuer, user.Username, secret),
+	}, nil
+}
+
+func (service *mfaService) EnableTOTP(ctx context.Context, principal *shared.Principal, data *dto.TOTPCodeRequest) (*domain.RecoveryCodesEntity, error) {
+	user, err := service.findUser(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, domain.ErrMFASetupRequired
+	}
+
+	if err := service.verifyThrottledTOTP(ctx, user, data); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled := true
+	_, err = service.userService.UpdateAUser(ctx, user.ID, usersRepository.UserUpdates{
+		MFAEnabled:    &enabled,
+		RecoveryCodes: &hashes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("mfa enabled", zap.String("user_id", principal.UserID))
+
+	return &domain.RecoveryCodesEntity{RecoveryCodes: codes}, nil
+}
+
+func (service *mfaService) DisableTOTP(ctx context.Context, principal *shared.Principal, data *dto.TOTPCodeRequest) error {
+	user, err := service.findUser(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return domain.ErrMFANotEnabled
+	}
+
+	if err := service.verifyThrottledTOTP(ctx, user, data); err != nil {
+		return err
+	}
+
+	disabled := false
+	emptySecret := ""
+	noCodes := []string{}
+	_, err = service.userService.UpdateAUser(ctx, user.ID, usersRepository.UserUpdates{
+		MFAEnabled:    &disabled,
+		TOTPSecret:    &emptySecret,
+		RecoveryCodes: &noCodes,
+	})
+	if err != nil {
+		return err
+	}
+	zap.L().Info("mfa disabled", zap.String("user_id", principal.UserID))
+
+	return nil
+}
+
+func (service *mfaService) CreateChallenge(ctx context.Context, user *userDomain.UserEntity) (*domain.MFAChallengeEntity, error) {
+	jti, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	err = service.repo.CreateMFAChallenge(ctx, &domain.PendingMFAChallengeEntity{
+		ID:        jti,
+		UserID:    user.ID.Hex(),
+		ExpiresAt: time.Now().Add(mfaTokenExpiresIn).UnixMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	token, err := service.jwtService.GenerateMFAToken(user.ID.Hex(), jti, mfaTokenExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.MFAChallengeEntity{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiredIn:   int64(mfaTokenExpiresIn.Seconds()),
+		Methods:     []string{mfaMethodTOTP, mfaMethodRecovery},
+	}, nil
+}
+
+func (service *mfaService) VerifyChallenge(ctx context.Context, data *dto.MFAVerifyRequest) (*userDomain.UserEntity, error) {
+	claims, err := service.jwtService.ParseMFAToken(data.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := service.findUser(ctx, claims.UserID)
+	if err != nil {
+		if err == userDomain.ErrUserNotFound {
+			return nil, domain.ErrMFATokenInvalid
+		}
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, domain.ErrMFATokenInvalid
+	}
+
+	if data.RecoveryCode != "" {
+		consumed, err := service.userService.ConsumeRecoveryCode(ctx, user.ID, utils.HashToken(normalizeRecoveryCode(data.RecoveryCode)))
+		if err != nil {
+			return nil, err
+		}
+		if !consumed {
+			return nil, domain.ErrMFAInvalidCode
+		}
+		zap.L().Info("recovery code used", zap.String("user_id", user.ID.Hex()))
+	} else if err := service.verifyTOTP(ctx, user, data.Code); err != nil {
+		return nil, err
+	}
+
+	// Consumed once the factor is accepted, so that the same challenge cannot start a second session
+	challenge, err := service.repo.ConsumeMFAChallenge(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID != user.ID.Hex() {
+		return nil, domain.ErrMFATokenInvalid
+	}
+	return user, nil
+}
+
+// verifyThrottledTOTP checks the code of a signed in user. Failures count against the same key as the
+// second login step, a stolen session must not give unlimited guesses at the secret.
+func (service *mfaService) verifyThrottledTOTP(ctx context.Context, user *userDomain.UserEntity, data *dto.TOTPCodeRequest) error {
+	throttleKey := mfaThrottlePrefix + user.ID.Hex()
+	if err := service.throttler.Check(ctx, throttleKey, data.ClientIP); err != nil {
+		return err
+	}
+	if err := service.verifyTOTP(ctx, user, data.Code); err != nil {
+		if err == domain.ErrMFAInvalidCode {
+			service.throttler.RecordFailure(ctx, throttleKey, data.ClientIP)
+		}
+		return err
+	}
+	service.throttler.Reset(ctx, throttleKey)
+	return nil
+}
+
+// verifyTOTP checks the code against the user's secret and consumes its time step so it cannot be replayed
+func (service *mfaService) verifyTOTP(ctx context.Context, user *userDomain.UserEntity, code string) error {
+	secret, err := utils.DecryptString(user.TOTPSecret, service.encryptionKey)
+	if err != nil {
+		zap.L().Error("error decrypting totp secret", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+		return domain.ErrAuthInternalServerError
+	}
+
+	step, ok := utils.ValidateTOTPCode(secret, code, time.Now())
+	if !ok {
+		return domain.ErrMFAInvalidCode
+	}
+	consumed, err := service.userService.ConsumeTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return domain.ErrMFAInvalidCode
+	}
+	return nil
+}
+
+func (service *mfaService) findUser(ctx context.Context, userID string) (*userDomain.UserEntity, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, userDomain.ErrUserInvalidID
+	}
+	return service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &userObjectID})
+}
+
+// generateRecoveryCodes returns the plaintext codes formatted as XXXXX-XXXXX and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			return nil, nil, domain.ErrAuthInternalServerError
+		}
+		code := secret[:recoveryCodeLength]
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, utils.HashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode accepts codes typed in lower case or without the separator
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+const testMFAKey = "test-mfa-encryption-key"
+
+// mfaFixture is a user with TOTP enabled and the recovery codes "AAAAA-AAAAA" and "BBBBB-BBBBB"
+type mfaFixture struct {
+	users   *fakeUserService
+	user    *userDomain.UserEntity
+	secret  string
+	service MFAService
+}
+
+func newMFAFixture(t *testing.T) *mfaFixture {
+	t.Helper()
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() = %v", err)
+	}
+	encrypted, err := utils.EncryptString(secret, testMFAKey)
+	if err != nil {
+		t.Fatalf("EncryptString() = %v", err)
+	}
+	user := newTestUser()
+	user.MFAEnabled = true
+	user.TOTPSecret = encrypted
+	user.RecoveryCodes = []string{utils.HashToken("AAAAAAAAAA"), utils.HashToken("BBBBBBBBBB")}
+	users := newFakeUserService(user)
+
+	throttler := NewLoginThrottler(repository.NewMemoryLoginAttemptRepository(), DefaultUsernameThrottleLimits, DefaultIPThrottleLimits)
+	service := NewMFAService(users, newTestJWTService(), repository.NewMemoryAuthRepository(), throttler, "Test", testMFAKey)
+	return &mfaFixture{users: users, user: user, secret: secret, service: service}
+}
+
+// code returns the TOTP code of the time step at offset from now
+func (f *mfaFixture) code(t *testing.T, offset int64) string {
+	t.Helper()
+	code, err := utils.GenerateTOTPCode(f.secret, time.Now().Unix()/utils.TOTPPeriod+offset)
+	if err != nil {
+		t.Fatalf("GenerateTOTPCode() = %v", err)
+	}
+	return code
+}
+
+func (f *mfaFixture) challenge(t *testing.T) string {
+	t.Helper()
+	challenge, err := f.service.CreateChallenge(context.Background(), f.user)
+	if err != nil {
+		t.Fatalf("CreateChallenge() = %v", err)
+	}
+	return challenge.MFAToken
+}
+
+func TestVerifyChallengeIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	f := newMFAFixture(t)
+	token := f.challenge(t)
+
+	if _, err := f.service.VerifyChallenge(ctx, &dto.MFAVerifyRequest{MFAToken: token, RecoveryCode: "aaaaa-aaaaa"}); err != nil {
+		t.Fatalf("VerifyChallenge() = %v", err)
+	}
+	// Another valid factor must not turn the same challenge into a second session
+	_, err := f.service.VerifyChallenge(ctx, &dto.MFAVerifyRequest{MFAToken: token, Code: f.code(t, 0)})
+	if !errors.Is(err, domain.ErrMFATokenInvalid) {
+		t.Fatalf("VerifyChallenge() with a used challenge = %v, want %v", err, domain.ErrMFATokenInvalid)
+	}
+}
+
+func TestVerifyChallengeSurvivesAWrongCode(t *testing.T) {
+	ctx := context.Background()
+	f := newMFAFixture(t)
+	token := f.challenge(t)
+
+	_, err := f.service.VerifyChallenge(ctx, &dto.MFAVerifyRequest{MFAToken: token, RecoveryCode: "CCCCC-CCCCC"})
+	if !errors.Is(err, domain.ErrMFAInvalidCode) {
+		t.Fatalf("VerifyChallenge() with a wrong code = %v, want %v", err, domain.ErrMFAInvalidCode)
+	}
+	user, err := f.service.VerifyChallenge(ctx, &dto.MFAVerifyRequest{MFAToken: token, Code: f.code(t, 0)})
+	if err != nil {
+		t.Fatalf("VerifyChallenge() after a wrong code = %v", err)
+	}
+	if user.ID != f.user.ID {
+		t.Fatalf("VerifyChallenge() = user %s, want %s", user.ID.Hex(), f.user.ID.Hex())
+	}
+}
+
+// An MFA token signed by the service without a recorded challenge cannot be completed
+func TestVerifyChallengeUnknownChallenge(t *testing.T) {
+	f := newMFAFixture(t)
+	token, err := newTestJWTService().GenerateMFAToken(f.user.ID.Hex(), "never-recorded", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateMFAToken() = %v", err)
+	}
+
+	_, err = f.service.VerifyChallenge(context.Background(), &dto.MFAVerifyRequest{MFAToken: token, Code: f.code(t, 0)})
+	if !errors.Is(err, domain.ErrMFATokenInvalid) {
+		t.Fatalf("VerifyChallenge() = %v, want %v", err, domain.ErrMFATokenInvalid)
+	}
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	ctx := context.Background()
+	users := newFakeUserService()
+	user := users.add(newTestUser())
+	throttler := NewLoginThrottler(repository.NewMemoryLoginAttemptRepository(), DefaultUsernameThrottleLimits, DefaultIPThrottleLimits)
+	service := NewMFAService(users, newTestJWTService(), repository.NewMemoryAuthRepository(), throttler, "Test", testMFAKey)
+	principal := &shared.Principal{UserID: user.ID.Hex(), Role: user.Role}
+
+	if _, err := service.EnableTOTP(ctx, principal, &dto.TOTPCodeRequest{Code: "123456"}); !errors.Is(err, domain.ErrMFASetupRequired) {
+		t.Fatalf("EnableTOTP() before SetupTOTP = %v, want %v", err, domain.ErrMFASetupRequired)
+	}
+
+	setup, err := service.SetupTOTP(ctx, principal)
+	if err != nil {
+		t.Fatalf("SetupTOTP() = %v", err)
+	}
+	if !strings.HasPrefix(setup.OTPAuthURI, "otpauth://totp/Test:alice?") || !strings.Contains(setup.OTPAuthURI, "secret="+setup.Secret) {
+		t.Fatalf("SetupTOTP() URI = %s", setup.OTPAuthURI)
+	}
+	stored, _ := users.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &user.ID})
+	if stored.TOTPSecret == "" || stored.TOTPSecret == setup.Secret || stored.MFAEnabled {
+		t.Fatalf("after SetupTOTP() the user has secret %q and MFA enabled %v, want a pending encrypted secret", stored.TOTPSecret, stored.MFAEnabled)
+	}
+
+	codeAt := func(offset int64) string {
+		code, err := utils.GenerateTOTPCode(setup.Secret, time.Now().Unix()/utils.TOTPPeriod+offset)
+		if err != nil {
+			t.Fatalf("GenerateTOTPCode() = %v", err)
+		}
+		return code
+	}
+	recovery, err := service.EnableTOTP(ctx, principal, &dto.TOTPCodeRequest{Code: codeAt(0)})
+	if err != nil {
+		t.Fatalf("EnableTOTP() = %v", err)
+	}
+	if len(recovery.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("EnableTOTP() returned %d recovery codes, want %d", len(recovery.RecoveryCodes), recoveryCodeCount)
+	}
+	stored, _ = users.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &user.ID})
+	for i, hash := range stored.RecoveryCodes {
+		if hash != utils.HashToken(normalizeRecoveryCode(recovery.RecoveryCodes[i])) {
+			t.Fatalf("recovery code %d is not stored as its hash", i)
+		}
+	}
+	if _, err := service.SetupTOTP(ctx, principal); !errors.Is(err, domain.ErrMFAAlreadyEnabled) {
+		t.Fatalf("SetupTOTP() once enabled = %v, want %v", err, domain.ErrMFAAlreadyEnabled)
+	}
+
+	// The code that enabled TOTP was consumed, disabling needs a fresh one
+	if err := service.DisableTOTP(ctx, principal, &dto.TOTPCodeRequest{Code: codeAt(0)}); !errors.Is(err, domain.ErrMFAInvalidCode) {
+		t.Fatalf("DisableTOTP() with a used code = %v, want %v", err, domain.ErrMFAInvalidCode)
+	}
+	if err := service.DisableTOTP(ctx, principal, &dto.TOTPCodeRequest{Code: codeAt(1)}); err != nil {
+		t.Fatalf("DisableTOTP() = %v", err)
+	}
+	stored, _ = users.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &user.ID})
+	if stored.MFAEnabled || stored.TOTPSecret != "" || len(stored.RecoveryCodes) != 0 {
+		t.Fatalf("after DisableTOTP() the user still has MFA: %+v", stored)
+	}
+}
+
+func TestVerifyChallengeFactors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request func(f *mfaFixture, t *testing.T) dto.MFAVerifyRequest
+		wantErr error
+	}{
+		{"current code", func(f *mfaFixture, t *testing.T) dto.MFAVerifyRequest {
+			return dto.MFAVerifyRequest{Code: f.code(t, 0)}
+		}, nil},
+		{"code of the previous step", func(f *mfaFixture, t *testing.T) dto.MFAVerifyRequest {
+			return dto.MFAVerifyRequest{Code: f.code(t, -1)}
+		}, nil},
+		{"code two steps old", func(f *mfaFixture, t *testing.T) dto.MFAVerifyRequest {
+			return dto.MFAVerifyRequest{Code: f.code(t, -2)}
+		}, domain.ErrMFAInvalidCode},
+		{"recovery code as shown", func(*mfaFixture, *testing.T) dto.MFAVerifyRequest {
+			return dto.MFAVerifyRequest{RecoveryCode: "BBBBB-BBBBB"}
+		}, nil},
+		{"recovery code typed loosely", func(*mfaFixture, *testing.T) dto.MFAVerifyRequest {
+			return dto.MFAVerifyRequest{RecoveryCode: " bbbbb bbbbb "}
+		}, nil},
+		{"unknown recovery code", func(*mfaFixture, *testing.T) dto.MFAVerifyRequest {
+			return dto.MFAVerifyRequest{RecoveryCode: "CCCCC-CCCCC"}
+		}, domain.ErrMFAInvalidCode},
+		{"garbage token", func(*mfaFixture, *testing.T) dto.MFAVerifyRequest {
+			return dto.MFAVerifyRequest{MFAToken: "not-a-token", RecoveryCode: "BBBBB-BBBBB"}
+		}, domain.ErrMFATokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMFAFixture(t)
+			request := tt.request(f, t)
+			if request.MFAToken == "" {
+				request.MFAToken = f.challenge(t)
+			}
+			_, err := f.service.VerifyChallenge(context.Background(), &request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyChallenge() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Each code works once, whatever the challenge
+func TestFactorsAreSingleUse(t *testing.T) {
+	ctx := context.Background()
+	f := newMFAFixture(t)
+
+	totp := f.code(t, 0)
+	if _, err := f.service.VerifyChallenge(ctx, &dto.MFAVerifyRequest{MFAToken: f.challenge(t), Code: totp}); err != nil {
+		t.Fatalf("VerifyChallenge() = %v", err)
+	}
+	if _, err := f.service.VerifyChallenge(ctx, &dto.MFAVerifyRequest{MFAToken: f.challenge(t), Code: totp}); !errors.Is(err, domain.ErrMFAInvalidCode) {
+		t.Fatalf("VerifyChallenge() with a replayed code = %v, want %v", err, domain.ErrMFAInvalidCode)
+	}
+
+	if _, err := f.service.VerifyChallenge(ctx, &dto.MFAVerifyRequest{MFAToken: f.challenge(t), RecoveryCode: "AAAAA-AAAAA"}); err != nil {
+		t.Fatalf("VerifyChallenge() = %v", err)
+	}
+	if _, err := f.service.VerifyChallenge(ctx, &dto.MFAVerifyRequest{MFAToken: f.challenge(t), RecoveryCode: "AAAAA-AAAAA"}); !errors.Is(err, domain.ErrMFAInvalidCode) {
+		t.Fatalf("VerifyChallenge() with a used recovery code = %v, want %v", err, domain.ErrMFAInvalidCode)
+	}
+}
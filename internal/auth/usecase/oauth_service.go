@@ -0,0 +1,437 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// OAuth use case: authorization server for our own apps (OAuth 2.1 authorization code + PKCE, OpenID Connect)
+
+const (
+	authorizationCodeExpiresIn = time.Minute
+	oauthClientSecretBytes     = 32
+	pkceVerifierMinLength      = 43
+	pkceVerifierMaxLength      = 128
+
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	ScopePhone   = "phone"
+	ScopeAddress = "address"
+
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+var oauthSupportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopePhone, ScopeAddress}
+
+type OAuthService interface {
+	RegisterClient(ctx context.Context, principal *shared.Principal, data *dto.CreateOAuthClientRequest) (*domain.OAuthClientEntity, error)
+	ListClients(ctx context.Context) ([]*domain.OAuthClientEntity, error)
+
+	// ResolveClient checks the client and the redirect uri of an authorization request.
+	// Its errors must be shown to the user, never redirected, since the redirect uri cannot be trusted.
+	ResolveClient(ctx context.Context, data *dto.AuthorizationRequest) (*domain.OAuthClientEntity, error)
+	// ValidateAuthorizationRequest checks the rest of the request, its errors are redirected to the client
+	ValidateAuthorizationRequest(client *domain.OAuthClientEntity, data *dto.AuthorizationRequest) error
+	// Authorize issues an authorization code to the authenticated user and returns the redirect to the client
+	Authorize(ctx context.Context, client *domain.OAuthClientEntity, data *dto.AuthorizationRequest, user *userDomain.UserEntity, device dto.DeviceInfo) (string, error)
+	// ErrorRedirectURL returns the redirect carrying an authorization error to the client
+	ErrorRedirectURL(data *dto.AuthorizationRequest, err error) string
+
+	Token(ctx context.Context, data *dto.TokenRequest) (*domain.OAuthTokenEntity, error)
+	// AuthenticateClient checks the client credentials, public clients only send their client id
+	AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*domain.OAuthClientEntity, error)
+	Configuration() *domain.OpenIDConfigurationEntity
+}
+
+type oauthService struct {
+	repo        repository.OAuthRepository
+	authService AuthService
+	userService userUseCase.UserService
+	jwtService  JWTService
+	issuer      string
+}
+
+// NewOAuthService creates the authorization server.
+// issuer is the public base URL of this API, e.g. https://api.example.com, it prefixes every endpoint.
+func NewOAuthService(repo repository.OAuthRepository, authService AuthService, userService userUseCase.UserService, jwtService JWTService, issuer string) OAuthService {
+	return &oauthService{
+		repo:        repo,
+		authService: authService,
+		userService: userService,
+		jwtService:  jwtService,
+		issuer:      strings.TrimSuffix(issuer, "/"),
+	}
+}
+
+func (service *oauthService) RegisterClient(ctx context.Context, principal *shared.Principal, data *dto.CreateOAuthClientRequest) (*domain.OAuthClientEntity, error) {
+	for _, redirectURI := range data.RedirectURIs {
+		if !isValidRedirectURI(redirectURI) {
+			return nil, domain.ErrOAuthClientInvalidRedirectURI
+		}
+	}
+	scopes := data.Scopes
+	if len(scopes) == 0 {
+		scopes = oauthSupportedScopes
+	}
+
+	clientID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	client := &domain.OAuthClientEntity{
+		ID:           clientID,
+		Name:         data.Name,
+		Public:       data.Public,
+		RedirectURIs: data.RedirectURIs,
+		Scopes:       scopes,
+		CreatedBy:    principal.UserID,
+	}
+	// Confidential clients get a secret, it is only returned now
+	if !data.Public {
+		secret, err := utils.GenerateRandomToken(oauthClientSecretBytes)
+		if err != nil {
+			return nil, domain.ErrAuthInternalServerError
+		}
+		client.SecretHash = utils.HashToken(secret)
+		client.ClientSecret = secret
+	}
+
+	client, err = service.repo.CreateClient(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("oauth client registered",
+		zap.String("client_id", client.ID),
+		zap.String("created_by", principal.UserID),
+	)
+	return client, nil
+}
+
+func (service *oauthService) ListClients(ctx context.Context) ([]*domain.OAuthClientEntity, error) {
+	return service.repo.ListClients(ctx)
+}
+
+func (service *oauthService) ResolveClient(ctx context.Context, data *dto.AuthorizationRequest) (*domain.OAuthClientEntity, error) {
+	if data.ClientID == "" {
+		return nil, domain.ErrOAuthInvalidRequest
+	}
+	client, err := service.repo.FindClientByID(ctx, data.ClientID)
+	if err != nil {
+		if err == domain.ErrOAuthClientNotFound {
+			return nil, domain.ErrOAuthInvalidClient
+		}
+		return nil, err
+	}
+
+	// The redirect uri may be omitted only when the client registered a single one
+	if data.RedirectURI == "" && len(client.RedirectURIs) == 1 {
+		data.RedirectURI = client.RedirectURIs[0]
+	}
+	if !containsString(client.RedirectURIs, data.RedirectURI) {
+		return nil, domain.ErrOAuthInvalidRedirectURI
+	}
+	return client, nil
+}
+
+func (service *oauthService) ValidateAuthorizationRequest(client *domain.OAuthClientEntity, data *dto.AuthorizationRequest) error {
+	if data.ResponseType != "code" {
+		return domain.ErrOAuthUnsupportedResponseType
+	}
+	// OAuth 2.1 requires PKCE for every client, plain challenges are not accepted
+	if data.CodeChallenge == "" || data.CodeChallengeMethod != "S256" {
+		return domain.ErrOAuthPKCERequired
+	}
+
+	scopes := strings.Fields(data.Scope)
+	for _, scope := range scopes {
+		if !containsString(client.Scopes, scope) {
+			return domain.ErrOAuthInvalidScope
+		}
+	}
+	// ID tokens cannot be issued while tokens are signed with the shared secret
+	if containsString(scopes, ScopeOpenID) && len(service.jwtService.IDTokenAlgorithms()) == 0 {
+		zap.L().Warn("openid scope requested but JWT_SIGNING_KEYS is not configured", zap.String("client_id", client.ID))
+		return domain.ErrOAuthInvalidScope
+	}
+	return nil
+}
+
+func (service *oauthService) Authorize(ctx context.Context, client *domain.OAuthClientEntity, data *dto.AuthorizationRequest, user *userDomain.UserEntity, device dto.DeviceInfo) (string, error) {
+	code, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", domain.ErrAuthInternalServerError
+	}
+
+	err = service.repo.CreateAuthorizationCode(ctx, &domain.OAuthAuthorizationCodeEntity{
+		ID:            utils.HashToken(code),
+		ClientID:      client.ID,
+		UserID:        user.ID.Hex(),
+		RedirectURI:   data.RedirectURI,
+		Scope:         strings.Join(strings.Fields(data.Scope), " "),
+		Nonce:         data.Nonce,
+		CodeChallenge: data.CodeChallenge,
+		AuthTime:      time.Now().Unix(),
+		ExpiresAt:     time.Now().Add(authorizationCodeExpiresIn).UnixMilli(),
+		ClientIP:      device.ClientIP,
+		UserAgent:     device.UserAgent,
+	})
+	if err != nil {
+		return "", err
+	}
+	zap.L().Info("authorization code issued",
+		zap.String("client_id", client.ID),
+		zap.String("user_id", user.ID.Hex()),
+	)
+
+	query := url.Values{}
+	query.Set("code", code)
+	if data.State != "" {
+		query.Set("state", data.State)
+	}
+	// RFC 9207, lets the client check which server answered
+	query.Set("iss", service.issuer)
+	return appendQuery(data.RedirectURI, query), nil
+}
+
+func (service *oauthService) ErrorRedirectURL(data *dto.AuthorizationRequest, err error) string {
+	oauthErr := domain.ErrOAuthServerError
+	if ce, ok := err.(*utils.CustomError); ok {
+		oauthErr = ce
+	}
+
+	query := url.Values{}
+	query.Set("error", oauthErr.Code())
+	query.Set("error_description", oauthErr.Error())
+	if data.State != "" {
+		query.Set("state", data.State)
+	}
+	query.Set("iss", service.issuer)
+	return appendQuery(data.RedirectURI, query)
+}
+
+func (service *oauthService) Token(ctx context.Context, data *dto.TokenRequest) (*domain.OAuthTokenEntity, error) {
+	client, err := service.AuthenticateClient(ctx, data.ClientID, data.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch data.GrantType {
+	case GrantTypeAuthorizationCode:
+		return service.exchangeAuthorizationCode(ctx, client, data)
+	case GrantTypeRefreshToken:
+		if data.RefreshToken == "" {
+			return nil, domain.ErrOAuthInvalidRequest
+		}
+		auth, err := service.authService.RefreshClientToken(ctx, data.RefreshToken, client.ID)
+		if err != nil {
+			return nil, toOAuthGrantError(err)
+		}
+		return newOAuthTokenEntity(auth, ""), nil
+	case "":
+		return nil, domain.ErrOAuthInvalidRequest
+	default:
+		return nil, domain.ErrOAuthUnsupportedGrantType
+	}
+}
+
+func (service *oauthService) Configuration() *domain.OpenIDConfigurationEntity {
+	return &domain.OpenIDConfigurationEntity{
+		Issuer:                                 service.issuer,
+		AuthorizationEndpoint:                  service.issuer + "/oauth/authorize",
+		TokenEndpoint:                          service.issuer + "/oauth/token",
+		UserInfoEndpoint:                       service.issuer + "/oauth/userinfo",
+		IntrospectionEndpoint:                  service.issuer + "/oauth/introspect",
+		RevocationEndpoint:                     service.issuer + "/oauth/revoke",
+		JWKSURI:                                service.issuer + "/.well-known/jwks.json",
+		ScopesSupported:                        oauthSupportedScopes,
+		ResponseTypesSupported:                 []string{"code"},
+		GrantTypesSupported:                    []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+		SubjectTypesSupported:                  []string{"public"},
+		IDTokenSigningAlgValuesSupported:       service.jwtService.IDTokenAlgorithms(),
+		TokenEndpointAuthMethodsSupported:      []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:          []string{"S256"},
+		ClaimsSupported:                        []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "azp", "preferred_username", "name", "gender", "updated_at", "email", "email_verified", "phone_number", "address"},
+		AuthorizationResponseIssParamSupported: true,
+	}
+}
+
+func (service *oauthService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*domain.OAuthClientEntity, error) {
+	if clientID == "" {
+		return nil, domain.ErrOAuthInvalidClient
+	}
+	client, err := service.repo.FindClientByID(ctx, clientID)
+	if err != nil {
+		if err == domain.ErrOAuthClientNotFound {
+			return nil, domain.ErrOAuthInvalidClient
+		}
+		return nil, err
+	}
+
+	if client.Public {
+		if clientSecret != "" {
+			return nil, domain.ErrOAuthInvalidClient
+		}
+		return client, nil
+	}
+	if clientSecret == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		zap.L().Warn("oauth client authentication failed", zap.String("client_id", client.ID))
+		return nil, domain.ErrOAuthInvalidClient
+	}
+	return client, nil
+}
+
+// exchangeAuthorizationCode redeems a code for tokens, the code must be bound to the client, the redirect uri and the PKCE verifier
+func (service *oauthService) exchangeAuthorizationCode(ctx context.Context, client *domain.OAuthClientEntity, data *dto.TokenRequest) (*domain.OAuthTokenEntity, error) {
+	if data.Code == "" || len(data.CodeVerifier) < pkceVerifierMinLength || len(data.CodeVerifier) > pkceVerifierMaxLength {
+		return nil, domain.ErrOAuthInvalidRequest
+	}
+	code, err := service.repo.ConsumeAuthorizationCode(ctx, utils.HashToken(data.Code))
+	if err != nil {
+		return nil, err
+	}
+	if code.ClientID != client.ID || code.RedirectURI != data.RedirectURI {
+		return nil, domain.ErrOAuthInvalidGrant
+	}
+	challenge := sha256.Sum256([]byte(data.CodeVerifier))
+	if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(challenge[:])), []byte(code.CodeChallenge)) != 1 {
+		return nil, domain.ErrOAuthInvalidGrant
+	}
+
+	user, err := service.findUser(ctx, code.UserID)
+	if err != nil {
+		if err == userDomain.ErrUserNotFound {
+			return nil, domain.ErrOAuthInvalidGrant
+		}
+		return nil, err
+	}
+
+	device := dto.DeviceInfo{ClientIP: code.ClientIP, UserAgent: code.UserAgent}
+	auth, err := service.authService.StartClientSession(ctx, user, client.ID, code.Scope, device)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken := ""
+	scopes := strings.Fields(code.Scope)
+	if containsString(scopes, ScopeOpenID) {
+		claims := newIDTokenClaims(user, scopes)
+		claims.Issuer = service.issuer
+		claims.Audience = jwt.ClaimStrings{client.ID}
+		claims.AuthorizedParty = client.ID
+		claims.Nonce = code.Nonce
+		claims.AuthTime = code.AuthTime
+		idToken, err = service.jwtService.GenerateIDToken(claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newOAuthTokenEntity(auth, idToken), nil
+}
+
+func (service *oauthService) findUser(ctx context.Context, userID string) (*userDomain.UserEntity, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, userDomain.ErrUserNotFound
+	}
+	return service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &userObjectID})
+}
+
+// newIDTokenClaims derives the identity claims of the user from the same fields as the access token Claims
+func newIDTokenClaims(user *userDomain.UserEntity, scopes []string) *IDTokenClaims {
+	claims := &IDTokenClaims{}
+	claims.Subject = user.ID.Hex()
+	if containsString(scopes, ScopeProfile) {
+		claims.PreferredUsername = user.Username
+		claims.Name = user.Name
+	}
+	if containsString(scopes, ScopeEmail) {
+		emailVerified := user.EmailVerified
+		claims.Email = user.Email
+		claims.EmailVerified = &emailVerified
+	}
+	return claims
+}
+
+func profileUpdatedAt(user *userDomain.UserEntity, scopes []string) int64 {
+	if !containsString(scopes, ScopeProfile) {
+		return 0
+	}
+	return user.UpdatedAt / 1000
+}
+
+func newOAuthTokenEntity(auth *domain.JWTAuthEntity, idToken string) *domain.OAuthTokenEntity {
+	return &domain.OAuthTokenEntity{
+		AccessToken:  auth.AccessToken,
+		TokenType:    auth.TokenType,
+		ExpiresIn:    auth.ExpiredIn,
+		RefreshToken: auth.RefreshToken,
+		IDToken:      idToken,
+		Scope:        auth.Scope,
+	}
+}
+
+// toOAuthGrantError maps refresh token errors to the OAuth invalid_grant error
+func toOAuthGrantError(err error) error {
+	switch err {
+	case domain.ErrJWTRefreshTokenInvalid, domain.ErrJWTRefreshTokenExpired, domain.ErrAuthTokenNotFound:
+		return domain.ErrOAuthInvalidGrant
+	default:
+		return err
+	}
+}
+
+// isValidRedirectURI accepts absolute URIs without fragment: https, http on the loopback interface,
+// or a private-use scheme for native apps (e.g. com.example.app:/callback)
+func isValidRedirectURI(redirectURI string) bool {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+		return false
+	}
+	switch parsed.Scheme {
+	case "https":
+		return parsed.Host != ""
+	case "http":
+		return isLoopbackHost(parsed.Hostname())
+	case "javascript", "data", "file":
+		return false
+	default:
+		return strings.Contains(parsed.Scheme, ".")
+	}
+}
+
+func appendQuery(rawURL string, query url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + query.Encode()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,333 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+const (
+	testOAuthIssuer   = "https://auth.example.com"
+	testRedirectURI   = "https://app.example.com/callback"
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testOAuthNonce    = "n-0S6_WzA2Mj"
+	testOAuthState    = "af0ifjsldkj"
+	testOAuthAudience = "https://api.example.com"
+)
+
+// oauthFixture is the authorization server on top of authFixture, with a public and a confidential client
+type oauthFixture struct {
+	*authFixture
+	service      OAuthService
+	public       *domain.OAuthClientEntity
+	confidential *domain.OAuthClientEntity
+}
+
+func newOAuthFixture(t *testing.T, jwtService JWTService) *oauthFixture {
+	t.Helper()
+	auth := newAuthFixture(t)
+	f := &oauthFixture{
+		authFixture: auth,
+		service:     NewOAuthService(repository.NewMemoryOAuthRepository(), auth.service, auth.users, jwtService, testOAuthIssuer+"/"),
+	}
+	admin := &shared.Principal{UserID: auth.user.ID.Hex(), Role: shared.RoleSuperAdmin}
+	var err error
+	f.public, err = f.service.RegisterClient(context.Background(), admin, &dto.CreateOAuthClientRequest{Name: "SPA", RedirectURIs: []string{testRedirectURI}, Public: true})
+	if err != nil {
+		t.Fatalf("RegisterClient(public) = %v", err)
+	}
+	f.confidential, err = f.service.RegisterClient(context.Background(), admin, &dto.CreateOAuthClientRequest{Name: "Backend", RedirectURIs: []string{testRedirectURI}})
+	if err != nil {
+		t.Fatalf("RegisterClient(confidential) = %v", err)
+	}
+	return f
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func newAuthorizationRequest(client *domain.OAuthClientEntity, scope string) *dto.AuthorizationRequest {
+	return &dto.AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         testRedirectURI,
+		Scope:               scope,
+		State:               testOAuthState,
+		Nonce:               testOAuthNonce,
+		CodeChallenge:       codeChallenge(testCodeVerifier),
+		CodeChallengeMethod: "S256",
+	}
+}
+
+// authorize runs the authorization endpoint for alice and returns the code from the redirect
+func (f *oauthFixture) authorize(t *testing.T, client *domain.OAuthClientEntity, scope string) string {
+	t.Helper()
+	ctx := context.Background()
+	request := newAuthorizationRequest(client, scope)
+	resolved, err := f.service.ResolveClient(ctx, request)
+	if err != nil {
+		t.Fatalf("ResolveClient() = %v", err)
+	}
+	if err := f.service.ValidateAuthorizationRequest(resolved, request); err != nil {
+		t.Fatalf("ValidateAuthorizationRequest() = %v", err)
+	}
+	redirect, err := f.service.Authorize(ctx, resolved, request, f.user, dto.DeviceInfo{ClientIP: "203.0.113.7", UserAgent: "test"})
+	if err != nil {
+		t.Fatalf("Authorize() = %v", err)
+	}
+
+	parsed, err := url.Parse(redirect)
+	if err != nil || !strings.HasPrefix(redirect, testRedirectURI+"?") {
+		t.Fatalf("Authorize() redirect = %q, want %s with a query", redirect, testRedirectURI)
+	}
+	query := parsed.Query()
+	if query.Get("state") != testOAuthState || query.Get("iss") != testOAuthIssuer || query.Get("code") == "" {
+		t.Fatalf("Authorize() redirect query = %v, want code, state and iss", query)
+	}
+	return query.Get("code")
+}
+
+func (f *oauthFixture) exchange(client *domain.OAuthClientEntity, code string, edit func(data *dto.TokenRequest)) (*domain.OAuthTokenEntity, error) {
+	data := &dto.TokenRequest{
+		GrantType:    GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: testCodeVerifier,
+		ClientID:     client.ID,
+		ClientSecret: client.ClientSecret,
+	}
+	if edit != nil {
+		edit(data)
+	}
+	return f.service.Token(context.Background(), data)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	f := newOAuthFixture(t, newTestJWTService())
+	ctx := context.Background()
+
+	for _, client := range []*domain.OAuthClientEntity{f.public, f.confidential} {
+		t.Run(client.Name, func(t *testing.T) {
+			code := f.authorize(t, client, "profile email")
+			token, err := f.exchange(client, code, nil)
+			if err != nil {
+				t.Fatalf("Token() = %v", err)
+			}
+			if token.Scope != "profile email" || token.RefreshToken == "" || token.IDToken != "" {
+				t.Fatalf("Token() = %+v, want a scoped access and refresh token without ID token", token)
+			}
+
+			principal, err := f.authFixture.service.Authenticate(ctx, token.AccessToken)
+			if err != nil {
+				t.Fatalf("Authenticate() = %v", err)
+			}
+			if principal.UserID != f.user.ID.Hex() || principal.ClientID != client.ID || !principal.HasScope(ScopeEmail) {
+				t.Fatalf("Authenticate() = %+v, want alice through the client with the granted scope", principal)
+			}
+
+			// The code is single use
+			if _, err := f.exchange(client, code, nil); err != domain.ErrOAuthInvalidGrant {
+				t.Fatalf("Token() with a redeemed code = %v, want %v", err, domain.ErrOAuthInvalidGrant)
+			}
+
+			// The refresh token is rotated through the token endpoint by the same client only
+			refreshed, err := f.service.Token(ctx, &dto.TokenRequest{GrantType: GrantTypeRefreshToken, RefreshToken: token.RefreshToken, ClientID: client.ID, ClientSecret: client.ClientSecret})
+			if err != nil {
+				t.Fatalf("Token(refresh_token) = %v", err)
+			}
+			if refreshed.Scope != token.Scope || refreshed.RefreshToken == token.RefreshToken {
+				t.Fatalf("Token(refresh_token) = %+v, want a rotated token with the same scope", refreshed)
+			}
+			if _, err := f.refresh(refreshed.RefreshToken); err != domain.ErrJWTRefreshTokenInvalid {
+				t.Fatalf("RefreshToken() with a client token = %v, want %v", err, domain.ErrJWTRefreshTokenInvalid)
+			}
+		})
+	}
+}
+
+func TestAuthorizationCodeExchangeRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(f *oauthFixture, data *dto.TokenRequest)
+		want error
+	}{
+		{"wrong verifier", func(f *oauthFixture, data *dto.TokenRequest) {
+			data.CodeVerifier = strings.Repeat("a", pkceVerifierMinLength)
+		}, domain.ErrOAuthInvalidGrant},
+		{"short verifier", func(f *oauthFixture, data *dto.TokenRequest) {
+			data.CodeVerifier = testCodeVerifier[:pkceVerifierMinLength-1]
+		}, domain.ErrOAuthInvalidRequest},
+		{"no verifier", func(f *oauthFixture, data *dto.TokenRequest) { data.CodeVerifier = "" }, domain.ErrOAuthInvalidRequest},
+		{"other redirect uri", func(f *oauthFixture, data *dto.TokenRequest) {
+			data.RedirectURI = "https://app.example.com/other"
+		}, domain.ErrOAuthInvalidGrant},
+		{"other client", func(f *oauthFixture, data *dto.TokenRequest) {
+			data.ClientID, data.ClientSecret = f.confidential.ID, f.confidential.ClientSecret
+		}, domain.ErrOAuthInvalidGrant},
+		{"unknown code", func(f *oauthFixture, data *dto.TokenRequest) { data.Code = "unknown" }, domain.ErrOAuthInvalidGrant},
+		{"secret sent by a public client", func(f *oauthFixture, data *dto.TokenRequest) {
+			data.ClientSecret = "secret"
+		}, domain.ErrOAuthInvalidClient},
+		{"unknown grant type", func(f *oauthFixture, data *dto.TokenRequest) { data.GrantType = "password" }, domain.ErrOAuthUnsupportedGrantType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOAuthFixture(t, newTestJWTService())
+			code := f.authorize(t, f.public, "profile")
+			_, err := f.exchange(f.public, code, func(data *dto.TokenRequest) { tt.edit(f, data) })
+			if err != tt.want {
+				t.Fatalf("Token() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateClient(t *testing.T) {
+	f := newOAuthFixture(t, newTestJWTService())
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		clientID string
+		secret   string
+		wantErr  bool
+	}{
+		{"public without secret", f.public.ID, "", false},
+		{"public with secret", f.public.ID, "secret", true},
+		{"confidential with its secret", f.confidential.ID, f.confidential.ClientSecret, false},
+		{"confidential without secret", f.confidential.ID, "", true},
+		{"confidential with a wrong secret", f.confidential.ID, f.confidential.ClientSecret + "x", true},
+		{"unknown client", "unknown", "", true},
+		{"no client", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.service.AuthenticateClient(ctx, tt.clientID, tt.secret)
+			if tt.wantErr && err != domain.ErrOAuthInvalidClient {
+				t.Fatalf("AuthenticateClient() = %v, want %v", err, domain.ErrOAuthInvalidClient)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("AuthenticateClient() = %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveClient(t *testing.T) {
+	f := newOAuthFixture(t, newTestJWTService())
+	ctx := context.Background()
+
+	// A client with a single redirect uri may omit it
+	request := newAuthorizationRequest(f.public, "")
+	request.RedirectURI = ""
+	if _, err := f.service.ResolveClient(ctx, request); err != nil || request.RedirectURI != testRedirectURI {
+		t.Fatalf("ResolveClient() without redirect uri = %v, redirect %q, want the registered one", err, request.RedirectURI)
+	}
+
+	request = newAuthorizationRequest(f.public, "")
+	request.RedirectURI = "https://evil.example.com/callback"
+	if _, err := f.service.ResolveClient(ctx, request); err != domain.ErrOAuthInvalidRedirectURI {
+		t.Fatalf("ResolveClient() with another redirect uri = %v, want %v", err, domain.ErrOAuthInvalidRedirectURI)
+	}
+
+	request = newAuthorizationRequest(f.public, "")
+	request.ClientID = "unknown"
+	if _, err := f.service.ResolveClient(ctx, request); err != domain.ErrOAuthInvalidClient {
+		t.Fatalf("ResolveClient() with an unknown client = %v, want %v", err, domain.ErrOAuthInvalidClient)
+	}
+}
+
+func TestValidateAuthorizationRequest(t *testing.T) {
+	f := newOAuthFixture(t, newTestJWTService())
+	client := &domain.OAuthClientEntity{ID: "client", RedirectURIs: []string{testRedirectURI}, Scopes: []string{ScopeOpenID, ScopeProfile}}
+
+	tests := []struct {
+		name string
+		edit func(data *dto.AuthorizationRequest)
+		want error
+	}{
+		{"valid", func(data *dto.AuthorizationRequest) {}, nil},
+		{"token response type", func(data *dto.AuthorizationRequest) { data.ResponseType = "token" }, domain.ErrOAuthUnsupportedResponseType},
+		{"no challenge", func(data *dto.AuthorizationRequest) { data.CodeChallenge = "" }, domain.ErrOAuthPKCERequired},
+		{"plain challenge", func(data *dto.AuthorizationRequest) { data.CodeChallengeMethod = "plain" }, domain.ErrOAuthPKCERequired},
+		{"scope not granted to the client", func(data *dto.AuthorizationRequest) { data.Scope = "profile email" }, domain.ErrOAuthInvalidScope},
+		{"openid without signing keys", func(data *dto.AuthorizationRequest) { data.Scope = "openid profile" }, domain.ErrOAuthInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := newAuthorizationRequest(client, "profile")
+			tt.edit(data)
+			if err := f.service.ValidateAuthorizationRequest(client, data); err != tt.want {
+				t.Fatalf("ValidateAuthorizationRequest() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizationCodeFlowIDToken(t *testing.T) {
+	key := newTestSigningKey(t, "key-1", time.Now().Add(-time.Hour), time.Time{})
+	manager, err := NewKeyManager([]*SigningKey{key}, time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeyManager() = %v", err)
+	}
+	jwtService := NewJWTService(JWTConfig{KeyManager: manager, ExpiresIn: 15 * time.Minute, Issuer: testIssuer, Audience: testOAuthAudience})
+	f := newOAuthFixture(t, jwtService)
+
+	code := f.authorize(t, f.public, "openid email")
+	token, err := f.exchange(f.public, code, nil)
+	if err != nil {
+		t.Fatalf("Token() = %v", err)
+	}
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(token.IDToken, claims, func(*jwt.Token) (any, error) { return key.PublicKey, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(testOAuthIssuer),
+		jwt.WithAudience(f.public.ID),
+	)
+	if err != nil {
+		t.Fatalf("parse ID token: %v", err)
+	}
+	if claims.Subject != f.user.ID.Hex() || claims.Nonce != testOAuthNonce || claims.AuthorizedParty != f.public.ID || claims.AuthTime == 0 {
+		t.Fatalf("ID token claims = %+v, want alice with the nonce, azp and auth_time", claims)
+	}
+	if claims.Email != f.user.Email || claims.PreferredUsername != "" {
+		t.Fatalf("ID token claims = %+v, want the email claims only", claims)
+	}
+}
+
+func TestIsValidRedirectURI(t *testing.T) {
+	tests := []struct {
+		redirectURI string
+		want        bool
+	}{
+		{"https://app.example.com/callback", true},
+		{"https://app.example.com/callback?tenant=1", true},
+		{"http://127.0.0.1:8080/callback", true},
+		{"http://localhost/callback", true},
+		{"com.example.app:/callback", true},
+		{"http://app.example.com/callback", false},
+		{"https://app.example.com/callback#fragment", false},
+		{"https:///callback", false},
+		{"/callback", false},
+		{"javascript:alert(1)", false},
+		{"myapp:/callback", false},
+	}
+	for _, tt := range tests {
+		if got := isValidRedirectURI(tt.redirectURI); got != tt.want {
+			t.Errorf("isValidRedirectURI(%q) = %v, want %v", tt.redirectURI, got, tt.want)
+		}
+	}
+}
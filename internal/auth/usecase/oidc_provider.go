@@ -0,0 +1,363 @@
+package usecase
+
+import (
+	"context"
+	"crypto"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.uber.org/zap"
+)
+
+// External OpenID Connect providers: discovery, authorization code exchange and ID token validation
+
+const (
+	oidcDiscoveryCacheFor  = time.Hour
+	oidcJWKSMinRefresh     = time.Minute // Unknown kids refetch the key set at most this often
+	oidcClockLeeway        = time.Minute
+	oidcMaxResponseSize    = 1 << 20
+	oidcDefaultHTTPTimeout = 10 * time.Second
+)
+
+var oidcDefaultScopes = []string{"openid", "email", "profile"}
+
+// ID tokens are only accepted with asymmetric signatures verified against the provider keys
+var oidcIDTokenAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// OIDCProviderConfig is an external identity provider registered as an OAuth client
+type OIDCProviderConfig struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"` // Our callback, e.g. https://api.example.com/api/v1/auth/oidc/google/callback
+	Scopes       []string `json:"scopes"`
+}
+
+// OIDCIDTokenClaims are the ID token claims used to find or provision the local user
+type OIDCIDTokenClaims struct {
+	Email             string   `json:"email"`
+	EmailVerified     oidcBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Nonce             string   `json:"nonce"`
+	AuthorizedParty   string   `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// oidcBool accepts both true and "true", some providers send email_verified as a string
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+type oidcDiscoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	TokenType        string `json:"token_type"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// oidcProvider talks to one provider, the discovery document and the key set are cached
+type oidcProvider struct {
+	config     *OIDCProviderConfig
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscoveryDocument
+	discoveredAt  time.Time
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// LoadOIDCProviders parses the JSON array of providers from OIDC_PROVIDERS
+func LoadOIDCProviders(spec string) ([]*OIDCProviderConfig, error) {
+	var providers []*OIDCProviderConfig
+	if err := json.Unmarshal([]byte(spec), &providers); err != nil {
+		return nil, fmt.Errorf("invalid oidc providers: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for _, provider := range providers {
+		if provider.Name == "" || provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			return nil, errors.New("oidc provider needs name, issuer, client_id and redirect_url")
+		}
+		if names[provider.Name] {
+			return nil, fmt.Errorf("duplicated oidc provider %q", provider.Name)
+		}
+		names[provider.Name] = true
+
+		issuer, err := url.Parse(provider.Issuer)
+		if err != nil || issuer.Host == "" {
+			return nil, fmt.Errorf("invalid issuer of oidc provider %q", provider.Name)
+		}
+		// Plain http is only accepted for a provider running on this machine
+		if issuer.Scheme != "https" && !(issuer.Scheme == "http" && isLoopbackHost(issuer.Hostname())) {
+			return nil, fmt.Errorf("issuer of oidc provider %q must use https", provider.Name)
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = oidcDefaultScopes
+		}
+	}
+	return providers, nil
+}
+
+func isLoopbackHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func newOIDCProvider(config *OIDCProviderConfig, httpClient *http.Client) *oidcProvider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: oidcDefaultHTTPTimeout}
+	}
+	return &oidcProvider{config: config, httpClient: httpClient}
+}
+
+// authorizationURL builds the authorization code request with PKCE (S256)
+func (p *oidcProvider) authorizationURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// exchangeCode redeems the authorization code and returns the raw ID token
+func (p *oidcProvider) exchangeCode(ctx context.Context, code, codeVerifier string) (string, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", domain.ErrAuthInternalServerError
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var tokenResponse oidcTokenResponse
+	status, err := p.doJSON(request, &tokenResponse)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK || tokenResponse.IDToken == "" {
+		zap.L().Warn("oidc provider rejected the code exchange",
+			zap.String("provider", p.config.Name),
+			zap.Int("status", status),
+			zap.String("error", tokenResponse.Error),
+			zap.String("error_description", tokenResponse.ErrorDescription),
+		)
+		return "", domain.ErrOIDCCodeExchangeFailed
+	}
+	return tokenResponse.IDToken, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience, lifetime and nonce of an ID token
+func (p *oidcProvider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OIDCIDTokenClaims, error) {
+	claims := &OIDCIDTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.publicKey(ctx, kid)
+		},
+		jwt.WithValidMethods(oidcIDTokenAlgorithms),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockLeeway),
+	)
+	if err != nil {
+		if errors.Is(err, domain.ErrOIDCProviderUnavailable) {
+			return nil, domain.ErrOIDCProviderUnavailable
+		}
+		zap.L().Warn("invalid oidc id token", zap.String("provider", p.config.Name), zap.Error(err))
+		return nil, domain.ErrOIDCIDTokenInvalid
+	}
+
+	if claims.Subject == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, domain.ErrOIDCIDTokenInvalid
+	}
+	// With several audiences the token must have been issued to us
+	if (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != p.config.ClientID {
+		return nil, domain.ErrOIDCIDTokenInvalid
+	}
+	return claims, nil
+}
+
+// metadata returns the discovery document, fetched from {issuer}/.well-known/openid-configuration
+func (p *oidcProvider) metadata(ctx context.Context) (*oidcDiscoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveredAt) < oidcDiscoveryCacheFor {
+		return p.discovery, nil
+	}
+
+	endpoint := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+
+	var discovery oidcDiscoveryDocument
+	status, err := p.doJSON(request, &discovery)
+	if err != nil {
+		return nil, err
+	}
+	// The document must describe the configured issuer, otherwise tokens of another issuer could be accepted
+	if status != http.StatusOK || discovery.Issuer != p.config.Issuer ||
+		discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		zap.L().Error("invalid oidc discovery document",
+			zap.String("provider", p.config.Name),
+			zap.Int("status", status),
+			zap.String("issuer", discovery.Issuer),
+		)
+		return nil, domain.ErrOIDCProviderUnavailable
+	}
+
+	p.discovery = &discovery
+	p.discoveredAt = time.Now()
+	return p.discovery, nil
+}
+
+// publicKey resolves a verification key by kid, the key set is refetched when the kid is unknown
+func (p *oidcProvider) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcJWKSMinRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	var jwks domain.JWKSEntity
+	status, err := p.doJSON(request, &jwks)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		zap.L().Error("error fetching oidc key set", zap.String("provider", p.config.Name), zap.Int("status", status))
+		return nil, domain.ErrOIDCProviderUnavailable
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for i := range jwks.Keys {
+		jwk := &jwks.Keys[i]
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwkToPublicKey(jwk)
+		if err != nil {
+			zap.L().Warn("skipping oidc signing key", zap.String("provider", p.config.Name), zap.String("kid", jwk.KeyID), zap.Error(err))
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a cached key, a token without kid is accepted only when the set has a single key.
+// The caller must hold the lock.
+func (p *oidcProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// doJSON sends the request and decodes the JSON body whatever the status is
+func (p *oidcProvider) doJSON(request *http.Request, target interface{}) (int, error) {
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		zap.L().Error("error calling oidc provider", zap.String("provider", p.config.Name), zap.Error(err))
+		return 0, domain.ErrOIDCProviderUnavailable
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, oidcMaxResponseSize))
+	if err != nil {
+		return 0, domain.ErrOIDCProviderUnavailable
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		zap.L().Error("invalid oidc provider response",
+			zap.String("provider", p.config.Name),
+			zap.Int("status", response.StatusCode),
+			zap.Error(err),
+		)
+		return 0, domain.ErrOIDCProviderUnavailable
+	}
+	return response.StatusCode, nil
+}
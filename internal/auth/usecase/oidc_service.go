@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// OIDC use case: login with external identity providers (authorization code flow with PKCE)
+
+const (
+	oidcLoginStateExpiresIn = 10 * time.Minute
+	oidcUsernameMaxBase     = 14 // Leaves room for the random suffix within the 20 characters limit
+	oidcUsernameAttempts    = 3
+)
+
+var oidcUsernameInvalidChars = regexp.MustCompile(`[^a-z0-9._-]`)
+
+type OIDCService interface {
+	Providers() []*domain.OIDCProviderEntity
+	// StartLogin returns the provider authorization URL and the state the browser must present on callback
+	StartLogin(ctx context.Context, provider string) (string, string, error)
+	// CompleteLogin handles the redirect back from the provider and logs in the user with the verified email,
+	// the user is provisioned on first login
+	CompleteLogin(ctx context.Context, provider string, data *dto.OIDCCallbackRequest) (*domain.JWTAuthEntity, *domain.MFAChallengeEntity, error)
+}
+
+type oidcService struct {
+	userService userUseCase.UserService
+	authService AuthService
+	repo        repository.AuthRepository
+	providers   map[string]*oidcProvider
+	names       []string
+}
+
+// NewOIDCService creates the OIDC relying party for the configured providers.
+// httpClient is used to call the providers, nil uses a client with a default timeout.
+func NewOIDCService(userService userUseCase.UserService, authService AuthService, repo repository.AuthRepository, providers []*OIDCProviderConfig, httpClient *http.Client) OIDCService {
+	service := &oidcService{
+		userService: userService,
+		authService: authService,
+		repo:        repo,
+		providers:   make(map[string]*oidcProvider),
+	}
+	for _, config := range providers {
+		service.providers[config.Name] = newOIDCProvider(config, httpClient)
+		service.names = append(service.names, config.Name)
+	}
+	return service
+}
+
+func (service *oidcService) Providers() []*domain.OIDCProviderEntity {
+	providers := make([]*domain.OIDCProviderEntity, 0, len(service.names))
+	for _, name := range service.names {
+		providers = append(providers, &domain.OIDCProviderEntity{
+			Name:   name,
+			Issuer: service.providers[name].config.Issuer,
+		})
+	}
+	return providers
+}
+
+func (service *oidcService) StartLogin(ctx context.Context, providerName string) (string, string, error) {
+	provider, ok := service.providers[providerName]
+	if !ok {
+		return "", "", domain.ErrOIDCProviderNotFound
+	}
+
+	state, errState := utils.GenerateRandomToken(32)
+	nonce, errNonce := utils.GenerateRandomToken(32)
+	codeVerifier, errVerifier := utils.GenerateRandomToken(32)
+	if errState != nil || errNonce != nil || errVerifier != nil {
+		return "", "", domain.ErrAuthInternalServerError
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	authorizationURL, err := provider.authorizationURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", "", err
+	}
+
+	err = service.repo.CreateOIDCLoginState(ctx, &domain.OIDCLoginStateEntity{
+		ID:           utils.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcLoginStateExpiresIn).UnixMilli(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return authorizationURL, state, nil
+}
+
+func (service *oidcService) CompleteLogin(ctx context.Context, providerName string, data *dto.OIDCCallbackRequest) (*domain.JWTAuthEntity, *domain.MFAChallengeEntity, error) {
+	provider, ok := service.providers[providerName]
+	if !ok {
+		return nil, nil, domain.ErrOIDCProviderNotFound
+	}
+	// The state must come back to the browser that started the login, this stops login CSRF
+	if data.BrowserState == "" || data.BrowserState != data.State {
+		return nil, nil, domain.ErrOIDCStateInvalid
+	}
+	state, err := service.repo.ConsumeOIDCLoginState(ctx, utils.HashToken(data.State))
+	if err != nil {
+		return nil, nil, err
+	}
+	if state.Provider != providerName {
+		return nil, nil, domain.ErrOIDCStateInvalid
+	}
+	if data.Error != "" {
+		zap.L().Info("oidc login denied by provider",
+			zap.String("provider", providerName),
+			zap.String("error", data.Error),
+			zap.String("error_description", data.ErrorDescription),
+		)
+		return nil, nil, domain.ErrOIDCLoginCancelled
+	}
+	if data.Code == "" {
+		return nil, nil, domain.ErrOIDCCodeExchangeFailed
+	}
+
+	rawIDToken, err := provider.exchangeCode(ctx, data.Code, state.CodeVerifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	claims, err := provider.verifyIDToken(ctx, rawIDToken, state.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Accounts are matched by email, so only an email the provider verified can be trusted
+	if claims.Email == "" || !bool(claims.EmailVerified) {
+		return nil, nil, domain.ErrOIDCEmailNotVerified
+	}
+
+	user, err := service.findOrCreateUser(ctx, providerName, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	zap.L().Info("user logged in with oidc",
+		zap.String("provider", providerName),
+		zap.String("user_id", user.ID.Hex()),
+	)
+
+	return service.authService.LoginExternalUser(ctx, user, dto.DeviceInfo{ClientIP: data.ClientIP, UserAgent: data.UserAgent})
+}
+
+// findOrCreateUser returns the user with the email, a new user is provisioned when there is none
+func (service *oidcService) findOrCreateUser(ctx context.Context, providerName string, claims *OIDCIDTokenClaims) (*userDomain.UserEntity, error) {
+	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{Email: &claims.Email})
+	if err == nil {
+		if user.EmailVerified {
+			return user, nil
+		}
+		return service.claimUnverifiedUser(ctx, providerName, user)
+	}
+	if err != userDomain.ErrUserNotFound {
+		return nil, err
+	}
+
+	// The password is random and never shown, the user can set one with the password reset flow
+	password, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+	base := oidcUsernameBase(claims)
+
+	username := base
+	for attempt := 0; attempt < oidcUsernameAttempts; attempt++ {
+		newUser := userDomain.NewUserEntity(username, claims.Email, password, name, "", "", shared.RoleUser, shared.GenderUnknown)
+		newUser.EmailVerified = true
+		newUser.VerifiedAt = time.Now().UnixMilli()
+
+		created, err := service.userService.CreateUser(ctx, newUser)
+		if err == nil {
+			zap.L().Info("user provisioned from oidc",
+				zap.String("provider", providerName),
+				zap.String("user_id", created.ID.Hex()),
+			)
+			return created, nil
+		}
+		if err != userDomain.ErrUserUsernameAlreadyExists {
+			return nil, err
+		}
+
+		suffix, err := utils.GenerateRandomToken(4)
+		if err != nil {
+			return nil, domain.ErrAuthInternalServerError
+		}
+		username = base + "_" + strings.ToLower(suffix)[:5]
+	}
+	return nil, userDomain.ErrUserUsernameAlreadyExists
+}
+
+// claimUnverifiedUser links an unverified local account to the provider identity.
+// Anyone could have registered that account with the email, so its password and sessions are discarded.
+func (service *oidcService) claimUnverifiedUser(ctx context.Context, providerName string, user *userDomain.UserEntity) (*userDomain.UserEntity, error) {
+	password, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	if err := service.userService.UpdatePassword(ctx, user.ID, password); err != nil {
+		return nil, err
+	}
+	if _, err := service.repo.IncrementUserTokenVersion(ctx, user.ID.Hex()); err != nil {
+		return nil, err
+	}
+	if err := service.repo.RevokeRefreshTokenFamiliesByUserID(ctx, user.ID.Hex()); err != nil {
+		return nil, err
+	}
+
+	verified := true
+	verifiedAt := time.Now().UnixMilli()
+	user, err = service.userService.UpdateAUser(ctx, user.ID, usersRepository.UserUpdates{
+		EmailVerified: &verified,
+		VerifiedAt:    &verifiedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Warn("unverified user claimed through oidc, password and sessions were reset",
+		zap.String("provider", providerName),
+		zap.String("user_id", user.ID.Hex()),
+	)
+	return user, nil
+}
+
+// oidcUsernameBase derives a username from the preferred username or the email
+func oidcUsernameBase(claims *OIDCIDTokenClaims) string {
+	candidate := claims.PreferredUsername
+	if candidate == "" || strings.Contains(candidate, "@") {
+		candidate, _, _ = strings.Cut(claims.Email, "@")
+	}
+	candidate = oidcUsernameInvalidChars.ReplaceAllString(strings.ToLower(candidate), "")
+	if len(candidate) > oidcUsernameMaxBase {
+		candidate = candidate[:oidcUsernameMaxBase]
+	}
+	for len(candidate) < 5 {
+		candidate += "0"
+	}
+	return candidate
+}
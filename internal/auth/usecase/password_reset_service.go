@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/mailer"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Password reset use case: single-use reset tokens sent by email
+
+const (
+	passwordResetTokenExpiresIn = 30 * time.Minute
+	passwordResetTokenBytes     = 32
+	passwordResetMailTimeout    = 15 * time.Second // Finding the user, storing the token and sending the email
+)
+
+type PasswordResetService interface {
+	// ForgotPassword emails a reset link when the email belongs to a user, requests are limited per email and IP.
+	// It returns nil for unknown emails too, so the response does not reveal which emails are registered.
+	ForgotPassword(ctx context.Context, data *dto.ForgotPasswordRequest) error
+	// ResetPassword consumes the reset token, sets the new password and ends every session of the user
+	ResetPassword(ctx context.Context, data *dto.ResetPasswordRequest) error
+}
+
+type passwordResetService struct {
+	userService userUseCase.UserService
+	repo        repository.AuthRepository
+	throttler   EmailRequestThrottler
+	mailer      mailer.Mailer
+	resetURL    string
+}
+
+// NewPasswordResetService creates the password reset service.
+// resetURL is the page of the client app that reads the token query parameter and calls POST /auth/password/reset.
+func NewPasswordResetService(userService userUseCase.UserService, repo repository.AuthRepository, throttler EmailRequestThrottler, mailer mailer.Mailer, resetURL string) PasswordResetService {
+	return &passwordResetService{
+		userService: userService,
+		repo:        repo,
+		throttler:   throttler,
+		mailer:      mailer,
+		resetURL:    resetURL,
+	}
+}
+
+func (service *passwordResetService) ForgotPassword(ctx context.Context, data *dto.ForgotPasswordRequest) error {
+	email := strings.TrimSpace(data.Email)
+	if err := service.throttler.Allow(ctx, email, data.ClientIP); err != nil {
+		return err
+	}
+
+	// Everything else happens in the background so known and unknown emails answer in the same time
+	go service.sendResetLink(context.WithoutCancel(ctx), email)
+	return nil
+}
+
+// sendResetLink emails a reset link when the email belongs to a user, errors are only logged
+func (service *passwordResetService) sendResetLink(ctx context.Context, email string) {
+	ctx, cancel := context.WithTimeout(ctx, passwordResetMailTimeout)
+	defer cancel()
+
+	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{
+		Email: &email,
+	})
+	if err != nil {
+		if err == userDomain.ErrUserNotFound {
+			zap.L().Info("password reset requested for unknown email")
+			return
+		}
+		zap.L().Error("error finding the user of a password reset", zap.Error(err))
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(passwordResetTokenBytes)
+	if err != nil {
+		zap.L().Error("error generating password reset token", zap.Error(err))
+		return
+	}
+	err = service.repo.CreatePasswordResetToken(ctx, &domain.PasswordResetTokenEntity{
+		ID:        utils.HashToken(token),
+		UserID:    user.ID.Hex(),
+		ExpiresAt: time.Now().Add(passwordResetTokenExpiresIn).UnixMilli(),
+	})
+	if err != nil {
+		zap.L().Error("error storing password reset token", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+		return
+	}
+
+	if err := service.mailer.Send(ctx, service.buildResetMessage(user.Email, token)); err != nil {
+		zap.L().Error("error sending password reset email", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+	}
+}
+
+func (service *passwordResetService) ResetPassword(ctx context.Context, data *dto.ResetPasswordRequest) error {
+	tokenHash := utils.HashToken(data.Token)
+
+	// The new password is checked before the token is used, so a rejected password does not burn the link
+	token, err := service.repo.FindPasswordResetToken(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(token.UserID)
+	if err != nil {
+		return domain.ErrPasswordResetTokenInvalid
+	}
+	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &userObjectID})
+	if err != nil {
+		if err == userDomain.ErrUserNotFound {
+			return domain.ErrPasswordResetTokenInvalid
+		}
+		return err
+	}
+	if err := service.userService.ValidatePassword(user, data.NewPassword); err != nil {
+		if ce, ok := err.(*utils.CustomError); ok {
+			return ce.WithField("new_password")
+		}
+		return err
+	}
+
+	if _, err := service.repo.ConsumePasswordResetToken(ctx, tokenHash); err != nil {
+		return err
+	}
+	if err := service.userService.UpdatePassword(ctx, userObjectID, data.NewPassword); err != nil {
+		return err
+	}
+
+	// Other reset links and every issued token must stop working once the password changed
+	if err := service.repo.InvalidatePasswordResetTokens(ctx, token.UserID); err != nil {
+		return err
+	}
+	if _, err := service.repo.IncrementUserTokenVersion(ctx, token.UserID); err != nil {
+		return err
+	}
+	if err := service.repo.RevokeRefreshTokenFamiliesByUserID(ctx, token.UserID); err != nil {
+		return err
+	}
+
+	zap.L().Info("user password reset", zap.String("user_id", token.UserID))
+	return nil
+}
+
+func (service *passwordResetService) buildResetMessage(email, token string) *mailer.Message {
+	link := service.resetURL + "?token=" + url.QueryEscape(token)
+	minutes := int(passwordResetTokenExpiresIn.Minutes())
+
+	return &mailer.Message{
+		To:      []string{email},
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("We received a request to reset your password.\n\n"+
+			"Open the link below to choose a new password, it expires in %d minutes:\n%s\n\n"+
+			"If you did not request this, you can ignore this email.", minutes, link),
+		HTML: fmt.Sprintf("<p>We received a request to reset your password.</p>"+
+			"<p><a href=\"%s\">Choose a new password</a>, the link expires in %d minutes.</p>"+
+			"<p>If you did not request this, you can ignore this email.</p>", link, minutes),
+	}
+}
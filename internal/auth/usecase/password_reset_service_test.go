@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/mailer"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+const testResetURL = "https://app.example.com/reset-password"
+
+// mailbox receives the emails sent in the background
+type mailbox chan *mailer.Message
+
+func (box mailbox) Send(ctx context.Context, message *mailer.Message) error {
+	box <- message
+	return nil
+}
+
+// resetLinkToken waits for the next reset email and returns the token of its link
+func (box mailbox) resetLinkToken(t *testing.T) string {
+	t.Helper()
+	select {
+	case message := <-box:
+		link := regexp.MustCompile(regexp.QuoteMeta(testResetURL) + `\?token=\S+`).FindString(message.Text)
+		parsed, err := url.Parse(link)
+		if link == "" || err != nil {
+			t.Fatalf("no reset link in %q", message.Text)
+		}
+		return parsed.Query().Get("token")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reset email was sent")
+		return ""
+	}
+}
+
+func newPasswordResetFixture(t *testing.T) (*authFixture, PasswordResetService, mailbox) {
+	t.Helper()
+	f := newAuthFixture(t)
+	box := make(mailbox, 4)
+	throttler := NewEmailRequestThrottler(repository.NewMemoryLoginAttemptRepository(), DefaultEmailRequestLimits)
+	return f, NewPasswordResetService(f.users, f.repo, throttler, box, testResetURL), box
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	f, service, box := newPasswordResetFixture(t)
+	session := f.login(t)
+
+	if err := service.ForgotPassword(ctx, &dto.ForgotPasswordRequest{Email: "Alice@Example.com", ClientIP: "198.51.100.1"}); err != nil {
+		t.Fatalf("ForgotPassword() = %v", err)
+	}
+	token := box.resetLinkToken(t)
+
+	// A rejected password does not use the link up
+	err := service.ResetPassword(ctx, &dto.ResetPasswordRequest{Token: token, NewPassword: "short"})
+	if ce, ok := err.(*utils.CustomError); !ok || ce.Code() != userDomain.ErrUserInvalidPassword.Code() || ce.Field() != "new_password" {
+		t.Fatalf("ResetPassword() with a weak password = %v, want %v on new_password", err, userDomain.ErrUserInvalidPassword)
+	}
+	if err := service.ResetPassword(ctx, &dto.ResetPasswordRequest{Token: token, NewPassword: "a much better passphrase"}); err != nil {
+		t.Fatalf("ResetPassword() = %v", err)
+	}
+	if f.user.Password != "a much better passphrase" {
+		t.Fatalf("password = %q, want the new one", f.user.Password)
+	}
+
+	// The link is single use and the sessions opened with the old password are over
+	if err := service.ResetPassword(ctx, &dto.ResetPasswordRequest{Token: token, NewPassword: "yet another passphrase"}); !errors.Is(err, domain.ErrPasswordResetTokenInvalid) {
+		t.Fatalf("ResetPassword() with a used link = %v, want %v", err, domain.ErrPasswordResetTokenInvalid)
+	}
+	if _, err := f.service.Authenticate(ctx, session.AccessToken); !errors.Is(err, domain.ErrJWTTokenRevoked) {
+		t.Fatalf("Authenticate() after the reset = %v, want %v", err, domain.ErrJWTTokenRevoked)
+	}
+	if _, err := f.refresh(session.RefreshToken); !errors.Is(err, domain.ErrJWTRefreshTokenInvalid) {
+		t.Fatalf("refresh after the reset = %v, want %v", err, domain.ErrJWTRefreshTokenInvalid)
+	}
+}
+
+// Resetting with one link voids the others sent before
+func TestPasswordResetVoidsOtherLinks(t *testing.T) {
+	ctx := context.Background()
+	_, service, box := newPasswordResetFixture(t)
+
+	tokens := make([]string, 2)
+	for i := range tokens {
+		if err := service.ForgotPassword(ctx, &dto.ForgotPasswordRequest{Email: "alice@example.com"}); err != nil {
+			t.Fatalf("ForgotPassword() = %v", err)
+		}
+		tokens[i] = box.resetLinkToken(t)
+	}
+
+	if err := service.ResetPassword(ctx, &dto.ResetPasswordRequest{Token: tokens[1], NewPassword: "a much better passphrase"}); err != nil {
+		t.Fatalf("ResetPassword() = %v", err)
+	}
+	if err := service.ResetPassword(ctx, &dto.ResetPasswordRequest{Token: tokens[0], NewPassword: "yet another passphrase"}); !errors.Is(err, domain.ErrPasswordResetTokenInvalid) {
+		t.Fatalf("ResetPassword() with an older link = %v, want %v", err, domain.ErrPasswordResetTokenInvalid)
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	_, service, box := newPasswordResetFixture(t)
+
+	// Answered like a known email, and nothing is sent
+	if err := service.ForgotPassword(context.Background(), &dto.ForgotPasswordRequest{Email: "nobody@example.com"}); err != nil {
+		t.Fatalf("ForgotPassword() = %v, want nil", err)
+	}
+	select {
+	case message := <-box:
+		t.Fatalf("an email was sent to %v", message.To)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestForgotPasswordIsRateLimited(t *testing.T) {
+	ctx := context.Background()
+	_, service, box := newPasswordResetFixture(t)
+
+	for i := int64(0); i < DefaultEmailRequestLimits.PerEmail; i++ {
+		if err := service.ForgotPassword(ctx, &dto.ForgotPasswordRequest{Email: "alice@example.com"}); err != nil {
+			t.Fatalf("ForgotPassword() #%d = %v", i, err)
+		}
+		box.resetLinkToken(t)
+	}
+	if err := service.ForgotPassword(ctx, &dto.ForgotPasswordRequest{Email: "alice@example.com"}); err == nil {
+		t.Fatal("ForgotPassword() over the limit = nil, want an error")
+	}
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// Reference tokens: clients get an opaque access token, the signed one it stands for never leaves the server.
+// Other resource servers resolve them with the token introspection endpoint.
+
+// Access token formats, set in ACCESS_TOKEN_FORMAT
+const (
+	AccessTokenFormatJWT       = "jwt"
+	AccessTokenFormatReference = "reference"
+)
+
+// referenceTokenLength is the number of random bytes of a reference token
+const referenceTokenLength = 32
+
+type referenceTokenService struct {
+	JWTService
+	repo repository.AuthRepository
+}
+
+// NewReferenceTokenService issues reference tokens in place of the access tokens signed by jwtService.
+// Refresh and ID tokens are left unchanged. Signed access tokens are still accepted, so the ones issued
+// before the switch keep working until they expire.
+func NewReferenceTokenService(jwtService JWTService, repo repository.AuthRepository) JWTService {
+	return &referenceTokenService{JWTService: jwtService, repo: repo}
+}
+
+func (service *referenceTokenService) GenerateJWT(ctx context.Context, claims *Claims, refreshClaims *RefreshClaims) (*domain.JWTAuthEntity, error) {
+	auth, err := service.JWTService.GenerateJWT(ctx, claims, refreshClaims)
+	if err != nil {
+		return nil, err
+	}
+	return service.reference(ctx, auth)
+}
+
+func (service *referenceTokenService) GenerateAccessToken(ctx context.Context, claims *Claims) (*domain.JWTAuthEntity, error) {
+	auth, err := service.JWTService.GenerateAccessToken(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	return service.reference(ctx, auth)
+}
+
+func (service *referenceTokenService) GenerateImpersonationToken(ctx context.Context, claims *Claims, expiresIn time.Duration) (*domain.JWTAuthEntity, error) {
+	auth, err := service.JWTService.GenerateImpersonationToken(ctx, claims, expiresIn)
+	if err != nil {
+		return nil, err
+	}
+	return service.reference(ctx, auth)
+}
+
+// ParseAccessToken resolves a reference token to its signed access token before parsing it
+func (service *referenceTokenService) ParseAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	// A signed token has three dot separated parts, a reference token has none
+	if !strings.Contains(tokenString, ".") {
+		reference, err := service.repo.FindReferenceToken(ctx, utils.HashToken(tokenString))
+		if err != nil {
+			return nil, err
+		}
+		tokenString = reference.Token
+	}
+	return service.JWTService.ParseAccessToken(ctx, tokenString)
+}
+
+// reference stores the signed access token and replaces it with a new reference token
+func (service *referenceTokenService) reference(ctx context.Context, auth *domain.JWTAuthEntity) (*domain.JWTAuthEntity, error) {
+	token, err := utils.GenerateRandomToken(referenceTokenLength)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	err = service.repo.CreateReferenceToken(ctx, &domain.ReferenceTokenEntity{
+		ID:        utils.HashToken(token),
+		Token:     auth.AccessToken,
+		ExpiresAt: time.Now().Add(time.Duration(auth.ExpiredIn) * time.Second).UnixMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	auth.AccessToken = token
+	return auth, nil
+}
@@ -0,0 +1,319 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Service account use case: machine identities authenticating with the client credentials grant
+
+const (
+	serviceAccountIDPrefix     = "sa_" // Keeps client ids apart from user ids, they share the token version store
+	serviceAccountSecretBytes  = 32
+	serviceAccountSecretHint   = 4
+	DefaultSecretRotationGrace = 24 * time.Hour
+	MaxSecretRotationGrace     = 30 * 24 * time.Hour
+
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+type ServiceAccountService interface {
+	// CreateServiceAccount creates the account and its first secret
+	CreateServiceAccount(ctx context.Context, principal *shared.Principal, data *dto.CreateServiceAccountRequest) (*domain.ServiceAccountCredentialsEntity, error)
+	ListServiceAccounts(ctx context.Context) ([]*domain.ServiceAccountEntity, error)
+	GetServiceAccount(ctx context.Context, id string) (*domain.ServiceAccountEntity, error)
+	// RotateSecret issues a new secret, the current ones stay valid for the overlap window so workers can be redeployed
+	RotateSecret(ctx context.Context, id string, data *dto.RotateServiceAccountSecretRequest) (*domain.ServiceAccountCredentialsEntity, error)
+	// RevokeSecret stops a secret immediately, tokens already issued with it stay valid until they expire
+	RevokeSecret(ctx context.Context, id, secretID string) (*domain.ServiceAccountEntity, error)
+	// DeleteServiceAccount deletes the account and rejects every token issued to it
+	DeleteServiceAccount(ctx context.Context, id string) error
+
+	// Token handles the client credentials grant (RFC 6749 section 4.4), no refresh token is issued
+	Token(ctx context.Context, data *dto.TokenRequest) (*domain.OAuthTokenEntity, error)
+	// AuthenticateClient checks the client id and secret of a service account
+	AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*domain.ServiceAccountEntity, error)
+}
+
+type serviceAccountService struct {
+	repo       repository.ServiceAccountRepository
+	authRepo   repository.AuthRepository
+	jwtService JWTService
+}
+
+func NewServiceAccountService(repo repository.ServiceAccountRepository, authRepo repository.AuthRepository, jwtService JWTService) ServiceAccountService {
+	return &serviceAccountService{
+		repo:       repo,
+		authRepo:   authRepo,
+		jwtService: jwtService,
+	}
+}
+
+func (service *serviceAccountService) CreateServiceAccount(ctx context.Context, principal *shared.Principal, data *dto.CreateServiceAccountRequest) (*domain.ServiceAccountCredentialsEntity, error) {
+	for _, scope := range data.Scopes {
+		if !containsString(shared.ServiceAccountScopes, scope) {
+			return nil, domain.ErrServiceAccountInvalidScope
+		}
+	}
+
+	id, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	secret, secretEntity, err := newServiceAccountSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := service.repo.CreateServiceAccount(ctx, &domain.ServiceAccountEntity{
+		ID:          serviceAccountIDPrefix + id,
+		Name:        data.Name,
+		Description: data.Description,
+		Scopes:      uniqueStrings(data.Scopes),
+		Secrets:     []domain.ServiceAccountSecretEntity{*secretEntity},
+		CreatedBy:   principal.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("service account created",
+		zap.String("client_id", account.ID),
+		zap.Strings("scopes", account.Scopes),
+		zap.String("created_by", principal.UserID),
+	)
+
+	return &domain.ServiceAccountCredentialsEntity{
+		ServiceAccount: account,
+		ClientID:       account.ID,
+		ClientSecret:   secret,
+		SecretID:       secretEntity.ID,
+	}, nil
+}
+
+func (service *serviceAccountService) ListServiceAccounts(ctx context.Context) ([]*domain.ServiceAccountEntity, error) {
+	return service.repo.ListServiceAccounts(ctx)
+}
+
+func (service *serviceAccountService) GetServiceAccount(ctx context.Context, id string) (*domain.ServiceAccountEntity, error) {
+	return service.repo.FindServiceAccountByID(ctx, id)
+}
+
+func (service *serviceAccountService) RotateSecret(ctx context.Context, id string, data *dto.RotateServiceAccountSecretRequest) (*domain.ServiceAccountCredentialsEntity, error) {
+	account, err := service.repo.FindServiceAccountByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	grace := DefaultSecretRotationGrace
+	if data.OverlapSeconds != nil {
+		grace = time.Duration(*data.OverlapSeconds) * time.Second
+	}
+	if grace > MaxSecretRotationGrace {
+		grace = MaxSecretRotationGrace
+	}
+
+	// The current secrets expire at the end of the overlap window, unless they already expire earlier.
+	// Without overlap they are dropped at once.
+	now := time.Now()
+	overlapEndsAt := now.Add(grace).UnixMilli()
+	secrets := make([]domain.ServiceAccountSecretEntity, 0, len(account.Secrets)+1)
+	for _, secret := range account.Secrets {
+		if grace <= 0 || !secret.IsActive(now.UnixMilli()) {
+			continue
+		}
+		if secret.ExpiresAt == 0 || secret.ExpiresAt > overlapEndsAt {
+			secret.ExpiresAt = overlapEndsAt
+		}
+		secrets = append(secrets, secret)
+	}
+	secret, secretEntity, err := newServiceAccountSecret()
+	if err != nil {
+		return nil, err
+	}
+	secrets = append(secrets, *secretEntity)
+
+	account, err = service.repo.UpdateServiceAccountSecrets(ctx, id, secrets)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("service account secret rotated",
+		zap.String("client_id", account.ID),
+		zap.String("secret_id", secretEntity.ID),
+		zap.Duration("overlap", grace),
+	)
+
+	return &domain.ServiceAccountCredentialsEntity{
+		ServiceAccount: account,
+		ClientID:       account.ID,
+		ClientSecret:   secret,
+		SecretID:       secretEntity.ID,
+	}, nil
+}
+
+func (service *serviceAccountService) RevokeSecret(ctx context.Context, id, secretID string) (*domain.ServiceAccountEntity, error) {
+	account, err := service.repo.FindServiceAccountByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	found := false
+	secrets := make([]domain.ServiceAccountSecretEntity, 0, len(account.Secrets))
+	for _, secret := range account.Secrets {
+		if secret.ID == secretID {
+			found = true
+			continue
+		}
+		if secret.IsActive(now) {
+			secrets = append(secrets, secret)
+		}
+	}
+	if !found {
+		return nil, domain.ErrServiceAccountSecretNotFound
+	}
+	if len(secrets) == 0 {
+		return nil, domain.ErrServiceAccountLastSecret
+	}
+
+	account, err = service.repo.UpdateServiceAccountSecrets(ctx, id, secrets)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("service account secret revoked",
+		zap.String("client_id", account.ID),
+		zap.String("secret_id", secretID),
+	)
+	return account, nil
+}
+
+func (service *serviceAccountService) DeleteServiceAccount(ctx context.Context, id string) error {
+	if err := service.repo.DeleteServiceAccount(ctx, id); err != nil {
+		return err
+	}
+	// Tokens are stateless, bumping the version rejects the ones already issued
+	if _, err := service.authRepo.IncrementUserTokenVersion(ctx, id); err != nil {
+		return err
+	}
+	zap.L().Info("service account deleted", zap.String("client_id", id))
+	return nil
+}
+
+func (service *serviceAccountService) Token(ctx context.Context, data *dto.TokenRequest) (*domain.OAuthTokenEntity, error) {
+	if data.GrantType != GrantTypeClientCredentials {
+		return nil, domain.ErrOAuthUnsupportedGrantType
+	}
+	account, err := service.authenticate(ctx, data.ClientID, data.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a scope parameter the token gets every scope of the account
+	scopes := account.Scopes
+	if requested := strings.Fields(data.Scope); len(requested) > 0 {
+		for _, s := range requested {
+			if !containsString(account.Scopes, s) {
+				return nil, domain.ErrOAuthInvalidScope
+			}
+		}
+		scopes = uniqueStrings(requested)
+	}
+
+	jti, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, domain.ErrAuthInternalServerError
+	}
+	version, err := service.authRepo.GetUserTokenVersion(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{
+		Username:      account.Name,
+		TokenVersion:  version,
+		ClientID:      account.ID,
+		Scope:         strings.Join(scopes, " "),
+		PrincipalType: shared.PrincipalTypeServiceAccount,
+	}
+	claims.ID = jti
+	claims.Subject = account.ID
+	auth, err := service.jwtService.GenerateAccessToken(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("service account token issued",
+		zap.String("client_id", account.ID),
+		zap.String("scope", claims.Scope),
+	)
+
+	return &domain.OAuthTokenEntity{
+		AccessToken: auth.AccessToken,
+		TokenType:   auth.TokenType,
+		ExpiresIn:   auth.ExpiredIn,
+		Scope:       claims.Scope,
+	}, nil
+}
+
+func (service *serviceAccountService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*domain.ServiceAccountEntity, error) {
+	return service.authenticate(ctx, clientID, clientSecret)
+}
+
+// authenticate checks the secret against every active secret of the account
+func (service *serviceAccountService) authenticate(ctx context.Context, clientID, clientSecret string) (*domain.ServiceAccountEntity, error) {
+	if !strings.HasPrefix(clientID, serviceAccountIDPrefix) || clientSecret == "" {
+		return nil, domain.ErrOAuthInvalidClient
+	}
+	account, err := service.repo.FindServiceAccountByID(ctx, clientID)
+	if err != nil {
+		if err == domain.ErrServiceAccountNotFound {
+			return nil, domain.ErrOAuthInvalidClient
+		}
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	hash := []byte(utils.HashToken(clientSecret))
+	for _, secret := range account.Secrets {
+		if secret.IsActive(now) && subtle.ConstantTimeCompare(hash, []byte(secret.Hash)) == 1 {
+			return account, nil
+		}
+	}
+	zap.L().Warn("service account authentication failed", zap.String("client_id", clientID))
+	return nil, domain.ErrOAuthInvalidClient
+}
+
+// newServiceAccountSecret generates a secret, only its hash and a short hint are stored
+func newServiceAccountSecret() (string, *domain.ServiceAccountSecretEntity, error) {
+	secret, err := utils.GenerateRandomToken(serviceAccountSecretBytes)
+	if err != nil {
+		return "", nil, domain.ErrAuthInternalServerError
+	}
+	secretID, err := utils.GenerateRandomToken(8)
+	if err != nil {
+		return "", nil, domain.ErrAuthInternalServerError
+	}
+	return secret, &domain.ServiceAccountSecretEntity{
+		ID:        secretID,
+		Hash:      utils.HashToken(secret),
+		Hint:      secret[len(secret)-serviceAccountSecretHint:],
+		CreatedAt: time.Now().UnixMilli(),
+	}, nil
+}
+
+func uniqueStrings(values []string) []string {
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !containsString(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+// serviceAccountFixture is the service account service sharing the token version store of authFixture,
+// with one account granted users:read and tokens:introspect
+type serviceAccountFixture struct {
+	*authFixture
+	service     ServiceAccountService
+	credentials *domain.ServiceAccountCredentialsEntity
+}
+
+func newServiceAccountFixture(t *testing.T) *serviceAccountFixture {
+	t.Helper()
+	auth := newAuthFixture(t)
+	f := &serviceAccountFixture{
+		authFixture: auth,
+		service:     NewServiceAccountService(repository.NewMemoryServiceAccountRepository(), auth.repo, newTestJWTService()),
+	}
+	var err error
+	f.credentials, err = f.service.CreateServiceAccount(context.Background(), &shared.Principal{UserID: auth.user.ID.Hex()}, &dto.CreateServiceAccountRequest{
+		Name:   "gateway",
+		Scopes: []string{shared.ScopeUsersRead, shared.ScopeTokensIntrospect, shared.ScopeUsersRead},
+	})
+	if err != nil {
+		t.Fatalf("CreateServiceAccount() = %v", err)
+	}
+	return f
+}
+
+func (f *serviceAccountFixture) token(clientSecret, scope string) (*domain.OAuthTokenEntity, error) {
+	return f.service.Token(context.Background(), &dto.TokenRequest{
+		GrantType:    GrantTypeClientCredentials,
+		Scope:        scope,
+		ClientID:     f.credentials.ClientID,
+		ClientSecret: clientSecret,
+	})
+}
+
+func TestCreateServiceAccount(t *testing.T) {
+	f := newServiceAccountFixture(t)
+	account := f.credentials.ServiceAccount
+	if account.ID != f.credentials.ClientID || !strings.HasPrefix(account.ID, serviceAccountIDPrefix) {
+		t.Fatalf("CreateServiceAccount() client id = %q, want the account id with the %q prefix", f.credentials.ClientID, serviceAccountIDPrefix)
+	}
+	if !slices.Equal(account.Scopes, []string{shared.ScopeUsersRead, shared.ScopeTokensIntrospect}) {
+		t.Fatalf("CreateServiceAccount() scopes = %v, want them without duplicates", account.Scopes)
+	}
+	if len(account.Secrets) != 1 || account.Secrets[0].Hash == f.credentials.ClientSecret {
+		t.Fatalf("CreateServiceAccount() secrets = %+v, want one hashed secret", account.Secrets)
+	}
+
+	_, err := f.service.CreateServiceAccount(context.Background(), &shared.Principal{}, &dto.CreateServiceAccountRequest{Name: "worker", Scopes: []string{shared.ScopeProfileRead}})
+	if err != domain.ErrServiceAccountInvalidScope {
+		t.Fatalf("CreateServiceAccount() with a user scope = %v, want %v", err, domain.ErrServiceAccountInvalidScope)
+	}
+}
+
+func TestClientCredentialsGrant(t *testing.T) {
+	f := newServiceAccountFixture(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		scope string
+		want  []string
+	}{
+		{"every scope of the account by default", "", []string{shared.ScopeUsersRead, shared.ScopeTokensIntrospect}},
+		{"requested scope", shared.ScopeUsersRead, []string{shared.ScopeUsersRead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := f.token(f.credentials.ClientSecret, tt.scope)
+			if err != nil {
+				t.Fatalf("Token() = %v", err)
+			}
+			if token.RefreshToken != "" {
+				t.Fatal("Token() issued a refresh token")
+			}
+
+			principal, err := f.authFixture.service.Authenticate(ctx, token.AccessToken)
+			if err != nil {
+				t.Fatalf("Authenticate() = %v", err)
+			}
+			if !principal.IsServiceAccount() || principal.ClientID != f.credentials.ClientID || principal.UserID != "" || principal.Username != "gateway" {
+				t.Fatalf("Authenticate() = %+v, want the service account", principal)
+			}
+			if !slices.Equal(principal.Scopes, tt.want) {
+				t.Fatalf("Authenticate() scopes = %v, want %v", principal.Scopes, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientCredentialsGrantRejected(t *testing.T) {
+	f := newServiceAccountFixture(t)
+
+	if _, err := f.token(f.credentials.ClientSecret, shared.ScopeAuthzCheck); err != domain.ErrOAuthInvalidScope {
+		t.Fatalf("Token() with a scope the account was not granted = %v, want %v", err, domain.ErrOAuthInvalidScope)
+	}
+	if _, err := f.token(f.credentials.ClientSecret+"x", ""); err != domain.ErrOAuthInvalidClient {
+		t.Fatalf("Token() with a wrong secret = %v, want %v", err, domain.ErrOAuthInvalidClient)
+	}
+	if _, err := f.token("", ""); err != domain.ErrOAuthInvalidClient {
+		t.Fatalf("Token() without secret = %v, want %v", err, domain.ErrOAuthInvalidClient)
+	}
+
+	// Only ids with the service account prefix are looked up, user ids never match
+	_, err := f.service.Token(context.Background(), &dto.TokenRequest{GrantType: GrantTypeClientCredentials, ClientID: f.user.ID.Hex(), ClientSecret: f.credentials.ClientSecret})
+	if err != domain.ErrOAuthInvalidClient {
+		t.Fatalf("Token() with a user id = %v, want %v", err, domain.ErrOAuthInvalidClient)
+	}
+	_, err = f.service.Token(context.Background(), &dto.TokenRequest{GrantType: GrantTypeAuthorizationCode, ClientID: f.credentials.ClientID, ClientSecret: f.credentials.ClientSecret})
+	if err != domain.ErrOAuthUnsupportedGrantType {
+		t.Fatalf("Token() with the authorization_code grant = %v, want %v", err, domain.ErrOAuthUnsupportedGrantType)
+	}
+}
+
+func TestRotateSecret(t *testing.T) {
+	f := newServiceAccountFixture(t)
+	ctx := context.Background()
+	id := f.credentials.ClientID
+	first := f.credentials
+
+	// Both secrets work during the overlap window
+	second, err := f.service.RotateSecret(ctx, id, &dto.RotateServiceAccountSecretRequest{})
+	if err != nil {
+		t.Fatalf("RotateSecret() = %v", err)
+	}
+	if second.ClientSecret == first.ClientSecret || len(second.ServiceAccount.Secrets) != 2 {
+		t.Fatalf("RotateSecret() = %+v, want a new secret next to the current one", second.ServiceAccount.Secrets)
+	}
+	for _, credentials := range []*domain.ServiceAccountCredentialsEntity{first, second} {
+		if _, err := f.service.AuthenticateClient(ctx, id, credentials.ClientSecret); err != nil {
+			t.Fatalf("AuthenticateClient() with secret %s during the overlap = %v", credentials.SecretID, err)
+		}
+	}
+
+	// No overlap stops the current secrets now
+	overlap := int64(0)
+	third, err := f.service.RotateSecret(ctx, id, &dto.RotateServiceAccountSecretRequest{OverlapSeconds: &overlap})
+	if err != nil {
+		t.Fatalf("RotateSecret() without overlap = %v", err)
+	}
+	for _, credentials := range []*domain.ServiceAccountCredentialsEntity{first, second} {
+		if _, err := f.service.AuthenticateClient(ctx, id, credentials.ClientSecret); err != domain.ErrOAuthInvalidClient {
+			t.Fatalf("AuthenticateClient() with rotated secret %s = %v, want %v", credentials.SecretID, err, domain.ErrOAuthInvalidClient)
+		}
+	}
+	if _, err := f.service.AuthenticateClient(ctx, id, third.ClientSecret); err != nil {
+		t.Fatalf("AuthenticateClient() with the new secret = %v", err)
+	}
+
+	// Expired secrets are dropped at the next rotation
+	fourth, err := f.service.RotateSecret(ctx, id, &dto.RotateServiceAccountSecretRequest{})
+	if err != nil {
+		t.Fatalf("RotateSecret() = %v", err)
+	}
+	if len(fourth.ServiceAccount.Secrets) != 2 {
+		t.Fatalf("RotateSecret() kept %d secrets, want the previous and the new one", len(fourth.ServiceAccount.Secrets))
+	}
+}
+
+func TestRevokeSecret(t *testing.T) {
+	f := newServiceAccountFixture(t)
+	ctx := context.Background()
+	id := f.credentials.ClientID
+
+	if _, err := f.service.RevokeSecret(ctx, id, f.credentials.SecretID); err != domain.ErrServiceAccountLastSecret {
+		t.Fatalf("RevokeSecret() of the only secret = %v, want %v", err, domain.ErrServiceAccountLastSecret)
+	}
+	if _, err := f.service.RevokeSecret(ctx, id, "unknown"); err != domain.ErrServiceAccountSecretNotFound {
+		t.Fatalf("RevokeSecret() of an unknown secret = %v, want %v", err, domain.ErrServiceAccountSecretNotFound)
+	}
+
+	rotated, err := f.service.RotateSecret(ctx, id, &dto.RotateServiceAccountSecretRequest{})
+	if err != nil {
+		t.Fatalf("RotateSecret() = %v", err)
+	}
+	account, err := f.service.RevokeSecret(ctx, id, f.credentials.SecretID)
+	if err != nil {
+		t.Fatalf("RevokeSecret() = %v", err)
+	}
+	if len(account.Secrets) != 1 || account.Secrets[0].ID != rotated.SecretID {
+		t.Fatalf("RevokeSecret() secrets = %+v, want the rotated one only", account.Secrets)
+	}
+	if _, err := f.service.AuthenticateClient(ctx, id, f.credentials.ClientSecret); err != domain.ErrOAuthInvalidClient {
+		t.Fatalf("AuthenticateClient() with the revoked secret = %v, want %v", err, domain.ErrOAuthInvalidClient)
+	}
+}
+
+func TestDeleteServiceAccount(t *testing.T) {
+	f := newServiceAccountFixture(t)
+	ctx := context.Background()
+	token, err := f.token(f.credentials.ClientSecret, "")
+	if err != nil {
+		t.Fatalf("Token() = %v", err)
+	}
+
+	if err := f.service.DeleteServiceAccount(ctx, f.credentials.ClientID); err != nil {
+		t.Fatalf("DeleteServiceAccount() = %v", err)
+	}
+	if _, err := f.authFixture.service.Authenticate(ctx, token.AccessToken); err != domain.ErrJWTTokenRevoked {
+		t.Fatalf("Authenticate() with a token of the deleted account = %v, want %v", err, domain.ErrJWTTokenRevoked)
+	}
+	if _, err := f.token(f.credentials.ClientSecret, ""); err != domain.ErrOAuthInvalidClient {
+		t.Fatalf("Token() for the deleted account = %v, want %v", err, domain.ErrOAuthInvalidClient)
+	}
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"go.uber.org/zap"
+)
+
+// Session use case: the devices a user is signed in on, each login starts a session
+
+// maxUserAgentLength caps the user agent stored on a session, the header is client controlled
+const maxUserAgentLength = 512
+
+type SessionService interface {
+	// ListSessions returns the active sessions of the user, the one of the request is marked as current
+	ListSessions(ctx context.Context, principal *shared.Principal) ([]*domain.SessionEntity, error)
+	// RevokeSession signs the device out: its refresh token stops working and its access tokens are rejected
+	RevokeSession(ctx context.Context, principal *shared.Principal, id string) error
+}
+
+type sessionService struct {
+	repo repository.AuthRepository
+}
+
+func NewSessionService(repo repository.AuthRepository) SessionService {
+	return &sessionService{repo: repo}
+}
+
+func (service *sessionService) ListSessions(ctx context.Context, principal *shared.Principal) ([]*domain.SessionEntity, error) {
+	if principal == nil || !principal.IsLoginSession() {
+		return nil, domain.ErrSessionManagementForbidden
+	}
+
+	sessions, err := service.repo.ListActiveSessionsByUserID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == principal.SessionID
+	}
+	return sessions, nil
+}
+
+func (service *sessionService) RevokeSession(ctx context.Context, principal *shared.Principal, id string) error {
+	if principal == nil || !principal.IsLoginSession() {
+		return domain.ErrSessionManagementForbidden
+	}
+
+	if err := service.repo.RevokeSession(ctx, principal.UserID, id); err != nil {
+		return err
+	}
+	zap.L().Info("session revoked",
+		zap.String("user_id", principal.UserID),
+		zap.String("session_id", id),
+		zap.Bool("current", id == principal.SessionID),
+	)
+	return nil
+}
+
+// describeUserAgent turns a user agent into a short label such as "Chrome on macOS".
+// It only needs to be good enough for a user to recognise their devices.
+func describeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"), strings.Contains(userAgent, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(userAgent, "curl/"):
+		return "curl"
+	case strings.HasPrefix(userAgent, "PostmanRuntime/"):
+		return "Postman"
+	case strings.HasPrefix(userAgent, "Go-http-client/"):
+		return "Go HTTP client"
+	}
+
+	// iOS and Android user agents also mention the desktop systems they claim to be like
+	system := ""
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		system = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		system = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		system = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"):
+		system = "macOS"
+	case strings.Contains(userAgent, "CrOS"):
+		system = "ChromeOS"
+	case strings.Contains(userAgent, "Linux"):
+		system = "Linux"
+	}
+	if system == "" {
+		return browser
+	}
+	return browser + " on " + system
+}
+
+// truncate cuts the value to at most max bytes without splitting a UTF-8 character
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	value = value[:max]
+	for len(value) > 0 && !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+const (
+	testChromeOnMacOS   = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36"
+	testSafariOnIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Mobile/15E148 Safari/604.1"
+	testFirefoxOnLinux  = "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0"
+	testEdgeOnWindows   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0"
+	testChromeOnAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36"
+)
+
+// signIn logs alice in from the device and returns her tokens and the principal of the access token
+func signIn(t *testing.T, f *authFixture, userAgent string) (*domain.JWTAuthEntity, *shared.Principal) {
+	t.Helper()
+	auth, _, err := f.service.LoginExternalUser(context.Background(), f.user, dto.DeviceInfo{ClientIP: "203.0.113.7", UserAgent: userAgent})
+	if err != nil {
+		t.Fatalf("LoginExternalUser() = %v", err)
+	}
+	principal, err := f.service.Authenticate(context.Background(), auth.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	return auth, principal
+}
+
+func TestListSessions(t *testing.T) {
+	f := newAuthFixture(t)
+	service := NewSessionService(f.repo)
+	_, laptop := signIn(t, f, testChromeOnMacOS)
+	_, phone := signIn(t, f, testSafariOnIPhone)
+
+	sessions, err := service.ListSessions(context.Background(), phone)
+	if err != nil {
+		t.Fatalf("ListSessions() = %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("ListSessions() returned %d sessions, want 2", len(sessions))
+	}
+	devices := map[string]*domain.SessionEntity{}
+	for _, session := range sessions {
+		devices[session.Device] = session
+	}
+	if session := devices["Safari on iOS"]; session == nil || session.ID != phone.SessionID || !session.Current || session.IP != "203.0.113.7" {
+		t.Fatalf("ListSessions() phone = %+v, want the current session", session)
+	}
+	if session := devices["Chrome on macOS"]; session == nil || session.ID != laptop.SessionID || session.Current {
+		t.Fatalf("ListSessions() laptop = %+v, want another session", session)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	service := NewSessionService(f.repo)
+	laptopAuth, laptop := signIn(t, f, testChromeOnMacOS)
+	phoneAuth, phone := signIn(t, f, testSafariOnIPhone)
+
+	// Signing the laptop out from the phone
+	if err := service.RevokeSession(ctx, phone, laptop.SessionID); err != nil {
+		t.Fatalf("RevokeSession() = %v", err)
+	}
+	if _, err := f.service.Authenticate(ctx, laptopAuth.AccessToken); err != domain.ErrJWTTokenRevoked {
+		t.Fatalf("Authenticate() on the revoked session = %v, want %v", err, domain.ErrJWTTokenRevoked)
+	}
+	if _, err := f.refresh(laptopAuth.RefreshToken); err != domain.ErrJWTRefreshTokenInvalid {
+		t.Fatalf("refresh on the revoked session = %v, want %v", err, domain.ErrJWTRefreshTokenInvalid)
+	}
+	if err := service.RevokeSession(ctx, phone, laptop.SessionID); err != domain.ErrSessionNotFound {
+		t.Fatalf("RevokeSession() again = %v, want %v", err, domain.ErrSessionNotFound)
+	}
+
+	// The phone stays signed in
+	if _, err := f.service.Authenticate(ctx, phoneAuth.AccessToken); err != nil {
+		t.Fatalf("Authenticate() on the phone = %v", err)
+	}
+	if _, err := f.refresh(phoneAuth.RefreshToken); err != nil {
+		t.Fatalf("refresh on the phone = %v", err)
+	}
+	sessions, err := service.ListSessions(ctx, phone)
+	if err != nil || len(sessions) != 1 || sessions[0].ID != phone.SessionID {
+		t.Fatalf("ListSessions() = %v, %v, want the phone only", sessions, err)
+	}
+}
+
+func TestRevokeSessionOfAnotherUser(t *testing.T) {
+	f := newAuthFixture(t)
+	service := NewSessionService(f.repo)
+	auth, alice := signIn(t, f, testChromeOnMacOS)
+
+	bob := &shared.Principal{Type: shared.PrincipalTypeUser, UserID: "bob", SessionID: "bob-session"}
+	if err := service.RevokeSession(context.Background(), bob, alice.SessionID); err != domain.ErrSessionNotFound {
+		t.Fatalf("RevokeSession() of alice's session by bob = %v, want %v", err, domain.ErrSessionNotFound)
+	}
+	if _, err := f.service.Authenticate(context.Background(), auth.AccessToken); err != nil {
+		t.Fatalf("Authenticate() = %v, want the session still active", err)
+	}
+}
+
+// Sessions are managed from a login session only, not with an API key, an OAuth client token or while impersonating
+func TestSessionManagementForbidden(t *testing.T) {
+	f := newAuthFixture(t)
+	service := NewSessionService(f.repo)
+	_, alice := signIn(t, f, testChromeOnMacOS)
+
+	principals := map[string]*shared.Principal{
+		"api key":         {Type: shared.PrincipalTypeUser, UserID: alice.UserID, APIKeyID: "key"},
+		"oauth client":    {Type: shared.PrincipalTypeUser, UserID: alice.UserID, ClientID: "client", SessionID: alice.SessionID},
+		"impersonation":   {Type: shared.PrincipalTypeUser, UserID: alice.UserID, ActorID: "admin", SessionID: alice.SessionID},
+		"service account": {Type: shared.PrincipalTypeServiceAccount, ClientID: "sa_gateway"},
+		"anonymous":       nil,
+	}
+	for name, principal := range principals {
+		if _, err := service.ListSessions(context.Background(), principal); err != domain.ErrSessionManagementForbidden {
+			t.Errorf("ListSessions() by %s = %v, want %v", name, err, domain.ErrSessionManagementForbidden)
+		}
+		if err := service.RevokeSession(context.Background(), principal, alice.SessionID); err != domain.ErrSessionManagementForbidden {
+			t.Errorf("RevokeSession() by %s = %v, want %v", name, err, domain.ErrSessionManagementForbidden)
+		}
+	}
+}
+
+func TestDescribeUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{testChromeOnMacOS, "Chrome on macOS"},
+		{testSafariOnIPhone, "Safari on iOS"},
+		{testFirefoxOnLinux, "Firefox on Linux"},
+		{testEdgeOnWindows, "Edge on Windows"},
+		{testChromeOnAndroid, "Chrome on Android"},
+		{"curl/8.7.1", "curl"},
+		{"PostmanRuntime/7.42.0", "Postman"},
+		{"Go-http-client/1.1", "Go HTTP client"},
+		{"something else", "Unknown browser"},
+		{"", "Unknown device"},
+	}
+	for _, tt := range tests {
+		if got := describeUserAgent(tt.userAgent); got != tt.want {
+			t.Errorf("describeUserAgent(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate() = %q, want the value unchanged", got)
+	}
+	// "é" is two bytes, cutting inside it drops the whole character
+	got := truncate(strings.Repeat("é", 4), 5)
+	if got != "éé" || !utf8.ValidString(got) {
+		t.Errorf("truncate() = %q, want %q", got, "éé")
+	}
+}
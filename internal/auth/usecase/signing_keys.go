@@ -0,0 +1,334 @@
+package usecase
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"go.uber.org/zap"
+)
+
+// Asymmetric signing keys and scheduled rotation
+
+// SigningKey is a private key used to sign tokens, identified by its kid
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+	ActivateAt time.Time // The key becomes the signing key from this time, zero means immediately
+	RetireAt   time.Time // The key stops signing from this time, zero means never
+}
+
+// SignedTokenLifetime is the longest lifetime of the tokens signed with the keys: access, ID and MFA tokens
+func SignedTokenLifetime(accessTokenExpiresIn time.Duration) time.Duration {
+	return max(accessTokenExpiresIn, mfaTokenExpiresIn)
+}
+
+// KeyManager selects the signing key and resolves verification keys by kid.
+// Keys are rotated on a schedule: the signing key is the most recently activated key,
+// keys that are not active yet are already published so verifiers can cache them before use.
+// A retired key no longer signs, it is still published and accepted until the last token it signed has expired.
+type KeyManager interface {
+	SigningKey() (*SigningKey, error)
+	VerificationKey(kid string) (*SigningKey, error)
+	Algorithms() []string
+	JWKS() *domain.JWKSEntity
+}
+
+type keyManager struct {
+	keys []*SigningKey // Sorted by activation time
+	// maxTokenLifetime is how long the tokens signed by a key are accepted after the key is retired
+	maxTokenLifetime time.Duration
+}
+
+// NewKeyManager manages the keys, maxTokenLifetime is the longest lifetime of the tokens they sign (SignedTokenLifetime)
+func NewKeyManager(keys []*SigningKey, maxTokenLifetime time.Duration) (KeyManager, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key.ID] {
+			return nil, fmt.Errorf("duplicate signing key id %q", key.ID)
+		}
+		seen[key.ID] = true
+		if !key.RetireAt.IsZero() && !key.RetireAt.After(key.ActivateAt) {
+			return nil, fmt.Errorf("signing key %q must be retired after its activation", key.ID)
+		}
+	}
+
+	sorted := append([]*SigningKey(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActivateAt.Before(sorted[j].ActivateAt)
+	})
+	if sorted[0].ActivateAt.After(time.Now()) {
+		zap.L().Warn("no signing key is active yet, tokens cannot be signed until the first activation",
+			zap.String("kid", sorted[0].ID),
+			zap.Time("activate_at", sorted[0].ActivateAt),
+		)
+	}
+
+	manager := &keyManager{keys: sorted, maxTokenLifetime: maxTokenLifetime}
+	if _, err := manager.SigningKey(); err != nil && !sorted[0].ActivateAt.After(time.Now()) {
+		zap.L().Warn("every active signing key is retired, tokens cannot be signed until the next activation")
+	}
+	return manager, nil
+}
+
+// SigningKey returns the most recently activated key that is not retired
+func (m *keyManager) SigningKey() (*SigningKey, error) {
+	now := time.Now()
+	var active *SigningKey
+	for _, key := range m.keys {
+		if key.ActivateAt.After(now) {
+			break
+		}
+		if key.RetireAt.IsZero() || key.RetireAt.After(now) {
+			active = key
+		}
+	}
+	if active == nil {
+		return nil, errors.New("no active signing key")
+	}
+	return active, nil
+}
+
+// VerificationKey returns the key with the given kid, scheduled keys included and expired keys excluded
+func (m *keyManager) VerificationKey(kid string) (*SigningKey, error) {
+	now := time.Now()
+	for _, key := range m.keys {
+		if key.ID == kid {
+			if m.expired(key, now) {
+				return nil, fmt.Errorf("signing key %q is retired", kid)
+			}
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key id %q", kid)
+}
+
+// expired reports whether the key was retired long enough ago for every token it signed to have expired.
+// The leeway matches the one of the token checks.
+func (m *keyManager) expired(key *SigningKey, now time.Time) bool {
+	return !key.RetireAt.IsZero() && now.After(key.RetireAt.Add(m.maxTokenLifetime+tokenLeeway))
+}
+
+// Algorithms returns the distinct algorithms of the keys that are not expired
+func (m *keyManager) Algorithms() []string {
+	now := time.Now()
+	algorithms := []string{}
+	seen := map[string]bool{}
+	for _, key := range m.keys {
+		if m.expired(key, now) {
+			continue
+		}
+		if !seen[key.Method.Alg()] {
+			seen[key.Method.Alg()] = true
+			algorithms = append(algorithms, key.Method.Alg())
+		}
+	}
+	return algorithms
+}
+
+// JWKS returns the public part of every key that is not expired
+func (m *keyManager) JWKS() *domain.JWKSEntity {
+	now := time.Now()
+	jwks := &domain.JWKSEntity{Keys: make([]domain.JWKEntity, 0, len(m.keys))}
+	for _, key := range m.keys {
+		if m.expired(key, now) {
+			continue
+		}
+		jwk, err := publicKeyToJWK(key.ID, key.Method.Alg(), key.PublicKey)
+		if err != nil {
+			zap.L().Error("error encoding public key as jwk", zap.String("kid", key.ID), zap.Error(err))
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, *jwk)
+	}
+	return jwks
+}
+
+// LoadSigningKeys parses a comma separated list of "kid:path/to/key.pem[@activation[/retirement]]" entries,
+// both times are RFC 3339 and either can be empty, e.g. "2026-10:keys/2026-10.pem@2026-10-01T00:00:00Z/2026-11-01T00:00:00Z".
+// Supported keys are RSA (RS256), ECDSA P-256/P-384/P-521 (ES256/ES384/ES512) and Ed25519 (EdDSA).
+func LoadSigningKeys(spec string) ([]*SigningKey, error) {
+	keys := []*SigningKey{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, location, found := strings.Cut(entry, ":")
+		if !found || kid == "" || location == "" {
+			return nil, fmt.Errorf("invalid signing key entry %q, expected kid:path[@activation[/retirement]]", entry)
+		}
+
+		var activateAt, retireAt time.Time
+		if at := strings.LastIndex(location, "@"); at >= 0 {
+			activation, retirement, _ := strings.Cut(location[at+1:], "/")
+			var err error
+			if activateAt, err = parseKeyTime(activation); err != nil {
+				return nil, fmt.Errorf("invalid activation time of signing key %q: %w", kid, err)
+			}
+			if retireAt, err = parseKeyTime(retirement); err != nil {
+				return nil, fmt.Errorf("invalid retirement time of signing key %q: %w", kid, err)
+			}
+			location = location[:at]
+		}
+
+		pemBytes, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("error reading signing key %q: %w", kid, err)
+		}
+		key, err := ParseSigningKeyPEM(kid, pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		key.ActivateAt = activateAt
+		key.RetireAt = retireAt
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parseKeyTime parses an RFC 3339 time, empty is the zero time
+func parseKeyTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// ParseSigningKeyPEM parses a PKCS#8, PKCS#1 or SEC 1 private key and picks the matching algorithm
+func ParseSigningKeyPEM(kid string, pemBytes []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %q is not PEM encoded", kid)
+	}
+
+	var privateKey crypto.PrivateKey
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing signing key %q: %w", kid, err)
+	}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("rsa signing key %q must be at least 2048 bits", kid)
+		}
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, PrivateKey: key, PublicKey: &key.PublicKey}, nil
+	case *ecdsa.PrivateKey:
+		var method jwt.SigningMethod
+		switch key.Curve {
+		case elliptic.P256():
+			method = jwt.SigningMethodES256
+		case elliptic.P384():
+			method = jwt.SigningMethodES384
+		case elliptic.P521():
+			method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported ecdsa curve of signing key %q", kid)
+		}
+		return &SigningKey{ID: kid, Method: method, PrivateKey: key, PublicKey: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, PrivateKey: key, PublicKey: key.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type of signing key %q", kid)
+	}
+}
+
+// publicKeyToJWK encodes a public key as a JSON Web Key
+func publicKeyToJWK(kid, algorithm string, publicKey crypto.PublicKey) (*domain.JWKEntity, error) {
+	jwk := &domain.JWKEntity{KeyID: kid, Algorithm: algorithm, Use: "sig"}
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = key.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	default:
+		return nil, errors.New("unsupported public key type")
+	}
+	return jwk, nil
+}
+
+// jwkToPublicKey decodes a JSON Web Key published by another issuer
+func jwkToPublicKey(jwk *domain.JWKEntity) (crypto.PublicKey, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, errors.New("invalid rsa modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported ec curve %q", jwk.Curve)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, errors.New("invalid ec point")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("ec point is not on the curve")
+		}
+		return key, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if jwk.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+}
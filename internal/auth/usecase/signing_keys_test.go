@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestSigningKey(t *testing.T, kid string, activateAt, retireAt time.Time) *SigningKey {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, PrivateKey: privateKey, PublicKey: publicKey, ActivateAt: activateAt, RetireAt: retireAt}
+}
+
+func TestKeyManagerRetirement(t *testing.T) {
+	const lifetime = time.Hour
+	now := time.Now()
+	manager, err := NewKeyManager([]*SigningKey{
+		newTestSigningKey(t, "expired", now.Add(-48*time.Hour), now.Add(-2*lifetime)),
+		newTestSigningKey(t, "retired", now.Add(-24*time.Hour), now.Add(-lifetime/2)),
+		newTestSigningKey(t, "active", now.Add(-lifetime/2), time.Time{}),
+		newTestSigningKey(t, "scheduled", now.Add(24*time.Hour), now.Add(48*time.Hour)),
+	}, lifetime)
+	if err != nil {
+		t.Fatalf("NewKeyManager() = %v", err)
+	}
+
+	signing, err := manager.SigningKey()
+	if err != nil || signing.ID != "active" {
+		t.Fatalf("SigningKey() = %v, %v, want the active key", signing, err)
+	}
+	published := map[string]bool{}
+	for _, jwk := range manager.JWKS().Keys {
+		published[jwk.KeyID] = true
+	}
+
+	tests := []struct {
+		kid      string
+		accepted bool
+	}{
+		{"expired", false},
+		{"retired", true},
+		{"active", true},
+		{"scheduled", true},
+		{"unknown", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kid, func(t *testing.T) {
+			_, err := manager.VerificationKey(tt.kid)
+			if (err == nil) != tt.accepted {
+				t.Fatalf("VerificationKey(%q) = %v, want accepted %v", tt.kid, err, tt.accepted)
+			}
+			if published[tt.kid] != tt.accepted {
+				t.Fatalf("JWKS() publishes %q = %v, want %v", tt.kid, published[tt.kid], tt.accepted)
+			}
+		})
+	}
+}
+
+func TestNewKeyManagerRetiredBeforeActivation(t *testing.T) {
+	now := time.Now()
+	_, err := NewKeyManager([]*SigningKey{newTestSigningKey(t, "backwards", now, now.Add(-time.Hour))}, time.Hour)
+	if err == nil {
+		t.Fatal("NewKeyManager() = nil, want an error")
+	}
+}
+
+func TestLoadSigningKeys(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	activation := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	retirement := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		spec           string
+		wantErr        bool
+		wantActivateAt time.Time
+		wantRetireAt   time.Time
+	}{
+		{name: "no times", spec: "k:" + path},
+		{name: "activation", spec: "k:" + path + "@2026-10-01T00:00:00Z", wantActivateAt: activation},
+		{name: "activation and retirement", spec: "k:" + path + "@2026-10-01T00:00:00Z/2026-11-01T00:00:00Z", wantActivateAt: activation, wantRetireAt: retirement},
+		{name: "retirement only", spec: "k:" + path + "@/2026-11-01T00:00:00Z", wantRetireAt: retirement},
+		{name: "invalid activation", spec: "k:" + path + "@tomorrow", wantErr: true},
+		{name: "invalid retirement", spec: "k:" + path + "@2026-10-01T00:00:00Z/never", wantErr: true},
+		{name: "missing path", spec: "k:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := LoadSigningKeys(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("LoadSigningKeys() = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadSigningKeys() = %v", err)
+			}
+			if len(keys) != 1 || !keys[0].ActivateAt.Equal(tt.wantActivateAt) || !keys[0].RetireAt.Equal(tt.wantRetireAt) {
+				t.Fatalf("LoadSigningKeys() = %+v, want activation %v and retirement %v", keys, tt.wantActivateAt, tt.wantRetireAt)
+			}
+		})
+	}
+}
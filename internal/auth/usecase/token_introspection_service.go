@@ -0,0 +1,307 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Token introspection (RFC 7662) and revocation (RFC 7009), for resource servers such as the API gateway
+// that cannot verify our tokens themselves or need to know whether they were revoked.
+// Access tokens are always tried first whatever the token_type_hint: an access token carries every claim of
+// a refresh token, so it would be taken for one, while a refresh token never passes as an access token.
+
+const (
+	TokenUseAccessToken  = "access_token"
+	TokenUseRefreshToken = "refresh_token"
+)
+
+type TokenIntrospectionService interface {
+	// Introspect tells whether an access or refresh token is active and what it was issued for.
+	// Invalid, expired, revoked and unknown tokens are reported as inactive, they are not errors.
+	Introspect(ctx context.Context, data *dto.TokenIntrospectionRequest) (*domain.TokenIntrospectionEntity, error)
+	// Revoke revokes an access token, or the refresh token family and session of a refresh token.
+	// Tokens that are already invalid are ignored, like the RFC requires.
+	Revoke(ctx context.Context, data *dto.TokenIntrospectionRequest) error
+}
+
+type tokenIntrospectionService struct {
+	authService           AuthService
+	userService           userUseCase.UserService
+	repo                  repository.AuthRepository
+	jwtService            JWTService
+	serviceAccountService ServiceAccountService
+	oauthService          OAuthService
+}
+
+// tokenCaller is the client calling the introspection or revocation endpoint
+type tokenCaller struct {
+	clientID string
+	// Service accounts with the tokens:introspect scope may handle every token, other clients only their own
+	trusted bool
+}
+
+// NewTokenIntrospectionService creates the introspection and revocation service.
+// Service accounts can always call it, OAuth clients only when oauthService is set (the authorization server is enabled).
+func NewTokenIntrospectionService(authService AuthService, userService userUseCase.UserService, repo repository.AuthRepository, jwtService JWTService, serviceAccountService ServiceAccountService, oauthService OAuthService) TokenIntrospectionService {
+	return &tokenIntrospectionService{
+		authService:           authService,
+		userService:           userService,
+		repo:                  repo,
+		jwtService:            jwtService,
+		serviceAccountService: serviceAccountService,
+		oauthService:          oauthService,
+	}
+}
+
+func (service *tokenIntrospectionService) Introspect(ctx context.Context, data *dto.TokenIntrospectionRequest) (*domain.TokenIntrospectionEntity, error) {
+	// Public clients cannot introspect, they cannot keep the credentials the RFC requires
+	caller, err := service.authenticateCaller(ctx, data.ClientID, data.ClientSecret, false)
+	if err != nil {
+		return nil, err
+	}
+	if data.Token == "" {
+		return nil, domain.ErrOAuthInvalidRequest
+	}
+
+	for _, introspect := range []func(context.Context, string) (*domain.TokenIntrospectionEntity, error){service.introspectAccessToken, service.introspectRefreshToken} {
+		introspection, err := introspect(ctx, data.Token)
+		if err != nil {
+			return nil, err
+		}
+		if introspection == nil {
+			continue
+		}
+		// A client learns nothing about the tokens of other clients
+		if !caller.mayHandle(introspection.ClientID) {
+			return &domain.TokenIntrospectionEntity{Active: false}, nil
+		}
+		return introspection, nil
+	}
+	return &domain.TokenIntrospectionEntity{Active: false}, nil
+}
+
+func (service *tokenIntrospectionService) Revoke(ctx context.Context, data *dto.TokenIntrospectionRequest) error {
+	caller, err := service.authenticateCaller(ctx, data.ClientID, data.ClientSecret, true)
+	if err != nil {
+		return err
+	}
+	if data.Token == "" {
+		return domain.ErrOAuthInvalidRequest
+	}
+
+	for _, revokeToken := range []func(context.Context, *tokenCaller, string) (bool, error){service.revokeAccessToken, service.revokeRefreshToken} {
+		matched, err := revokeToken(ctx, caller, data.Token)
+		if err != nil || matched {
+			return err
+		}
+	}
+	return nil
+}
+
+// introspectAccessToken runs the same checks as Authenticate, it returns nil when the token is not an active access token
+func (service *tokenIntrospectionService) introspectAccessToken(ctx context.Context, token string) (*domain.TokenIntrospectionEntity, error) {
+	claims, err := service.authService.ValidateAccessToken(ctx, token)
+	if err != nil {
+		if isTokenRejected(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	introspection := &domain.TokenIntrospectionEntity{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		TokenUse:  TokenUseAccessToken,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		Subject:   claims.SubjectID(),
+		Audience:  claims.Audience,
+		Issuer:    claims.Issuer,
+		ID:        claims.ID,
+	}
+	// Access tokens do not carry the username by default, it is read by the subject
+	if claims.PrincipalType != shared.PrincipalTypeServiceAccount {
+		user, err := service.findUser(ctx, claims.UserID)
+		if err != nil {
+			if err == userDomain.ErrUserNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		introspection.Username = user.Username
+	}
+	if claims.IssuedAt != nil {
+		introspection.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		introspection.NotBefore = claims.NotBefore.Unix()
+	}
+	if claims.Actor != nil {
+		introspection.Actor = &domain.TokenActorEntity{Subject: claims.Actor.Subject, Username: claims.Actor.Username}
+	}
+	return introspection, nil
+}
+
+// introspectRefreshToken checks the token is the current one of a live family, it returns nil otherwise.
+// Unlike a refresh, presenting a rotated token here does not revoke the family, the caller is not the client holding it.
+func (service *tokenIntrospectionService) introspectRefreshToken(ctx context.Context, token string) (*domain.TokenIntrospectionEntity, error) {
+	claims, family, err := service.findRefreshTokenFamily(ctx, token)
+	if err != nil || family == nil {
+		return nil, err
+	}
+	if family.Revoked || family.ExpiresAt < time.Now().UnixMilli() || family.CurrentJTI != claims.ID {
+		return nil, nil
+	}
+
+	introspection := &domain.TokenIntrospectionEntity{
+		Active:    true,
+		Scope:     family.Scope,
+		ClientID:  family.ClientID,
+		TokenUse:  TokenUseRefreshToken,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		Subject:   claims.UserID,
+		Audience:  claims.Audience,
+		Issuer:    claims.Issuer,
+		ID:        claims.ID,
+	}
+	if claims.IssuedAt != nil {
+		introspection.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		introspection.NotBefore = claims.NotBefore.Unix()
+	}
+	return introspection, nil
+}
+
+// revokeAccessToken adds the token to the denylist, it reports whether the token was an access token
+func (service *tokenIntrospectionService) revokeAccessToken(ctx context.Context, caller *tokenCaller, token string) (bool, error) {
+	claims, err := service.jwtService.ParseAccessToken(ctx, token)
+	if err != nil {
+		// Expired tokens need no revocation
+		return false, nil
+	}
+	if !caller.mayHandle(claims.ClientID) {
+		zap.L().Warn("token revocation refused, the token was issued to another client",
+			zap.String("client_id", caller.clientID),
+			zap.String("token_id", claims.ID),
+		)
+		return true, nil
+	}
+
+	err = service.repo.RevokeToken(ctx, &domain.RevokedTokenEntity{
+		ID:        claims.ID,
+		UserID:    claims.SubjectID(),
+		ExpiresAt: claims.ExpiresAt.UnixMilli(),
+	})
+	if err != nil {
+		return true, err
+	}
+	zap.L().Info("access token revoked",
+		zap.String("client_id", caller.clientID),
+		zap.String("token_id", claims.ID),
+	)
+	return true, nil
+}
+
+// revokeRefreshToken revokes the whole family of a refresh token, its session ends with it so the
+// access tokens issued in the family are rejected too. It reports whether the token was a refresh token.
+func (service *tokenIntrospectionService) revokeRefreshToken(ctx context.Context, caller *tokenCaller, token string) (bool, error) {
+	_, family, err := service.findRefreshTokenFamily(ctx, token)
+	if err != nil || family == nil {
+		return false, err
+	}
+	if !caller.mayHandle(family.ClientID) {
+		zap.L().Warn("token revocation refused, the token was issued to another client",
+			zap.String("client_id", caller.clientID),
+			zap.String("family_id", family.ID),
+		)
+		return true, nil
+	}
+
+	if err := service.repo.RevokeRefreshTokenFamily(ctx, family.ID); err != nil {
+		return true, err
+	}
+	zap.L().Info("refresh token family revoked",
+		zap.String("client_id", caller.clientID),
+		zap.String("family_id", family.ID),
+	)
+	return true, nil
+}
+
+// findRefreshTokenFamily verifies a refresh token and loads its family, both are nil when the token is invalid or unknown
+func (service *tokenIntrospectionService) findRefreshTokenFamily(ctx context.Context, token string) (*RefreshClaims, *domain.RefreshTokenFamilyEntity, error) {
+	claims, err := service.jwtService.ParseRefreshToken(token)
+	if err != nil {
+		return nil, nil, nil
+	}
+	family, err := service.repo.FindRefreshTokenFamilyByID(ctx, claims.FamilyID)
+	if err != nil {
+		if isTokenRejected(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	if family.UserID != claims.UserID {
+		return nil, nil, nil
+	}
+	return claims, family, nil
+}
+
+// authenticateCaller accepts service accounts and, when the authorization server is enabled, OAuth clients
+func (service *tokenIntrospectionService) authenticateCaller(ctx context.Context, clientID, clientSecret string, allowPublic bool) (*tokenCaller, error) {
+	if strings.HasPrefix(clientID, serviceAccountIDPrefix) {
+		account, err := service.serviceAccountService.AuthenticateClient(ctx, clientID, clientSecret)
+		if err != nil {
+			return nil, err
+		}
+		return &tokenCaller{clientID: account.ID, trusted: containsString(account.Scopes, shared.ScopeTokensIntrospect)}, nil
+	}
+
+	if service.oauthService == nil {
+		return nil, domain.ErrOAuthInvalidClient
+	}
+	client, err := service.oauthService.AuthenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.Public && !allowPublic {
+		return nil, domain.ErrOAuthInvalidClient
+	}
+	return &tokenCaller{clientID: client.ID}, nil
+}
+
+// mayHandle reports whether the caller may see or revoke a token issued to clientID, empty for our own login
+func (caller *tokenCaller) mayHandle(clientID string) bool {
+	return caller.trusted || (clientID != "" && clientID == caller.clientID)
+}
+
+// isTokenRejected tells the errors of an invalid token apart from the failures of the repositories
+func isTokenRejected(err error) bool {
+	switch err {
+	case domain.ErrJWTTokenInvalid, domain.ErrJWTTokenExpired, domain.ErrJWTTokenRevoked,
+		domain.ErrJWTRefreshTokenInvalid, domain.ErrJWTRefreshTokenExpired, domain.ErrAuthTokenNotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+func (service *tokenIntrospectionService) findUser(ctx context.Context, userID string) (*userDomain.UserEntity, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, userDomain.ErrUserNotFound
+	}
+	return service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &userObjectID})
+}
@@ -0,0 +1,293 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+// introspectionFixture is the introspection service of oauthFixture, called by the gateway service account
+// (granted tokens:introspect), a worker service account (not granted it) and the OAuth clients
+type introspectionFixture struct {
+	*oauthFixture
+	accounts ServiceAccountService
+	gateway  *domain.ServiceAccountCredentialsEntity
+	worker   *domain.ServiceAccountCredentialsEntity
+	tokens   TokenIntrospectionService
+}
+
+func newIntrospectionFixture(t *testing.T) *introspectionFixture {
+	t.Helper()
+	oauth := newOAuthFixture(t, newTestJWTService())
+	service := NewServiceAccountService(repository.NewMemoryServiceAccountRepository(), oauth.repo, newTestJWTService())
+	f := &introspectionFixture{
+		oauthFixture: oauth,
+		accounts:     service,
+		tokens:       NewTokenIntrospectionService(oauth.authFixture.service, oauth.users, oauth.repo, newTestJWTService(), service, oauth.service),
+	}
+	admin := &shared.Principal{UserID: oauth.user.ID.Hex()}
+	var err error
+	f.gateway, err = service.CreateServiceAccount(context.Background(), admin, &dto.CreateServiceAccountRequest{Name: "gateway", Scopes: []string{shared.ScopeTokensIntrospect}})
+	if err != nil {
+		t.Fatalf("CreateServiceAccount(gateway) = %v", err)
+	}
+	f.worker, err = service.CreateServiceAccount(context.Background(), admin, &dto.CreateServiceAccountRequest{Name: "worker", Scopes: []string{shared.ScopeUsersRead}})
+	if err != nil {
+		t.Fatalf("CreateServiceAccount(worker) = %v", err)
+	}
+	return f
+}
+
+// clientTokens issues tokens of alice to the OAuth client through the authorization code flow
+func (f *introspectionFixture) clientTokens(t *testing.T, client *domain.OAuthClientEntity) *domain.OAuthTokenEntity {
+	t.Helper()
+	token, err := f.exchange(client, f.authorize(t, client, "profile"), nil)
+	if err != nil {
+		t.Fatalf("Token() = %v", err)
+	}
+	return token
+}
+
+func (f *introspectionFixture) introspect(t *testing.T, clientID, clientSecret, token string) *domain.TokenIntrospectionEntity {
+	t.Helper()
+	introspection, err := f.tokens.Introspect(context.Background(), &dto.TokenIntrospectionRequest{Token: token, ClientID: clientID, ClientSecret: clientSecret})
+	if err != nil {
+		t.Fatalf("Introspect() = %v", err)
+	}
+	return introspection
+}
+
+func (f *introspectionFixture) revoke(t *testing.T, clientID, clientSecret, token string) {
+	t.Helper()
+	if err := f.tokens.Revoke(context.Background(), &dto.TokenIntrospectionRequest{Token: token, ClientID: clientID, ClientSecret: clientSecret}); err != nil {
+		t.Fatalf("Revoke() = %v", err)
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	f := newIntrospectionFixture(t)
+	login := f.login(t)
+	client := f.clientTokens(t, f.confidential)
+	gatewayID, gatewaySecret := f.gateway.ClientID, f.gateway.ClientSecret
+	worker, err := f.accounts.Token(context.Background(), &dto.TokenRequest{GrantType: GrantTypeClientCredentials, ClientID: f.worker.ClientID, ClientSecret: f.worker.ClientSecret})
+	if err != nil {
+		t.Fatalf("Token(client_credentials) = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		use      string
+		clientID string
+		subject  string
+		username string
+		scope    string
+	}{
+		{"access token of our login", login.AccessToken, TokenUseAccessToken, "", f.user.ID.Hex(), f.user.Username, ""},
+		{"refresh token of our login", login.RefreshToken, TokenUseRefreshToken, "", f.user.ID.Hex(), "", ""},
+		{"access token of a client", client.AccessToken, TokenUseAccessToken, f.confidential.ID, f.user.ID.Hex(), f.user.Username, "profile"},
+		{"refresh token of a client", client.RefreshToken, TokenUseRefreshToken, f.confidential.ID, f.user.ID.Hex(), "", "profile"},
+		{"service account token", worker.AccessToken, TokenUseAccessToken, f.worker.ClientID, f.worker.ClientID, "", shared.ScopeUsersRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			introspection := f.introspect(t, gatewayID, gatewaySecret, tt.token)
+			if !introspection.Active || introspection.TokenUse != tt.use || introspection.ClientID != tt.clientID || introspection.Scope != tt.scope {
+				t.Fatalf("Introspect() = %+v, want an active %s of client %q with scope %q", introspection, tt.use, tt.clientID, tt.scope)
+			}
+			if introspection.Subject != tt.subject || introspection.Username != tt.username || introspection.ID == "" || introspection.ExpiresAt == 0 {
+				t.Fatalf("Introspect() = %+v, want subject %q and username %q", introspection, tt.subject, tt.username)
+			}
+		})
+	}
+}
+
+func TestIntrospectInactive(t *testing.T) {
+	tests := []struct {
+		name string
+		// token returns the token to introspect, after changing the state
+		token func(t *testing.T, f *introspectionFixture) string
+	}{
+		{"garbage", func(t *testing.T, f *introspectionFixture) string { return "not-a-token" }},
+		{"logged out access token", func(t *testing.T, f *introspectionFixture) string {
+			login := f.login(t)
+			principal, err := f.authFixture.service.Authenticate(context.Background(), login.AccessToken)
+			if err != nil {
+				t.Fatalf("Authenticate() = %v", err)
+			}
+			if err := f.authFixture.service.Logout(context.Background(), principal); err != nil {
+				t.Fatalf("Logout() = %v", err)
+			}
+			return login.AccessToken
+		}},
+		{"rotated refresh token", func(t *testing.T, f *introspectionFixture) string {
+			login := f.login(t)
+			if _, err := f.refresh(login.RefreshToken); err != nil {
+				t.Fatalf("refresh = %v", err)
+			}
+			return login.RefreshToken
+		}},
+		{"access token of a deleted user", func(t *testing.T, f *introspectionFixture) string {
+			login := f.login(t)
+			f.users.remove(f.user.ID)
+			return login.AccessToken
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newIntrospectionFixture(t)
+			if introspection := f.introspect(t, f.gateway.ClientID, f.gateway.ClientSecret, tt.token(t, f)); introspection.Active {
+				t.Fatalf("Introspect() = %+v, want inactive", introspection)
+			}
+		})
+	}
+}
+
+// Presenting a rotated refresh token to introspection does not count as a replay
+func TestIntrospectRotatedRefreshTokenKeepsTheFamily(t *testing.T) {
+	f := newIntrospectionFixture(t)
+	login := f.login(t)
+	rotated, err := f.refresh(login.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh = %v", err)
+	}
+	f.introspect(t, f.gateway.ClientID, f.gateway.ClientSecret, login.RefreshToken)
+	if _, err := f.refresh(rotated.RefreshToken); err != nil {
+		t.Fatalf("refresh after introspecting the rotated token = %v", err)
+	}
+}
+
+// Callers without the tokens:introspect scope only see the tokens issued to them
+func TestIntrospectClientScoping(t *testing.T) {
+	f := newIntrospectionFixture(t)
+	login := f.login(t)
+	confidential := f.clientTokens(t, f.confidential)
+	public := f.clientTokens(t, f.public)
+
+	tests := []struct {
+		name         string
+		clientID     string
+		clientSecret string
+		token        string
+		active       bool
+	}{
+		{"client with its access token", f.confidential.ID, f.confidential.ClientSecret, confidential.AccessToken, true},
+		{"client with its refresh token", f.confidential.ID, f.confidential.ClientSecret, confidential.RefreshToken, true},
+		{"client with a token of our login", f.confidential.ID, f.confidential.ClientSecret, login.AccessToken, false},
+		{"client with a token of another client", f.confidential.ID, f.confidential.ClientSecret, public.AccessToken, false},
+		{"service account without the scope", f.worker.ClientID, f.worker.ClientSecret, login.AccessToken, false},
+		{"service account without the scope with a client token", f.worker.ClientID, f.worker.ClientSecret, confidential.RefreshToken, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			introspection := f.introspect(t, tt.clientID, tt.clientSecret, tt.token)
+			if introspection.Active != tt.active {
+				t.Fatalf("Introspect() = %+v, want active %v", introspection, tt.active)
+			}
+			if !tt.active && !reflect.DeepEqual(introspection, &domain.TokenIntrospectionEntity{}) {
+				t.Fatalf("Introspect() = %+v, want nothing but active false", introspection)
+			}
+		})
+	}
+}
+
+func TestIntrospectRejectedCaller(t *testing.T) {
+	f := newIntrospectionFixture(t)
+	token := f.login(t).AccessToken
+
+	tests := []struct {
+		name string
+		data *dto.TokenIntrospectionRequest
+		want error
+	}{
+		{"public client", &dto.TokenIntrospectionRequest{Token: token, ClientID: f.public.ID}, domain.ErrOAuthInvalidClient},
+		{"wrong client secret", &dto.TokenIntrospectionRequest{Token: token, ClientID: f.confidential.ID, ClientSecret: "wrong"}, domain.ErrOAuthInvalidClient},
+		{"wrong service account secret", &dto.TokenIntrospectionRequest{Token: token, ClientID: f.gateway.ClientID, ClientSecret: "wrong"}, domain.ErrOAuthInvalidClient},
+		{"no client", &dto.TokenIntrospectionRequest{Token: token}, domain.ErrOAuthInvalidClient},
+		{"no token", &dto.TokenIntrospectionRequest{ClientID: f.gateway.ClientID, ClientSecret: f.gateway.ClientSecret}, domain.ErrOAuthInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.tokens.Introspect(context.Background(), tt.data); err != tt.want {
+				t.Fatalf("Introspect() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Without the authorization server only service accounts are accepted
+	tokens := NewTokenIntrospectionService(f.authFixture.service, f.users, f.repo, newTestJWTService(), f.accounts, nil)
+	_, err := tokens.Introspect(context.Background(), &dto.TokenIntrospectionRequest{Token: token, ClientID: f.confidential.ID, ClientSecret: f.confidential.ClientSecret})
+	if err != domain.ErrOAuthInvalidClient {
+		t.Fatalf("Introspect() by a client without the authorization server = %v, want %v", err, domain.ErrOAuthInvalidClient)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("access token", func(t *testing.T) {
+		f := newIntrospectionFixture(t)
+		token := f.clientTokens(t, f.confidential)
+		f.revoke(t, f.confidential.ID, f.confidential.ClientSecret, token.AccessToken)
+		if _, err := f.authFixture.service.Authenticate(ctx, token.AccessToken); err != domain.ErrJWTTokenRevoked {
+			t.Fatalf("Authenticate() with the revoked token = %v, want %v", err, domain.ErrJWTTokenRevoked)
+		}
+		// The refresh token is left alone
+		if _, err := f.oauthFixture.service.Token(ctx, &dto.TokenRequest{GrantType: GrantTypeRefreshToken, RefreshToken: token.RefreshToken, ClientID: f.confidential.ID, ClientSecret: f.confidential.ClientSecret}); err != nil {
+			t.Fatalf("Token(refresh_token) after revoking the access token = %v", err)
+		}
+	})
+
+	t.Run("refresh token by a public client", func(t *testing.T) {
+		f := newIntrospectionFixture(t)
+		token := f.clientTokens(t, f.public)
+		f.revoke(t, f.public.ID, "", token.RefreshToken)
+		// The family and its session end, the access tokens issued with it are rejected too
+		if _, err := f.oauthFixture.service.Token(ctx, &dto.TokenRequest{GrantType: GrantTypeRefreshToken, RefreshToken: token.RefreshToken, ClientID: f.public.ID}); err != domain.ErrOAuthInvalidGrant {
+			t.Fatalf("Token(refresh_token) after revocation = %v, want %v", err, domain.ErrOAuthInvalidGrant)
+		}
+		if _, err := f.authFixture.service.Authenticate(ctx, token.AccessToken); err != domain.ErrJWTTokenRevoked {
+			t.Fatalf("Authenticate() after revoking the refresh token = %v, want %v", err, domain.ErrJWTTokenRevoked)
+		}
+	})
+
+	t.Run("token of another client is ignored", func(t *testing.T) {
+		f := newIntrospectionFixture(t)
+		login := f.login(t)
+		other := f.clientTokens(t, f.public)
+		for _, token := range []string{login.AccessToken, login.RefreshToken, other.AccessToken, other.RefreshToken} {
+			f.revoke(t, f.confidential.ID, f.confidential.ClientSecret, token)
+		}
+		if _, err := f.authFixture.service.Authenticate(ctx, login.AccessToken); err != nil {
+			t.Fatalf("Authenticate() of our login = %v", err)
+		}
+		if _, err := f.refresh(login.RefreshToken); err != nil {
+			t.Fatalf("refresh of our login = %v", err)
+		}
+		if _, err := f.authFixture.service.Authenticate(ctx, other.AccessToken); err != nil {
+			t.Fatalf("Authenticate() of the other client = %v", err)
+		}
+	})
+
+	t.Run("any token by the trusted service account", func(t *testing.T) {
+		f := newIntrospectionFixture(t)
+		login := f.login(t)
+		f.revoke(t, f.gateway.ClientID, f.gateway.ClientSecret, login.RefreshToken)
+		if _, err := f.refresh(login.RefreshToken); err != domain.ErrJWTRefreshTokenInvalid {
+			t.Fatalf("refresh after revocation = %v, want %v", err, domain.ErrJWTRefreshTokenInvalid)
+		}
+		if _, err := f.authFixture.service.Authenticate(ctx, login.AccessToken); err != domain.ErrJWTTokenRevoked {
+			t.Fatalf("Authenticate() after revocation = %v, want %v", err, domain.ErrJWTTokenRevoked)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		f := newIntrospectionFixture(t)
+		f.revoke(t, f.gateway.ClientID, f.gateway.ClientSecret, "not-a-token")
+	})
+}
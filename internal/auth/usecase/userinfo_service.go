@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/auth/domain"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserInfo use case: the profile of the user an access token was issued to, access tokens carry no personal data
+
+// firstPartyScopes are the profile scopes of tokens issued by our own login, which can read the whole profile
+var firstPartyScopes = []string{ScopeProfile, ScopeEmail, ScopePhone, ScopeAddress}
+
+type UserInfoService interface {
+	// UserInfo returns the claims of the user of the access token. Tokens issued to OAuth clients need
+	// the openid scope and only get the claims of their scopes, tokens of our own login get every claim.
+	UserInfo(ctx context.Context, principal *shared.Principal) (*domain.UserInfoEntity, error)
+}
+
+type userInfoService struct {
+	userService userUseCase.UserService
+}
+
+func NewUserInfoService(userService userUseCase.UserService) UserInfoService {
+	return &userInfoService{userService: userService}
+}
+
+func (service *userInfoService) UserInfo(ctx context.Context, principal *shared.Principal) (*domain.UserInfoEntity, error) {
+	// Service accounts have no profile
+	if principal == nil || principal.IsServiceAccount() || principal.UserID == "" {
+		return nil, domain.ErrUserInfoUnavailable
+	}
+	scopes := firstPartyScopes
+	if principal.ClientID != "" {
+		if !principal.HasScope(ScopeOpenID) {
+			return nil, domain.ErrOAuthInsufficientScope
+		}
+		scopes = principal.Scopes
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(principal.UserID)
+	if err != nil {
+		return nil, userDomain.ErrUserNotFound
+	}
+	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &userObjectID})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := newIDTokenClaims(user, scopes)
+	userInfo := &domain.UserInfoEntity{
+		Subject:           user.ID.Hex(),
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+		UpdatedAt:         profileUpdatedAt(user, scopes),
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+	}
+	if containsString(scopes, ScopeProfile) {
+		userInfo.Gender = genderClaim(user.Gender)
+	}
+	if containsString(scopes, ScopePhone) {
+		userInfo.PhoneNumber = user.Phone
+	}
+	if containsString(scopes, ScopeAddress) && user.Address != "" {
+		userInfo.Address = &domain.UserInfoAddressEntity{Formatted: user.Address}
+	}
+	return userInfo, nil
+}
+
+// genderClaim returns the OpenID Connect gender claim, empty when the gender is not known
+func genderClaim(gender shared.Gender) string {
+	switch gender {
+	case shared.GenderMale:
+		return "male"
+	case shared.GenderFemale:
+		return "female"
+	default:
+		return ""
+	}
+}
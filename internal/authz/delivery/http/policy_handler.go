@@ -0,0 +1,357 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// HTTP handlers for the access policies, their versions, the decision API and the decision log
+
+type PolicyHandler struct {
+	policyService usecase.PolicyService
+}
+
+func NewPolicyHandler(policyService usecase.PolicyService) *PolicyHandler {
+	return &PolicyHandler{policyService: policyService}
+}
+
+// Check handles POST /authz/check request
+// @Summary Ask for an access decision
+// @Description Evaluate the access policies: may the subject perform the action on the resource now. The subject is the caller unless subject_id names a user, which needs the authz:check permission. Users sent as resources are described by their stored attributes. Every decision is logged.
+// @Tags Authorization
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.CheckRequest true "Decision request"
+// @Success 200 {object} domain.PolicyDecisionEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/check [post]
+func (h *PolicyHandler) Check(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.CheckRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+		return
+	}
+
+	decision, err := h.policyService.Check(c.Request.Context(), principal, &data, c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, decision)
+}
+
+// ListDecisions handles GET /authz/decisions request
+// @Summary List access decisions
+// @Description List the decisions of the decision API, newest first
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Param subject_id query string false "User id or service account client id the decision was about"
+// @Param policy_id query string false "Policy that decided"
+// @Param allowed query bool false "Outcome"
+// @Param limit query int false "Maximum number of decisions, defaults to 100"
+// @Success 200 {array} domain.PolicyDecisionEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /authz/decisions [get]
+func (h *PolicyHandler) ListDecisions(c *gin.Context) {
+	var query dto.PolicyDecisionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+		return
+	}
+
+	decisions, err := h.policyService.ListDecisions(c.Request.Context(), &query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, decisions)
+}
+
+// CreatePolicy handles POST /authz/policies request
+// @Summary Create an access policy
+// @Description Store a policy as its version 1, it applies to the next decision
+// @Tags Authorization
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.CreatePolicyRequest true "Policy"
+// @Success 201 {object} domain.PolicyEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /authz/policies [post]
+func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.CreatePolicyRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+		return
+	}
+
+	policy, err := h.policyService.CreatePolicy(c.Request.Context(), principal, &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, policy)
+}
+
+// ListPolicies handles GET /authz/policies request
+// @Summary List access policies
+// @Description Return the current version of every policy
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.PolicyEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /authz/policies [get]
+func (h *PolicyHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.policyService.ListPolicies(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, policies)
+}
+
+// GetPolicy handles GET /authz/policies/:id request
+// @Summary Get an access policy
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Policy id"
+// @Success 200 {object} domain.PolicyEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/policies/{id} [get]
+func (h *PolicyHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.policyService.GetPolicy(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, policy)
+}
+
+// UpdatePolicy handles PUT /authz/policies/:id request
+// @Summary Update an access policy
+// @Description Replace the policy with a new version. The request names the version it replaces and fails with 409 when someone changed the policy in between.
+// @Tags Authorization
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Policy id"
+// @Param body body dto.UpdatePolicyRequest true "Policy"
+// @Success 200 {object} domain.PolicyEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /authz/policies/{id} [put]
+func (h *PolicyHandler) UpdatePolicy(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.UpdatePolicyRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+		return
+	}
+
+	policy, err := h.policyService.UpdatePolicy(c.Request.Context(), principal, c.Param("id"), &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, policy)
+}
+
+// DeletePolicy handles DELETE /authz/policies/:id request
+// @Summary Delete an access policy
+// @Description Delete the policy, its history is kept and records the deletion
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Policy id"
+// @Param version query int true "Version being deleted"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /authz/policies/{id} [delete]
+func (h *PolicyHandler) DeletePolicy(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var query dto.DeletePolicyQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.policyService.DeletePolicy(c.Request.Context(), principal, c.Param("id"), query.Version); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListPolicyVersions handles GET /authz/policies/:id/versions request
+// @Summary List the versions of an access policy
+// @Description Return the history of the policy, newest first, including its deletions
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Policy id"
+// @Success 200 {array} domain.PolicyVersionEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/policies/{id}/versions [get]
+func (h *PolicyHandler) ListPolicyVersions(c *gin.Context) {
+	versions, err := h.policyService.ListPolicyVersions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, versions)
+}
+
+// GetPolicyVersion handles GET /authz/policies/:id/versions/:version request
+// @Summary Get a version of an access policy
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Policy id"
+// @Param version path int true "Version"
+// @Success 200 {object} domain.PolicyVersionEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/policies/{id}/versions/{version} [get]
+func (h *PolicyHandler) GetPolicyVersion(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		respondError(c, domain.ErrPolicyVersionNotFound)
+		return
+	}
+
+	policyVersion, err := h.policyService.GetPolicyVersion(c.Request.Context(), c.Param("id"), version)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, policyVersion)
+}
+
+// RestorePolicyVersion handles POST /authz/policies/:id/versions/:version/restore request
+// @Summary Restore a version of an access policy
+// @Description Make an old version current again, stored as a new version. Name the current version being replaced, or none when the policy was deleted.
+// @Tags Authorization
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Policy id"
+// @Param version path int true "Version to restore"
+// @Param body body dto.RestorePolicyRequest false "Current version"
+// @Success 200 {object} domain.PolicyEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /authz/policies/{id}/versions/{version}/restore [post]
+func (h *PolicyHandler) RestorePolicyVersion(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		respondError(c, domain.ErrPolicyVersionNotFound)
+		return
+	}
+	var data dto.RestorePolicyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&data); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+			return
+		}
+	}
+
+	policy, err := h.policyService.RestorePolicyVersion(c.Request.Context(), principal, c.Param("id"), version, &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, policy)
+}
+
+// SetUserAttributes handles PUT /authz/users/:userId/attributes request
+// @Summary Set the attributes of a user
+// @Description Replace the attributes the access policies read about the user, such as their organization
+// @Tags Authorization
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userId path string true "User id"
+// @Param body body dto.SetUserAttributesRequest true "Attributes"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/users/{userId}/attributes [put]
+func (h *PolicyHandler) SetUserAttributes(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.SetUserAttributesRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+		return
+	}
+
+	attributes, err := h.policyService.SetUserAttributes(c.Request.Context(), principal, c.Param("userId"), data.Attributes)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, attributes)
+}
@@ -0,0 +1,263 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// HTTP handlers for the permission catalog, custom roles and their assignment to users
+
+type RoleHandler struct {
+	roleService usecase.RoleService
+}
+
+func NewRoleHandler(roleService usecase.RoleService) *RoleHandler {
+	return &RoleHandler{roleService: roleService}
+}
+
+// ListPermissions handles GET /authz/permissions request
+// @Summary List permissions
+// @Description Return the permission catalog, custom roles bundle these permissions
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} shared.PermissionDefinition
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /authz/permissions [get]
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, h.roleService.ListPermissions())
+}
+
+// CreateRole handles POST /authz/roles request
+// @Summary Create a custom role
+// @Description Bundle permissions into a role, you can only grant permissions you have
+// @Tags Authorization
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.CreateRoleRequest true "Role"
+// @Success 201 {object} domain.RoleEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /authz/roles [post]
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.CreateRoleRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+		return
+	}
+
+	role, err := h.roleService.CreateRole(c.Request.Context(), principal, &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, role)
+}
+
+// ListRoles handles GET /authz/roles request
+// @Summary List roles
+// @Description Return the built-in roles followed by the custom roles
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.RoleEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /authz/roles [get]
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.roleService.ListRoles(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, roles)
+}
+
+// GetRole handles GET /authz/roles/:id request
+// @Summary Get a role
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role id"
+// @Success 200 {object} domain.RoleEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/roles/{id} [get]
+func (h *RoleHandler) GetRole(c *gin.Context) {
+	role, err := h.roleService.GetRole(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, role)
+}
+
+// UpdateRole handles PUT /authz/roles/:id request
+// @Summary Update a custom role
+// @Description Replace the name, description and permissions of the role, it applies to its users at once
+// @Tags Authorization
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role id"
+// @Param body body dto.UpdateRoleRequest true "Role"
+// @Success 200 {object} domain.RoleEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/roles/{id} [put]
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(c.Request.Context(), principal, c.Param("id"), &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, role)
+}
+
+// DeleteRole handles DELETE /authz/roles/:id request
+// @Summary Delete a custom role
+// @Description Delete the role and remove it from its users
+// @Tags Authorization
+// @Security BearerAuth
+// @Param id path string true "Role id"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/roles/{id} [delete]
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	if err := h.roleService.DeleteRole(c.Request.Context(), principal, c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetUserRoles handles GET /authz/users/:userId/roles request
+// @Summary Get the roles of a user
+// @Description Return the built-in role, the custom roles and the permissions of the user
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Param userId path string true "User id"
+// @Success 200 {object} domain.UserRolesEntity
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/users/{userId}/roles [get]
+func (h *RoleHandler) GetUserRoles(c *gin.Context) {
+	userRoles, err := h.roleService.GetUserRoles(c.Request.Context(), c.Param("userId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, userRoles)
+}
+
+// AssignRole handles POST /authz/users/:userId/roles request
+// @Summary Assign a custom role to a user
+// @Description You can only assign roles whose permissions you have
+// @Tags Authorization
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userId path string true "User id"
+// @Param body body dto.AssignRoleRequest true "Role"
+// @Success 200 {object} domain.UserRolesEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/users/{userId}/roles [post]
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	var data dto.AssignRoleRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "AUTHZ_INVALID_INPUT", err.Error())
+		return
+	}
+
+	userRoles, err := h.roleService.AssignRole(c.Request.Context(), principal, c.Param("userId"), &data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, userRoles)
+}
+
+// UnassignRole handles DELETE /authz/users/:userId/roles/:roleId request
+// @Summary Remove a custom role from a user
+// @Tags Authorization
+// @Produce json
+// @Security BearerAuth
+// @Param userId path string true "User id"
+// @Param roleId path string true "Role id"
+// @Success 200 {object} domain.UserRolesEntity
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /authz/users/{userId}/roles/{roleId} [delete]
+func (h *RoleHandler) UnassignRole(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		respondError(c, middleware.ErrUnauthenticated)
+		return
+	}
+	userRoles, err := h.roleService.UnassignRole(c.Request.Context(), principal, c.Param("userId"), c.Param("roleId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, userRoles)
+}
+
+func respondError(c *gin.Context, err error) {
+	if ce, ok := err.(*utils.CustomError); ok {
+		utils.CustomErrorResponse(c, ce)
+		return
+	}
+	utils.ErrorResponse(c,
+		domain.ErrAuthzInternalServerError.HTTPStatus(),
+		domain.ErrAuthzInternalServerError.Code(),
+		domain.ErrAuthzInternalServerError.Error())
+}
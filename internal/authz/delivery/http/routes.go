@@ -0,0 +1,70 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+// HTTP routes configuration
+
+// RegisterRoleRoutes registers the permission catalog, the custom roles and their assignment.
+// Changes are refused while impersonating, the admin must act under their own name.
+// The assignments of a user are also subject to the access policies, with the permission of the route as action.
+// Every route needs at least the admin role on top of its permission.
+func RegisterRoleRoutes(router *gin.RouterGroup, roleService usecase.RoleService, policyService usecase.PolicyService, authMiddleware gin.HandlerFunc) {
+	roleHandler := NewRoleHandler(roleService)
+	authz := router.Group("/authz", authMiddleware, middleware.RequireRole(shared.RoleAdmin))
+	{
+		authz.GET("/permissions", middleware.RequirePermission(shared.PermissionRolesRead), roleHandler.ListPermissions)
+	}
+
+	roles := authz.Group("/roles")
+	{
+		roles.GET("", middleware.RequirePermission(shared.PermissionRolesRead), roleHandler.ListRoles)
+		roles.GET("/:id", middleware.RequirePermission(shared.PermissionRolesRead), roleHandler.GetRole)
+		roles.POST("", middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionRolesManage), roleHandler.CreateRole)
+		roles.PUT("/:id", middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionRolesManage), roleHandler.UpdateRole)
+		roles.DELETE("/:id", middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionRolesManage), roleHandler.DeleteRole)
+	}
+
+	userRoles := authz.Group("/users/:userId/roles")
+	{
+		userRoles.GET("", middleware.RequirePermission(shared.PermissionRolesRead), requireUserPolicy(policyService, shared.PermissionRolesRead), roleHandler.GetUserRoles)
+		userRoles.POST("", middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionRolesAssign), requireUserPolicy(policyService, shared.PermissionRolesAssign), roleHandler.AssignRole)
+		userRoles.DELETE("/:roleId", middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionRolesAssign), requireUserPolicy(policyService, shared.PermissionRolesAssign), roleHandler.UnassignRole)
+	}
+}
+
+// RegisterPolicyRoutes registers the decision API, the access policies and their versions, and the decision log.
+// Any authenticated caller can check its own access, the other routes need at least the admin role on top of their
+// permission and policy changes are refused while impersonating.
+func RegisterPolicyRoutes(router *gin.RouterGroup, policyService usecase.PolicyService, authMiddleware gin.HandlerFunc) {
+	policyHandler := NewPolicyHandler(policyService)
+	requireAdmin := middleware.RequireRole(shared.RoleAdmin)
+	authz := router.Group("/authz", authMiddleware)
+	{
+		authz.POST("/check", policyHandler.Check)
+		authz.GET("/decisions", requireAdmin, middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionAuditRead), policyHandler.ListDecisions)
+		authz.PUT("/users/:userId/attributes", requireAdmin, middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionPoliciesManage),
+			requireUserPolicy(policyService, shared.PermissionPoliciesManage), policyHandler.SetUserAttributes)
+	}
+
+	policies := authz.Group("/policies", requireAdmin)
+	{
+		policies.GET("", middleware.RequirePermission(shared.PermissionPoliciesRead), policyHandler.ListPolicies)
+		policies.GET("/:id", middleware.RequirePermission(shared.PermissionPoliciesRead), policyHandler.GetPolicy)
+		policies.GET("/:id/versions", middleware.RequirePermission(shared.PermissionPoliciesRead), policyHandler.ListPolicyVersions)
+		policies.GET("/:id/versions/:version", middleware.RequirePermission(shared.PermissionPoliciesRead), policyHandler.GetPolicyVersion)
+		policies.POST("", middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionPoliciesManage), policyHandler.CreatePolicy)
+		policies.PUT("/:id", middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionPoliciesManage), policyHandler.UpdatePolicy)
+		policies.DELETE("/:id", middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionPoliciesManage), policyHandler.DeletePolicy)
+		policies.POST("/:id/versions/:version/restore", middleware.ForbidImpersonation(), middleware.RequirePermission(shared.PermissionPoliciesManage), policyHandler.RestorePolicyVersion)
+	}
+}
+
+// requireUserPolicy enforces the access policies on the user of the userId path parameter
+func requireUserPolicy(policyService usecase.PolicyService, permission shared.Permission) gin.HandlerFunc {
+	return middleware.RequirePolicy(policyService, string(permission), usecase.ResourceTypeUser, "userId")
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"net/http"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// Domain-specific errors
+var (
+	ErrRoleNotFound = utils.NewCustomError("ROLE_NOT_FOUND",
+		http.StatusNotFound,
+		"role not found",
+	)
+	ErrRoleAlreadyExists = utils.NewCustomError("ROLE_ALREADY_EXISTS",
+		http.StatusConflict,
+		"a role with this id already exists",
+	)
+	ErrRoleInvalidID = utils.NewCustomError("ROLE_INVALID_ID",
+		http.StatusBadRequest,
+		"role ids are 3 to 50 lowercase letters, digits, dashes or underscores, starting with a letter",
+	)
+	ErrRoleBuiltIn = utils.NewCustomError("ROLE_BUILT_IN",
+		http.StatusBadRequest,
+		"built-in roles cannot be changed or assigned as custom roles",
+	)
+	ErrRoleInvalidPermission = utils.NewCustomError("ROLE_INVALID_PERMISSION",
+		http.StatusBadRequest,
+		"the permission is not in the catalog",
+	)
+	// A role can only be managed by someone holding every permission it grants, this stops privilege escalation
+	ErrRolePermissionNotHeld = utils.NewCustomError("ROLE_PERMISSION_NOT_HELD",
+		http.StatusForbidden,
+		"you cannot grant permissions you do not have",
+	)
+	ErrRoleAssignmentNotFound = utils.NewCustomError("ROLE_ASSIGNMENT_NOT_FOUND",
+		http.StatusNotFound,
+		"the role is not assigned to the user",
+	)
+	ErrPolicyNotFound = utils.NewCustomError("POLICY_NOT_FOUND",
+		http.StatusNotFound,
+		"policy not found",
+	)
+	ErrPolicyAlreadyExists = utils.NewCustomError("POLICY_ALREADY_EXISTS",
+		http.StatusConflict,
+		"a policy with this id already exists",
+	)
+	// The policy changed since the version the client read, it must read it again and retry
+	ErrPolicyVersionConflict = utils.NewCustomError("POLICY_VERSION_CONFLICT",
+		http.StatusConflict,
+		"the policy was changed by someone else, read it again and retry",
+	)
+	ErrPolicyVersionNotFound = utils.NewCustomError("POLICY_VERSION_NOT_FOUND",
+		http.StatusNotFound,
+		"policy version not found",
+	)
+	ErrPolicyVersionDeleted = utils.NewCustomError("POLICY_VERSION_DELETED",
+		http.StatusBadRequest,
+		"this version records the deletion of the policy, it cannot be restored",
+	)
+	// Checking the access of someone else needs the authz:check permission
+	ErrPolicyCheckForbidden = utils.NewCustomError("POLICY_CHECK_FORBIDDEN",
+		http.StatusForbidden,
+		"you can only check your own access",
+	)
+	// An access policy denied the request of an API route
+	ErrPolicyDenied = utils.NewCustomError("POLICY_DENIED",
+		http.StatusForbidden,
+		"the request is denied by an access policy",
+	)
+	// Attribute names are used in the dotted paths of the policies
+	ErrUserAttributeInvalidName = utils.NewCustomError("USER_ATTRIBUTE_INVALID_NAME",
+		http.StatusBadRequest,
+		"attribute names are 1 to 50 letters, digits or underscores",
+	)
+	ErrAuthzInternalServerError = utils.NewCustomError("AUTHZ_INTERNAL_SERVER_ERROR",
+		http.StatusInternalServerError,
+		"internal server error",
+	)
+)
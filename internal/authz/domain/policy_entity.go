@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/policy"
+)
+
+// Access policy entities: the policies evaluated by the decision API, their history and the decisions made
+
+// PolicyEntity is the current version of a policy. Version starts at 1 and grows with every change,
+// an update must name the version it replaces so concurrent changes are not lost.
+type PolicyEntity struct {
+	policy.Policy `bson:",inline"`
+	Version       int    `bson:"version" json:"version"`
+	CreatedBy     string `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	UpdatedBy     string `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	CreatedAt     int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt     int64  `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// PolicyVersionEntity keeps every version of a policy, the history is append only.
+// A deleted policy gets a last version with Deleted set and no document.
+type PolicyVersionEntity struct {
+	ID        string         `bson:"_id" json:"-"` // "<policy id>:<version>"
+	PolicyID  string         `bson:"policy_id" json:"policy_id"`
+	Version   int            `bson:"version" json:"version"`
+	Policy    *policy.Policy `bson:"policy,omitempty" json:"policy,omitempty"`
+	Deleted   bool           `bson:"deleted,omitempty" json:"deleted,omitempty"`
+	ChangedBy string         `bson:"changed_by" json:"changed_by"`
+	CreatedAt int64          `bson:"created_at" json:"created_at"`
+}
+
+// NewPolicyVersionID returns the id of a version of the policy
+func NewPolicyVersionID(policyID string, version int) string {
+	return fmt.Sprintf("%s:%d", policyID, version)
+}
+
+// PolicyDecisionEntity logs a decision of the decision API: who asked, about whom, what and the outcome
+type PolicyDecisionEntity struct {
+	ID            string `bson:"_id" json:"id"`
+	Allowed       bool   `bson:"allowed" json:"allowed"`
+	PolicyID      string `bson:"policy_id,omitempty" json:"policy_id,omitempty"` // Policy that decided, empty when none applied
+	PolicyVersion int    `bson:"policy_version,omitempty" json:"policy_version,omitempty"`
+	Reason        string `bson:"reason" json:"reason"`
+	SubjectID     string `bson:"subject_id" json:"subject_id"` // User id or service account client id
+	Action        string `bson:"action" json:"action"`
+	ResourceType  string `bson:"resource_type" json:"resource_type"`
+	ResourceID    string `bson:"resource_id,omitempty" json:"resource_id,omitempty"`
+	CallerID      string `bson:"caller_id" json:"caller_id"` // Differs from SubjectID when a service asks on behalf of a user
+	IP            string `bson:"ip,omitempty" json:"ip,omitempty"`
+	CreatedAt     int64  `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+
+// Authorization entities: custom roles bundling permissions of the catalog, and their assignment to users
+
+// RoleEntity is a role and the permissions it grants. Custom roles are stored, their id is a slug chosen
+// by the admin. Built-in roles are defined in code, they are listed with BuiltIn set and cannot be changed.
+type RoleEntity struct {
+	ID          string              `bson:"_id" json:"id"`
+	Name        string              `bson:"name" json:"name"`
+	Description string              `bson:"description,omitempty" json:"description,omitempty"`
+	Permissions []shared.Permission `bson:"permissions" json:"permissions"`
+	BuiltIn     bool                `bson:"-" json:"built_in"`
+	CreatedBy   string              `bson:"created_by,omitempty" json:"created_by,omitempty"` // Admin who created the role
+	CreatedAt   int64               `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt   int64               `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// RoleAssignmentEntity grants a custom role to a user, on top of the built-in role of the user
+type RoleAssignmentEntity struct {
+	ID         string `bson:"_id" json:"-"` // "<user id>:<role id>", a role is assigned once
+	UserID     string `bson:"user_id" json:"user_id"`
+	RoleID     string `bson:"role_id" json:"role_id"`
+	AssignedBy string `bson:"assigned_by,omitempty" json:"assigned_by,omitempty"`
+	CreatedAt  int64  `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// NewRoleAssignmentID returns the id of the assignment of the role to the user
+func NewRoleAssignmentID(userID, roleID string) string {
+	return userID + ":" + roleID
+}
+
+// UserRolesEntity is the built-in role of a user, the custom roles assigned to them and the permissions granted together
+type UserRolesEntity struct {
+	UserID      string              `json:"user_id"`
+	Role        shared.Role         `json:"role"`
+	CustomRoles []*RoleEntity       `json:"custom_roles"`
+	Permissions []shared.Permission `json:"permissions"`
+}
@@ -0,0 +1,82 @@
+package dto
+
+import (
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/policy"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+// Data transfer objects for the authz module
+
+type CreateRoleRequest struct {
+	ID          string              `json:"id" binding:"required,min=3,max=50"`
+	Name        string              `json:"name" binding:"required,min=3,max=100"`
+	Description string              `json:"description" binding:"max=500"`
+	Permissions []shared.Permission `json:"permissions" binding:"required,min=1,dive,required"`
+}
+
+type UpdateRoleRequest struct {
+	Name        string              `json:"name" binding:"required,min=3,max=100"`
+	Description string              `json:"description" binding:"max=500"`
+	Permissions []shared.Permission `json:"permissions" binding:"required,min=1,dive,required"`
+}
+
+type AssignRoleRequest struct {
+	RoleID string `json:"role_id" binding:"required"`
+}
+
+// Policies are validated by policy.Policy.Validate, the bindings only bound their size
+
+type CreatePolicyRequest struct {
+	ID          string             `json:"id" binding:"required,max=100"`
+	Description string             `json:"description" binding:"max=500"`
+	Effect      policy.Effect      `json:"effect" binding:"required,oneof=allow deny"`
+	Subjects    []string           `json:"subjects" binding:"required,min=1,max=50"`
+	Actions     []string           `json:"actions" binding:"required,min=1,max=50"`
+	Resources   []string           `json:"resources" binding:"required,min=1,max=50"`
+	Conditions  []policy.Condition `json:"conditions" binding:"max=20"`
+}
+
+type UpdatePolicyRequest struct {
+	Version     int                `json:"version" binding:"required,min=1"` // Version being replaced, from the last read of the policy
+	Description string             `json:"description" binding:"max=500"`
+	Effect      policy.Effect      `json:"effect" binding:"required,oneof=allow deny"`
+	Subjects    []string           `json:"subjects" binding:"required,min=1,max=50"`
+	Actions     []string           `json:"actions" binding:"required,min=1,max=50"`
+	Resources   []string           `json:"resources" binding:"required,min=1,max=50"`
+	Conditions  []policy.Condition `json:"conditions" binding:"max=20"`
+}
+
+type DeletePolicyQuery struct {
+	Version int `form:"version" binding:"required,min=1"` // Version being deleted, from the last read of the policy
+}
+
+type RestorePolicyRequest struct {
+	Version int `json:"version" binding:"omitempty,min=1"` // Current version being replaced, omitted when the policy was deleted
+}
+
+type CheckRequest struct {
+	// SubjectID is the user the decision is about, the caller when empty
+	SubjectID string        `json:"subject_id" binding:"omitempty,max=100"`
+	Action    string        `json:"action" binding:"required,max=100"`
+	Resource  CheckResource `json:"resource" binding:"required"`
+	// Context holds request attributes known to the caller, policies read them under environment.context
+	Context map[string]any `json:"context" binding:"max=50"`
+}
+
+type CheckResource struct {
+	Type string `json:"type" binding:"required,max=100"`
+	ID   string `json:"id" binding:"omitempty,max=100"`
+	// Attributes describe a resource unknown to this service, the stored attributes of users replace them
+	Attributes map[string]any `json:"attributes" binding:"max=50"`
+}
+
+type PolicyDecisionQuery struct {
+	SubjectID string `form:"subject_id"`
+	PolicyID  string `form:"policy_id"`
+	Allowed   *bool  `form:"allowed"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=500"` // Defaults to 100
+}
+
+type SetUserAttributesRequest struct {
+	Attributes map[string]string `json:"attributes" binding:"required,max=20,dive,min=1,max=200"` // Empty values are refused, a missing attribute never matches
+}
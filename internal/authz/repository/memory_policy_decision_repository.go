@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"go.uber.org/zap"
+)
+
+// In-memory implementation of policy decision repository, used for local development and tests.
+// Data is lost on restart and is not shared between instances.
+
+// memoryDecisionLimit bounds the decisions kept in memory, the oldest are dropped first
+const memoryDecisionLimit = 10000
+
+type memoryPolicyDecisionRepository struct {
+	mu        sync.RWMutex
+	decisions []domain.PolicyDecisionEntity // Oldest first
+}
+
+func NewMemoryPolicyDecisionRepository() PolicyDecisionRepository {
+	return &memoryPolicyDecisionRepository{}
+}
+
+// Memory - EnsureIndexes has nothing to create
+func (r *memoryPolicyDecisionRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Memory - CreateDecision stores a decision
+func (r *memoryPolicyDecisionRepository) CreateDecision(ctx context.Context, decision *domain.PolicyDecisionEntity) error {
+	if decision == nil || decision.ID == "" {
+		zap.L().Error("policy decision is invalid")
+		return domain.ErrAuthzInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if decision.CreatedAt == 0 {
+		decision.CreatedAt = time.Now().UnixMilli()
+	}
+	r.decisions = append(r.decisions, *decision)
+	if len(r.decisions) > memoryDecisionLimit {
+		r.decisions = append([]domain.PolicyDecisionEntity(nil), r.decisions[len(r.decisions)-memoryDecisionLimit:]...)
+	}
+	return nil
+}
+
+// Memory - ListDecisions returns the decisions matching the filters, newest first
+func (r *memoryPolicyDecisionRepository) ListDecisions(ctx context.Context, filters PolicyDecisionFilters) ([]*domain.PolicyDecisionEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	decisions := make([]*domain.PolicyDecisionEntity, 0)
+	for i := len(r.decisions) - 1; i >= 0; i-- {
+		decision := r.decisions[i]
+		if (filters.SubjectID != "" && decision.SubjectID != filters.SubjectID) ||
+			(filters.PolicyID != "" && decision.PolicyID != filters.PolicyID) ||
+			(filters.Allowed != nil && decision.Allowed != *filters.Allowed) {
+			continue
+		}
+		decisions = append(decisions, &decision)
+		if filters.Limit > 0 && len(decisions) == filters.Limit {
+			break
+		}
+	}
+	return decisions, nil
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/policy"
+	"go.uber.org/zap"
+)
+
+// In-memory implementation of policy repository, used for local development and tests.
+// Data is lost on restart and is not shared between instances.
+
+type memoryPolicyRepository struct {
+	mu       sync.RWMutex
+	policies map[string]domain.PolicyEntity
+	versions map[string]domain.PolicyVersionEntity
+}
+
+func NewMemoryPolicyRepository() PolicyRepository {
+	return &memoryPolicyRepository{
+		policies: make(map[string]domain.PolicyEntity),
+		versions: make(map[string]domain.PolicyVersionEntity),
+	}
+}
+
+// Memory - EnsureIndexes has nothing to create
+func (r *memoryPolicyRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Memory - CreatePolicy stores a new policy
+func (r *memoryPolicyRepository) CreatePolicy(ctx context.Context, entity *domain.PolicyEntity) (*domain.PolicyEntity, error) {
+	if entity == nil || entity.ID == "" || entity.Version == 0 {
+		zap.L().Error("policy is invalid")
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.policies[entity.ID]; ok {
+		return nil, domain.ErrPolicyAlreadyExists
+	}
+	entity.CreatedAt = time.Now().UnixMilli()
+	entity.UpdatedAt = time.Now().UnixMilli()
+	r.policies[entity.ID] = copyPolicyEntity(*entity)
+
+	return entity, nil
+}
+
+// Memory - FindPolicyByID finds a policy by its id
+func (r *memoryPolicyRepository) FindPolicyByID(ctx context.Context, id string) (*domain.PolicyEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entity, ok := r.policies[id]
+	if !ok {
+		return nil, domain.ErrPolicyNotFound
+	}
+	entity = copyPolicyEntity(entity)
+	return &entity, nil
+}
+
+// Memory - ListPolicies returns every policy, sorted by id
+func (r *memoryPolicyRepository) ListPolicies(ctx context.Context) ([]*domain.PolicyEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policies := make([]*domain.PolicyEntity, 0, len(r.policies))
+	for _, entity := range r.policies {
+		entity = copyPolicyEntity(entity)
+		policies = append(policies, &entity)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+	return policies, nil
+}
+
+// Memory - UpdatePolicy replaces the policy if nobody changed it since expectedVersion
+func (r *memoryPolicyRepository) UpdatePolicy(ctx context.Context, entity *domain.PolicyEntity, expectedVersion int) (*domain.PolicyEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.policies[entity.ID]
+	if !ok {
+		return nil, domain.ErrPolicyNotFound
+	}
+	if stored.Version != expectedVersion {
+		return nil, domain.ErrPolicyVersionConflict
+	}
+	entity.UpdatedAt = time.Now().UnixMilli()
+	r.policies[entity.ID] = copyPolicyEntity(*entity)
+
+	return entity, nil
+}
+
+// Memory - DeletePolicy deletes the policy if nobody changed it since expectedVersion
+func (r *memoryPolicyRepository) DeletePolicy(ctx context.Context, id string, expectedVersion int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.policies[id]
+	if !ok {
+		return domain.ErrPolicyNotFound
+	}
+	if stored.Version != expectedVersion {
+		return domain.ErrPolicyVersionConflict
+	}
+	delete(r.policies, id)
+	return nil
+}
+
+// Memory - CreatePolicyVersion appends a version to the history
+func (r *memoryPolicyRepository) CreatePolicyVersion(ctx context.Context, version *domain.PolicyVersionEntity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version.ID = domain.NewPolicyVersionID(version.PolicyID, version.Version)
+	if _, ok := r.versions[version.ID]; ok {
+		zap.L().Error("policy version already exists", zap.String("policy_id", version.PolicyID), zap.Int("version", version.Version))
+		return domain.ErrAuthzInternalServerError
+	}
+	version.CreatedAt = time.Now().UnixMilli()
+	r.versions[version.ID] = copyPolicyVersion(*version)
+	return nil
+}
+
+// Memory - LatestPolicyVersion returns the last version number of the policy, 0 when it never existed
+func (r *memoryPolicyRepository) LatestPolicyVersion(ctx context.Context, policyID string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	latest := 0
+	for _, version := range r.versions {
+		if version.PolicyID == policyID && version.Version > latest {
+			latest = version.Version
+		}
+	}
+	return latest, nil
+}
+
+// Memory - ListPolicyVersions returns the versions of the policy, newest first
+func (r *memoryPolicyRepository) ListPolicyVersions(ctx context.Context, policyID string) ([]*domain.PolicyVersionEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := make([]*domain.PolicyVersionEntity, 0)
+	for _, version := range r.versions {
+		if version.PolicyID == policyID {
+			version = copyPolicyVersion(version)
+			versions = append(versions, &version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version > versions[j].Version })
+	return versions, nil
+}
+
+// Memory - FindPolicyVersion finds a version of a policy
+func (r *memoryPolicyRepository) FindPolicyVersion(ctx context.Context, policyID string, version int) (*domain.PolicyVersionEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found, ok := r.versions[domain.NewPolicyVersionID(policyID, version)]
+	if !ok {
+		return nil, domain.ErrPolicyVersionNotFound
+	}
+	found = copyPolicyVersion(found)
+	return &found, nil
+}
+
+// copyPolicy copies the lists of the policy so callers cannot change the stored one.
+// Condition values are shared, nothing modifies them.
+func copyPolicy(p policy.Policy) policy.Policy {
+	p.Subjects = append([]string(nil), p.Subjects...)
+	p.Actions = append([]string(nil), p.Actions...)
+	p.Resources = append([]string(nil), p.Resources...)
+	p.Conditions = append([]policy.Condition(nil), p.Conditions...)
+	return p
+}
+
+func copyPolicyEntity(entity domain.PolicyEntity) domain.PolicyEntity {
+	entity.Policy = copyPolicy(entity.Policy)
+	return entity
+}
+
+func copyPolicyVersion(version domain.PolicyVersionEntity) domain.PolicyVersionEntity {
+	if version.Policy != nil {
+		copied := copyPolicy(*version.Policy)
+		version.Policy = &copied
+	}
+	return version
+}
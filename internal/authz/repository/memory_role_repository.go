@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"go.uber.org/zap"
+)
+
+// In-memory implementation of role repository, used for local development and tests.
+// Data is lost on restart and is not shared between instances.
+
+type memoryRoleRepository struct {
+	mu          sync.RWMutex
+	roles       map[string]domain.RoleEntity
+	assignments map[string]domain.RoleAssignmentEntity
+}
+
+func NewMemoryRoleRepository() RoleRepository {
+	return &memoryRoleRepository{
+		roles:       make(map[string]domain.RoleEntity),
+		assignments: make(map[string]domain.RoleAssignmentEntity),
+	}
+}
+
+// Memory - EnsureIndexes has nothing to create
+func (r *memoryRoleRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// Memory - CreateRole stores a new custom role
+func (r *memoryRoleRepository) CreateRole(ctx context.Context, role *domain.RoleEntity) (*domain.RoleEntity, error) {
+	if role == nil || role.ID == "" {
+		zap.L().Error("role is invalid")
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.roles[role.ID]; ok {
+		return nil, domain.ErrRoleAlreadyExists
+	}
+	role.CreatedAt = time.Now().UnixMilli()
+	role.UpdatedAt = time.Now().UnixMilli()
+	r.roles[role.ID] = copyRole(*role)
+
+	return role, nil
+}
+
+// Memory - FindRoleByID finds a custom role by its id
+func (r *memoryRoleRepository) FindRoleByID(ctx context.Context, id string) (*domain.RoleEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, domain.ErrRoleNotFound
+	}
+	role = copyRole(role)
+	return &role, nil
+}
+
+// Memory - FindRolesByIDs returns the custom roles that exist among ids, sorted by id
+func (r *memoryRoleRepository) FindRolesByIDs(ctx context.Context, ids []string) ([]*domain.RoleEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := make([]*domain.RoleEntity, 0, len(ids))
+	for _, id := range ids {
+		if role, ok := r.roles[id]; ok {
+			role = copyRole(role)
+			roles = append(roles, &role)
+		}
+	}
+	sortRoles(roles)
+	return roles, nil
+}
+
+// Memory - ListRoles returns every custom role, sorted by id
+func (r *memoryRoleRepository) ListRoles(ctx context.Context) ([]*domain.RoleEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := make([]*domain.RoleEntity, 0, len(r.roles))
+	for _, role := range r.roles {
+		role = copyRole(role)
+		roles = append(roles, &role)
+	}
+	sortRoles(roles)
+	return roles, nil
+}
+
+// Memory - UpdateRole replaces the name, description and permissions of the role
+func (r *memoryRoleRepository) UpdateRole(ctx context.Context, id, name, description string, permissions []shared.Permission) (*domain.RoleEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, domain.ErrRoleNotFound
+	}
+	role.Name = name
+	role.Description = description
+	role.Permissions = permissions
+	role.UpdatedAt = time.Now().UnixMilli()
+	r.roles[id] = copyRole(role)
+
+	role = copyRole(role)
+	return &role, nil
+}
+
+// Memory - DeleteRole deletes the role and its assignments
+func (r *memoryRoleRepository) DeleteRole(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.roles[id]; !ok {
+		return domain.ErrRoleNotFound
+	}
+	delete(r.roles, id)
+	for assignmentID, assignment := range r.assignments {
+		if assignment.RoleID == id {
+			delete(r.assignments, assignmentID)
+		}
+	}
+	return nil
+}
+
+// Memory - AssignRole stores the assignment once
+func (r *memoryRoleRepository) AssignRole(ctx context.Context, assignment *domain.RoleAssignmentEntity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	assignment.ID = domain.NewRoleAssignmentID(assignment.UserID, assignment.RoleID)
+	if _, ok := r.assignments[assignment.ID]; ok {
+		return nil
+	}
+	assignment.CreatedAt = time.Now().UnixMilli()
+	r.assignments[assignment.ID] = *assignment
+	return nil
+}
+
+// Memory - UnassignRole deletes the assignment of the role to the user
+func (r *memoryRoleRepository) UnassignRole(ctx context.Context, userID, roleID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := domain.NewRoleAssignmentID(userID, roleID)
+	if _, ok := r.assignments[id]; !ok {
+		return domain.ErrRoleAssignmentNotFound
+	}
+	delete(r.assignments, id)
+	return nil
+}
+
+// Memory - ListRoleIDsByUserID returns the ids of the custom roles assigned to the user
+func (r *memoryRoleRepository) ListRoleIDsByUserID(ctx context.Context, userID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roleIDs := make([]string, 0)
+	for _, assignment := range r.assignments {
+		if assignment.UserID == userID {
+			roleIDs = append(roleIDs, assignment.RoleID)
+		}
+	}
+	sort.Strings(roleIDs)
+	return roleIDs, nil
+}
+
+// copyRole copies the permissions so callers cannot change the stored role
+func copyRole(role domain.RoleEntity) domain.RoleEntity {
+	role.Permissions = append([]shared.Permission(nil), role.Permissions...)
+	return role
+}
+
+func sortRoles(roles []*domain.RoleEntity) {
+	sort.Slice(roles, func(i, j int) bool { return roles[i].ID < roles[j].ID })
+}
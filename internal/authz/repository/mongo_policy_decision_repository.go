@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// MongoDB implementation of policy decision repository
+
+const PolicyDecisionCollection = "policy_decisions"
+
+const (
+	// PolicyDecisionRetention is how long decisions are kept, a TTL index removes older ones
+	PolicyDecisionRetention = 90 * 24 * time.Hour
+	// createdDateField holds created_at as a date for the TTL index, which ignores unix milliseconds.
+	// It is only written by this repository and is not part of the entity.
+	createdDateField = "created_date"
+)
+
+type mongoPolicyDecisionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoPolicyDecisionRepository(database *mongo.Database) PolicyDecisionRepository {
+	return &mongoPolicyDecisionRepository{
+		collection: database.Collection(PolicyDecisionCollection),
+	}
+}
+
+// Mongo - EnsureIndexes creates the indexes on the subject and the policy of the decisions,
+// and the TTL index removing them after PolicyDecisionRetention. Older decisions get their date first.
+func (r *mongoPolicyDecisionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(ctx,
+		primitive.D{{Key: createdDateField, Value: primitive.D{{Key: "$exists", Value: false}}}},
+		mongo.Pipeline{{{Key: "$set", Value: primitive.D{{Key: createdDateField, Value: primitive.D{{Key: "$toDate", Value: "$created_at"}}}}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: primitive.D{{Key: "subject_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: primitive.D{{Key: "policy_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: primitive.D{{Key: "created_at", Value: -1}}},
+		{
+			Keys:    primitive.D{{Key: createdDateField, Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(PolicyDecisionRetention.Seconds())),
+		},
+	})
+	return err
+}
+
+// Mongo - CreateDecision stores a decision
+func (r *mongoPolicyDecisionRepository) CreateDecision(ctx context.Context, decision *domain.PolicyDecisionEntity) error {
+	if decision == nil || decision.ID == "" {
+		zap.L().Error("policy decision is invalid")
+		return domain.ErrAuthzInternalServerError
+	}
+
+	if decision.CreatedAt == 0 {
+		decision.CreatedAt = time.Now().UnixMilli()
+	}
+	document, err := withCreatedDate(decision)
+	if err == nil {
+		_, err = r.collection.InsertOne(ctx, document)
+	}
+	if err != nil {
+		zap.L().Error("error inserting policy decision", zap.Error(err))
+		return domain.ErrAuthzInternalServerError
+	}
+
+	return nil
+}
+
+// Mongo - ListDecisions returns the decisions matching the filters, newest first
+func (r *mongoPolicyDecisionRepository) ListDecisions(ctx context.Context, filters PolicyDecisionFilters) ([]*domain.PolicyDecisionEntity, error) {
+	filter := primitive.D{}
+	if filters.SubjectID != "" {
+		filter = append(filter, primitive.E{Key: "subject_id", Value: filters.SubjectID})
+	}
+	if filters.PolicyID != "" {
+		filter = append(filter, primitive.E{Key: "policy_id", Value: filters.PolicyID})
+	}
+	if filters.Allowed != nil {
+		filter = append(filter, primitive.E{Key: "allowed", Value: *filters.Allowed})
+	}
+
+	opts := options.Find().SetSort(primitive.D{{Key: "created_at", Value: -1}})
+	if filters.Limit > 0 {
+		opts.SetLimit(int64(filters.Limit))
+	}
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		zap.L().Error("error listing policy decisions", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+	defer cursor.Close(ctx)
+
+	decisions := make([]*domain.PolicyDecisionEntity, 0)
+	if err := cursor.All(ctx, &decisions); err != nil {
+		zap.L().Error("error decoding policy decisions", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return decisions, nil
+}
+
+// withCreatedDate returns the document of the decision with the creation date read by the TTL index
+func withCreatedDate(decision *domain.PolicyDecisionEntity) (primitive.D, error) {
+	data, err := bson.Marshal(decision)
+	if err != nil {
+		return nil, err
+	}
+	document := primitive.D{}
+	if err := bson.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return append(document, primitive.E{Key: createdDateField, Value: primitive.NewDateTimeFromTime(time.UnixMilli(decision.CreatedAt))}), nil
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// MongoDB implementation of policy repository
+
+const (
+	PolicyCollection        = "policies"
+	PolicyVersionCollection = "policy_versions"
+)
+
+type mongoPolicyRepository struct {
+	policies *mongo.Collection
+	versions *mongo.Collection
+}
+
+func NewMongoPolicyRepository(database *mongo.Database) PolicyRepository {
+	return &mongoPolicyRepository{
+		policies: database.Collection(PolicyCollection),
+		versions: database.Collection(PolicyVersionCollection),
+	}
+}
+
+// Mongo - EnsureIndexes creates the index on the policy of the versions
+func (r *mongoPolicyRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.versions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: primitive.D{{Key: "policy_id", Value: 1}, {Key: "version", Value: -1}},
+	})
+	return err
+}
+
+// Mongo - CreatePolicy stores a new policy
+func (r *mongoPolicyRepository) CreatePolicy(ctx context.Context, policy *domain.PolicyEntity) (*domain.PolicyEntity, error) {
+	if policy == nil || policy.ID == "" || policy.Version == 0 {
+		zap.L().Error("policy is invalid")
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	policy.CreatedAt = time.Now().UnixMilli()
+	policy.UpdatedAt = time.Now().UnixMilli()
+	_, err := r.policies.InsertOne(ctx, policy)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, domain.ErrPolicyAlreadyExists
+		}
+		zap.L().Error("error inserting policy", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return policy, nil
+}
+
+// Mongo - FindPolicyByID finds a policy by its id
+func (r *mongoPolicyRepository) FindPolicyByID(ctx context.Context, id string) (*domain.PolicyEntity, error) {
+	policy := &domain.PolicyEntity{}
+	err := r.policies.FindOne(ctx, primitive.D{{Key: "_id", Value: id}}).Decode(policy)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrPolicyNotFound
+		}
+		zap.L().Error("error finding policy", zap.String("policy_id", id), zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return policy, nil
+}
+
+// Mongo - ListPolicies returns every policy, sorted by id
+func (r *mongoPolicyRepository) ListPolicies(ctx context.Context) ([]*domain.PolicyEntity, error) {
+	cursor, err := r.policies.Find(ctx, primitive.D{}, options.Find().SetSort(primitive.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		zap.L().Error("error finding policies", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+	defer cursor.Close(ctx)
+
+	policies := make([]*domain.PolicyEntity, 0)
+	if err := cursor.All(ctx, &policies); err != nil {
+		zap.L().Error("error decoding policies", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return policies, nil
+}
+
+// Mongo - UpdatePolicy replaces the policy if nobody changed it since expectedVersion
+func (r *mongoPolicyRepository) UpdatePolicy(ctx context.Context, policy *domain.PolicyEntity, expectedVersion int) (*domain.PolicyEntity, error) {
+	policy.UpdatedAt = time.Now().UnixMilli()
+	result, err := r.policies.ReplaceOne(ctx,
+		primitive.D{{Key: "_id", Value: policy.ID}, {Key: "version", Value: expectedVersion}},
+		policy,
+	)
+	if err != nil {
+		zap.L().Error("error updating policy", zap.String("policy_id", policy.ID), zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+	if result.MatchedCount == 0 {
+		return nil, r.versionMismatch(ctx, policy.ID)
+	}
+
+	return policy, nil
+}
+
+// Mongo - DeletePolicy deletes the policy if nobody changed it since expectedVersion
+func (r *mongoPolicyRepository) DeletePolicy(ctx context.Context, id string, expectedVersion int) error {
+	result, err := r.policies.DeleteOne(ctx, primitive.D{{Key: "_id", Value: id}, {Key: "version", Value: expectedVersion}})
+	if err != nil {
+		zap.L().Error("error deleting policy", zap.String("policy_id", id), zap.Error(err))
+		return domain.ErrAuthzInternalServerError
+	}
+	if result.DeletedCount == 0 {
+		return r.versionMismatch(ctx, id)
+	}
+	return nil
+}
+
+// versionMismatch tells a missing policy apart from one changed by someone else
+func (r *mongoPolicyRepository) versionMismatch(ctx context.Context, id string) error {
+	if _, err := r.FindPolicyByID(ctx, id); err != nil {
+		return err
+	}
+	return domain.ErrPolicyVersionConflict
+}
+
+// Mongo - CreatePolicyVersion appends a version, the id is unique per policy and version
+func (r *mongoPolicyRepository) CreatePolicyVersion(ctx context.Context, version *domain.PolicyVersionEntity) error {
+	version.ID = domain.NewPolicyVersionID(version.PolicyID, version.Version)
+	version.CreatedAt = time.Now().UnixMilli()
+	_, err := r.versions.InsertOne(ctx, version)
+	if err != nil {
+		zap.L().Error("error inserting policy version", zap.String("policy_id", version.PolicyID), zap.Int("version", version.Version), zap.Error(err))
+		return domain.ErrAuthzInternalServerError
+	}
+	return nil
+}
+
+// Mongo - LatestPolicyVersion returns the last version number of the policy, 0 when it never existed
+func (r *mongoPolicyRepository) LatestPolicyVersion(ctx context.Context, policyID string) (int, error) {
+	version := &domain.PolicyVersionEntity{}
+	err := r.versions.FindOne(ctx,
+		primitive.D{{Key: "policy_id", Value: policyID}},
+		options.FindOne().SetSort(primitive.D{{Key: "version", Value: -1}}).SetProjection(primitive.D{{Key: "version", Value: 1}}),
+	).Decode(version)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		zap.L().Error("error finding policy version", zap.String("policy_id", policyID), zap.Error(err))
+		return 0, domain.ErrAuthzInternalServerError
+	}
+	return version.Version, nil
+}
+
+// Mongo - ListPolicyVersions returns the versions of the policy, newest first
+func (r *mongoPolicyRepository) ListPolicyVersions(ctx context.Context, policyID string) ([]*domain.PolicyVersionEntity, error) {
+	cursor, err := r.versions.Find(ctx,
+		primitive.D{{Key: "policy_id", Value: policyID}},
+		options.Find().SetSort(primitive.D{{Key: "version", Value: -1}}),
+	)
+	if err != nil {
+		zap.L().Error("error finding policy versions", zap.String("policy_id", policyID), zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+	defer cursor.Close(ctx)
+
+	versions := make([]*domain.PolicyVersionEntity, 0)
+	if err := cursor.All(ctx, &versions); err != nil {
+		zap.L().Error("error decoding policy versions", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return versions, nil
+}
+
+// Mongo - FindPolicyVersion finds a version of a policy
+func (r *mongoPolicyRepository) FindPolicyVersion(ctx context.Context, policyID string, version int) (*domain.PolicyVersionEntity, error) {
+	found := &domain.PolicyVersionEntity{}
+	err := r.versions.FindOne(ctx, primitive.D{{Key: "_id", Value: domain.NewPolicyVersionID(policyID, version)}}).Decode(found)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrPolicyVersionNotFound
+		}
+		zap.L().Error("error finding policy version", zap.String("policy_id", policyID), zap.Int("version", version), zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return found, nil
+}
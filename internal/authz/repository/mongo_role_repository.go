@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// MongoDB implementation of role repository
+
+const (
+	RoleCollection           = "roles"
+	RoleAssignmentCollection = "role_assignments"
+)
+
+type mongoRoleRepository struct {
+	roles       *mongo.Collection
+	assignments *mongo.Collection
+}
+
+func NewMongoRoleRepository(database *mongo.Database) RoleRepository {
+	return &mongoRoleRepository{
+		roles:       database.Collection(RoleCollection),
+		assignments: database.Collection(RoleAssignmentCollection),
+	}
+}
+
+// Mongo - EnsureIndexes creates the index on the user of the assignments
+func (r *mongoRoleRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.assignments.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: primitive.D{{Key: "user_id", Value: 1}}},
+		{Keys: primitive.D{{Key: "role_id", Value: 1}}},
+	})
+	return err
+}
+
+// Mongo - CreateRole stores a new custom role
+func (r *mongoRoleRepository) CreateRole(ctx context.Context, role *domain.RoleEntity) (*domain.RoleEntity, error) {
+	if role == nil || role.ID == "" {
+		zap.L().Error("role is invalid")
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	role.CreatedAt = time.Now().UnixMilli()
+	role.UpdatedAt = time.Now().UnixMilli()
+	_, err := r.roles.InsertOne(ctx, role)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, domain.ErrRoleAlreadyExists
+		}
+		zap.L().Error("error inserting role", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return role, nil
+}
+
+// Mongo - FindRoleByID finds a custom role by its id
+func (r *mongoRoleRepository) FindRoleByID(ctx context.Context, id string) (*domain.RoleEntity, error) {
+	role := &domain.RoleEntity{}
+	err := r.roles.FindOne(ctx, primitive.D{{Key: "_id", Value: id}}).Decode(role)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrRoleNotFound
+		}
+		zap.L().Error("error finding role", zap.String("role_id", id), zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return role, nil
+}
+
+// Mongo - FindRolesByIDs returns the custom roles that exist among ids, sorted by id
+func (r *mongoRoleRepository) FindRolesByIDs(ctx context.Context, ids []string) ([]*domain.RoleEntity, error) {
+	if len(ids) == 0 {
+		return []*domain.RoleEntity{}, nil
+	}
+	filter := primitive.D{{Key: "_id", Value: primitive.D{{Key: "$in", Value: ids}}}}
+	return r.findRoles(ctx, filter)
+}
+
+// Mongo - ListRoles returns every custom role, sorted by id
+func (r *mongoRoleRepository) ListRoles(ctx context.Context) ([]*domain.RoleEntity, error) {
+	return r.findRoles(ctx, primitive.D{})
+}
+
+func (r *mongoRoleRepository) findRoles(ctx context.Context, filter primitive.D) ([]*domain.RoleEntity, error) {
+	cursor, err := r.roles.Find(ctx, filter, options.Find().SetSort(primitive.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		zap.L().Error("error finding roles", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+	defer cursor.Close(ctx)
+
+	roles := make([]*domain.RoleEntity, 0)
+	if err := cursor.All(ctx, &roles); err != nil {
+		zap.L().Error("error decoding roles", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return roles, nil
+}
+
+// Mongo - UpdateRole replaces the name, description and permissions of the role
+func (r *mongoRoleRepository) UpdateRole(ctx context.Context, id, name, description string, permissions []shared.Permission) (*domain.RoleEntity, error) {
+	update := primitive.D{{Key: "$set", Value: primitive.D{
+		{Key: "name", Value: name},
+		{Key: "description", Value: description},
+		{Key: "permissions", Value: permissions},
+		{Key: "updated_at", Value: time.Now().UnixMilli()},
+	}}}
+	role := &domain.RoleEntity{}
+	err := r.roles.FindOneAndUpdate(ctx, primitive.D{{Key: "_id", Value: id}}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(role)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrRoleNotFound
+		}
+		zap.L().Error("error updating role", zap.String("role_id", id), zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	return role, nil
+}
+
+// Mongo - DeleteRole deletes the role, then its assignments
+func (r *mongoRoleRepository) DeleteRole(ctx context.Context, id string) error {
+	result, err := r.roles.DeleteOne(ctx, primitive.D{{Key: "_id", Value: id}})
+	if err != nil {
+		zap.L().Error("error deleting role", zap.String("role_id", id), zap.Error(err))
+		return domain.ErrAuthzInternalServerError
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrRoleNotFound
+	}
+
+	// Assignments left behind by a failure grant nothing, the role they point to is gone
+	if _, err := r.assignments.DeleteMany(ctx, primitive.D{{Key: "role_id", Value: id}}); err != nil {
+		zap.L().Error("error deleting role assignments", zap.String("role_id", id), zap.Error(err))
+		return domain.ErrAuthzInternalServerError
+	}
+	return nil
+}
+
+// Mongo - AssignRole stores the assignment once, the id is unique per user and role
+func (r *mongoRoleRepository) AssignRole(ctx context.Context, assignment *domain.RoleAssignmentEntity) error {
+	assignment.ID = domain.NewRoleAssignmentID(assignment.UserID, assignment.RoleID)
+	assignment.CreatedAt = time.Now().UnixMilli()
+	_, err := r.assignments.UpdateOne(ctx,
+		primitive.D{{Key: "_id", Value: assignment.ID}},
+		primitive.D{{Key: "$setOnInsert", Value: assignment}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		zap.L().Error("error assigning role", zap.String("user_id", assignment.UserID), zap.String("role_id", assignment.RoleID), zap.Error(err))
+		return domain.ErrAuthzInternalServerError
+	}
+	return nil
+}
+
+// Mongo - UnassignRole deletes the assignment of the role to the user
+func (r *mongoRoleRepository) UnassignRole(ctx context.Context, userID, roleID string) error {
+	result, err := r.assignments.DeleteOne(ctx, primitive.D{{Key: "_id", Value: domain.NewRoleAssignmentID(userID, roleID)}})
+	if err != nil {
+		zap.L().Error("error unassigning role", zap.String("user_id", userID), zap.String("role_id", roleID), zap.Error(err))
+		return domain.ErrAuthzInternalServerError
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrRoleAssignmentNotFound
+	}
+	return nil
+}
+
+// Mongo - ListRoleIDsByUserID returns the ids of the custom roles assigned to the user
+func (r *mongoRoleRepository) ListRoleIDsByUserID(ctx context.Context, userID string) ([]string, error) {
+	cursor, err := r.assignments.Find(ctx, primitive.D{{Key: "user_id", Value: userID}},
+		options.Find().SetProjection(primitive.D{{Key: "role_id", Value: 1}}).SetSort(primitive.D{{Key: "role_id", Value: 1}}),
+	)
+	if err != nil {
+		zap.L().Error("error finding role assignments", zap.String("user_id", userID), zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+	defer cursor.Close(ctx)
+
+	assignments := make([]*domain.RoleAssignmentEntity, 0)
+	if err := cursor.All(ctx, &assignments); err != nil {
+		zap.L().Error("error decoding role assignments", zap.Error(err))
+		return nil, domain.ErrAuthzInternalServerError
+	}
+
+	roleIDs := make([]string, 0, len(assignments))
+	for _, assignment := range assignments {
+		roleIDs = append(roleIDs, assignment.RoleID)
+	}
+	return roleIDs, nil
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+)
+
+// Policy decision repository interface, decisions are append only
+
+type PolicyDecisionRepository interface {
+	// EnsureIndexes creates the indexes used to list the decisions about a subject
+	EnsureIndexes(ctx context.Context) error
+	CreateDecision(ctx context.Context, decision *domain.PolicyDecisionEntity) error
+	// ListDecisions returns the decisions matching the filters, newest first
+	ListDecisions(ctx context.Context, filters PolicyDecisionFilters) ([]*domain.PolicyDecisionEntity, error)
+}
+
+// PolicyDecisionFilters selects decisions, empty fields match every decision
+type PolicyDecisionFilters struct {
+	SubjectID string
+	PolicyID  string
+	Allowed   *bool
+	Limit     int
+}
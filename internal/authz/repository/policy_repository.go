@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+)
+
+// Policy repository interface: the current policies and the append only history of their versions
+
+type PolicyRepository interface {
+	// EnsureIndexes creates the index used to list the versions of a policy
+	EnsureIndexes(ctx context.Context) error
+
+	// CreatePolicy returns domain.ErrPolicyAlreadyExists when the id is taken
+	CreatePolicy(ctx context.Context, policy *domain.PolicyEntity) (*domain.PolicyEntity, error)
+	FindPolicyByID(ctx context.Context, id string) (*domain.PolicyEntity, error)
+	// ListPolicies returns every policy, sorted by id
+	ListPolicies(ctx context.Context) ([]*domain.PolicyEntity, error)
+	// UpdatePolicy replaces the policy if its stored version is still expectedVersion,
+	// it returns domain.ErrPolicyVersionConflict when the policy changed in between
+	UpdatePolicy(ctx context.Context, policy *domain.PolicyEntity, expectedVersion int) (*domain.PolicyEntity, error)
+	// DeletePolicy deletes the policy if its stored version is still expectedVersion,
+	// it returns domain.ErrPolicyVersionConflict when the policy changed in between
+	DeletePolicy(ctx context.Context, id string, expectedVersion int) error
+
+	// CreatePolicyVersion appends a version to the history of a policy
+	CreatePolicyVersion(ctx context.Context, version *domain.PolicyVersionEntity) error
+	// LatestPolicyVersion returns the last version number of the policy, 0 when it never existed.
+	// A policy created again after its deletion continues the numbering.
+	LatestPolicyVersion(ctx context.Context, policyID string) (int, error)
+	// ListPolicyVersions returns the versions of the policy, newest first
+	ListPolicyVersions(ctx context.Context, policyID string) ([]*domain.PolicyVersionEntity, error)
+	FindPolicyVersion(ctx context.Context, policyID string, version int) (*domain.PolicyVersionEntity, error)
+}
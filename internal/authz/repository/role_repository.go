@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+// Role repository interface
+
+type RoleRepository interface {
+	// EnsureIndexes creates the index used to find the roles of a user, it runs on every request
+	EnsureIndexes(ctx context.Context) error
+
+	// CreateRole returns domain.ErrRoleAlreadyExists when the id is taken
+	CreateRole(ctx context.Context, role *domain.RoleEntity) (*domain.RoleEntity, error)
+	FindRoleByID(ctx context.Context, id string) (*domain.RoleEntity, error)
+	// FindRolesByIDs returns the roles that exist among ids, sorted by id
+	FindRolesByIDs(ctx context.Context, ids []string) ([]*domain.RoleEntity, error)
+	ListRoles(ctx context.Context) ([]*domain.RoleEntity, error)
+	// UpdateRole replaces the name, description and permissions of the role and returns the updated role
+	UpdateRole(ctx context.Context, id, name, description string, permissions []shared.Permission) (*domain.RoleEntity, error)
+	// DeleteRole deletes the role and its assignments
+	DeleteRole(ctx context.Context, id string) error
+
+	// AssignRole stores the assignment, assigning a role twice keeps the first assignment
+	AssignRole(ctx context.Context, assignment *domain.RoleAssignmentEntity) error
+	// UnassignRole returns domain.ErrRoleAssignmentNotFound when the role is not assigned to the user
+	UnassignRole(ctx context.Context, userID, roleID string) error
+	// ListRoleIDsByUserID returns the ids of the custom roles assigned to the user
+	ListRoleIDsByUserID(ctx context.Context, userID string) ([]string, error)
+}
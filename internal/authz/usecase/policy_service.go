@@ -0,0 +1,487 @@
+package usecase
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/policy"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Access policy use case: versioned attribute based policies, the decision API evaluating them and their enforcement on routes.
+// Policies are read on every check so a change applies to the next request, every decision is logged.
+
+// defaultPolicyDecisionLimit is the number of decisions listed when the query sets no limit
+const defaultPolicyDecisionLimit = 100
+
+// ResourceTypeUser is the resource type of the users, their stored attributes are loaded by the decision API
+const ResourceTypeUser = "user"
+
+// userAttributeKeyPattern keeps attribute keys usable in the dotted paths of the policies
+var userAttributeKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,50}$`)
+
+type PolicyService interface {
+	ListPolicies(ctx context.Context) ([]*domain.PolicyEntity, error)
+	GetPolicy(ctx context.Context, id string) (*domain.PolicyEntity, error)
+	CreatePolicy(ctx context.Context, principal *shared.Principal, data *dto.CreatePolicyRequest) (*domain.PolicyEntity, error)
+	// UpdatePolicy replaces the policy, data names the version it replaces
+	UpdatePolicy(ctx context.Context, principal *shared.Principal, id string, data *dto.UpdatePolicyRequest) (*domain.PolicyEntity, error)
+	DeletePolicy(ctx context.Context, principal *shared.Principal, id string, version int) error
+	// ListPolicyVersions returns the history of the policy, newest first
+	ListPolicyVersions(ctx context.Context, id string) ([]*domain.PolicyVersionEntity, error)
+	GetPolicyVersion(ctx context.Context, id string, version int) (*domain.PolicyVersionEntity, error)
+	// RestorePolicyVersion makes an old version current again, as a new version
+	RestorePolicyVersion(ctx context.Context, principal *shared.Principal, id string, version int, data *dto.RestorePolicyRequest) (*domain.PolicyEntity, error)
+
+	// SetUserAttributes replaces the attributes of a user the policies read, such as their organization
+	SetUserAttributes(ctx context.Context, principal *shared.Principal, userID string, attributes map[string]string) (map[string]string, error)
+
+	// Check decides whether the subject may perform the action on the resource and logs the decision.
+	// The subject is the caller, or the user named by the request when the caller holds authz:check.
+	Check(ctx context.Context, principal *shared.Principal, data *dto.CheckRequest, ip string) (*domain.PolicyDecisionEntity, error)
+	// Enforce decides whether the caller may perform the action on the resource of an API route and logs the decision.
+	// Routes no policy targets by action and resource type are left to their permission checks and are not logged.
+	Enforce(ctx context.Context, principal *shared.Principal, action, resourceType, resourceID, ip string) error
+	// ListDecisions returns the logged decisions matching the query, newest first
+	ListDecisions(ctx context.Context, query *dto.PolicyDecisionQuery) ([]*domain.PolicyDecisionEntity, error)
+}
+
+type policyService struct {
+	repo         repository.PolicyRepository
+	decisionRepo repository.PolicyDecisionRepository
+	roleService  RoleService
+	userService  userUseCase.UserService
+}
+
+func NewPolicyService(repo repository.PolicyRepository, decisionRepo repository.PolicyDecisionRepository, roleService RoleService, userService userUseCase.UserService) PolicyService {
+	return &policyService{
+		repo:         repo,
+		decisionRepo: decisionRepo,
+		roleService:  roleService,
+		userService:  userService,
+	}
+}
+
+func (service *policyService) ListPolicies(ctx context.Context) ([]*domain.PolicyEntity, error) {
+	return service.repo.ListPolicies(ctx)
+}
+
+func (service *policyService) GetPolicy(ctx context.Context, id string) (*domain.PolicyEntity, error) {
+	return service.repo.FindPolicyByID(ctx, id)
+}
+
+func (service *policyService) CreatePolicy(ctx context.Context, principal *shared.Principal, data *dto.CreatePolicyRequest) (*domain.PolicyEntity, error) {
+	document := policy.Policy{
+		ID:          data.ID,
+		Description: data.Description,
+		Effect:      data.Effect,
+		Subjects:    data.Subjects,
+		Actions:     data.Actions,
+		Resources:   data.Resources,
+		Conditions:  data.Conditions,
+	}
+	if err := document.Validate(); err != nil {
+		return nil, err
+	}
+	return service.createPolicy(ctx, principal, document)
+}
+
+func (service *policyService) UpdatePolicy(ctx context.Context, principal *shared.Principal, id string, data *dto.UpdatePolicyRequest) (*domain.PolicyEntity, error) {
+	document := policy.Policy{
+		ID:          id,
+		Description: data.Description,
+		Effect:      data.Effect,
+		Subjects:    data.Subjects,
+		Actions:     data.Actions,
+		Resources:   data.Resources,
+		Conditions:  data.Conditions,
+	}
+	if err := document.Validate(); err != nil {
+		return nil, err
+	}
+	return service.replacePolicy(ctx, principal, document, data.Version)
+}
+
+func (service *policyService) DeletePolicy(ctx context.Context, principal *shared.Principal, id string, version int) error {
+	if err := service.repo.DeletePolicy(ctx, id, version); err != nil {
+		return err
+	}
+	if err := service.recordVersion(ctx, principal, id, version+1, nil); err != nil {
+		return err
+	}
+	zap.L().Info("policy deleted", zap.String("policy_id", id), zap.Int("version", version), zap.String("deleted_by", principal.UserID))
+	return nil
+}
+
+func (service *policyService) ListPolicyVersions(ctx context.Context, id string) ([]*domain.PolicyVersionEntity, error) {
+	versions, err := service.repo.ListPolicyVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, domain.ErrPolicyNotFound
+	}
+	return versions, nil
+}
+
+func (service *policyService) GetPolicyVersion(ctx context.Context, id string, version int) (*domain.PolicyVersionEntity, error) {
+	return service.repo.FindPolicyVersion(ctx, id, version)
+}
+
+func (service *policyService) RestorePolicyVersion(ctx context.Context, principal *shared.Principal, id string, version int, data *dto.RestorePolicyRequest) (*domain.PolicyEntity, error) {
+	old, err := service.repo.FindPolicyVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if old.Deleted || old.Policy == nil {
+		return nil, domain.ErrPolicyVersionDeleted
+	}
+	// The policy format may have been tightened since the version was written
+	if err := old.Policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	// A deleted policy is created again, an existing one is replaced like an update
+	if data.Version == 0 {
+		return service.createPolicy(ctx, principal, *old.Policy)
+	}
+	return service.replacePolicy(ctx, principal, *old.Policy, data.Version)
+}
+
+// createPolicy stores a new policy, it continues the numbering of a policy deleted before
+func (service *policyService) createPolicy(ctx context.Context, principal *shared.Principal, document policy.Policy) (*domain.PolicyEntity, error) {
+	latest, err := service.repo.LatestPolicyVersion(ctx, document.ID)
+	if err != nil {
+		return nil, err
+	}
+	created, err := service.repo.CreatePolicy(ctx, &domain.PolicyEntity{
+		Policy:    document,
+		Version:   latest + 1,
+		CreatedBy: principal.UserID,
+		UpdatedBy: principal.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := service.recordVersion(ctx, principal, created.ID, created.Version, &created.Policy); err != nil {
+		return nil, err
+	}
+	zap.L().Info("policy created", zap.String("policy_id", created.ID), zap.Int("version", created.Version), zap.String("created_by", principal.UserID))
+	return created, nil
+}
+
+// replacePolicy stores the document as the next version, if the current one is still expectedVersion
+func (service *policyService) replacePolicy(ctx context.Context, principal *shared.Principal, document policy.Policy, expectedVersion int) (*domain.PolicyEntity, error) {
+	current, err := service.repo.FindPolicyByID(ctx, document.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != expectedVersion {
+		return nil, domain.ErrPolicyVersionConflict
+	}
+
+	updated, err := service.repo.UpdatePolicy(ctx, &domain.PolicyEntity{
+		Policy:    document,
+		Version:   expectedVersion + 1,
+		CreatedBy: current.CreatedBy,
+		UpdatedBy: principal.UserID,
+		CreatedAt: current.CreatedAt,
+	}, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.recordVersion(ctx, principal, updated.ID, updated.Version, &updated.Policy); err != nil {
+		return nil, err
+	}
+	zap.L().Info("policy updated", zap.String("policy_id", updated.ID), zap.Int("version", updated.Version), zap.String("updated_by", principal.UserID))
+	return updated, nil
+}
+
+// recordVersion appends a version to the history, a nil document records the deletion.
+// The policy itself is already changed, a failure here leaves a gap in the history that is logged.
+func (service *policyService) recordVersion(ctx context.Context, principal *shared.Principal, id string, version int, document *policy.Policy) error {
+	err := service.repo.CreatePolicyVersion(ctx, &domain.PolicyVersionEntity{
+		PolicyID:  id,
+		Version:   version,
+		Policy:    document,
+		Deleted:   document == nil,
+		ChangedBy: principal.UserID,
+	})
+	if err != nil {
+		zap.L().Error("policy version missing from the history", zap.String("policy_id", id), zap.Int("version", version))
+	}
+	return err
+}
+
+func (service *policyService) SetUserAttributes(ctx context.Context, principal *shared.Principal, userID string, attributes map[string]string) (map[string]string, error) {
+	for key := range attributes {
+		if !userAttributeKeyPattern.MatchString(key) {
+			return nil, domain.ErrUserAttributeInvalidName.WithField("attributes")
+		}
+	}
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, userDomain.ErrUserNotFound
+	}
+
+	user, err := service.userService.UpdateAUser(ctx, objectID, usersRepository.UserUpdates{Attributes: &attributes})
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("user attributes set",
+		zap.String("user_id", userID),
+		zap.Any("attributes", user.Attributes),
+		zap.String("set_by", principal.UserID),
+	)
+	if user.Attributes == nil {
+		return map[string]string{}, nil
+	}
+	return user.Attributes, nil
+}
+
+func (service *policyService) Check(ctx context.Context, principal *shared.Principal, data *dto.CheckRequest, ip string) (*domain.PolicyDecisionEntity, error) {
+	subjectID := principal.SubjectID()
+	var subject policy.Attributes
+	var err error
+	if data.SubjectID == "" || data.SubjectID == subjectID {
+		subject, err = service.principalAttributes(ctx, principal)
+	} else {
+		if !principal.HasPermission(shared.PermissionAuthzCheck) {
+			return nil, domain.ErrPolicyCheckForbidden
+		}
+		subjectID = data.SubjectID
+		subject, err = service.userAttributes(ctx, data.SubjectID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := service.resourceAttributes(ctx, &data.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, versions, err := service.listDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.decide(ctx, principal, subjectID, subject, data, resource, documents, versions, ip)
+}
+
+func (service *policyService) Enforce(ctx context.Context, principal *shared.Principal, action, resourceType, resourceID, ip string) error {
+	documents, versions, err := service.listDocuments(ctx)
+	if err != nil {
+		return err
+	}
+	if !policy.Targets(documents, action, resourceType) {
+		return nil
+	}
+
+	subject, err := service.principalAttributes(ctx, principal)
+	if err != nil {
+		return err
+	}
+	data := &dto.CheckRequest{Action: action, Resource: dto.CheckResource{Type: resourceType, ID: resourceID}}
+	resource, err := service.resourceAttributes(ctx, &data.Resource)
+	if err != nil {
+		return err
+	}
+	decision, err := service.decide(ctx, principal, principal.SubjectID(), subject, data, resource, documents, versions, ip)
+	if err != nil {
+		return err
+	}
+	if !decision.Allowed {
+		return domain.ErrPolicyDenied
+	}
+	return nil
+}
+
+// listDocuments returns the current policies and their versions by policy id
+func (service *policyService) listDocuments(ctx context.Context) ([]policy.Policy, map[string]int, error) {
+	policies, err := service.repo.ListPolicies(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	documents := make([]policy.Policy, 0, len(policies))
+	versions := make(map[string]int, len(policies))
+	for _, entity := range policies {
+		documents = append(documents, entity.Policy)
+		versions[entity.ID] = entity.Version
+	}
+	return documents, versions, nil
+}
+
+// decide evaluates the request with the policies, then logs and stores the decision
+func (service *policyService) decide(ctx context.Context, principal *shared.Principal, subjectID string, subject policy.Attributes, data *dto.CheckRequest,
+	resource policy.Attributes, documents []policy.Policy, versions map[string]int, ip string) (*domain.PolicyDecisionEntity, error) {
+	outcome := policy.Evaluate(documents, policy.Request{
+		Subject:  subject,
+		Action:   data.Action,
+		Resource: resource,
+		Environment: policy.Attributes{
+			"time":    time.Now().UTC(),
+			"ip":      ip,
+			"context": data.Context,
+		},
+	})
+
+	id, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, domain.ErrAuthzInternalServerError
+	}
+	decision := &domain.PolicyDecisionEntity{
+		ID:            id,
+		Allowed:       outcome.Allowed,
+		PolicyID:      outcome.PolicyID,
+		PolicyVersion: versions[outcome.PolicyID],
+		Reason:        outcome.Reason,
+		SubjectID:     subjectID,
+		Action:        data.Action,
+		ResourceType:  data.Resource.Type,
+		ResourceID:    data.Resource.ID,
+		CallerID:      principal.SubjectID(),
+		IP:            ip,
+	}
+	zap.L().Info("access decision",
+		zap.String("decision_id", decision.ID),
+		zap.Bool("allowed", decision.Allowed),
+		zap.String("policy_id", decision.PolicyID),
+		zap.String("subject_id", decision.SubjectID),
+		zap.String("action", decision.Action),
+		zap.String("resource_type", decision.ResourceType),
+		zap.String("resource_id", decision.ResourceID),
+	)
+	// The decision stands even when it cannot be stored, the application log above keeps it
+	if err := service.decisionRepo.CreateDecision(ctx, decision); err != nil {
+		zap.L().Error("access decision not stored", zap.String("decision_id", decision.ID), zap.Error(err))
+	}
+	return decision, nil
+}
+
+func (service *policyService) ListDecisions(ctx context.Context, query *dto.PolicyDecisionQuery) ([]*domain.PolicyDecisionEntity, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultPolicyDecisionLimit
+	}
+	return service.decisionRepo.ListDecisions(ctx, repository.PolicyDecisionFilters{
+		SubjectID: query.SubjectID,
+		PolicyID:  query.PolicyID,
+		Allowed:   query.Allowed,
+		Limit:     limit,
+	})
+}
+
+// principalAttributes describes the caller with the permissions of the credential in use, which may be narrower than those of the user
+func (service *policyService) principalAttributes(ctx context.Context, principal *shared.Principal) (policy.Attributes, error) {
+	subject := policy.Attributes{
+		"id":           principal.SubjectID(),
+		"type":         string(principal.Type),
+		"permissions":  principal.Permissions,
+		"impersonated": principal.IsImpersonated(),
+		"api_key":      principal.APIKeyID != "",
+	}
+	if principal.ClientID != "" {
+		subject["client_id"] = principal.ClientID
+		subject["scopes"] = principal.Scopes
+	}
+	if principal.IsServiceAccount() {
+		return subject, nil
+	}
+
+	user, err := service.findUser(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	customRoles, err := service.roleService.GetUserRoles(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range userEntityAttributes(user, customRoles.CustomRoles) {
+		subject[key] = value
+	}
+	return subject, nil
+}
+
+// userAttributes describes a user other than the caller, with everything their roles grant
+func (service *policyService) userAttributes(ctx context.Context, userID string) (policy.Attributes, error) {
+	user, err := service.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := service.roleService.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := userEntityAttributes(user, roles.CustomRoles)
+	subject["id"] = userID
+	subject["type"] = string(shared.PrincipalTypeUser)
+	subject["permissions"] = roles.Permissions
+	subject["impersonated"] = false
+	subject["api_key"] = false
+	return subject, nil
+}
+
+// resourceAttributes describes the resource, the stored attributes of a user replace the ones sent by the caller
+func (service *policyService) resourceAttributes(ctx context.Context, resource *dto.CheckResource) (policy.Attributes, error) {
+	attributes := policy.Attributes{
+		"type":       resource.Type,
+		"attributes": resource.Attributes,
+	}
+	if resource.ID != "" {
+		attributes["id"] = resource.ID
+	}
+	if resource.Type != ResourceTypeUser || resource.ID == "" {
+		return attributes, nil
+	}
+
+	user, err := service.findUser(ctx, resource.ID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := service.roleService.GetUserRoles(ctx, resource.ID)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range userEntityAttributes(user, roles.CustomRoles) {
+		attributes[key] = value
+	}
+	return attributes, nil
+}
+
+func (service *policyService) findUser(ctx context.Context, userID string) (*userDomain.UserEntity, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, userDomain.ErrUserNotFound
+	}
+	return service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &objectID})
+}
+
+// userEntityAttributes are the stored properties of a user the policies may read, never their personal details
+func userEntityAttributes(user *userDomain.UserEntity, customRoles []*domain.RoleEntity) policy.Attributes {
+	roleIDs := make([]string, 0, len(customRoles))
+	for _, role := range customRoles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	attributes := user.Attributes
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+	return policy.Attributes{
+		"role":           string(user.Role),
+		"custom_roles":   roleIDs,
+		"email_verified": user.EmailVerified,
+		"mfa_enabled":    user.MFAEnabled,
+		"attributes":     attributes,
+	}
+}
@@ -0,0 +1,344 @@
+package usecase
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/dto"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/authz/repository"
+	userDomain "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	usersRepository "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	userUseCase "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Role use case: custom roles bundling permissions, assigned to users on top of their built-in role.
+// Permissions are resolved on every request, so changing a role or an assignment applies at once.
+
+var roleIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{2,49}$`)
+
+var builtInRoleNames = map[shared.Role]string{
+	shared.RoleUser:       "User",
+	shared.RoleAdmin:      "Admin",
+	shared.RoleSuperAdmin: "Super admin",
+}
+
+type RoleService interface {
+	ListPermissions() []shared.PermissionDefinition
+
+	// CreateRole creates a custom role, the principal must hold every permission of the role
+	CreateRole(ctx context.Context, principal *shared.Principal, data *dto.CreateRoleRequest) (*domain.RoleEntity, error)
+	// ListRoles returns the built-in roles followed by the custom roles
+	ListRoles(ctx context.Context) ([]*domain.RoleEntity, error)
+	GetRole(ctx context.Context, id string) (*domain.RoleEntity, error)
+	// UpdateRole replaces the role, the principal must hold every permission of the role before and after the change
+	UpdateRole(ctx context.Context, principal *shared.Principal, id string, data *dto.UpdateRoleRequest) (*domain.RoleEntity, error)
+	// DeleteRole deletes the role and removes it from its users
+	DeleteRole(ctx context.Context, principal *shared.Principal, id string) error
+
+	GetUserRoles(ctx context.Context, userID string) (*domain.UserRolesEntity, error)
+	// AssignRole assigns a custom role to the user, the principal must hold every permission of the role
+	AssignRole(ctx context.Context, principal *shared.Principal, userID string, data *dto.AssignRoleRequest) (*domain.UserRolesEntity, error)
+	UnassignRole(ctx context.Context, principal *shared.Principal, userID, roleID string) (*domain.UserRolesEntity, error)
+
+	// ResolvePermissions expands the built-in role, the custom roles and the scopes of the principal into permissions.
+	// API keys and tokens issued to OAuth clients only keep the permissions their scopes cover,
+	// service accounts get the scopes that are permissions.
+	ResolvePermissions(ctx context.Context, principal *shared.Principal) ([]shared.Permission, error)
+}
+
+type roleService struct {
+	repo        repository.RoleRepository
+	userService userUseCase.UserService
+}
+
+func NewRoleService(repo repository.RoleRepository, userService userUseCase.UserService) RoleService {
+	return &roleService{repo: repo, userService: userService}
+}
+
+func (service *roleService) ListPermissions() []shared.PermissionDefinition {
+	return shared.PermissionCatalog
+}
+
+func (service *roleService) CreateRole(ctx context.Context, principal *shared.Principal, data *dto.CreateRoleRequest) (*domain.RoleEntity, error) {
+	if !roleIDPattern.MatchString(data.ID) {
+		return nil, domain.ErrRoleInvalidID
+	}
+	if shared.Role(data.ID).IsValid() {
+		return nil, domain.ErrRoleAlreadyExists
+	}
+	permissions, err := validatePermissions(principal, data.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := service.repo.CreateRole(ctx, &domain.RoleEntity{
+		ID:          data.ID,
+		Name:        data.Name,
+		Description: data.Description,
+		Permissions: permissions,
+		CreatedBy:   principal.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("role created",
+		zap.String("role_id", role.ID),
+		zap.Any("permissions", role.Permissions),
+		zap.String("created_by", principal.UserID),
+	)
+	return role, nil
+}
+
+func (service *roleService) ListRoles(ctx context.Context) ([]*domain.RoleEntity, error) {
+	customRoles, err := service.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roles := []*domain.RoleEntity{
+		builtInRole(shared.RoleUser),
+		builtInRole(shared.RoleAdmin),
+		builtInRole(shared.RoleSuperAdmin),
+	}
+	return append(roles, customRoles...), nil
+}
+
+func (service *roleService) GetRole(ctx context.Context, id string) (*domain.RoleEntity, error) {
+	if shared.Role(id).IsValid() {
+		return builtInRole(shared.Role(id)), nil
+	}
+	return service.repo.FindRoleByID(ctx, id)
+}
+
+func (service *roleService) UpdateRole(ctx context.Context, principal *shared.Principal, id string, data *dto.UpdateRoleRequest) (*domain.RoleEntity, error) {
+	if shared.Role(id).IsValid() {
+		return nil, domain.ErrRoleBuiltIn
+	}
+	role, err := service.repo.FindRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Taking a permission away is also a change to the users of the role
+	if !holdsAll(principal, role.Permissions) {
+		return nil, domain.ErrRolePermissionNotHeld
+	}
+	permissions, err := validatePermissions(principal, data.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err = service.repo.UpdateRole(ctx, id, data.Name, data.Description, permissions)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("role updated",
+		zap.String("role_id", role.ID),
+		zap.Any("permissions", role.Permissions),
+		zap.String("updated_by", principal.UserID),
+	)
+	return role, nil
+}
+
+func (service *roleService) DeleteRole(ctx context.Context, principal *shared.Principal, id string) error {
+	if shared.Role(id).IsValid() {
+		return domain.ErrRoleBuiltIn
+	}
+	role, err := service.repo.FindRoleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !holdsAll(principal, role.Permissions) {
+		return domain.ErrRolePermissionNotHeld
+	}
+
+	if err := service.repo.DeleteRole(ctx, id); err != nil {
+		return err
+	}
+	zap.L().Info("role deleted", zap.String("role_id", id), zap.String("deleted_by", principal.UserID))
+	return nil
+}
+
+func (service *roleService) GetUserRoles(ctx context.Context, userID string) (*domain.UserRolesEntity, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, userDomain.ErrUserNotFound
+	}
+	user, err := service.userService.FindAUserByFilters(ctx, usersRepository.UserFilters{ID: &objectID})
+	if err != nil {
+		return nil, err
+	}
+
+	customRoles, err := service.findUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UserRolesEntity{
+		UserID:      userID,
+		Role:        user.Role,
+		CustomRoles: customRoles,
+		Permissions: mergePermissions(user.Role.Permissions(), customRoles),
+	}, nil
+}
+
+func (service *roleService) AssignRole(ctx context.Context, principal *shared.Principal, userID string, data *dto.AssignRoleRequest) (*domain.UserRolesEntity, error) {
+	// Built-in roles are a property of the user, they are not assigned here
+	if shared.Role(data.RoleID).IsValid() {
+		return nil, domain.ErrRoleBuiltIn
+	}
+	role, err := service.repo.FindRoleByID(ctx, data.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if !holdsAll(principal, role.Permissions) {
+		return nil, domain.ErrRolePermissionNotHeld
+	}
+	// Fails when the user does not exist
+	if _, err := service.GetUserRoles(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	err = service.repo.AssignRole(ctx, &domain.RoleAssignmentEntity{
+		UserID:     userID,
+		RoleID:     role.ID,
+		AssignedBy: principal.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("role assigned",
+		zap.String("user_id", userID),
+		zap.String("role_id", role.ID),
+		zap.String("assigned_by", principal.UserID),
+	)
+	return service.GetUserRoles(ctx, userID)
+}
+
+func (service *roleService) UnassignRole(ctx context.Context, principal *shared.Principal, userID, roleID string) (*domain.UserRolesEntity, error) {
+	if shared.Role(roleID).IsValid() {
+		return nil, domain.ErrRoleBuiltIn
+	}
+	// The role may be gone already, its assignments then grant nothing and can be removed by anyone allowed to assign
+	role, err := service.repo.FindRoleByID(ctx, roleID)
+	if err != nil && err != domain.ErrRoleNotFound {
+		return nil, err
+	}
+	if role != nil && !holdsAll(principal, role.Permissions) {
+		return nil, domain.ErrRolePermissionNotHeld
+	}
+
+	if err := service.repo.UnassignRole(ctx, userID, roleID); err != nil {
+		return nil, err
+	}
+	zap.L().Info("role unassigned",
+		zap.String("user_id", userID),
+		zap.String("role_id", roleID),
+		zap.String("unassigned_by", principal.UserID),
+	)
+	return service.GetUserRoles(ctx, userID)
+}
+
+func (service *roleService) ResolvePermissions(ctx context.Context, principal *shared.Principal) ([]shared.Permission, error) {
+	if principal.IsServiceAccount() {
+		return scopePermissions(principal.Scopes), nil
+	}
+
+	customRoles, err := service.findUserRoles(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	permissions := mergePermissions(principal.Role.Permissions(), customRoles)
+
+	// Delegated credentials never grant more than their scopes
+	if principal.APIKeyID != "" || principal.ClientID != "" {
+		scoped := scopePermissions(principal.Scopes)
+		delegated := make([]shared.Permission, 0, len(scoped))
+		for _, permission := range permissions {
+			if shared.ContainsPermission(scoped, permission) {
+				delegated = append(delegated, permission)
+			}
+		}
+		permissions = delegated
+	}
+	return permissions, nil
+}
+
+func (service *roleService) findUserRoles(ctx context.Context, userID string) ([]*domain.RoleEntity, error) {
+	roleIDs, err := service.repo.ListRoleIDsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return service.repo.FindRolesByIDs(ctx, roleIDs)
+}
+
+func builtInRole(role shared.Role) *domain.RoleEntity {
+	return &domain.RoleEntity{
+		ID:          string(role),
+		Name:        builtInRoleNames[role],
+		Permissions: role.Permissions(),
+		BuiltIn:     true,
+	}
+}
+
+// validatePermissions checks the permissions are in the catalog and held by the principal, and removes duplicates
+func validatePermissions(principal *shared.Principal, permissions []shared.Permission) ([]shared.Permission, error) {
+	unique := make([]shared.Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		if !permission.IsValid() {
+			return nil, domain.ErrRoleInvalidPermission.WithField("permissions")
+		}
+		if !shared.ContainsPermission(unique, permission) {
+			unique = append(unique, permission)
+		}
+	}
+	if !holdsAll(principal, unique) {
+		return nil, domain.ErrRolePermissionNotHeld
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i] < unique[j] })
+	return unique, nil
+}
+
+// holdsAll reports whether the principal holds every permission, nobody grants more than they have
+func holdsAll(principal *shared.Principal, permissions []shared.Permission) bool {
+	for _, permission := range permissions {
+		if !principal.HasPermission(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergePermissions returns the permissions of the built-in role and the custom roles, in catalog order
+func mergePermissions(permissions []shared.Permission, customRoles []*domain.RoleEntity) []shared.Permission {
+	granted := map[shared.Permission]bool{}
+	for _, permission := range permissions {
+		granted[permission] = true
+	}
+	for _, role := range customRoles {
+		for _, permission := range role.Permissions {
+			granted[permission] = true
+		}
+	}
+
+	merged := make([]shared.Permission, 0, len(granted))
+	for _, permission := range shared.AllPermissions() {
+		if granted[permission] {
+			merged = append(merged, permission)
+		}
+	}
+	return merged
+}
+
+// scopePermissions returns the scopes that are permissions of the catalog
+func scopePermissions(scopes []string) []shared.Permission {
+	permissions := make([]shared.Permission, 0, len(scopes))
+	for _, scope := range scopes {
+		if permission := shared.Permission(scope); permission.IsValid() {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
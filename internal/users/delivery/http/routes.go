@@ -5,14 +5,21 @@ package http
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
 )
 
-func RegisterUserRoutes(router *gin.RouterGroup, userService usecase.UserService) {
-	userHandler := NewUserHandler(userService)
+// RegisterUserRoutes registers the user endpoints.
+// authMiddleware is provided by the auth module and protects the routes that need an authenticated user,
+// apiKeyAuth does the same and also accepts personal API keys granted the scope.
+// userPolicy is provided by the authz module and enforces the access policies on the user named by the path parameter.
+func RegisterUserRoutes(router *gin.RouterGroup, userService usecase.UserService, registrationService usecase.RegistrationService, verificationService usecase.EmailVerificationService, authMiddleware gin.HandlerFunc, apiKeyAuth func(scope string) gin.HandlerFunc, userPolicy func(action, idParam string) gin.HandlerFunc) {
+	userHandler := NewUserHandler(userService, registrationService, verificationService)
 	users := router.Group("/users")
 	{
 		users.POST("/register", userHandler.RegisterUser)
-		// users.GET("/me", NewUserHandler(userService).GetMe)
-		users.GET("/:id", userHandler.ViewUserInformation)
+		users.POST("/verify-email", userHandler.VerifyEmail)
+		users.POST("/verify-email/resend", userHandler.ResendVerificationEmail)
+		users.GET("/me", apiKeyAuth(shared.ScopeProfileRead), userHandler.GetMe)
+		users.GET("/:id", apiKeyAuth(shared.ScopeUsersRead), userPolicy(string(shared.PermissionUsersRead), "id"), userHandler.ViewUserInformation)
 	}
 }
@@ -10,6 +10,8 @@ import (
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/dto"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/usecase"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -18,23 +20,28 @@ import (
 // e.g., the usecase layer
 
 type UserHandler struct {
-	service usecase.UserService // Because usecase.UserService is an interface, no need to use pointer here
+	service             usecase.UserService // Because usecase.UserService is an interface, no need to use pointer here
+	registrationService usecase.RegistrationService
+	verificationService usecase.EmailVerificationService
 }
 
-func NewUserHandler(service usecase.UserService) *UserHandler {
-	return &UserHandler{service: service}
+func NewUserHandler(service usecase.UserService, registrationService usecase.RegistrationService, verificationService usecase.EmailVerificationService) *UserHandler {
+	return &UserHandler{service: service, registrationService: registrationService, verificationService: verificationService}
 }
 
 // RegisterUser handles POST /users/register request
 // @Summary Register a new user
-// @Description Register a new user with the given information
+// @Description Register a new user with the given information, a verification link is sent to the email.
+// @Description When existing emails are hidden, the response is 202 without user data whether or not the email was taken.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param body body dto.CreateUserRequest true "User information"
 // @Success 201 {object} map[string]interface{}
+// @Success 202 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
 // @Router /users/register [post]
 func (h *UserHandler) RegisterUser(c *gin.Context) {
 	// Using dto here
@@ -55,10 +62,11 @@ func (h *UserHandler) RegisterUser(c *gin.Context) {
 		Gender:   data.Gender, // already shared.Gender
 	}
 
-	user, err := h.service.CreateUser(c.Request.Context(), userEntity)
+	user, err := h.registrationService.Register(c.Request.Context(), userEntity)
 	if err != nil {
 		if ce, ok := err.(*utils.CustomError); ok {
-			utils.ErrorResponse(c, ce.HTTPStatus(), ce.Code(), ce.Error())
+			// Password policy errors name the field
+			utils.CustomErrorResponse(c, ce)
 			return
 		}
 		utils.ErrorResponse(c,
@@ -68,33 +76,158 @@ func (h *UserHandler) RegisterUser(c *gin.Context) {
 		return
 	}
 
+	// New and already registered emails must get the same response
+	if h.registrationService.HidesExistingEmails() {
+		utils.SuccessResponse(c, http.StatusAccepted, gin.H{
+			"message": "registration received, check your email to continue",
+		})
+		return
+	}
+
 	utils.SuccessResponse(c, http.StatusCreated, user)
 }
 
-// func (h *UserHandler) GetMe(c *gin.Context) {
-// 	// Get the user id from the context
-// 	userID := c.Param("id")
-// 	user, err := h.service.GetUserByID(c.Request.Context(), userID)
-// 	if err != nil {
-// 		if ce, ok := err.(*utils.CustomError); ok {
-// 			utils.ErrorResponse(c, ce.HTTPStatus(), ce.Code(), ce.Error())
-// 			return
-// 		}
-// 		utils.ErrorResponse(c, domain.ErrUserInternalServerError.HTTPStatus(), domain.ErrUserInternalServerError.Code(), domain.ErrUserInternalServerError.Error())
-// 		return
-// 	}
-// 	utils.SuccessResponse(c, http.StatusOK, user)
-// }
+// VerifyEmail handles POST /users/verify-email request
+// @Summary Verify email
+// @Description Mark the email of a user as verified with the token from the verification link
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body dto.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/verify-email [post]
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var data dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "USER_INVALID_INPUT", err.Error())
+		return
+	}
+
+	user, err := h.verificationService.VerifyEmail(c.Request.Context(), data.Token)
+	if err != nil {
+		if ce, ok := err.(*utils.CustomError); ok {
+			utils.ErrorResponse(c, ce.HTTPStatus(), ce.Code(), ce.Error())
+			return
+		}
+		utils.ErrorResponse(c,
+			domain.ErrUserInternalServerError.HTTPStatus(),
+			domain.ErrUserInternalServerError.Code(),
+			domain.ErrUserInternalServerError.Error())
+		return
+	}
+	// Clear password from response
+	user.Password = ""
+
+	utils.SuccessResponse(c, http.StatusOK, user)
+}
+
+// ResendVerificationEmail handles POST /users/verify-email/resend request
+// @Summary Resend verification email
+// @Description Send a new verification link, the response is the same whether the email is registered or not
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body dto.ResendVerificationEmailRequest true "User email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/verify-email/resend [post]
+func (h *UserHandler) ResendVerificationEmail(c *gin.Context) {
+	var data dto.ResendVerificationEmailRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "USER_INVALID_INPUT", err.Error())
+		return
+	}
+
+	if err := h.verificationService.ResendVerificationEmail(c.Request.Context(), data.Email, c.ClientIP()); err != nil {
+		if ce, ok := err.(*utils.CustomError); ok {
+			utils.CustomErrorResponse(c, ce)
+			return
+		}
+		utils.ErrorResponse(c,
+			domain.ErrUserInternalServerError.HTTPStatus(),
+			domain.ErrUserInternalServerError.Code(),
+			domain.ErrUserInternalServerError.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, gin.H{
+		"message": "if the email is registered and not verified yet, a verification link has been sent",
+	})
+}
+
+// GetMe handles GET /users/me request
+// @Summary View current user information
+// @Description View information of the authenticated user
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me [get]
+func (h *UserHandler) GetMe(c *gin.Context) {
+	// Get the authenticated user from the context
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c,
+			middleware.ErrUnauthenticated.HTTPStatus(),
+			middleware.ErrUnauthenticated.Code(),
+			middleware.ErrUnauthenticated.Error())
+		return
+	}
+	// Service accounts authenticate without being a user
+	if principal.IsServiceAccount() {
+		utils.ErrorResponse(c,
+			domain.ErrUserServiceAccount.HTTPStatus(),
+			domain.ErrUserServiceAccount.Code(),
+			domain.ErrUserServiceAccount.Error())
+		return
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(principal.UserID)
+	if err != nil {
+		utils.ErrorResponse(c,
+			domain.ErrUserInvalidID.HTTPStatus(),
+			domain.ErrUserInvalidID.Code(),
+			domain.ErrUserInvalidID.Error())
+		return
+	}
+
+	user, err := h.service.FindAUserByFilters(c.Request.Context(), repository.UserFilters{ID: &userObjectID})
+	if err != nil {
+		if ce, ok := err.(*utils.CustomError); ok {
+			utils.ErrorResponse(c, ce.HTTPStatus(), ce.Code(), ce.Error())
+			return
+		}
+		utils.ErrorResponse(c,
+			domain.ErrUserInternalServerError.HTTPStatus(),
+			domain.ErrUserInternalServerError.Code(),
+			domain.ErrUserInternalServerError.Error())
+		return
+	}
+	// Clear password from response
+	user.Password = ""
+
+	utils.SuccessResponse(c, http.StatusOK, user)
+}
 
 // ViewUserInformation handles GET /users/:id request
 // @Summary View user information
-// @Description View user information by user id
+// @Description View user information by user id. Users can only view themselves, admins and service accounts with the users:read scope can view anyone.
 // @Tags Users
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "User ID"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /users/{id} [get]
 func (h *UserHandler) ViewUserInformation(c *gin.Context) {
@@ -116,6 +249,16 @@ func (h *UserHandler) ViewUserInformation(c *gin.Context) {
 		return
 	}
 
+	// Users can only read themselves unless granted users:read, by a role or as a service account scope
+	principal, _ := middleware.GetPrincipal(c)
+	if !middleware.IsSelfOrHasPermission(principal, userObjectID.Hex(), shared.PermissionUsersRead) {
+		utils.ErrorResponse(c,
+			middleware.ErrPermissionDenied.HTTPStatus(),
+			middleware.ErrPermissionDenied.Code(),
+			middleware.ErrPermissionDenied.Error())
+		return
+	}
+
 	user, err := h.service.FindAUserByFilters(c.Request.Context(), repository.UserFilters{ID: &userObjectID})
 	if err != nil {
 		if ce, ok := err.(*utils.CustomError); ok {
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+func TestGetMeServiceAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// The service is never reached, a service account is turned away before any lookup
+	handler := NewUserHandler(nil, nil, nil)
+	router.GET("/users/me", func(c *gin.Context) {
+		c.Set(middleware.PrincipalKey, &shared.Principal{Type: shared.PrincipalTypeServiceAccount, ClientID: "sa-1"})
+	}, handler.GetMe)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if recorder.Code != http.StatusForbidden || body.Code != "USER_SERVICE_ACCOUNT" {
+		t.Fatalf("GET /users/me = %d %s, want 403 USER_SERVICE_ACCOUNT", recorder.Code, body.Code)
+	}
+}
@@ -19,11 +19,15 @@ var (
 	ErrUserInvalidInput    = utils.NewCustomError("USER_INVALID_INPUT", http.StatusBadRequest, "invalid input data")
 	ErrUserInvalidID       = utils.NewCustomError("USER_INVALID_ID", http.StatusBadRequest, "invalid user id")
 
+	// Email verification errors
+	ErrUserEmailVerificationTokenInvalid = utils.NewCustomError("USER_EMAIL_VERIFICATION_TOKEN_INVALID", http.StatusBadRequest, "invalid or expired email verification token")
+
 	// Conflict errors
 	ErrUserUsernameAlreadyExists = utils.NewCustomError("USER_USERNAME_ALREADY_EXISTS", http.StatusConflict, "username already exists")
 	ErrUserEmailAlreadyExists    = utils.NewCustomError("USER_EMAIL_ALREADY_EXISTS", http.StatusConflict, "email already exists")
 	ErrUserWrongPassword         = utils.NewCustomError("USER_WRONG_PASSWORD", http.StatusUnauthorized, "wrong password")
 	ErrUserNotHasRole            = utils.NewCustomError("USER_NOT_HAS_ROLE", http.StatusForbidden, "user does not have the required role")
+	ErrUserServiceAccount        = utils.NewCustomError("USER_SERVICE_ACCOUNT", http.StatusForbidden, "service accounts have no user profile")
 
 	// Not found errors
 	ErrUserNotFound = utils.NewCustomError("USER_NOT_FOUND", http.StatusNotFound, "user not found")
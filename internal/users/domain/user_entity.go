@@ -3,9 +3,10 @@ package domain
 // User domain entity
 
 import (
+	"strings"
 	"time"
 
-	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -21,6 +22,29 @@ type UserEntity struct {
 	Gender    shared.Gender      `bson:"gender,omitempty" json:"gender,omitempty"`
 	CreatedAt int64              `bson:"created_at,omitempty" json:"created_at,omitempty"`
 	UpdatedAt int64              `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+
+	// Email verification
+	EmailVerified bool  `bson:"email_verified" json:"email_verified"`
+	VerifiedAt    int64 `bson:"verified_at,omitempty" json:"verified_at,omitempty"`
+
+	// Multi-factor authentication
+	MFAEnabled       bool     `bson:"mfa_enabled" json:"mfa_enabled"`
+	TOTPSecret       string   `bson:"totp_secret,omitempty" json:"-"`         // Encrypted, set at enrollment before MFA is enabled
+	TOTPLastUsedStep int64    `bson:"totp_last_used_step,omitempty" json:"-"` // Last accepted time step, prevents code replays
+	RecoveryCodes    []string `bson:"recovery_codes,omitempty" json:"-"`      // SHA-256 hashes of the unused recovery codes
+
+	// Attributes read by the access policies, such as the organization of the user, set by admins
+	Attributes map[string]string `bson:"attributes,omitempty" json:"attributes,omitempty"`
+}
+
+// NormalizeUsername returns the stored form of a username, usernames are unique regardless of case
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// NormalizeEmail returns the stored form of an email, emails are unique regardless of case
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 // NewUserEntity is a constructor for the UserEntity struct
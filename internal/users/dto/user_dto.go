@@ -1,15 +1,23 @@
 package dto
 
-import "github.com/luannguyenthanh-ba-dev/go-ai-security/internal/shared"
+import "github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
 
 // User DTOs for request/response
 
 type CreateUserRequest struct {
-	Username string        `json:"username" binding:"required,min=5,max=20"`                     // required, min 5 characters, max 20 characters
+	Username string        `json:"username" binding:"required,min=5,max=20,excludes=@"`          // required, min 5 characters, max 20 characters, no "@" so it cannot be mistaken for an email at login
 	Email    string        `json:"email" binding:"required,email"`                               // required, email format
-	Password string        `json:"password" binding:"required,min=6,max=20"`                     // required, min 6 characters, max 20 characters
+	Password string        `json:"password" binding:"required"`                                  // required, checked against the password policy
 	Name     string        `json:"name" binding:"required,min=3,max=50"`                         // required, min 3 characters, max 50 characters
 	Phone    string        `json:"phone" binding:"omitempty,min=10,max=15" example:"0912345678"` // optional, min 10 characters, max 15 characters
 	Address  string        `json:"address" binding:"omitempty,max=255"`                          // optional, max 255 characters
 	Gender   shared.Gender `json:"gender" binding:"omitempty,oneof=1 2 3"`                       // optional, one of 1, 2, 3
 }
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"` // required, token from the verification link
+}
+
+type ResendVerificationEmailRequest struct {
+	Email string `json:"email" binding:"required,email"` // required, email format
+}
@@ -2,17 +2,23 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
-	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/shared"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
 // MongoDB implementation of user repository
 
+// caseInsensitiveCollation compares strings ignoring case, the username and email indexes use it
+var caseInsensitiveCollation = &options.Collation{Locale: "en", Strength: 2}
+
 type mongoUserRepository struct {
 	collection *mongo.Collection
 }
@@ -21,6 +27,26 @@ func NewMongoUserRepository(collection *mongo.Collection) UserRepository {
 	return &mongoUserRepository{collection: collection}
 }
 
+// Mongo - EnsureIndexes creates the unique, case-insensitive indexes on username and email
+func (r *mongoUserRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    primitive.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetName("username_ci_unique").SetUnique(true).SetCollation(caseInsensitiveCollation),
+		},
+		{
+			Keys:    primitive.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName("email_ci_unique").SetUnique(true).SetCollation(caseInsensitiveCollation),
+		},
+	})
+	if err != nil {
+		zap.L().Error("error creating user indexes", zap.Error(err))
+		return domain.ErrUserInternalServerError
+	}
+
+	return nil
+}
+
 // Mongo - CreateUser creates a new user in the database and returns the created user
 func (r *mongoUserRepository) CreateUser(ctx context.Context, user *domain.UserEntity) (*domain.UserEntity, error) {
 	if user == nil {
@@ -43,6 +69,13 @@ func (r *mongoUserRepository) CreateUser(ctx context.Context, user *domain.UserE
 
 	_, err := r.collection.InsertOne(ctx, user)
 	if err != nil {
+		// The unique indexes catch registrations racing past the checks of the use case
+		if mongo.IsDuplicateKeyError(err) {
+			if strings.Contains(err.Error(), "email_ci_unique") {
+				return nil, domain.ErrUserEmailAlreadyExists
+			}
+			return nil, domain.ErrUserUsernameAlreadyExists
+		}
 		zap.L().Error("error inserting user", zap.Error(err))
 		// Wrap infra error before returning to usecase
 		return nil, domain.ErrUserInternalServerError
@@ -82,13 +115,105 @@ func (r *mongoUserRepository) FindAUserByFilters(ctx context.Context, filters Us
 	// 	filter = append(filter, primitive.E{Key: "created_at", Value: primitive.E{Key: "$lte", Value: *filters.ToTime}})
 	// }
 
-	// Find one user by filters
+	// Find one user by filters, with the collation of the indexes so username and email lookups ignore case
 	user := &domain.UserEntity{} // Use pointer because we want to return the user by reference
-	err := r.collection.FindOne(ctx, filter).Decode(user)
+	err := r.collection.FindOne(ctx, filter, options.FindOne().SetCollation(caseInsensitiveCollation)).Decode(user)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrUserNotFound
+		}
 		zap.L().Error("error finding user by filters", zap.Error(err))
-		return nil, domain.ErrUserNotFound
+		return nil, domain.ErrUserInternalServerError
 	}
 
 	return user, nil
 }
+
+// Mongo - UpdateAUser updates the provided fields of a user and returns the updated user
+func (r *mongoUserRepository) UpdateAUser(ctx context.Context, id primitive.ObjectID, updates UserUpdates) (*domain.UserEntity, error) {
+	set := primitive.D{{Key: "updated_at", Value: time.Now().UnixMilli()}}
+
+	if updates.Password != nil {
+		set = append(set, primitive.E{Key: "password", Value: *updates.Password})
+	}
+	if updates.EmailVerified != nil {
+		set = append(set, primitive.E{Key: "email_verified", Value: *updates.EmailVerified})
+	}
+	if updates.VerifiedAt != nil {
+		set = append(set, primitive.E{Key: "verified_at", Value: *updates.VerifiedAt})
+	}
+	if updates.MFAEnabled != nil {
+		set = append(set, primitive.E{Key: "mfa_enabled", Value: *updates.MFAEnabled})
+	}
+	if updates.TOTPSecret != nil {
+		set = append(set, primitive.E{Key: "totp_secret", Value: *updates.TOTPSecret})
+	}
+	if updates.TOTPLastUsedStep != nil {
+		set = append(set, primitive.E{Key: "totp_last_used_step", Value: *updates.TOTPLastUsedStep})
+	}
+	if updates.RecoveryCodes != nil {
+		set = append(set, primitive.E{Key: "recovery_codes", Value: *updates.RecoveryCodes})
+	}
+	if updates.Attributes != nil {
+		set = append(set, primitive.E{Key: "attributes", Value: *updates.Attributes})
+	}
+
+	user := &domain.UserEntity{}
+	err := r.collection.FindOneAndUpdate(ctx,
+		primitive.D{{Key: "_id", Value: id}},
+		primitive.D{{Key: "$set", Value: set}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrUserNotFound
+		}
+		zap.L().Error("error updating user", zap.String("user_id", id.Hex()), zap.Error(err))
+		return nil, domain.ErrUserInternalServerError
+	}
+
+	return user, nil
+}
+
+// Mongo - ConsumeTOTPStep atomically moves the last used TOTP step forward
+func (r *mongoUserRepository) ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	filter := primitive.D{
+		{Key: "_id", Value: id},
+		{Key: "$or", Value: primitive.A{
+			primitive.D{{Key: "totp_last_used_step", Value: primitive.D{{Key: "$lt", Value: step}}}},
+			primitive.D{{Key: "totp_last_used_step", Value: primitive.D{{Key: "$exists", Value: false}}}},
+		}},
+	}
+	update := primitive.D{{Key: "$set", Value: primitive.D{
+		{Key: "totp_last_used_step", Value: step},
+		{Key: "updated_at", Value: time.Now().UnixMilli()},
+	}}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		zap.L().Error("error consuming totp step", zap.String("user_id", id.Hex()), zap.Error(err))
+		return false, domain.ErrUserInternalServerError
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// Mongo - ConsumeRecoveryCode atomically removes a recovery code hash
+func (r *mongoUserRepository) ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	filter := primitive.D{
+		{Key: "_id", Value: id},
+		{Key: "recovery_codes", Value: codeHash},
+	}
+	update := primitive.D{
+		{Key: "$pull", Value: primitive.D{{Key: "recovery_codes", Value: codeHash}}},
+		{Key: "$set", Value: primitive.D{{Key: "updated_at", Value: time.Now().UnixMilli()}}},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		zap.L().Error("error consuming recovery code", zap.String("user_id", id.Hex()), zap.Error(err))
+		return false, domain.ErrUserInternalServerError
+	}
+
+	return result.ModifiedCount == 1, nil
+}
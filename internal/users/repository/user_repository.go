@@ -4,11 +4,20 @@ import (
 	"context"
 
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // User repository interface
 
 type UserRepository interface {
+	// EnsureIndexes creates the unique, case-insensitive indexes on username and email.
+	// It fails while existing users collide, see cmd/normalizeusers.
+	EnsureIndexes(ctx context.Context) error
 	CreateUser(ctx context.Context, user *domain.UserEntity) (*domain.UserEntity, error)
 	FindAUserByFilters(ctx context.Context, filters UserFilters) (*domain.UserEntity, error)
+	UpdateAUser(ctx context.Context, id primitive.ObjectID, updates UserUpdates) (*domain.UserEntity, error)
+	// ConsumeTOTPStep records step as the last used TOTP step, false if it is not newer than the stored one
+	ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error)
+	// ConsumeRecoveryCode removes the recovery code hash, false if the user does not have it
+	ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error)
 }
@@ -0,0 +1,14 @@
+package repository
+
+// User updates, only the non-nil fields are written
+
+type UserUpdates struct {
+	Password         *string // Already hashed
+	EmailVerified    *bool
+	VerifiedAt       *int64
+	MFAEnabled       *bool
+	TOTPSecret       *string
+	TOTPLastUsedStep *int64
+	RecoveryCodes    *[]string
+	Attributes       *map[string]string
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/mailer"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Email verification use case: signed verification links sent by email
+
+const (
+	emailVerificationTokenExpiresIn = 24 * time.Hour
+	emailVerificationMailTimeout    = 15 * time.Second
+)
+
+type EmailVerificationService interface {
+	// SendVerificationEmail emails a signed verification link to the user, in the background
+	SendVerificationEmail(ctx context.Context, user *domain.UserEntity) error
+	// ResendVerificationEmail sends a new link when the email belongs to an unverified user, requests are limited
+	// per email and client IP. It returns nil in every other case, so the response does not reveal which emails are registered.
+	ResendVerificationEmail(ctx context.Context, email, clientIP string) error
+	// VerifyEmail checks the token and marks the email of the user as verified
+	VerifyEmail(ctx context.Context, token string) (*domain.UserEntity, error)
+}
+
+// EmailRequestLimiter rejects the emails requested too often for an address or from a client IP.
+// It is implemented by the auth module.
+type EmailRequestLimiter interface {
+	Allow(ctx context.Context, email, clientIP string) error
+}
+
+type emailVerificationService struct {
+	userService     UserService
+	limiter         EmailRequestLimiter
+	mailer          mailer.Mailer
+	secret          string
+	verificationURL string
+}
+
+// NewEmailVerificationService creates the email verification service.
+// secret signs the links, verificationURL is the client page that reads the token query parameter and calls POST /users/verify-email.
+func NewEmailVerificationService(userService UserService, limiter EmailRequestLimiter, mailer mailer.Mailer, secret, verificationURL string) EmailVerificationService {
+	return &emailVerificationService{
+		userService:     userService,
+		limiter:         limiter,
+		mailer:          mailer,
+		secret:          secret,
+		verificationURL: verificationURL,
+	}
+}
+
+func (service *emailVerificationService) SendVerificationEmail(ctx context.Context, user *domain.UserEntity) error {
+	if user.EmailVerified {
+		return nil
+	}
+
+	// The token is bound to the email so a link stops working when the email changes
+	expiresAt := time.Now().Add(emailVerificationTokenExpiresIn).Unix()
+	token := utils.SignValue(user.ID.Hex()+":"+strconv.FormatInt(expiresAt, 10)+":"+utils.HashToken(user.Email), service.secret)
+	message := service.buildVerificationMessage(user.Email, token)
+
+	go func(userID string) {
+		sendCtx, cancel := context.WithTimeout(context.Background(), emailVerificationMailTimeout)
+		defer cancel()
+		if err := service.mailer.Send(sendCtx, message); err != nil {
+			zap.L().Error("error sending verification email", zap.String("user_id", userID), zap.Error(err))
+		}
+	}(user.ID.Hex())
+
+	return nil
+}
+
+func (service *emailVerificationService) ResendVerificationEmail(ctx context.Context, email, clientIP string) error {
+	if err := service.limiter.Allow(ctx, email, clientIP); err != nil {
+		return err
+	}
+
+	// The user is looked up in the background so known and unknown emails answer in the same time
+	go func() {
+		findCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), emailVerificationMailTimeout)
+		defer cancel()
+		user, err := service.userService.FindAUserByFilters(findCtx, repository.UserFilters{Email: &email})
+		if err != nil {
+			if err != domain.ErrUserNotFound {
+				zap.L().Error("error finding the user of a verification email", zap.Error(err))
+			}
+			return
+		}
+		_ = service.SendVerificationEmail(findCtx, user)
+	}()
+
+	return nil
+}
+
+func (service *emailVerificationService) VerifyEmail(ctx context.Context, token string) (*domain.UserEntity, error) {
+	value, ok := utils.VerifySignedValue(token, service.secret)
+	if !ok {
+		return nil, domain.ErrUserEmailVerificationTokenInvalid
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return nil, domain.ErrUserEmailVerificationTokenInvalid
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, domain.ErrUserEmailVerificationTokenInvalid
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(parts[0])
+	if err != nil {
+		return nil, domain.ErrUserEmailVerificationTokenInvalid
+	}
+
+	user, err := service.userService.FindAUserByFilters(ctx, repository.UserFilters{ID: &userObjectID})
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return nil, domain.ErrUserEmailVerificationTokenInvalid
+		}
+		return nil, err
+	}
+	if utils.HashToken(user.Email) != parts[2] {
+		return nil, domain.ErrUserEmailVerificationTokenInvalid
+	}
+	// Verifying twice is harmless, keep the first verification time
+	if user.EmailVerified {
+		return user, nil
+	}
+
+	verified := true
+	verifiedAt := time.Now().UnixMilli()
+	user, err = service.userService.UpdateAUser(ctx, userObjectID, repository.UserUpdates{
+		EmailVerified: &verified,
+		VerifiedAt:    &verifiedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("user email verified", zap.String("user_id", user.ID.Hex()))
+
+	return user, nil
+}
+
+func (service *emailVerificationService) buildVerificationMessage(email, token string) *mailer.Message {
+	link := service.verificationURL + "?token=" + url.QueryEscape(token)
+	hours := int(emailVerificationTokenExpiresIn.Hours())
+
+	return &mailer.Message{
+		To:      []string{email},
+		Subject: "Verify your email",
+		Text: fmt.Sprintf("Welcome! Please confirm your email address.\n\n"+
+			"Open the link below to verify it, it expires in %d hours:\n%s", hours, link),
+		HTML: fmt.Sprintf("<p>Welcome! Please confirm your email address.</p>"+
+			"<p><a href=\"%s\">Verify my email</a>, the link expires in %d hours.</p>", link, hours),
+	}
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/mailer"
+	"go.uber.org/zap"
+)
+
+// Registration use case: account creation followed by the verification email
+
+const registrationMailTimeout = 15 * time.Second
+
+type RegistrationService interface {
+	// Register creates the user and sends the verification email.
+	// When existing emails are hidden, it returns a nil user and no error if the email is already registered,
+	// and the owner of the email is notified instead.
+	Register(ctx context.Context, user *domain.UserEntity) (*domain.UserEntity, error)
+	// HidesExistingEmails reports whether registration responses must not reveal if the email was taken
+	HidesExistingEmails() bool
+}
+
+type registrationService struct {
+	userService         UserService
+	verificationService EmailVerificationService
+	mailer              mailer.Mailer
+	hideExistingEmails  bool
+}
+
+// NewRegistrationService creates the registration service.
+// hideExistingEmails enables the anti-enumeration mode where a taken email is reported to its owner by mail only.
+func NewRegistrationService(userService UserService, verificationService EmailVerificationService, mailer mailer.Mailer, hideExistingEmails bool) RegistrationService {
+	return &registrationService{
+		userService:         userService,
+		verificationService: verificationService,
+		mailer:              mailer,
+		hideExistingEmails:  hideExistingEmails,
+	}
+}
+
+func (service *registrationService) Register(ctx context.Context, user *domain.UserEntity) (*domain.UserEntity, error) {
+	email := user.Email
+	password := user.Password
+
+	// Checked first, a rejected password must not reveal whether the email was taken
+	if err := service.userService.ValidatePassword(user, password); err != nil {
+		return nil, err
+	}
+
+	created, err := service.userService.CreateUser(ctx, user)
+	if err != nil {
+		if err == domain.ErrUserEmailAlreadyExists && service.hideExistingEmails {
+			// A new account pays for hashing the password, spend the same time here
+			if _, err := service.userService.ComparePassword(ctx, nil, password); err != nil {
+				return nil, err
+			}
+			service.notifyExistingAccount(email)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// The account exists even if the email cannot be sent, the user can ask for a new link
+	if err := service.verificationService.SendVerificationEmail(ctx, created); err != nil {
+		zap.L().Error("error sending verification email", zap.String("user_id", created.ID.Hex()), zap.Error(err))
+	}
+
+	return created, nil
+}
+
+func (service *registrationService) HidesExistingEmails() bool {
+	return service.hideExistingEmails
+}
+
+// notifyExistingAccount tells the owner of the email that someone tried to register with it, in the background
+func (service *registrationService) notifyExistingAccount(email string) {
+	message := &mailer.Message{
+		To:      []string{email},
+		Subject: "Someone tried to register with your email",
+		Text: "Someone tried to create a new account with this email address, but you already have an account.\n\n" +
+			"If it was you, log in with your existing account or reset your password.\n" +
+			"If it was not you, you can ignore this email.",
+		HTML: "<p>Someone tried to create a new account with this email address, but you already have an account.</p>" +
+			"<p>If it was you, log in with your existing account or reset your password.</p>" +
+			"<p>If it was not you, you can ignore this email.</p>",
+	}
+
+	go func() {
+		sendCtx, cancel := context.WithTimeout(context.Background(), registrationMailTimeout)
+		defer cancel()
+		if err := service.mailer.Send(sendCtx, message); err != nil {
+			zap.L().Error("error sending existing account email", zap.Error(err))
+		}
+	}()
+}
@@ -5,7 +5,10 @@ import (
 
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordhash"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordpolicy"
 	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -15,26 +18,46 @@ import (
 type UserService interface {
 	CreateUser(ctx context.Context, user *domain.UserEntity) (*domain.UserEntity, error)
 	FindAUserByFilters(ctx context.Context, filters repository.UserFilters) (*domain.UserEntity, error)
+	UpdateAUser(ctx context.Context, id primitive.ObjectID, updates repository.UserUpdates) (*domain.UserEntity, error)
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error
+	// ValidatePassword checks a password chosen by the user against the password policy,
+	// the error names the "password" field
+	ValidatePassword(user *domain.UserEntity, password string) error
+	// ComparePassword checks the password of a user, a nil user takes as long and fails.
+	// It returns utils.ErrWorkerPoolSaturated when too many passwords are being hashed.
+	ComparePassword(ctx context.Context, user *domain.UserEntity, password string) (bool, error)
+	// PasswordNeedsRehash reports whether the stored hash uses an outdated algorithm or cost
+	PasswordNeedsRehash(user *domain.UserEntity) bool
+	ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error)
+	ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error)
 }
 
 type userService struct {
-	repo       repository.UserRepository
-	saltRounds int
+	repo           repository.UserRepository
+	hasher         *passwordhash.Hasher
+	hashingPool    *utils.WorkerPool
+	passwordPolicy *passwordpolicy.Policy
 }
 
-func NewUserService(r repository.UserRepository, saltRounds int) UserService {
-	return &userService{repo: r, saltRounds: saltRounds}
+// NewUserService hashes and compares passwords in hashingPool, off the request's CPU budget
+func NewUserService(r repository.UserRepository, hasher *passwordhash.Hasher, hashingPool *utils.WorkerPool, passwordPolicy *passwordpolicy.Policy) UserService {
+	return &userService{repo: r, hasher: hasher, hashingPool: hashingPool, passwordPolicy: passwordPolicy}
 }
 
 func (service *userService) CreateUser(ctx context.Context, user *domain.UserEntity) (*domain.UserEntity, error) {
-	g, ctx := errgroup.WithContext(ctx)
+	// Stored lowercase so "Alice@Example.com" and "alice@example.com" are the same account
+	user.Username = domain.NormalizeUsername(user.Username)
+	user.Email = domain.NormalizeEmail(user.Email)
+
+	// gctx is cancelled once the checks are done, only the checks may use it
+	g, gctx := errgroup.WithContext(ctx)
 
 	// Check existing user by username or email
 	g.Go(func() error {
-		existingEmailUser, err := service.repo.FindAUserByFilters(ctx, repository.UserFilters{
+		existingEmailUser, err := service.repo.FindAUserByFilters(gctx, repository.UserFilters{
 			Email: &user.Email,
 		})
-		if err != nil {
+		if err != nil && err != domain.ErrUserNotFound {
 			return err
 		}
 		if existingEmailUser != nil {
@@ -46,10 +69,10 @@ func (service *userService) CreateUser(ctx context.Context, user *domain.UserEnt
 
 	// Check existing user by username
 	g.Go(func() error {
-		existingUsernameUser, err := service.repo.FindAUserByFilters(ctx, repository.UserFilters{
+		existingUsernameUser, err := service.repo.FindAUserByFilters(gctx, repository.UserFilters{
 			Username: &user.Username,
 		})
-		if err != nil {
+		if err != nil && err != domain.ErrUserNotFound {
 			return err
 		}
 		if existingUsernameUser != nil {
@@ -67,7 +90,7 @@ func (service *userService) CreateUser(ctx context.Context, user *domain.UserEnt
 
 	// Create user
 	// Hash password
-	hashedPassword, err := utils.HashPassword(user.Password, service.saltRounds)
+	hashedPassword, err := service.hashPassword(ctx, user.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +108,15 @@ func (service *userService) CreateUser(ctx context.Context, user *domain.UserEnt
 }
 
 func (service *userService) FindAUserByFilters(ctx context.Context, filters repository.UserFilters) (*domain.UserEntity, error) {
+	if filters.Username != nil {
+		username := domain.NormalizeUsername(*filters.Username)
+		filters.Username = &username
+	}
+	if filters.Email != nil {
+		email := domain.NormalizeEmail(*filters.Email)
+		filters.Email = &email
+	}
+
 	user, err := service.repo.FindAUserByFilters(ctx, filters)
 	if err != nil {
 		return nil, err
@@ -94,3 +126,66 @@ func (service *userService) FindAUserByFilters(ctx context.Context, filters repo
 	}
 	return user, nil
 }
+
+func (service *userService) UpdateAUser(ctx context.Context, id primitive.ObjectID, updates repository.UserUpdates) (*domain.UserEntity, error) {
+	return service.repo.UpdateAUser(ctx, id, updates)
+}
+
+// UpdatePassword hashes and stores a new password
+func (service *userService) UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error {
+	hashedPassword, err := service.hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	_, err = service.repo.UpdateAUser(ctx, id, repository.UserUpdates{Password: &hashedPassword})
+	return err
+}
+
+func (service *userService) ValidatePassword(user *domain.UserEntity, password string) error {
+	err := service.passwordPolicy.Check(password, passwordpolicy.Subject{
+		Username: user.Username,
+		Email:    user.Email,
+		Name:     user.Name,
+	})
+	if ce, ok := err.(*utils.CustomError); ok {
+		return ce.WithField("password")
+	}
+	return err
+}
+
+func (service *userService) ComparePassword(ctx context.Context, user *domain.UserEntity, password string) (bool, error) {
+	var match bool
+	err := service.hashingPool.Do(ctx, func() {
+		if user == nil {
+			match = service.hasher.VerifyDummy(password)
+			return
+		}
+		match = service.hasher.Verify(password, user.Password)
+	})
+	return match, err
+}
+
+// hashPassword hashes in the hashing pool, a saturated pool fails before spending any CPU
+func (service *userService) hashPassword(ctx context.Context, password string) (string, error) {
+	var hashedPassword string
+	var hashErr error
+	if err := service.hashingPool.Do(ctx, func() {
+		hashedPassword, hashErr = service.hasher.Hash(password)
+	}); err != nil {
+		return "", err
+	}
+	return hashedPassword, hashErr
+}
+
+func (service *userService) PasswordNeedsRehash(user *domain.UserEntity) bool {
+	return service.hasher.NeedsRehash(user.Password)
+}
+
+func (service *userService) ConsumeTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	return service.repo.ConsumeTOTPStep(ctx, id, step)
+}
+
+func (service *userService) ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	return service.repo.ConsumeRecoveryCode(ctx, id, codeHash)
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/domain"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/internal/users/repository"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordhash"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordpolicy"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// stubUserRepository keeps users in memory and records whether CreateUser got a live context
+type stubUserRepository struct {
+	repository.UserRepository
+	mu            sync.Mutex
+	users         []*domain.UserEntity
+	createCtxErrs []error
+}
+
+func (r *stubUserRepository) CreateUser(ctx context.Context, user *domain.UserEntity) (*domain.UserEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.createCtxErrs = append(r.createCtxErrs, ctx.Err())
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	user.ID = primitive.NewObjectID()
+	stored := *user
+	r.users = append(r.users, &stored)
+	return user, nil
+}
+
+func (r *stubUserRepository) FindAUserByFilters(ctx context.Context, filters repository.UserFilters) (*domain.UserEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if (filters.Email != nil && user.Email == *filters.Email) || (filters.Username != nil && user.Username == *filters.Username) {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func newTestUserService(repo repository.UserRepository) UserService {
+	hasher := passwordhash.New(passwordhash.NewArgon2idHasher(passwordhash.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1}))
+	return NewUserService(repo, hasher, utils.NewWorkerPool(1, 1, 0), passwordpolicy.New())
+}
+
+// The duplicate checks run in an errgroup whose context ends with them, hashing and storing must not use it
+func TestCreateUserOnAnIdleServer(t *testing.T) {
+	repo := &stubUserRepository{}
+	service := newTestUserService(repo)
+
+	// The pool picked between a free slot and the ended context at random, one run in two failed
+	for i := range 50 {
+		user := domain.NewUserEntity(primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()+"@example.com", "correct horse battery", "Test", "", "", shared.RoleUser, shared.GenderUnknown)
+		created, err := service.CreateUser(context.Background(), user)
+		if err != nil {
+			t.Fatalf("CreateUser() #%d = %v", i, err)
+		}
+		if created.Password != "" {
+			t.Fatalf("CreateUser() #%d returned the password hash", i)
+		}
+	}
+	for i, err := range repo.createCtxErrs {
+		if err != nil {
+			t.Fatalf("repository CreateUser #%d got an ended context: %v", i, err)
+		}
+	}
+	if hash := repo.users[0].Password; hash == "" || hash == "correct horse battery" {
+		t.Fatalf("stored password = %q, want a hash", hash)
+	}
+}
+
+func TestCreateUserDuplicates(t *testing.T) {
+	repo := &stubUserRepository{}
+	service := newTestUserService(repo)
+	existing := domain.NewUserEntity("alice", "alice@example.com", "correct horse battery", "Alice", "", "", shared.RoleUser, shared.GenderUnknown)
+	if _, err := service.CreateUser(context.Background(), existing); err != nil {
+		t.Fatalf("CreateUser() = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		email    string
+		wantErr  error
+	}{
+		{"same email in another case", "bob", "Alice@Example.com", domain.ErrUserEmailAlreadyExists},
+		{"same username in another case", "ALICE", "other@example.com", domain.ErrUserUsernameAlreadyExists},
+		{"new user", "carol", "carol@example.com", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := domain.NewUserEntity(tt.username, tt.email, "correct horse battery", "Test", "", "", shared.RoleUser, shared.GenderUnknown)
+			_, err := service.CreateUser(context.Background(), user)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateUser() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
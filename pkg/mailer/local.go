@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Local adapters for development and tests, nothing leaves the machine
+
+type consoleMailer struct {
+	from string
+}
+
+// NewConsoleMailer logs emails instead of sending them
+func NewConsoleMailer(from string) Mailer {
+	return &consoleMailer{from: from}
+}
+
+func (m *consoleMailer) Send(ctx context.Context, message *Message) error {
+	zap.L().Info("email (console mailer)",
+		zap.String("from", m.from),
+		zap.Strings("to", message.To),
+		zap.String("subject", message.Subject),
+		zap.String("text", message.Text),
+	)
+	return nil
+}
+
+type fileMailer struct {
+	from string
+	dir  string
+}
+
+// NewFileMailer writes every email as a .eml file into dir
+func NewFileMailer(from, dir string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &fileMailer{from: from, dir: dir}, nil
+}
+
+func (m *fileMailer) Send(ctx context.Context, message *Message) error {
+	var content strings.Builder
+	fmt.Fprintf(&content, "From: %s\r\n", m.from)
+	fmt.Fprintf(&content, "To: %s\r\n", strings.Join(message.To, ", "))
+	fmt.Fprintf(&content, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&content, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	content.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	content.WriteString(message.Text)
+
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	if err := os.WriteFile(filepath.Join(m.dir, name), []byte(content.String()), 0o640); err != nil {
+		zap.L().Error("error writing email file", zap.Error(err))
+		return err
+	}
+	return nil
+}
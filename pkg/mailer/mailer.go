@@ -0,0 +1,18 @@
+package mailer
+
+// Outgoing email
+
+import "context"
+
+// Message is an email to send, the sender address is configured on the mailer
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
@@ -0,0 +1,82 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testMessage = &Message{
+	To:      []string{"alice@example.com"},
+	Subject: "Reset your password",
+	Text:    "Open the link below",
+	HTML:    "<p>Open the link below</p>",
+}
+
+func TestResendMailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"accepted", http.StatusOK, false},
+		{"rejected", http.StatusUnprocessableEntity, true},
+		{"server error", http.StatusInternalServerError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got resendRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer re_test" {
+					t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decode request: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			m := NewResendMailer("re_test", "noreply@example.com").(*resendMailer)
+			m.endpoint = server.URL
+			err := m.Send(context.Background(), testMessage)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() = %v, want error %v", err, tt.wantErr)
+			}
+			if got.From != "noreply@example.com" || got.Subject != testMessage.Subject || len(got.To) != 1 || got.HTML != testMessage.HTML {
+				t.Fatalf("request = %+v", got)
+			}
+		})
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer("noreply@example.com", dir)
+	if err != nil {
+		t.Fatalf("NewFileMailer() = %v", err)
+	}
+	if err := m.Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("found %v, %v, want one .eml file", files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read email: %v", err)
+	}
+	for _, want := range []string{"From: noreply@example.com\r\n", "To: alice@example.com\r\n", "Subject: Reset your password\r\n", "\r\n\r\nOpen the link below"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("email is missing %q:\n%s", want, content)
+		}
+	}
+}
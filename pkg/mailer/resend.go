@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Resend HTTP API adapter (https://resend.com/docs/api-reference/emails/send-email)
+
+const resendEndpoint = "https://api.resend.com/emails"
+
+type resendMailer struct {
+	apiKey     string
+	from       string
+	endpoint   string
+	httpClient *http.Client
+}
+
+type resendRequest struct {
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text,omitempty"`
+	HTML    string   `json:"html,omitempty"`
+}
+
+func NewResendMailer(apiKey, from string) Mailer {
+	return &resendMailer{
+		apiKey:     apiKey,
+		from:       from,
+		endpoint:   resendEndpoint,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (m *resendMailer) Send(ctx context.Context, message *Message) error {
+	body, err := json.Marshal(resendRequest{
+		From:    m.from,
+		To:      message.To,
+		Subject: message.Subject,
+		Text:    message.Text,
+		HTML:    message.HTML,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+m.apiKey)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := m.httpClient.Do(request)
+	if err != nil {
+		zap.L().Error("error sending email with Resend", zap.Error(err))
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		zap.L().Error("Resend rejected the email",
+			zap.Int("status", response.StatusCode),
+			zap.String("body", string(responseBody)),
+		)
+		return fmt.Errorf("resend returned status %d", response.StatusCode)
+	}
+
+	return nil
+}
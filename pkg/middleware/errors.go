@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// Middleware errors
+var (
+	ErrAuthorizationHeaderMissing = utils.NewCustomError("AUTH_MISSING_TOKEN",
+		http.StatusUnauthorized,
+		"missing or malformed authorization header",
+	)
+	ErrUnauthenticated = utils.NewCustomError("AUTH_UNAUTHENTICATED",
+		http.StatusUnauthorized,
+		"authentication required",
+	)
+	ErrNotHasRole = utils.NewCustomError("USER_NOT_HAS_ROLE",
+		http.StatusForbidden,
+		"user does not have the required role",
+	)
+	ErrPermissionDenied = utils.NewCustomError("AUTH_PERMISSION_DENIED",
+		http.StatusForbidden,
+		"you do not have the permission required by this endpoint",
+	)
+	ErrInsufficientScope = utils.NewCustomError("AUTH_INSUFFICIENT_SCOPE",
+		http.StatusForbidden,
+		"the credential was not granted the scope required by this endpoint",
+	)
+	ErrImpersonationForbidden = utils.NewCustomError("AUTH_IMPERSONATION_FORBIDDEN",
+		http.StatusForbidden,
+		"this action is not allowed while impersonating a user",
+	)
+	ErrMiddlewareInternalServerError = utils.NewCustomError("INTERNAL_SERVER_ERROR",
+		http.StatusInternalServerError,
+		"internal server error",
+	)
+)
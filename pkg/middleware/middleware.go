@@ -1,3 +1,119 @@
 package middleware
 
 // HTTP middleware functions
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// PrincipalKey is the gin context key holding the authenticated *shared.Principal
+const PrincipalKey = "principal"
+
+// Authenticator resolves a raw bearer token into the authenticated principal.
+// It is implemented by the auth module so that other modules never depend on token internals.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*shared.Principal, error)
+}
+
+// APIKeyAuthenticator resolves a personal API key into the principal of the user who created it
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key, clientIP string) (*shared.Principal, error)
+}
+
+// RequireAuth rejects requests without a valid "Authorization: Bearer <token>" header
+// and stores the principal in both the gin context and the request context.
+func RequireAuth(authenticator Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			abortWithError(c, ErrAuthorizationHeaderMissing)
+			return
+		}
+
+		principal, err := authenticator.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		c.Set(PrincipalKey, principal)
+		c.Request = c.Request.WithContext(shared.ContextWithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// RequireAuthOrAPIKey works like RequireAuth and also accepts "Authorization: ApiKey <key>".
+// Routes opt in to API keys one by one, a key is accepted only when it was granted the scope.
+func RequireAuthOrAPIKey(authenticator Authenticator, apiKeys APIKeyAuthenticator, scope string) gin.HandlerFunc {
+	bearerAuth := RequireAuth(authenticator)
+	return func(c *gin.Context) {
+		key, ok := apiKey(c.GetHeader("Authorization"))
+		if !ok {
+			bearerAuth(c)
+			return
+		}
+
+		principal, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), key, c.ClientIP())
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if !principal.HasScope(scope) {
+			abortWithError(c, ErrInsufficientScope)
+			return
+		}
+
+		c.Set(PrincipalKey, principal)
+		c.Request = c.Request.WithContext(shared.ContextWithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// GetPrincipal returns the principal set by RequireAuth
+func GetPrincipal(c *gin.Context) (*shared.Principal, bool) {
+	value, exists := c.Get(PrincipalKey)
+	if !exists {
+		return nil, false
+	}
+	principal, ok := value.(*shared.Principal)
+	return principal, ok && principal != nil
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header value
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// apiKey extracts the key from an "Authorization: ApiKey <key>" header value
+func apiKey(header string) (string, bool) {
+	scheme, key, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "ApiKey") {
+		return "", false
+	}
+	key = strings.TrimSpace(key)
+	return key, key != ""
+}
+
+// abortWithError writes the error response and stops the handler chain
+func abortWithError(c *gin.Context, err error) {
+	if ce, ok := err.(*utils.CustomError); ok {
+		utils.CustomErrorResponse(c, ce)
+		c.Abort()
+		return
+	}
+	utils.ErrorResponse(c,
+		ErrMiddlewareInternalServerError.HTTPStatus(),
+		ErrMiddlewareInternalServerError.Code(),
+		ErrMiddlewareInternalServerError.Error())
+	c.Abort()
+}
@@ -0,0 +1,33 @@
+package middleware
+
+// Attribute based access control middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+// PolicyEnforcer decides a request with the access policies, it returns the error to respond with when denied.
+// It is implemented by the authz module.
+type PolicyEnforcer interface {
+	Enforce(ctx context.Context, principal *shared.Principal, action, resourceType, resourceID, ip string) error
+}
+
+// RequirePolicy enforces the access policies on the action over the resource named by the idParam path parameter.
+// The policies narrow the permission checks of the route, so it must be registered after them and after RequireAuth.
+func RequirePolicy(enforcer PolicyEnforcer, action, resourceType, idParam string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			abortWithError(c, ErrUnauthenticated)
+			return
+		}
+		if err := enforcer.Enforce(c.Request.Context(), principal, action, resourceType, c.Param(idParam), c.ClientIP()); err != nil {
+			abortWithError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
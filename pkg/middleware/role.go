@@ -0,0 +1,70 @@
+package middleware
+
+// Role and permission based access control middleware.
+// Admin routes layer both guards: RequireRole sets the minimum built-in role, then RequirePermission
+// narrows what each admin may do. Custom roles grant permissions, they never lift a user above their built-in role.
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+// RequireRole allows the request only when the principal's role is the given role or above it
+// in the hierarchy (super_admin > admin > user). It must be registered after RequireAuth.
+func RequireRole(minimum shared.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			abortWithError(c, ErrUnauthenticated)
+			return
+		}
+		if !principal.Role.HasAtLeast(minimum) {
+			abortWithError(c, ErrNotHasRole)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission allows the request only when the principal was granted the permission,
+// by its built-in role, its custom roles or the scopes of a service account. It must be registered after RequireAuth.
+func RequirePermission(permission shared.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			abortWithError(c, ErrUnauthenticated)
+			return
+		}
+		if !principal.HasPermission(permission) {
+			abortWithError(c, ErrPermissionDenied)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ForbidImpersonation rejects impersonation tokens, for sensitive actions such as changing credentials
+// that only the user may perform. It must be registered after RequireAuth.
+func ForbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			abortWithError(c, ErrUnauthenticated)
+			return
+		}
+		if principal.IsImpersonated() {
+			abortWithError(c, ErrImpersonationForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsSelfOrHasPermission reports whether the principal is the owner of userID or was granted the permission.
+// Handlers use it for resources that users may access for themselves only.
+func IsSelfOrHasPermission(principal *shared.Principal, userID string, permission shared.Permission) bool {
+	if principal == nil {
+		return false
+	}
+	return principal.UserID == userID || principal.HasPermission(permission)
+}
@@ -0,0 +1,115 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/middleware"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/shared"
+)
+
+// serve runs the guards behind a stub authentication that stores principal, nil leaves the request anonymous
+func serve(t *testing.T, principal *shared.Principal, guards ...gin.HandlerFunc) (int, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handlers := []gin.HandlerFunc{func(c *gin.Context) {
+		if principal != nil {
+			c.Set(middleware.PrincipalKey, principal)
+		}
+	}}
+	handlers = append(handlers, guards...)
+	handlers = append(handlers, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/", handlers...)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	var body struct {
+		Code string `json:"code"`
+	}
+	if recorder.Code != http.StatusNoContent {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode error response: %v", err)
+		}
+	}
+	return recorder.Code, body.Code
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *shared.Principal
+		minimum    shared.Role
+		wantStatus int
+		wantCode   string
+	}{
+		{"anonymous", nil, shared.RoleUser, http.StatusUnauthorized, "AUTH_UNAUTHENTICATED"},
+		{"user for a user route", &shared.Principal{Role: shared.RoleUser}, shared.RoleUser, http.StatusNoContent, ""},
+		{"user for an admin route", &shared.Principal{Role: shared.RoleUser}, shared.RoleAdmin, http.StatusForbidden, "USER_NOT_HAS_ROLE"},
+		{"admin for an admin route", &shared.Principal{Role: shared.RoleAdmin}, shared.RoleAdmin, http.StatusNoContent, ""},
+		{"super admin inherits admin", &shared.Principal{Role: shared.RoleSuperAdmin}, shared.RoleAdmin, http.StatusNoContent, ""},
+		{"admin for a super admin route", &shared.Principal{Role: shared.RoleAdmin}, shared.RoleSuperAdmin, http.StatusForbidden, "USER_NOT_HAS_ROLE"},
+		{"service account has no role", &shared.Principal{Type: shared.PrincipalTypeServiceAccount}, shared.RoleUser, http.StatusForbidden, "USER_NOT_HAS_ROLE"},
+		{"unknown role", &shared.Principal{Role: "root"}, shared.RoleUser, http.StatusForbidden, "USER_NOT_HAS_ROLE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := serve(t, tt.principal, middleware.RequireRole(tt.minimum))
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Fatalf("RequireRole(%s) = %d %s, want %d %s", tt.minimum, status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+// Admin routes layer the role floor and the permission, custom roles cannot lift a user onto them
+func TestRequireRoleWithPermission(t *testing.T) {
+	guards := []gin.HandlerFunc{middleware.RequireRole(shared.RoleAdmin), middleware.RequirePermission(shared.PermissionRolesManage)}
+
+	tests := []struct {
+		name       string
+		principal  *shared.Principal
+		wantStatus int
+		wantCode   string
+	}{
+		{"admin without the permission", &shared.Principal{Role: shared.RoleAdmin, Permissions: shared.RoleAdmin.Permissions()}, http.StatusForbidden, "AUTH_PERMISSION_DENIED"},
+		{"admin granted the permission by a custom role", &shared.Principal{Role: shared.RoleAdmin, Permissions: []shared.Permission{shared.PermissionRolesManage}}, http.StatusNoContent, ""},
+		{"user granted the permission by a custom role", &shared.Principal{Role: shared.RoleUser, Permissions: []shared.Permission{shared.PermissionRolesManage}}, http.StatusForbidden, "USER_NOT_HAS_ROLE"},
+		{"super admin", &shared.Principal{Role: shared.RoleSuperAdmin, Permissions: shared.RoleSuperAdmin.Permissions()}, http.StatusNoContent, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := serve(t, tt.principal, guards...)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Fatalf("guards = %d %s, want %d %s", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestIsSelfOrHasPermission(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *shared.Principal
+		userID    string
+		want      bool
+	}{
+		{"no principal", nil, "user-1", false},
+		{"self", &shared.Principal{UserID: "user-1", Role: shared.RoleUser}, "user-1", true},
+		{"someone else", &shared.Principal{UserID: "user-2", Role: shared.RoleUser}, "user-1", false},
+		{"granted users:read", &shared.Principal{UserID: "admin-1", Role: shared.RoleAdmin, Permissions: []shared.Permission{shared.PermissionUsersRead}}, "user-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := middleware.IsSelfOrHasPermission(tt.principal, tt.userID, shared.PermissionUsersRead); got != tt.want {
+				t.Fatalf("IsSelfOrHasPermission() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
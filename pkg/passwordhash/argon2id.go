@@ -0,0 +1,135 @@
+package passwordhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idParams are the cost parameters of argon2id, zero values use the defaults
+type Argon2idParams struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32 // Bytes
+	KeyLength   uint32 // Bytes
+}
+
+// Defaults recommended by OWASP for argon2id: 19 MiB of memory, 2 iterations, 1 degree of parallelism
+const (
+	DefaultArgon2idMemory      = 19 * 1024
+	DefaultArgon2idIterations  = 2
+	DefaultArgon2idParallelism = 1
+	defaultArgon2idSaltLength  = 16
+	defaultArgon2idKeyLength   = 32
+)
+
+// Argon2idHasher stores hashes in the PHC string format:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+//
+// with the salt and the hash in unpadded standard base64
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	if params.Memory == 0 {
+		params.Memory = DefaultArgon2idMemory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = DefaultArgon2idIterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = DefaultArgon2idParallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = defaultArgon2idSaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = defaultArgon2idKeyLength
+	}
+	return &Argon2idHasher{params: params}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		zap.L().Error("error hashing password", zap.Error(err))
+		return "", errors.New("error hashing password")
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id, argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+AlgorithmArgon2id+"$")
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) bool {
+	hash, err := parseArgon2id(encoded)
+	if err != nil {
+		zap.L().Error("error parsing argon2id hash", zap.Error(err))
+		return false
+	}
+	key := argon2.IDKey([]byte(password), hash.salt, hash.params.Iterations, hash.params.Memory, hash.params.Parallelism, hash.params.KeyLength)
+	return subtle.ConstantTimeCompare(key, hash.key) == 1
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	hash, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return hash.version != argon2.Version ||
+		hash.params.Memory != h.params.Memory ||
+		hash.params.Iterations != h.params.Iterations ||
+		hash.params.Parallelism != h.params.Parallelism ||
+		hash.params.KeyLength != h.params.KeyLength
+}
+
+type argon2idHash struct {
+	version int
+	params  Argon2idParams
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2id(encoded string) (*argon2idHash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return nil, errors.New("invalid argon2id hash format")
+	}
+
+	hash := &argon2idHash{}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &hash.version); err != nil {
+		return nil, errors.New("invalid argon2id hash version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.params.Memory, &hash.params.Iterations, &hash.params.Parallelism); err != nil {
+		return nil, errors.New("invalid argon2id hash parameters")
+	}
+	if hash.params.Iterations == 0 || hash.params.Parallelism == 0 {
+		return nil, errors.New("invalid argon2id hash parameters")
+	}
+
+	var err error
+	if hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errors.New("invalid argon2id hash salt")
+	}
+	if hash.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(hash.key) == 0 {
+		return nil, errors.New("invalid argon2id hash key")
+	}
+	hash.params.SaltLength = uint32(len(hash.salt))
+	hash.params.KeyLength = uint32(len(hash.key))
+	return hash, nil
+}
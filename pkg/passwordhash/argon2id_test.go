@@ -0,0 +1,57 @@
+package passwordhash
+
+import (
+	"testing"
+)
+
+func TestParseArgon2id(t *testing.T) {
+	// "salt-salt-salt-s" and "key-key-key-key-" in unpadded standard base64
+	const salt, key = "c2FsdC1zYWx0LXNhbHQtcw", "a2V5LWtleS1rZXkta2V5LQ"
+
+	tests := []struct {
+		name       string
+		encoded    string
+		wantErr    bool
+		wantParams Argon2idParams
+	}{
+		{
+			name:       "valid hash",
+			encoded:    "$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$" + key,
+			wantParams: Argon2idParams{Memory: 19456, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 16},
+		},
+		{
+			name:       "other parameters",
+			encoded:    "$argon2id$v=19$m=65536,t=3,p=4$" + salt + "$" + key,
+			wantParams: Argon2idParams{Memory: 65536, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 16},
+		},
+		{name: "other algorithm", encoded: "$argon2i$v=19$m=19456,t=2,p=1$" + salt + "$" + key, wantErr: true},
+		{name: "bcrypt hash", encoded: "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", wantErr: true},
+		{name: "missing key", encoded: "$argon2id$v=19$m=19456,t=2,p=1$" + salt, wantErr: true},
+		{name: "empty key", encoded: "$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$", wantErr: true},
+		{name: "invalid version", encoded: "$argon2id$version$m=19456,t=2,p=1$" + salt + "$" + key, wantErr: true},
+		{name: "missing parameter", encoded: "$argon2id$v=19$m=19456,t=2$" + salt + "$" + key, wantErr: true},
+		{name: "zero iterations", encoded: "$argon2id$v=19$m=19456,t=0,p=1$" + salt + "$" + key, wantErr: true},
+		{name: "zero parallelism", encoded: "$argon2id$v=19$m=19456,t=2,p=0$" + salt + "$" + key, wantErr: true},
+		{name: "padded salt", encoded: "$argon2id$v=19$m=19456,t=2,p=1$" + salt + "==$" + key, wantErr: true},
+		{name: "invalid key encoding", encoded: "$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$not*base64", wantErr: true},
+		{name: "empty", encoded: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := parseArgon2id(tt.encoded)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseArgon2id(%q) = %+v, want an error", tt.encoded, hash)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseArgon2id(%q) = %v", tt.encoded, err)
+			}
+			if hash.version != 19 || hash.params != tt.wantParams {
+				t.Fatalf("parseArgon2id(%q) = v%d %+v, want v19 %+v", tt.encoded, hash.version, hash.params, tt.wantParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package passwordhash
+
+import (
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxPasswordBytes is the longest password bcrypt can hash
+const bcryptMaxPasswordBytes = 72
+
+// BcryptHasher keeps bcrypt's own "$2a$<cost>$..." format, which already names the algorithm and the cost
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher uses bcrypt.DefaultCost when cost is not set
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost <= 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		zap.L().Error("error hashing password", zap.Error(err))
+		return "", errors.New("error hashing password")
+	}
+	return string(hashedPassword), nil
+}
+
+func (h *BcryptHasher) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h *BcryptHasher) Verify(password, encoded string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
+
+func (h *BcryptHasher) MaxPasswordBytes() int {
+	return bcryptMaxPasswordBytes
+}
@@ -0,0 +1,103 @@
+package passwordhash
+
+// Password hashing: new passwords are hashed with the configured algorithm, stored hashes of the
+// other supported algorithms still verify and are reported as needing a rehash
+
+import (
+	"errors"
+	"sync"
+)
+
+// PasswordHasher is one hashing algorithm. Its encoded hashes name the algorithm and its parameters,
+// so hashes made with older settings are recognized.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password
+	Hash(password string) (string, error)
+	// Supports reports whether encoded was produced by this algorithm
+	Supports(encoded string) bool
+	// Verify reports whether the password matches an encoded hash of this algorithm
+	Verify(password, encoded string) bool
+	// NeedsRehash reports whether encoded was produced with other parameters than the configured ones
+	NeedsRehash(encoded string) bool
+}
+
+// Hasher hashes with the current algorithm and verifies the hashes of every supported one
+type Hasher struct {
+	current PasswordHasher
+	hashers []PasswordHasher
+
+	dummyHash     string
+	dummyHashOnce sync.Once
+}
+
+// New returns a hasher for current, legacy lists the algorithms of hashes that are still stored
+func New(current PasswordHasher, legacy ...PasswordHasher) *Hasher {
+	return &Hasher{current: current, hashers: append([]PasswordHasher{current}, legacy...)}
+}
+
+// Config selects the algorithm of new hashes, zero values use the defaults
+type Config struct {
+	Algorithm  string // "argon2id" (default) or "bcrypt"
+	Argon2id   Argon2idParams
+	BcryptCost int
+}
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// NewFromConfig returns a hasher for the configured algorithm that still verifies the other one
+func NewFromConfig(cfg Config) (*Hasher, error) {
+	argon2id := NewArgon2idHasher(cfg.Argon2id)
+	bcrypt := NewBcryptHasher(cfg.BcryptCost)
+
+	switch cfg.Algorithm {
+	case "", AlgorithmArgon2id:
+		return New(argon2id, bcrypt), nil
+	case AlgorithmBcrypt:
+		return New(bcrypt, argon2id), nil
+	default:
+		return nil, errors.New("unsupported password hash algorithm " + cfg.Algorithm)
+	}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password is empty")
+	}
+	return h.current.Hash(password)
+}
+
+// Verify reports whether the password matches the encoded hash, false when no algorithm supports it
+func (h *Hasher) Verify(password, encoded string) bool {
+	for _, hasher := range h.hashers {
+		if hasher.Supports(encoded) {
+			return hasher.Verify(password, encoded)
+		}
+	}
+	return false
+}
+
+// NeedsRehash reports whether the encoded hash uses another algorithm or other parameters than new hashes
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	return !h.current.Supports(encoded) || h.current.NeedsRehash(encoded)
+}
+
+// VerifyDummy verifies the password against a fixed hash of the current algorithm and always fails.
+// Call it when the user does not exist so the request takes as long as a wrong password.
+func (h *Hasher) VerifyDummy(password string) bool {
+	h.dummyHashOnce.Do(func() {
+		h.dummyHash, _ = h.current.Hash("dummy-password")
+	})
+	_ = h.current.Verify(password, h.dummyHash)
+	return false
+}
+
+// MaxPasswordBytes is the longest password the current algorithm can hash, 0 when it has no limit
+func (h *Hasher) MaxPasswordBytes() int {
+	if limited, ok := h.current.(interface{ MaxPasswordBytes() int }); ok {
+		return limited.MaxPasswordBytes()
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package passwordhash_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordhash"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters keep the tests fast, only their differences matter here
+var (
+	testArgon2id = passwordhash.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1}
+	testBcrypt   = bcrypt.MinCost
+)
+
+func mustHash(t *testing.T, hasher interface{ Hash(string) (string, error) }, password string) string {
+	t.Helper()
+	encoded, err := hasher.Hash(password)
+	if err != nil {
+		t.Fatalf("Hash() = %v", err)
+	}
+	return encoded
+}
+
+func TestHasherVerify(t *testing.T) {
+	hasher := passwordhash.New(passwordhash.NewArgon2idHasher(testArgon2id), passwordhash.NewBcryptHasher(testBcrypt))
+	argon2idHash := mustHash(t, passwordhash.NewArgon2idHasher(testArgon2id), "secret-password")
+	bcryptHash := mustHash(t, passwordhash.NewBcryptHasher(testBcrypt), "secret-password")
+	// The salt and parameters of argon2idHash with the key of another password
+	otherHash := mustHash(t, passwordhash.NewArgon2idHasher(testArgon2id), "other-password")
+	tamperedHash := argon2idHash[:strings.LastIndex(argon2idHash, "$")] + otherHash[strings.LastIndex(otherHash, "$"):]
+
+	tests := []struct {
+		name     string
+		password string
+		encoded  string
+		want     bool
+	}{
+		{"argon2id, right password", "secret-password", argon2idHash, true},
+		{"argon2id, wrong password", "wrong-password", argon2idHash, false},
+		{"legacy bcrypt, right password", "secret-password", bcryptHash, true},
+		{"legacy bcrypt, wrong password", "wrong-password", bcryptHash, false},
+		{"argon2id, tampered key", "secret-password", tamperedHash, false},
+		{"unsupported algorithm", "secret-password", "$scrypt$ln=16,r=8,p=1$c2FsdA$a2V5", false},
+		{"empty hash", "secret-password", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasher.Verify(tt.password, tt.encoded); got != tt.want {
+				t.Fatalf("Verify(%q) = %v, want %v", tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasherNeedsRehash(t *testing.T) {
+	argon2id := passwordhash.NewArgon2idHasher(testArgon2id)
+	hasher := passwordhash.New(argon2id, passwordhash.NewBcryptHasher(testBcrypt))
+	current := mustHash(t, argon2id, "secret-password")
+
+	tests := []struct {
+		name    string
+		encoded string
+		want    bool
+	}{
+		{"current parameters", current, false},
+		{"more memory", mustHash(t, passwordhash.NewArgon2idHasher(passwordhash.Argon2idParams{Memory: 128, Iterations: 1, Parallelism: 1}), "secret-password"), true},
+		{"more iterations", mustHash(t, passwordhash.NewArgon2idHasher(passwordhash.Argon2idParams{Memory: 64, Iterations: 2, Parallelism: 1}), "secret-password"), true},
+		{"more parallelism", mustHash(t, passwordhash.NewArgon2idHasher(passwordhash.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 2}), "secret-password"), true},
+		{"longer key", mustHash(t, passwordhash.NewArgon2idHasher(passwordhash.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, KeyLength: 64}), "secret-password"), true},
+		// The salt length is not a cost parameter
+		{"longer salt", mustHash(t, passwordhash.NewArgon2idHasher(passwordhash.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 32}), "secret-password"), false},
+		{"older version", strings.Replace(current, "$v=19$", "$v=16$", 1), true},
+		{"legacy algorithm", mustHash(t, passwordhash.NewBcryptHasher(testBcrypt), "secret-password"), true},
+		{"unparsable hash", "$argon2id$v=19$garbage", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasher.NeedsRehash(tt.encoded); got != tt.want {
+				t.Fatalf("NeedsRehash(%q) = %v, want %v", tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBcryptNeedsRehash(t *testing.T) {
+	hasher := passwordhash.New(passwordhash.NewBcryptHasher(testBcrypt), passwordhash.NewArgon2idHasher(testArgon2id))
+
+	tests := []struct {
+		name    string
+		encoded string
+		want    bool
+	}{
+		{"current cost", mustHash(t, passwordhash.NewBcryptHasher(testBcrypt), "secret-password"), false},
+		{"higher cost", mustHash(t, passwordhash.NewBcryptHasher(testBcrypt+1), "secret-password"), true},
+		{"legacy algorithm", mustHash(t, passwordhash.NewArgon2idHasher(testArgon2id), "secret-password"), true},
+		{"unparsable hash", "$2a$xx$garbage", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasher.NeedsRehash(tt.encoded); got != tt.want {
+				t.Fatalf("NeedsRehash(%q) = %v, want %v", tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithm  string
+		wantPrefix string
+		wantErr    bool
+	}{
+		{name: "default", algorithm: "", wantPrefix: "$argon2id$"},
+		{name: "argon2id", algorithm: passwordhash.AlgorithmArgon2id, wantPrefix: "$argon2id$"},
+		{name: "bcrypt", algorithm: passwordhash.AlgorithmBcrypt, wantPrefix: "$2a$"},
+		{name: "unsupported", algorithm: "md5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := passwordhash.NewFromConfig(passwordhash.Config{Algorithm: tt.algorithm, Argon2id: testArgon2id, BcryptCost: testBcrypt})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewFromConfig() = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewFromConfig() = %v", err)
+			}
+			encoded := mustHash(t, hasher, "secret-password")
+			if !strings.HasPrefix(encoded, tt.wantPrefix) || !hasher.Verify("secret-password", encoded) {
+				t.Fatalf("Hash() = %q, want a verifiable %s hash", encoded, tt.wantPrefix)
+			}
+		})
+	}
+}
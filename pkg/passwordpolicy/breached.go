@@ -0,0 +1,115 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// BreachedList tells whether a password is known from a data breach
+type BreachedList interface {
+	Contains(password string) bool
+}
+
+// breachedHashLength is the length of a hex encoded SHA-1 hash, the first 5 characters are the
+// k-anonymity range prefix of the Pwned Passwords API
+const breachedHashLength = 40
+
+// fileBreachedList searches a local Pwned Passwords dump: one "SHA1:COUNT" line per password,
+// uppercase hex sorted by hash, as written by the official downloader. The count is optional.
+// The file is searched in place with a binary search on byte offsets, it can be tens of gigabytes.
+// Reads go through ReadAt, so concurrent lookups share the file handle safely.
+type fileBreachedList struct {
+	file *os.File
+	size int64
+}
+
+// OpenBreachedList opens the hash list and checks that it looks like one
+func OpenBreachedList(path string) (BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	list := &fileBreachedList{file: file, size: info.Size()}
+	first, err := list.lineAt(0)
+	if err != nil || len(first) < breachedHashLength || !isHex(first[:breachedHashLength]) {
+		file.Close()
+		return nil, errors.New("breached password list must start with a SHA-1 hash line")
+	}
+	return list, nil
+}
+
+// Contains hashes the password and looks the hash up, a list that cannot be read lets the password through
+func (l *fileBreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := []byte(strings.ToUpper(hex.EncodeToString(sum[:])))
+
+	// Find the first line at or after the offset whose hash is not smaller than the one searched
+	low, high := int64(0), l.size
+	for low < high {
+		mid := low + (high-low)/2
+		line, err := l.lineAt(mid)
+		if err != nil && !errors.Is(err, io.EOF) {
+			zap.L().Error("error reading breached password list", zap.Error(err))
+			return false
+		}
+		if line == nil || bytes.Compare(lineHash(line), hash) >= 0 {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+
+	line, err := l.lineAt(low)
+	if err != nil && !errors.Is(err, io.EOF) {
+		zap.L().Error("error reading breached password list", zap.Error(err))
+		return false
+	}
+	return line != nil && bytes.Equal(lineHash(line), hash)
+}
+
+// lineAt returns the first complete line starting at or after offset, nil past the last line
+func (l *fileBreachedList) lineAt(offset int64) ([]byte, error) {
+	if offset > 0 {
+		// offset may fall inside a line, step back one byte so a line starting exactly at offset is kept
+		offset--
+	}
+	reader := bufio.NewReader(io.NewSectionReader(l.file, offset, l.size-offset))
+	if offset > 0 {
+		if _, err := reader.ReadSlice('\n'); err != nil {
+			return nil, err
+		}
+	}
+	line, err := reader.ReadBytes('\n')
+	line = bytes.TrimRight(line, "\r\n")
+	if len(line) == 0 {
+		return nil, err
+	}
+	return line, nil
+}
+
+// lineHash returns the uppercase hash of a "HASH:COUNT" line
+func lineHash(line []byte) []byte {
+	if i := bytes.IndexByte(line, ':'); i >= 0 {
+		line = line[:i]
+	}
+	return bytes.ToUpper(line)
+}
+
+func isHex(value []byte) bool {
+	_, err := hex.DecodeString(string(value))
+	return err == nil
+}
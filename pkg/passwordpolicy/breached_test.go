@@ -0,0 +1,107 @@
+package passwordpolicy_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordpolicy"
+)
+
+// writeBreachedList writes the passwords as a sorted "SHA1:COUNT" list, like a Pwned Passwords dump
+func writeBreachedList(t *testing.T, passwords []string) string {
+	t.Helper()
+	lines := make([]string, 0, len(passwords))
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	slices.Sort(lines)
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600); err != nil {
+		t.Fatalf("write breached list: %v", err)
+	}
+	return path
+}
+
+func TestBreachedListContains(t *testing.T) {
+	breached := make([]string, 0, 200)
+	for i := range 200 {
+		breached = append(breached, fmt.Sprintf("breached-%d", i))
+	}
+	list, err := passwordpolicy.OpenBreachedList(writeBreachedList(t, breached))
+	if err != nil {
+		t.Fatalf("OpenBreachedList() = %v", err)
+	}
+
+	// The passwords whose hashes are on the first and last lines of the file
+	hashes := make(map[string]string, len(breached))
+	for _, password := range breached {
+		sum := sha1.Sum([]byte(password))
+		hashes[hex.EncodeToString(sum[:])] = password
+	}
+	sorted := slices.Sorted(maps.Keys(hashes))
+	first, last := hashes[sorted[0]], hashes[sorted[len(sorted)-1]]
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"first line", first, true},
+		{"last line", last, true},
+		{"middle line", "breached-100", true},
+		{"missing password", "not-breached", false},
+		{"missing, close to a listed one", "breached-200", false},
+		{"empty password", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := list.Contains(tt.password); got != tt.want {
+				t.Fatalf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenBreachedList(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"hash with count", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n", false},
+		{"hash without count", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n", false},
+		{"empty file", "", true},
+		{"not a hash", "password123\n", true},
+		{"short hash", "5BAA61E4C9B93F3F:12\n", true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("list-%d.txt", i))
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("write list: %v", err)
+			}
+			_, err := passwordpolicy.OpenBreachedList(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OpenBreachedList() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := passwordpolicy.OpenBreachedList(filepath.Join(dir, "missing.txt")); err == nil {
+			t.Fatal("OpenBreachedList() = nil, want an error")
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package passwordpolicy
+
+// Password policy: a list of rules a new password must pass, checked in order
+
+import (
+	"net/http"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// Subject is the account the password is chosen for, rules use it to reject passwords made of its details
+type Subject struct {
+	Username string
+	Email    string
+	Name     string
+}
+
+// Rule is one requirement of the policy.
+// Check returns a *utils.CustomError telling the user what to change, nil when the password is accepted.
+type Rule interface {
+	Check(password string, subject Subject) error
+}
+
+// Policy checks passwords against its rules
+type Policy struct {
+	rules []Rule
+}
+
+func New(rules ...Rule) *Policy {
+	return &Policy{rules: rules}
+}
+
+// Check returns the error of the first rule the password fails, nil when it passes them all
+func (p *Policy) Check(password string, subject Subject) error {
+	for _, rule := range p.rules {
+		if err := rule.Check(password, subject); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Config selects the built-in rules, zero values disable a rule or use its default
+type Config struct {
+	MinLength        int    // Characters, defaults to DefaultMinLength
+	MaxLength        int    // Characters, defaults to DefaultMaxLength
+	MaxBytes         int    // Limit of the password hash in bytes, 0 when there is none
+	MinCharClasses   int    // Lowercase, uppercase, digits and symbols, 0 disables the rule
+	MinStrength      int    // Estimated strength score from 0 to 4, 0 disables the rule
+	BreachedListFile string // Sorted SHA-1 hash list of breached passwords, empty disables the rule
+}
+
+const (
+	DefaultMinLength = 10
+	DefaultMaxLength = 128
+)
+
+// NewFromConfig builds the policy with the built-in rules, cheapest first
+func NewFromConfig(cfg Config) (*Policy, error) {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = DefaultMinLength
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = DefaultMaxLength
+	}
+
+	rules := []Rule{NewLengthRule(cfg.MinLength, cfg.MaxLength, cfg.MaxBytes)}
+	if cfg.MinCharClasses > 0 {
+		rules = append(rules, NewCharClassRule(cfg.MinCharClasses))
+	}
+	rules = append(rules, NewSimilarityRule())
+	if cfg.MinStrength > 0 {
+		rules = append(rules, NewStrengthRule(cfg.MinStrength))
+	}
+	if cfg.BreachedListFile != "" {
+		breached, err := OpenBreachedList(cfg.BreachedListFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, NewBreachedRule(breached))
+	}
+	return New(rules...), nil
+}
+
+func newPolicyError(code, message string) *utils.CustomError {
+	return utils.NewCustomError(code, http.StatusBadRequest, message)
+}
@@ -0,0 +1,97 @@
+package passwordpolicy_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordpolicy"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// errorCode returns the code of a policy error, empty when the password was accepted
+func errorCode(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var ce *utils.CustomError
+	if !errors.As(err, &ce) {
+		t.Fatalf("error %v is not a *utils.CustomError", err)
+	}
+	return ce.Code()
+}
+
+func TestRules(t *testing.T) {
+	subject := passwordpolicy.Subject{Username: "alice", Email: "alice.smith@example.com", Name: "Alice Smith"}
+
+	tests := []struct {
+		name     string
+		rule     passwordpolicy.Rule
+		password string
+		subject  passwordpolicy.Subject
+		wantCode string
+	}{
+		{"length: too short", passwordpolicy.NewLengthRule(10, 20, 0), "short", subject, "PASSWORD_TOO_SHORT"},
+		{"length: at the minimum", passwordpolicy.NewLengthRule(10, 20, 0), "0123456789", subject, ""},
+		{"length: at the maximum", passwordpolicy.NewLengthRule(10, 20, 0), strings.Repeat("x", 20), subject, ""},
+		{"length: too long", passwordpolicy.NewLengthRule(10, 20, 0), strings.Repeat("x", 21), subject, "PASSWORD_TOO_LONG"},
+		{"length: counts characters, not bytes", passwordpolicy.NewLengthRule(4, 4, 0), "ééé", subject, "PASSWORD_TOO_SHORT"},
+		{"length: multibyte within the byte limit", passwordpolicy.NewLengthRule(4, 10, 8), "éééé", subject, ""},
+		{"length: multibyte over the byte limit", passwordpolicy.NewLengthRule(4, 10, 8), "ééééé", subject, "PASSWORD_TOO_LONG"},
+
+		{"char classes: lowercase only", passwordpolicy.NewCharClassRule(3), "lowercaseonly", subject, "PASSWORD_TOO_FEW_CHARACTER_CLASSES"},
+		{"char classes: lower and digits", passwordpolicy.NewCharClassRule(3), "lower12345", subject, "PASSWORD_TOO_FEW_CHARACTER_CLASSES"},
+		{"char classes: lower, upper and digits", passwordpolicy.NewCharClassRule(3), "Lower12345", subject, ""},
+		{"char classes: symbols count", passwordpolicy.NewCharClassRule(4), "Lower1234!", subject, ""},
+
+		{"similarity: contains the username", passwordpolicy.NewSimilarityRule(), "xxALICExx99", subject, "PASSWORD_TOO_SIMILAR"},
+		{"similarity: contains the email local part", passwordpolicy.NewSimilarityRule(), "alice.smith!2024", subject, "PASSWORD_TOO_SIMILAR"},
+		{"similarity: contains a name word", passwordpolicy.NewSimilarityRule(), "mr-smith-rocks", subject, "PASSWORD_TOO_SIMILAR"},
+		{"similarity: contained in the email", passwordpolicy.NewSimilarityRule(), "example.com", subject, "PASSWORD_TOO_SIMILAR"},
+		{"similarity: unrelated password", passwordpolicy.NewSimilarityRule(), "correct horse battery", subject, ""},
+		{"similarity: short details are ignored", passwordpolicy.NewSimilarityRule(), "bob-the-builder", passwordpolicy.Subject{Username: "bob", Name: "Bo"}, ""},
+
+		{"strength: common password", passwordpolicy.NewStrengthRule(3), "P@ssw0rd", subject, "PASSWORD_TOO_WEAK"},
+		{"strength: username and a year", passwordpolicy.NewStrengthRule(2), "alice2024", subject, "PASSWORD_TOO_WEAK"},
+		{"strength: strong password", passwordpolicy.NewStrengthRule(3), "xK9#mQ2$vL7!", subject, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(t, tt.rule.Check(tt.password, tt.subject)); got != tt.wantCode {
+				t.Fatalf("Check(%q) = %q, want %q", tt.password, got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	subject := passwordpolicy.Subject{Username: "alice", Email: "alice@example.com"}
+	policy, err := passwordpolicy.NewFromConfig(passwordpolicy.Config{MinCharClasses: 3, MinStrength: 3})
+	if err != nil {
+		t.Fatalf("NewFromConfig() = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		wantCode string
+	}{
+		{"default minimum length", "Ab1!", "PASSWORD_TOO_SHORT"},
+		{"default maximum length", "Ab1!" + strings.Repeat("x", passwordpolicy.DefaultMaxLength), "PASSWORD_TOO_LONG"},
+		// Fails every later rule too, the cheaper character class rule answers first
+		{"character classes before similarity", "alicealice", "PASSWORD_TOO_FEW_CHARACTER_CLASSES"},
+		{"similarity before strength", "Alice12345", "PASSWORD_TOO_SIMILAR"},
+		{"strength", "Password123", "PASSWORD_TOO_WEAK"},
+		{"accepted", "xK9#mQ2$vL7!", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(t, policy.Check(tt.password, subject)); got != tt.wantCode {
+				t.Fatalf("Check(%q) = %q, want %q", tt.password, got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// Built-in rules
+
+// minSimilarityToken is the shortest account detail looked for in the password, shorter ones match too often
+const minSimilarityToken = 4
+
+// LengthRule bounds the number of characters, and the bytes when the hash cannot take more
+type LengthRule struct {
+	min, max, maxBytes int
+	errTooShort        *utils.CustomError
+	errTooLong         *utils.CustomError
+}
+
+func NewLengthRule(min, max, maxBytes int) *LengthRule {
+	return &LengthRule{
+		min:         min,
+		max:         max,
+		maxBytes:    maxBytes,
+		errTooShort: newPolicyError("PASSWORD_TOO_SHORT", fmt.Sprintf("password must be at least %d characters long", min)),
+		errTooLong:  newPolicyError("PASSWORD_TOO_LONG", fmt.Sprintf("password must be at most %d characters long", max)),
+	}
+}
+
+func (r *LengthRule) Check(password string, subject Subject) error {
+	length := utf8.RuneCountInString(password)
+	if length < r.min {
+		return r.errTooShort
+	}
+	if length > r.max || (r.maxBytes > 0 && len(password) > r.maxBytes) {
+		return r.errTooLong
+	}
+	return nil
+}
+
+// CharClassRule requires characters from several classes: lowercase, uppercase, digits and symbols
+type CharClassRule struct {
+	min int
+	err *utils.CustomError
+}
+
+func NewCharClassRule(min int) *CharClassRule {
+	return &CharClassRule{
+		min: min,
+		err: newPolicyError("PASSWORD_TOO_FEW_CHARACTER_CLASSES",
+			fmt.Sprintf("password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", min)),
+	}
+}
+
+func (r *CharClassRule) Check(password string, subject Subject) error {
+	if countCharClasses(password) < r.min {
+		return r.err
+	}
+	return nil
+}
+
+func countCharClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// SimilarityRule rejects passwords built from the username, the email or the name of the account
+type SimilarityRule struct {
+	err *utils.CustomError
+}
+
+func NewSimilarityRule() *SimilarityRule {
+	return &SimilarityRule{
+		err: newPolicyError("PASSWORD_TOO_SIMILAR", "password must not contain your username, email or name"),
+	}
+}
+
+func (r *SimilarityRule) Check(password string, subject Subject) error {
+	normalized := strings.ToLower(password)
+	for _, token := range similarityTokens(subject) {
+		if strings.Contains(normalized, token) || strings.Contains(token, normalized) {
+			return r.err
+		}
+	}
+	return nil
+}
+
+// similarityTokens returns the lowercase account details worth looking for: the username,
+// the email and its local part, and each word of the name
+func similarityTokens(subject Subject) []string {
+	candidates := []string{subject.Username, subject.Email}
+	if local, _, found := strings.Cut(subject.Email, "@"); found {
+		candidates = append(candidates, local)
+	}
+	candidates = append(candidates, strings.Fields(subject.Name)...)
+
+	tokens := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(strings.TrimSpace(candidate))
+		if utf8.RuneCountInString(candidate) >= minSimilarityToken {
+			tokens = append(tokens, candidate)
+		}
+	}
+	return tokens
+}
+
+// StrengthRule requires a minimum estimated strength, see EstimateStrength
+type StrengthRule struct {
+	min int
+	err *utils.CustomError
+}
+
+func NewStrengthRule(min int) *StrengthRule {
+	return &StrengthRule{
+		min: min,
+		err: newPolicyError("PASSWORD_TOO_WEAK",
+			"password is too easy to guess, avoid common words, sequences, repeated characters and dates"),
+	}
+}
+
+func (r *StrengthRule) Check(password string, subject Subject) error {
+	if EstimateStrength(password, similarityTokens(subject)...) < r.min {
+		return r.err
+	}
+	return nil
+}
+
+// BreachedRule rejects passwords found in a breached password list
+type BreachedRule struct {
+	list BreachedList
+	err  *utils.CustomError
+}
+
+func NewBreachedRule(list BreachedList) *BreachedRule {
+	return &BreachedRule{
+		list: list,
+		err: newPolicyError("PASSWORD_BREACHED",
+			"password appeared in a data breach, choose another one"),
+	}
+}
+
+func (r *BreachedRule) Check(password string, subject Subject) error {
+	if r.list.Contains(password) {
+		return r.err
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package passwordpolicy
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Strength estimation in the spirit of zxcvbn: the password is split into the patterns an attacker
+// tries first (common words, the account details, repeats, sequences, keyboard walks and years),
+// the cheapest split gives the number of guesses needed, which is mapped to a score from 0 to 4.
+
+const (
+	// bruteforceBits is the cost of a character no pattern covers, zxcvbn uses a cardinality of 10
+	bruteforceBits = 3.3219280948873623 // log2(10)
+	minPatternLen  = 3
+	minKeyboardLen = 4
+)
+
+// scoreThresholds are the guesses, in bits, needed for scores 1 to 4 (10^3, 10^6, 10^8 and 10^10 guesses)
+var scoreThresholds = []float64{9.97, 19.93, 26.58, 33.22}
+
+// commonWords are tried first by attackers, most common first
+var commonWords = []string{
+	"password", "qwerty", "letmein", "welcome", "admin", "iloveyou", "monkey", "dragon", "football",
+	"baseball", "sunshine", "princess", "master", "shadow", "superman", "batman", "trustno", "starwars",
+	"whatever", "freedom", "secret", "hello", "login", "access", "charlie", "michael", "jessica", "ashley",
+	"daniel", "jordan", "hunter", "ranger", "buster", "soccer", "hockey", "killer", "george", "computer",
+	"internet", "summer", "winter", "spring", "autumn", "love", "money", "mustang", "pepper", "cookie",
+	"cheese", "flower", "orange", "banana", "chocolate", "pokemon", "minecraft", "google", "apple",
+	"samsung", "security", "changeme", "default", "guest", "root", "test", "user", "company", "office",
+	"january", "february", "march", "april", "june", "july", "august", "september", "october",
+	"november", "december", "monday", "friday", "sunday", "administrator", "passphrase", "secure",
+}
+
+// leetSubstitutions undo the usual character swaps before looking words up
+var leetSubstitutions = map[rune]rune{
+	'@': 'a', '4': 'a', '3': 'e', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't',
+}
+
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// EstimateStrength returns a score from 0 (guessed almost at once) to 4 (over 10^10 guesses).
+// userInputs are lowercase words specific to the account, tried before any common word.
+func EstimateStrength(password string, userInputs ...string) int {
+	bits := estimateBits([]rune(password), userInputs)
+	score := 0
+	for _, threshold := range scoreThresholds {
+		if bits >= threshold {
+			score++
+		}
+	}
+	return score
+}
+
+// estimateBits finds the cheapest way to cover the password with patterns, in log2 of guesses
+func estimateBits(password []rune, userInputs []string) float64 {
+	n := len(password)
+	if n == 0 {
+		return 0
+	}
+	// Lowercased rune by rune so positions match the password
+	lower := make([]rune, n)
+	unleeted := make([]rune, n)
+	for i, c := range password {
+		c = unicode.ToLower(c)
+		lower[i] = c
+		if sub, ok := leetSubstitutions[c]; ok {
+			unleeted[i] = sub
+		} else {
+			unleeted[i] = c
+		}
+	}
+
+	// Account details first, then common words by rank
+	words := make([]string, 0, len(userInputs)+len(commonWords))
+	words = append(append(words, userInputs...), commonWords...)
+
+	// cost[i] is the cheapest cover of password[:i]
+	cost := make([]float64, n+1)
+	for i := 1; i <= n; i++ {
+		cost[i] = math.Inf(1)
+	}
+	relax := func(start, end int, bits float64) {
+		if cost[start]+bits < cost[end] {
+			cost[end] = cost[start] + bits
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		if math.IsInf(cost[i], 1) {
+			continue
+		}
+		relax(i, i+1, bruteforceBits)
+
+		for rank, word := range words {
+			if end, ok := matchWord(unleeted, i, word); ok {
+				relax(i, end, math.Log2(float64(rank+1))+variationBits(password[i:end], lower[i:end]))
+			}
+		}
+		if end := repeatEnd(lower, i); end-i >= minPatternLen {
+			relax(i, end, math.Log2(charClassSize(lower[i]))+math.Log2(float64(end-i)))
+		}
+		if end, descending := sequenceEnd(lower, i); end-i >= minPatternLen {
+			bits := math.Log2(charClassSize(lower[i])) + math.Log2(float64(end-i))
+			if descending {
+				bits++
+			}
+			relax(i, end, bits)
+		}
+		if end := keyboardEnd(lower, i); end-i >= minKeyboardLen {
+			relax(i, end, math.Log2(40)+math.Log2(float64(end-i)))
+		}
+		if i+4 <= n && isYear(lower[i:i+4]) {
+			relax(i, i+4, math.Log2(140))
+		}
+	}
+	return cost[n]
+}
+
+// matchWord reports whether word starts at position i, and where it ends
+func matchWord(text []rune, i int, word string) (int, bool) {
+	w := []rune(word)
+	if len(w) < minPatternLen || i+len(w) > len(text) {
+		return 0, false
+	}
+	for k, c := range w {
+		if text[i+k] != c {
+			return 0, false
+		}
+	}
+	return i + len(w), true
+}
+
+// variationBits adds a guess for the capitalization and the leet swaps of a matched word
+func variationBits(original, lower []rune) float64 {
+	bits := 0.0
+	if string(original) != string(lower) {
+		bits++
+	}
+	for _, c := range lower {
+		if _, ok := leetSubstitutions[c]; ok {
+			bits++
+			break
+		}
+	}
+	return bits
+}
+
+func repeatEnd(text []rune, i int) int {
+	end := i + 1
+	for end < len(text) && text[end] == text[i] {
+		end++
+	}
+	return end
+}
+
+// sequenceEnd follows characters going up or down by one, like "abcd" or "9876"
+func sequenceEnd(text []rune, i int) (int, bool) {
+	if i+1 >= len(text) {
+		return i + 1, false
+	}
+	delta := text[i+1] - text[i]
+	if delta != 1 && delta != -1 {
+		return i + 1, false
+	}
+	end := i + 2
+	for end < len(text) && text[end]-text[end-1] == delta {
+		end++
+	}
+	return end, delta == -1
+}
+
+// keyboardEnd follows neighbouring keys of a keyboard row, in either direction
+func keyboardEnd(text []rune, i int) int {
+	best := i
+	for _, row := range keyboardRows {
+		for _, walk := range []string{row, reverse(row)} {
+			end := i
+			for end < len(text) && strings.ContainsRune(walk, text[end]) {
+				if end > i && strings.IndexRune(walk, text[end]) != strings.IndexRune(walk, text[end-1])+1 {
+					break
+				}
+				end++
+			}
+			if end > best {
+				best = end
+			}
+		}
+	}
+	return best
+}
+
+func isYear(digits []rune) bool {
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	year := string(digits)
+	return year >= "1900" && year <= "2039"
+}
+
+// charClassSize is the number of characters an attacker tries for a pattern starting with c
+func charClassSize(c rune) float64 {
+	switch {
+	case unicode.IsDigit(c):
+		return 10
+	case unicode.IsLetter(c):
+		return 26
+	default:
+		return 33
+	}
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
@@ -0,0 +1,40 @@
+package passwordpolicy_test
+
+import (
+	"testing"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordpolicy"
+)
+
+func TestEstimateStrength(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		minScore   int
+		maxScore   int
+	}{
+		{"empty", "", nil, 0, 0},
+		{"common word", "password", nil, 0, 0},
+		{"common word with substitutions", "P@ssw0rd", nil, 0, 0},
+		{"repeated word", "passwordpassword", nil, 0, 0},
+		{"repeated character", "aaaaaaaaaaaa", nil, 0, 0},
+		{"alphabet sequence", "abcdefghijkl", nil, 0, 0},
+		{"digit sequence", "1234567890", nil, 0, 0},
+		{"keyboard row", "qwertyuiop", nil, 0, 0},
+		{"word and a year", "summer2024", nil, 0, 1},
+		{"user input and a year", "alice2024", []string{"alice"}, 0, 0},
+		{"same without the user input", "alice2024", nil, 1, 3},
+		{"passphrase", "correct horse battery staple", nil, 4, 4},
+		{"random mix", "xK9#mQ2$vL7!", nil, 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := passwordpolicy.EstimateStrength(tt.password, tt.userInputs...)
+			if got < tt.minScore || got > tt.maxScore {
+				t.Fatalf("EstimateStrength(%q) = %d, want %d to %d", tt.password, got, tt.minScore, tt.maxScore)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Evaluation of the policies against a request
+
+// Attributes are the properties of a subject, resource or environment.
+// Values are strings, numbers, booleans, times, lists and nested attribute maps.
+type Attributes map[string]any
+
+// Request is what is asked: may the subject perform the action on the resource in this environment.
+// The resource attributes hold its "type", and its "id" when it is a single entity.
+type Request struct {
+	Subject     Attributes
+	Action      string
+	Resource    Attributes
+	Environment Attributes
+}
+
+// Decision is the outcome of an evaluation
+type Decision struct {
+	Allowed bool
+	// PolicyID is the policy that decided, empty when no policy applied
+	PolicyID string
+	Reason   string
+}
+
+// Evaluate decides the request with the policies, the first applying deny policy wins over every allow policy.
+// The policies are expected to be valid. A condition that cannot be evaluated, because an attribute is missing
+// or has the wrong type, does not hold for an allow policy but holds for a deny policy: leaving attributes out
+// of a request must not skip a deny rule. Deny policies guard optional attributes with an exists condition.
+func Evaluate(policies []Policy, request Request) Decision {
+	attributes := Attributes{
+		"subject":     request.Subject,
+		"resource":    request.Resource,
+		"action":      request.Action,
+		"environment": request.Environment,
+	}
+
+	var allowedBy *Policy
+	for i := range policies {
+		policy := &policies[i]
+		applies, undetermined := policy.applies(request, attributes)
+		if !applies {
+			continue
+		}
+		if policy.Effect == EffectDeny {
+			reason := fmt.Sprintf("denied by policy %s", policy.ID)
+			if undetermined {
+				reason += ", a condition could not be evaluated"
+			}
+			return Decision{Allowed: false, PolicyID: policy.ID, Reason: reason}
+		}
+		if allowedBy == nil {
+			allowedBy = policy
+		}
+	}
+	if allowedBy == nil {
+		return Decision{Allowed: false, Reason: "no policy allows the request"}
+	}
+	return Decision{Allowed: true, PolicyID: allowedBy.ID, Reason: fmt.Sprintf("allowed by policy %s", allowedBy.ID)}
+}
+
+// Targets reports whether a policy names the action on the resource type, whatever its subjects and conditions.
+// Callers use it to leave requests no policy was written for to other checks instead of denying them by default.
+func Targets(policies []Policy, action, resourceType string) bool {
+	for i := range policies {
+		if matchAny(policies[i].Actions, action, matchAction) && matchAny(policies[i].Resources, resourceType, matchName) {
+			return true
+		}
+	}
+	return false
+}
+
+// applies reports whether the policy targets the request and all its conditions hold.
+// undetermined is set when a deny policy applies only because a condition could not be evaluated.
+func (p *Policy) applies(request Request, attributes Attributes) (applies bool, undetermined bool) {
+	if !matchAny(p.Actions, request.Action, matchAction) {
+		return false, false
+	}
+	resourceType, _ := request.Resource["type"].(string)
+	if !matchAny(p.Resources, resourceType, matchName) {
+		return false, false
+	}
+	subjectMatched := false
+	for _, subject := range p.Subjects {
+		if matchSubject(subject, request.Subject) {
+			subjectMatched = true
+			break
+		}
+	}
+	if !subjectMatched {
+		return false, false
+	}
+
+	for i := range p.Conditions {
+		holds, evaluated := p.Conditions[i].holds(attributes)
+		if !evaluated {
+			if p.Effect != EffectDeny {
+				return false, false
+			}
+			undetermined = true
+			continue
+		}
+		if !holds {
+			return false, false
+		}
+	}
+	return true, undetermined
+}
+
+func matchAny(patterns []string, value string, match func(pattern, value string) bool) bool {
+	for _, pattern := range patterns {
+		if match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchName(pattern, value string) bool {
+	return pattern == Wildcard || pattern == value
+}
+
+// matchAction also accepts "<resource>:*" for every action on a resource
+func matchAction(pattern, action string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, ":*"); ok {
+		return strings.HasPrefix(action, prefix+":")
+	}
+	return matchName(pattern, action)
+}
+
+// matchSubject matches "<attribute>:<value>" against a subject attribute equal to the value or a list holding it
+func matchSubject(selector string, subject Attributes) bool {
+	if selector == Wildcard {
+		return true
+	}
+	key, expected, _ := strings.Cut(selector, ":")
+	value, ok := lookup(subject, key)
+	if !ok {
+		return false
+	}
+	if list, ok := toList(value); ok {
+		return containsValue(list, expected)
+	}
+	return equal(value, expected)
+}
+
+// holds evaluates the condition, evaluated is false when an attribute is missing or its value has the wrong type
+func (c *Condition) holds(attributes Attributes) (holds bool, evaluated bool) {
+	value, found := lookup(attributes, c.Attribute)
+	switch c.Operator {
+	case OperatorExists:
+		return found, true
+	case OperatorNotExists:
+		return !found, true
+	}
+	if !found {
+		return false, false
+	}
+
+	expected := c.Value
+	if c.ValueFrom != "" {
+		var ok bool
+		if expected, ok = lookup(attributes, c.ValueFrom); !ok {
+			return false, false
+		}
+	}
+
+	switch c.Operator {
+	case OperatorEquals, OperatorNotEquals:
+		if !isScalar(value) || !isScalar(expected) {
+			return false, false
+		}
+		return equal(value, expected) == (c.Operator == OperatorEquals), true
+	case OperatorIn, OperatorNotIn:
+		list, ok := toList(expected)
+		if !ok || !isScalar(value) {
+			return false, false
+		}
+		return containsValue(list, value) == (c.Operator == OperatorIn), true
+	case OperatorContains:
+		list, ok := toList(value)
+		if !ok {
+			return false, false
+		}
+		return containsValue(list, expected), true
+	case OperatorGreaterThan, OperatorLessThan:
+		actual, ok := toFloat(value)
+		bound, boundOK := toFloat(expected)
+		if !ok || !boundOK {
+			return false, false
+		}
+		if c.Operator == OperatorGreaterThan {
+			return actual > bound, true
+		}
+		return actual < bound, true
+	case OperatorTimeBetween:
+		at, ok := c.localTime(value)
+		bounds, err := stringList(expected, "")
+		if !ok || err != nil || len(bounds) != 2 {
+			return false, false
+		}
+		clock := at.Format("15:04")
+		if bounds[0] <= bounds[1] {
+			return clock >= bounds[0] && clock < bounds[1], true
+		}
+		// The range spans midnight
+		return clock >= bounds[0] || clock < bounds[1], true
+	case OperatorWeekdayIn:
+		at, ok := c.localTime(value)
+		days, err := stringList(expected, "")
+		if !ok || err != nil {
+			return false, false
+		}
+		for _, day := range days {
+			if weekday, ok := weekdays[day]; ok && weekday == at.Weekday() {
+				return true, true
+			}
+		}
+		return false, true
+	case OperatorCIDRMatch:
+		address, ok := value.(string)
+		blocks, err := stringList(expected, "")
+		ip := net.ParseIP(address)
+		if !ok || err != nil || ip == nil {
+			return false, false
+		}
+		for _, block := range blocks {
+			if _, network, err := net.ParseCIDR(block); err == nil && network.Contains(ip) {
+				return true, true
+			}
+		}
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// localTime reads a time attribute, a time.Time or an RFC 3339 string, in the time zone of the condition
+func (c *Condition) localTime(value any) (time.Time, bool) {
+	var at time.Time
+	switch v := value.(type) {
+	case time.Time:
+		at = v
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, false
+		}
+		at = parsed
+	default:
+		return time.Time{}, false
+	}
+
+	location := time.UTC
+	if c.Timezone != "" {
+		loaded, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return time.Time{}, false
+		}
+		location = loaded
+	}
+	return at.In(location), true
+}
+
+// lookup follows a dotted path through nested maps
+func lookup(attributes Attributes, path string) (any, bool) {
+	var current any = attributes
+	for _, key := range strings.Split(path, ".") {
+		node := reflect.ValueOf(current)
+		if node.Kind() != reflect.Map || node.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		next := node.MapIndex(reflect.ValueOf(key).Convert(node.Type().Key()))
+		if !next.IsValid() {
+			return nil, false
+		}
+		current = next.Interface()
+	}
+	if current == nil {
+		return nil, false
+	}
+	if node := reflect.ValueOf(current); (node.Kind() == reflect.Map || node.Kind() == reflect.Slice) && node.IsNil() {
+		return nil, false
+	}
+	return current, true
+}
+
+// toList reads any slice, such as []string, []any or a decoded BSON array
+func toList(value any) ([]any, bool) {
+	if value == nil {
+		return nil, false
+	}
+	node := reflect.ValueOf(value)
+	if node.Kind() != reflect.Slice && node.Kind() != reflect.Array {
+		return nil, false
+	}
+	list := make([]any, node.Len())
+	for i := range list {
+		list[i] = node.Index(i).Interface()
+	}
+	return list, true
+}
+
+func containsValue(list []any, value any) bool {
+	for _, item := range list {
+		if equal(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares scalars, numbers by value whatever their type and named string types as strings
+func equal(a, b any) bool {
+	if !isScalar(a) || !isScalar(b) {
+		return false
+	}
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String:
+		return va.String() == vb.String()
+	case va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool:
+		return va.Bool() == vb.Bool()
+	default:
+		return false
+	}
+}
+
+func toFloat(value any) (float64, bool) {
+	if value == nil {
+		return 0, false
+	}
+	node := reflect.ValueOf(value)
+	switch node.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(node.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(node.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return node.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,248 @@
+package policy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/policy"
+)
+
+// request is an admin of acme updating a user of acme from the office network, at 03:30 UTC on a Wednesday
+func request() policy.Request {
+	return policy.Request{
+		Subject: policy.Attributes{
+			"id":         "admin-1",
+			"role":       []string{"admin", "user"},
+			"attributes": policy.Attributes{"organization": "acme", "clearance": 3, "groups": []any{"ops", "support"}},
+		},
+		Action:   "users:update",
+		Resource: policy.Attributes{"type": "user", "id": "user-1", "attributes": policy.Attributes{"organization": "acme"}},
+		Environment: policy.Attributes{
+			"ip":   "10.1.2.3",
+			"time": time.Date(2024, time.January, 3, 3, 30, 0, 0, time.UTC),
+		},
+	}
+}
+
+func allow(id string, conditions ...policy.Condition) policy.Policy {
+	return policy.Policy{
+		ID: id, Effect: policy.EffectAllow,
+		Subjects: []string{"role:admin"}, Actions: []string{"users:*"}, Resources: []string{"user"},
+		Conditions: conditions,
+	}
+}
+
+func deny(id string, conditions ...policy.Condition) policy.Policy {
+	p := allow(id, conditions...)
+	p.Effect = policy.EffectDeny
+	return p
+}
+
+func TestEvaluate(t *testing.T) {
+	sameOrganization := policy.Condition{Attribute: "resource.attributes.organization", Operator: policy.OperatorEquals, ValueFrom: "subject.attributes.organization"}
+	missingAttribute := policy.Condition{Attribute: "subject.attributes.department", Operator: policy.OperatorEquals, Value: "finance"}
+
+	tests := []struct {
+		name         string
+		policies     []policy.Policy
+		edit         func(r *policy.Request)
+		wantAllowed  bool
+		wantPolicyID string
+	}{
+		{
+			name:     "no policies deny by default",
+			policies: nil,
+		},
+		{
+			name:         "matching allow policy",
+			policies:     []policy.Policy{allow("allow-admins", sameOrganization)},
+			wantAllowed:  true,
+			wantPolicyID: "allow-admins",
+		},
+		{
+			name:         "first matching allow policy decides",
+			policies:     []policy.Policy{allow("allow-first"), allow("allow-second")},
+			wantAllowed:  true,
+			wantPolicyID: "allow-first",
+		},
+		{
+			name:         "deny overrides an earlier allow",
+			policies:     []policy.Policy{allow("allow-admins"), deny("deny-admins")},
+			wantPolicyID: "deny-admins",
+		},
+		{
+			name:         "deny whose condition does not hold is skipped",
+			policies:     []policy.Policy{allow("allow-admins"), deny("deny-other-organizations", policy.Condition{Attribute: "resource.attributes.organization", Operator: policy.OperatorNotEquals, ValueFrom: "subject.attributes.organization"})},
+			wantAllowed:  true,
+			wantPolicyID: "allow-admins",
+		},
+		{
+			name:     "allow condition fails",
+			policies: []policy.Policy{allow("allow-admins", sameOrganization)},
+			edit:     func(r *policy.Request) { r.Resource["attributes"] = policy.Attributes{"organization": "globex"} },
+		},
+		{
+			name:     "allow fails closed on a missing attribute",
+			policies: []policy.Policy{allow("allow-finance", missingAttribute)},
+		},
+		{
+			name:         "deny fails closed on a missing attribute",
+			policies:     []policy.Policy{allow("allow-admins"), deny("deny-finance", missingAttribute)},
+			wantPolicyID: "deny-finance",
+		},
+		{
+			name:     "deny fails closed on a wrong type",
+			policies: []policy.Policy{allow("allow-admins"), deny("deny-low-clearance", policy.Condition{Attribute: "subject.attributes.clearance", Operator: policy.OperatorLessThan, Value: 2})},
+			edit: func(r *policy.Request) {
+				r.Subject["attributes"] = policy.Attributes{"organization": "acme", "clearance": "high"}
+			},
+			wantPolicyID: "deny-low-clearance",
+		},
+		{
+			name: "deny guarded by exists is skipped when the attribute is missing",
+			policies: []policy.Policy{allow("allow-admins"), deny("deny-finance",
+				policy.Condition{Attribute: "subject.attributes.department", Operator: policy.OperatorExists}, missingAttribute)},
+			wantAllowed:  true,
+			wantPolicyID: "allow-admins",
+		},
+		{
+			name:     "other action",
+			policies: []policy.Policy{allow("allow-admins"), deny("deny-admins")},
+			edit:     func(r *policy.Request) { r.Action = "roles:assign" },
+		},
+		{
+			name:     "other resource type",
+			policies: []policy.Policy{allow("allow-admins")},
+			edit:     func(r *policy.Request) { r.Resource["type"] = "role" },
+		},
+		{
+			name:     "other subject",
+			policies: []policy.Policy{allow("allow-admins")},
+			edit:     func(r *policy.Request) { r.Subject["role"] = []string{"user"} },
+		},
+		{
+			name: "wildcards",
+			policies: []policy.Policy{{
+				ID: "allow-all", Effect: policy.EffectAllow,
+				Subjects: []string{policy.Wildcard}, Actions: []string{policy.Wildcard}, Resources: []string{policy.Wildcard},
+			}},
+			edit: func(r *policy.Request) {
+				r.Subject = policy.Attributes{}
+				r.Action = "roles:assign"
+				r.Resource["type"] = "role"
+			},
+			wantAllowed:  true,
+			wantPolicyID: "allow-all",
+		},
+		{
+			name: "subject attribute equal to the selector",
+			policies: []policy.Policy{{
+				ID: "allow-admin-1", Effect: policy.EffectAllow,
+				Subjects: []string{"id:admin-1"}, Actions: []string{"users:update"}, Resources: []string{"user"},
+			}},
+			wantAllowed:  true,
+			wantPolicyID: "allow-admin-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := request()
+			if tt.edit != nil {
+				tt.edit(&r)
+			}
+			decision := policy.Evaluate(tt.policies, r)
+			if decision.Allowed != tt.wantAllowed || decision.PolicyID != tt.wantPolicyID {
+				t.Fatalf("Evaluate() = %+v, want allowed %v by %q", decision, tt.wantAllowed, tt.wantPolicyID)
+			}
+		})
+	}
+}
+
+func TestEvaluateConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition policy.Condition
+		want      bool
+	}{
+		{"equals", policy.Condition{Attribute: "resource.id", Operator: policy.OperatorEquals, Value: "user-1"}, true},
+		{"equals, other value", policy.Condition{Attribute: "resource.id", Operator: policy.OperatorEquals, Value: "user-2"}, false},
+		{"equals, numbers of other types", policy.Condition{Attribute: "subject.attributes.clearance", Operator: policy.OperatorEquals, Value: 3.0}, true},
+		{"equals, list attribute", policy.Condition{Attribute: "subject.role", Operator: policy.OperatorEquals, Value: "admin"}, false},
+		{"not_equals", policy.Condition{Attribute: "resource.id", Operator: policy.OperatorNotEquals, Value: "user-2"}, true},
+		{"not_equals, missing attribute", policy.Condition{Attribute: "resource.owner", Operator: policy.OperatorNotEquals, Value: "user-2"}, false},
+		{"equals value_from", policy.Condition{Attribute: "resource.attributes.organization", Operator: policy.OperatorEquals, ValueFrom: "subject.attributes.organization"}, true},
+		{"equals, missing value_from", policy.Condition{Attribute: "resource.attributes.organization", Operator: policy.OperatorEquals, ValueFrom: "subject.attributes.team"}, false},
+		{"in", policy.Condition{Attribute: "action", Operator: policy.OperatorIn, Value: []any{"users:read", "users:update"}}, true},
+		{"in, not listed", policy.Condition{Attribute: "action", Operator: policy.OperatorIn, Value: []any{"users:read"}}, false},
+		{"not_in", policy.Condition{Attribute: "action", Operator: policy.OperatorNotIn, Value: []any{"users:delete"}}, true},
+		{"contains", policy.Condition{Attribute: "subject.attributes.groups", Operator: policy.OperatorContains, Value: "ops"}, true},
+		{"contains, not held", policy.Condition{Attribute: "subject.attributes.groups", Operator: policy.OperatorContains, Value: "finance"}, false},
+		{"contains, typed list", policy.Condition{Attribute: "subject.role", Operator: policy.OperatorContains, Value: "admin"}, true},
+		{"contains, scalar attribute", policy.Condition{Attribute: "resource.id", Operator: policy.OperatorContains, Value: "user-1"}, false},
+		{"greater_than", policy.Condition{Attribute: "subject.attributes.clearance", Operator: policy.OperatorGreaterThan, Value: 2}, true},
+		{"greater_than, equal", policy.Condition{Attribute: "subject.attributes.clearance", Operator: policy.OperatorGreaterThan, Value: 3}, false},
+		{"less_than", policy.Condition{Attribute: "subject.attributes.clearance", Operator: policy.OperatorLessThan, Value: 3.5}, true},
+		{"less_than, not a number", policy.Condition{Attribute: "resource.id", Operator: policy.OperatorLessThan, Value: 3}, false},
+		{"exists", policy.Condition{Attribute: "environment.ip", Operator: policy.OperatorExists}, true},
+		{"exists, missing", policy.Condition{Attribute: "environment.country", Operator: policy.OperatorExists}, false},
+		{"not_exists", policy.Condition{Attribute: "environment.country", Operator: policy.OperatorNotExists}, true},
+		{"not_exists, present", policy.Condition{Attribute: "environment.ip", Operator: policy.OperatorNotExists}, false},
+		{"time_between, UTC", policy.Condition{Attribute: "environment.time", Operator: policy.OperatorTimeBetween, Value: []any{"03:00", "04:00"}}, true},
+		{"time_between, end excluded", policy.Condition{Attribute: "environment.time", Operator: policy.OperatorTimeBetween, Value: []any{"02:00", "03:30"}}, false},
+		{"time_between, time zone", policy.Condition{Attribute: "environment.time", Operator: policy.OperatorTimeBetween, Value: []any{"09:00", "18:00"}, Timezone: "Asia/Ho_Chi_Minh"}, true},
+		{"time_between, across midnight", policy.Condition{Attribute: "environment.time", Operator: policy.OperatorTimeBetween, Value: []any{"22:00", "06:00"}}, true},
+		{"time_between, outside across midnight", policy.Condition{Attribute: "environment.time", Operator: policy.OperatorTimeBetween, Value: []any{"22:00", "03:00"}}, false},
+		{"weekday_in", policy.Condition{Attribute: "environment.time", Operator: policy.OperatorWeekdayIn, Value: []any{"monday", "wednesday"}}, true},
+		{"weekday_in, other days", policy.Condition{Attribute: "environment.time", Operator: policy.OperatorWeekdayIn, Value: []any{"saturday", "sunday"}}, false},
+		// 03:30 UTC on Wednesday is 22:30 on Tuesday in New York
+		{"weekday_in, time zone", policy.Condition{Attribute: "environment.time", Operator: policy.OperatorWeekdayIn, Value: []any{"tuesday"}, Timezone: "America/New_York"}, true},
+		{"cidr_match", policy.Condition{Attribute: "environment.ip", Operator: policy.OperatorCIDRMatch, Value: []any{"192.168.0.0/16", "10.0.0.0/8"}}, true},
+		{"cidr_match, outside", policy.Condition{Attribute: "environment.ip", Operator: policy.OperatorCIDRMatch, Value: []any{"192.168.0.0/16"}}, false},
+		{"cidr_match, not an IP", policy.Condition{Attribute: "resource.id", Operator: policy.OperatorCIDRMatch, Value: []any{"10.0.0.0/8"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := policy.Evaluate([]policy.Policy{allow("allow-admins", tt.condition)}, request())
+			if decision.Allowed != tt.want {
+				t.Fatalf("condition %+v held = %v, want %v", tt.condition, decision.Allowed, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargets(t *testing.T) {
+	policies := []policy.Policy{
+		allow("allow-admins"),
+		{
+			ID: "deny-role-changes", Effect: policy.EffectDeny,
+			Subjects: []string{"id:someone"}, Actions: []string{"roles:assign"}, Resources: []string{"role"},
+			Conditions: []policy.Condition{{Attribute: "subject.attributes.team", Operator: policy.OperatorExists}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		policies     []policy.Policy
+		action       string
+		resourceType string
+		want         bool
+	}{
+		{"action prefix", policies, "users:read", "user", true},
+		// Subjects and conditions do not matter
+		{"exact action", policies, "roles:assign", "role", true},
+		{"action on another resource type", policies, "users:read", "role", false},
+		{"untargeted action", policies, "policies:manage", "policy", false},
+		{"no policies", nil, "users:read", "user", false},
+		{"wildcards", []policy.Policy{{ID: "allow-all", Effect: policy.EffectAllow, Subjects: []string{"*"}, Actions: []string{"*"}, Resources: []string{"*"}}}, "policies:manage", "policy", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Targets(tt.policies, tt.action, tt.resourceType); got != tt.want {
+				t.Fatalf("Targets(%q, %q) = %v, want %v", tt.action, tt.resourceType, got, tt.want)
+			}
+		})
+	}
+}
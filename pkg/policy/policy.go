@@ -0,0 +1,275 @@
+package policy
+
+// Attribute based access policies, evaluated at request time.
+//
+// A policy applies to a request when one of its subjects, actions and resources matches, and takes
+// effect when all its conditions hold. A deny policy overrides every allow policy, a request no
+// policy allows is denied. Deny policies fail closed: a condition on a missing attribute holds for them.
+// For example "admins may only manage users in their own organization
+// during business hours":
+//
+//	{
+//	  "id": "admins-manage-own-organization",
+//	  "effect": "allow",
+//	  "subjects": ["role:admin"],
+//	  "actions": ["users:*"],
+//	  "resources": ["user"],
+//	  "conditions": [
+//	    {"attribute": "resource.attributes.organization", "operator": "equals", "value_from": "subject.attributes.organization"},
+//	    {"attribute": "environment.time", "operator": "weekday_in", "value": ["monday", "tuesday", "wednesday", "thursday", "friday"], "timezone": "Asia/Ho_Chi_Minh"},
+//	    {"attribute": "environment.time", "operator": "time_between", "value": ["09:00", "18:00"], "timezone": "Asia/Ho_Chi_Minh"}
+//	  ]
+//	}
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+type Operator string
+
+const (
+	OperatorEquals      Operator = "equals"
+	OperatorNotEquals   Operator = "not_equals"
+	OperatorIn          Operator = "in"
+	OperatorNotIn       Operator = "not_in"
+	OperatorContains    Operator = "contains" // The attribute is a list holding the value
+	OperatorGreaterThan Operator = "greater_than"
+	OperatorLessThan    Operator = "less_than"
+	OperatorExists      Operator = "exists"
+	OperatorNotExists   Operator = "not_exists"
+	OperatorTimeBetween Operator = "time_between" // Value is ["HH:MM", "HH:MM"], the range may span midnight
+	OperatorWeekdayIn   Operator = "weekday_in"   // Value lists lowercase English day names
+	OperatorCIDRMatch   Operator = "cidr_match"   // Value lists CIDR blocks the IP address must be in
+)
+
+// Wildcard matches every subject, action or resource type
+const Wildcard = "*"
+
+// Limits keeping the evaluation of a policy cheap
+const (
+	MaxMatchers   = 50
+	MaxConditions = 20
+	MaxListValues = 100
+)
+
+// Policy is the declarative access rule, stored as JSON or BSON
+type Policy struct {
+	ID          string `bson:"_id" json:"id"`
+	Description string `bson:"description,omitempty" json:"description,omitempty"`
+	Effect      Effect `bson:"effect" json:"effect"`
+	// Subjects select who the policy is about, "<attribute>:<value>" such as "role:admin" or "id:<user id>",
+	// matched against the subject attributes, or "*"
+	Subjects []string `bson:"subjects" json:"subjects"`
+	// Actions are permission like names, "users:update", "users:*" or "*"
+	Actions []string `bson:"actions" json:"actions"`
+	// Resources are resource types, "user" or "*"
+	Resources  []string    `bson:"resources" json:"resources"`
+	Conditions []Condition `bson:"conditions,omitempty" json:"conditions,omitempty"`
+}
+
+// Condition compares an attribute of the request with a value, or with another attribute when ValueFrom is set.
+// Attributes are dotted paths under subject, resource, action and environment, such as "subject.attributes.organization".
+// A condition on a missing attribute never holds, except not_exists.
+type Condition struct {
+	Attribute string   `bson:"attribute" json:"attribute"`
+	Operator  Operator `bson:"operator" json:"operator"`
+	Value     any      `bson:"value,omitempty" json:"value,omitempty"`
+	ValueFrom string   `bson:"value_from,omitempty" json:"value_from,omitempty"`
+	// Timezone is the IANA time zone of the time operators, UTC when empty
+	Timezone string `bson:"timezone,omitempty" json:"timezone,omitempty"`
+}
+
+var (
+	policyIDPattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{2,99}$`)
+	attributePattern = regexp.MustCompile(`^(subject|resource|action|environment)(\.[A-Za-z0-9_]+)*$`)
+	clockPattern     = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Validate checks the policy can be evaluated, the error is a *utils.CustomError naming the faulty field
+func (p *Policy) Validate() error {
+	if !policyIDPattern.MatchString(p.ID) {
+		return newInvalidPolicyError("id", "id must be 3 to 100 lowercase letters, digits, dots, dashes or underscores")
+	}
+	if p.Effect != EffectAllow && p.Effect != EffectDeny {
+		return newInvalidPolicyError("effect", "effect must be allow or deny")
+	}
+	for _, list := range []struct {
+		field    string
+		matchers []string
+	}{{"subjects", p.Subjects}, {"actions", p.Actions}, {"resources", p.Resources}} {
+		field, matchers := list.field, list.matchers
+		if len(matchers) == 0 || len(matchers) > MaxMatchers {
+			return newInvalidPolicyError(field, fmt.Sprintf("%s must list 1 to %d entries", field, MaxMatchers))
+		}
+		for _, matcher := range matchers {
+			if strings.TrimSpace(matcher) == "" {
+				return newInvalidPolicyError(field, fmt.Sprintf("%s cannot hold empty entries", field))
+			}
+		}
+	}
+	for _, subject := range p.Subjects {
+		if subject == Wildcard {
+			continue
+		}
+		key, _, ok := strings.Cut(subject, ":")
+		if !ok || !attributePattern.MatchString("subject."+key) {
+			return newInvalidPolicyError("subjects", fmt.Sprintf("subject %q must be \"*\" or \"<attribute>:<value>\"", subject))
+		}
+	}
+
+	if len(p.Conditions) > MaxConditions {
+		return newInvalidPolicyError("conditions", fmt.Sprintf("a policy has at most %d conditions", MaxConditions))
+	}
+	for i := range p.Conditions {
+		if err := p.Conditions[i].validate(); err != nil {
+			return newInvalidPolicyError(fmt.Sprintf("conditions[%d]", i), err.Error())
+		}
+	}
+	return nil
+}
+
+func (c *Condition) validate() error {
+	if !attributePattern.MatchString(c.Attribute) {
+		return fmt.Errorf("attribute %q must be a dotted path under subject, resource, action or environment", c.Attribute)
+	}
+	if c.ValueFrom != "" {
+		if !attributePattern.MatchString(c.ValueFrom) {
+			return fmt.Errorf("value_from %q must be a dotted path under subject, resource, action or environment", c.ValueFrom)
+		}
+		if c.Value != nil {
+			return fmt.Errorf("value and value_from cannot be both set")
+		}
+	}
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return fmt.Errorf("timezone %q is unknown", c.Timezone)
+		}
+	}
+
+	switch c.Operator {
+	case OperatorEquals, OperatorNotEquals, OperatorContains:
+		if c.ValueFrom == "" && !isScalar(c.Value) {
+			return fmt.Errorf("%s needs a string, number or boolean value", c.Operator)
+		}
+	case OperatorIn, OperatorNotIn:
+		if c.ValueFrom == "" {
+			if _, err := scalarList(c.Value); err != nil {
+				return fmt.Errorf("%s needs %v", c.Operator, err)
+			}
+		}
+	case OperatorGreaterThan, OperatorLessThan:
+		if _, ok := toFloat(c.Value); c.ValueFrom == "" && !ok {
+			return fmt.Errorf("%s needs a number value", c.Operator)
+		}
+	case OperatorExists, OperatorNotExists:
+		if c.Value != nil || c.ValueFrom != "" {
+			return fmt.Errorf("%s takes no value", c.Operator)
+		}
+	case OperatorTimeBetween:
+		bounds, err := stringList(c.Value, c.ValueFrom)
+		if err != nil || len(bounds) != 2 || !clockPattern.MatchString(bounds[0]) || !clockPattern.MatchString(bounds[1]) {
+			return fmt.Errorf("%s needs a value of two \"HH:MM\" times", c.Operator)
+		}
+	case OperatorWeekdayIn:
+		days, err := stringList(c.Value, c.ValueFrom)
+		if err != nil {
+			return fmt.Errorf("%s needs %v", c.Operator, err)
+		}
+		for _, day := range days {
+			if _, ok := weekdays[day]; !ok {
+				return fmt.Errorf("%q is not a lowercase English day name", day)
+			}
+		}
+	case OperatorCIDRMatch:
+		blocks, err := stringList(c.Value, c.ValueFrom)
+		if err != nil {
+			return fmt.Errorf("%s needs %v", c.Operator, err)
+		}
+		for _, block := range blocks {
+			if _, _, err := net.ParseCIDR(block); err != nil {
+				return fmt.Errorf("%q is not a CIDR block", block)
+			}
+		}
+	default:
+		return fmt.Errorf("operator %q is unknown", c.Operator)
+	}
+	return nil
+}
+
+// stringList reads the fixed list of strings the time and network operators need
+func stringList(value any, valueFrom string) ([]string, error) {
+	if valueFrom != "" {
+		return nil, fmt.Errorf("a value, value_from is not supported")
+	}
+	values, err := scalarList(value)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("a list of strings")
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// scalarList reads a non empty list of strings, numbers and booleans
+func scalarList(value any) ([]any, error) {
+	values, ok := toList(value)
+	if !ok || len(values) == 0 || len(values) > MaxListValues {
+		return nil, fmt.Errorf("a list of 1 to %d values", MaxListValues)
+	}
+	for _, v := range values {
+		if !isScalar(v) {
+			return nil, fmt.Errorf("a list of strings, numbers or booleans")
+		}
+	}
+	return values, nil
+}
+
+func isScalar(value any) bool {
+	if value == nil {
+		return false
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func newInvalidPolicyError(field, message string) error {
+	return utils.NewCustomError("POLICY_INVALID", http.StatusBadRequest, message).WithField(field)
+}
@@ -0,0 +1,91 @@
+package shared
+
+// Permission catalog shared between modules.
+// A permission is "resource:action". Built-in roles map to fixed permission sets, custom roles bundle any of them.
+
+type Permission string
+
+const (
+	PermissionUsersRead             Permission = "users:read"
+	PermissionUsersImpersonate      Permission = "users:impersonate"
+	PermissionRolesRead             Permission = "roles:read"
+	PermissionRolesManage           Permission = "roles:manage"
+	PermissionRolesAssign           Permission = "roles:assign"
+	PermissionOAuthClientsManage    Permission = "oauth_clients:manage"
+	PermissionServiceAccountsManage Permission = "service_accounts:manage"
+	PermissionAuditRead             Permission = "audit:read"
+	PermissionPoliciesRead          Permission = "policies:read"
+	PermissionPoliciesManage        Permission = "policies:manage"
+	PermissionAuthzCheck            Permission = "authz:check"
+)
+
+// PermissionDefinition describes a permission of the catalog
+type PermissionDefinition struct {
+	Name        Permission `json:"name"`
+	Description string     `json:"description"`
+}
+
+// PermissionCatalog lists every permission the API checks
+var PermissionCatalog = []PermissionDefinition{
+	{Name: PermissionUsersRead, Description: "Read the profile of any user"},
+	{Name: PermissionUsersImpersonate, Description: "Act as another user, every request is audited"},
+	{Name: PermissionRolesRead, Description: "List the roles and the roles of users"},
+	{Name: PermissionRolesManage, Description: "Create, update and delete custom roles"},
+	{Name: PermissionRolesAssign, Description: "Assign custom roles to users and remove them"},
+	{Name: PermissionOAuthClientsManage, Description: "Register and list OAuth clients"},
+	{Name: PermissionServiceAccountsManage, Description: "Manage service accounts and their secrets"},
+	{Name: PermissionAuditRead, Description: "Read the audit trail and the access decisions"},
+	{Name: PermissionPoliciesRead, Description: "List the access policies and their versions"},
+	{Name: PermissionPoliciesManage, Description: "Create, update, restore and delete access policies, and set the attributes of users"},
+	{Name: PermissionAuthzCheck, Description: "Ask for access decisions on behalf of other users"},
+}
+
+// builtInRolePermissions keeps the access the built-in roles had before permissions existed
+var builtInRolePermissions = map[Role][]Permission{
+	RoleUser: {},
+	RoleAdmin: {
+		PermissionUsersRead,
+		PermissionRolesRead,
+		PermissionOAuthClientsManage,
+		PermissionServiceAccountsManage,
+	},
+	RoleSuperAdmin: AllPermissions(),
+}
+
+// IsValid reports whether the permission is in the catalog
+func (p Permission) IsValid() bool {
+	for _, definition := range PermissionCatalog {
+		if definition.Name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// AllPermissions returns every permission of the catalog
+func AllPermissions() []Permission {
+	permissions := make([]Permission, 0, len(PermissionCatalog))
+	for _, definition := range PermissionCatalog {
+		permissions = append(permissions, definition.Name)
+	}
+	return permissions
+}
+
+// Permissions returns the permissions granted by a built-in role, nil for an unknown role
+func (r Role) Permissions() []Permission {
+	permissions, ok := builtInRolePermissions[r]
+	if !ok {
+		return nil
+	}
+	return append([]Permission(nil), permissions...)
+}
+
+// ContainsPermission reports whether the permission is in the list
+func ContainsPermission(permissions []Permission, permission Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
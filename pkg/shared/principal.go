@@ -0,0 +1,105 @@
+package shared
+
+import "context"
+
+// Authenticated principal shared between modules
+
+type principalContextKey struct{}
+
+// PrincipalType tells human users apart from machine identities
+type PrincipalType string
+
+const (
+	PrincipalTypeUser           PrincipalType = "user"
+	PrincipalTypeServiceAccount PrincipalType = "service_account"
+)
+
+// Scopes that can be granted to service accounts and API keys, the modules serving them check the scope
+const (
+	ScopeUsersRead   = "users:read"
+	ScopeProfileRead = "profile:read"
+	// ScopeTokensIntrospect lets a service account, such as the API gateway, introspect and revoke any token
+	ScopeTokensIntrospect = "tokens:introspect"
+	// ScopeAuthzCheck lets a service account ask for access decisions on behalf of users
+	ScopeAuthzCheck = "authz:check"
+)
+
+// ServiceAccountScopes lists every scope a service account can be granted
+var ServiceAccountScopes = []string{ScopeUsersRead, ScopeTokensIntrospect, ScopeAuthzCheck}
+
+// APIKeyScopes lists every scope a personal API key can be granted
+var APIKeyScopes = []string{ScopeProfileRead, ScopeUsersRead}
+
+// Principal is the authenticated caller of a request, resolved by the auth middleware.
+// Service accounts have no UserID and no Role, they are identified by their ClientID and limited to their Scopes.
+type Principal struct {
+	Type     PrincipalType `json:"type"`
+	UserID   string        `json:"user_id,omitempty"`
+	Username string        `json:"username"`
+	Role     Role          `json:"role,omitempty"`
+	TokenID  string        `json:"token_id,omitempty"`
+	// ExpiresAt is the expiry of the credential used for this request, in unix milliseconds
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+	// ClientID and Scopes are set when the credential was issued to an OAuth client
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	// APIKeyID is set when the request was authenticated with a personal API key, limited to Scopes
+	APIKeyID string `json:"api_key_id,omitempty"`
+	// SessionID is the login session the access token belongs to
+	SessionID string `json:"session_id,omitempty"`
+	// ActorID and ActorUsername are set when a super admin impersonates the user, they identify the admin
+	ActorID       string `json:"actor_id,omitempty"`
+	ActorUsername string `json:"actor_username,omitempty"`
+	// Permissions are resolved from the built-in role, the custom roles of the user and the scopes of the credential
+	Permissions []Permission `json:"permissions,omitempty"`
+}
+
+// IsServiceAccount reports whether the caller is a service account rather than a user
+func (p *Principal) IsServiceAccount() bool {
+	return p.Type == PrincipalTypeServiceAccount
+}
+
+// IsLoginSession reports whether the caller is a user signed in with their own login,
+// rather than with an API key, a service account, a token issued to an OAuth client or an impersonation token
+func (p *Principal) IsLoginSession() bool {
+	return p.UserID != "" && !p.IsServiceAccount() && p.ClientID == "" && p.APIKeyID == "" && !p.IsImpersonated()
+}
+
+// IsImpersonated reports whether a super admin is acting as the user
+func (p *Principal) IsImpersonated() bool {
+	return p.ActorID != ""
+}
+
+// SubjectID returns the identifier tokens of the principal are tracked by: the user id, or the client id of a service account
+func (p *Principal) SubjectID() string {
+	if p.IsServiceAccount() {
+		return p.ClientID
+	}
+	return p.UserID
+}
+
+// HasScope reports whether the credential was granted the scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the principal was granted the permission
+func (p *Principal) HasPermission(permission Permission) bool {
+	return ContainsPermission(p.Permissions, permission)
+}
+
+// ContextWithPrincipal returns a copy of ctx carrying the principal
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -21,6 +21,25 @@ func (r Role) IsValid() bool {
 	}
 }
 
+// Level returns the rank of the role in the hierarchy, a higher level includes all lower ones
+func (r Role) Level() int {
+	switch r {
+	case RoleSuperAdmin:
+		return 3
+	case RoleAdmin:
+		return 2
+	case RoleUser:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// HasAtLeast reports whether the role is the required role or above it in the hierarchy
+func (r Role) HasAtLeast(required Role) bool {
+	return r.IsValid() && r.Level() >= required.Level()
+}
+
 type Gender int
 
 const (
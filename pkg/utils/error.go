@@ -1,5 +1,7 @@
 package utils
 
+import "time"
+
 // HandleError interface
 
 type HandleError interface {
@@ -14,13 +16,37 @@ type CustomError struct {
 	code       string
 	httpStatus int
 	message    string
+	retryAfter time.Duration
+	field      string
 }
 
 func (e *CustomError) Error() string   { return e.message }
 func (e *CustomError) Code() string    { return e.code }
 func (e *CustomError) HTTPStatus() int { return e.httpStatus }
 
+// RetryAfter is how long the client should wait before retrying, 0 when not applicable
+func (e *CustomError) RetryAfter() time.Duration { return e.retryAfter }
+
+// Field is the request field the error is about, empty when it is not about a single field
+func (e *CustomError) Field() string { return e.field }
+
 // NewCustomError creates a new CustomError with code, http status and message.
 func NewCustomError(code string, httpStatus int, message string) *CustomError {
 	return &CustomError{code: code, httpStatus: httpStatus, message: message}
 }
+
+// WithRetryAfter returns a copy of the error telling the client when to retry.
+// The shared error value is never mutated.
+func (e *CustomError) WithRetryAfter(retryAfter time.Duration) *CustomError {
+	copied := *e
+	copied.retryAfter = retryAfter
+	return &copied
+}
+
+// WithField returns a copy of the error pointing at a request field.
+// The shared error value is never mutated.
+func (e *CustomError) WithField(field string) *CustomError {
+	copied := *e
+	copied.field = field
+	return &copied
+}
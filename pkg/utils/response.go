@@ -1,6 +1,11 @@
 package utils
 
-import "github.com/gin-gonic/gin"
+import (
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
 
 // Response functions
 
@@ -19,3 +24,21 @@ func ErrorResponse(c *gin.Context, statusCode int, code string, message string)
 		"code":       code,
 	})
 }
+
+// CustomErrorResponse writes a CustomError, setting the Retry-After header when the error carries one
+// and naming the invalid field when there is one
+func CustomErrorResponse(c *gin.Context, err *CustomError) {
+	if err.RetryAfter() > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter().Seconds()))))
+	}
+	if err.Field() != "" {
+		c.JSON(err.HTTPStatus(), map[string]interface{}{
+			"statusCode": err.HTTPStatus(),
+			"error":      err.Error(),
+			"code":       err.Code(),
+			"field":      err.Field(),
+		})
+		return
+	}
+	ErrorResponse(c, err.HTTPStatus(), err.Code(), err.Error())
+}
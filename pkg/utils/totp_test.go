@@ -0,0 +1,77 @@
+package utils_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890"
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, the last six of the eight digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		got, err := utils.GenerateTOTPCode(rfc6238Secret, unix/utils.TOTPPeriod)
+		if err != nil {
+			t.Fatalf("GenerateTOTPCode() = %v", err)
+		}
+		if got != want {
+			t.Errorf("GenerateTOTPCode() at %d = %s, want %s", unix, got, want)
+		}
+	}
+
+	// Authenticator apps show the secret in lower case or with spaces trimmed
+	lower, err := utils.GenerateTOTPCode(" "+strings.ToLower(rfc6238Secret), 59/utils.TOTPPeriod)
+	if err != nil || lower != "287082" {
+		t.Fatalf("GenerateTOTPCode() with a lower case secret = %s, %v", lower, err)
+	}
+	if _, err := utils.GenerateTOTPCode("not base32!", 1); err == nil {
+		t.Fatal("GenerateTOTPCode() with an invalid secret = nil, want an error")
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / utils.TOTPPeriod
+	code := func(step int64) string {
+		code, err := utils.GenerateTOTPCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatalf("GenerateTOTPCode() = %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", code(current), current, true},
+		{"previous step within the skew", code(current - 1), current - 1, true},
+		{"next step within the skew", code(current + 1), current + 1, true},
+		{"surrounded by spaces", " " + code(current) + " ", current, true},
+		{"two steps old", code(current - 2), 0, false},
+		{"too short", code(current)[:5], 0, false},
+		{"empty", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := utils.ValidateTOTPCode(rfc6238Secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Fatalf("ValidateTOTPCode(%q) = %d, %v, want %d, %v", tt.code, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
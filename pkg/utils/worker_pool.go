@@ -0,0 +1,65 @@
+package utils
+
+// Bounded execution of CPU-heavy jobs such as password hashing
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ErrWorkerPoolSaturated is returned when every slot is busy and the queue is full
+var ErrWorkerPoolSaturated = NewCustomError("SERVICE_BUSY",
+	http.StatusServiceUnavailable,
+	"the server is busy, retry later",
+)
+
+// DefaultWorkerPoolRetryAfter is sent to clients refused by a saturated pool
+const DefaultWorkerPoolRetryAfter = time.Second
+
+// WorkerPool runs at most concurrency jobs at once, up to queueDepth more wait for a free slot
+// and the rest are refused straight away, so a burst of requests cannot take every CPU.
+// Jobs run on the caller's goroutine, a waiting job only holds its queue place.
+type WorkerPool struct {
+	slots      chan struct{}
+	admitted   chan struct{} // Running and waiting jobs
+	retryAfter time.Duration
+}
+
+func NewWorkerPool(concurrency, queueDepth int, retryAfter time.Duration) *WorkerPool {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if queueDepth < 0 {
+		queueDepth = 0
+	}
+	if retryAfter <= 0 {
+		retryAfter = DefaultWorkerPoolRetryAfter
+	}
+	return &WorkerPool{
+		slots:      make(chan struct{}, concurrency),
+		admitted:   make(chan struct{}, concurrency+queueDepth),
+		retryAfter: retryAfter,
+	}
+}
+
+// Do runs job once a slot is free. It returns ErrWorkerPoolSaturated with a Retry-After duration when
+// the queue is full, or when the context ends while the job waits: the request timed out in the queue.
+func (p *WorkerPool) Do(ctx context.Context, job func()) error {
+	select {
+	case p.admitted <- struct{}{}:
+	default:
+		return ErrWorkerPoolSaturated.WithRetryAfter(p.retryAfter)
+	}
+	defer func() { <-p.admitted }()
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ErrWorkerPoolSaturated.WithRetryAfter(p.retryAfter)
+	}
+	defer func() { <-p.slots }()
+
+	job()
+	return nil
+}
@@ -0,0 +1,131 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/passwordhash"
+	"github.com/luannguyenthanh-ba-dev/go-ai-security/pkg/utils"
+)
+
+func TestWorkerPoolDo(t *testing.T) {
+	tests := []struct {
+		name       string
+		queueDepth int
+		timeout    time.Duration // Of the caller waiting in the queue
+		wantBusy   bool
+	}{
+		{
+			name:       "runs once a slot is free",
+			queueDepth: 1,
+			timeout:    time.Second,
+		},
+		{
+			name:       "refuses when the queue is full",
+			queueDepth: 0,
+			timeout:    time.Second,
+			wantBusy:   true,
+		},
+		{
+			name:       "answers busy when the context ends in the queue",
+			queueDepth: 1,
+			timeout:    10 * time.Millisecond,
+			wantBusy:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := utils.NewWorkerPool(1, tt.queueDepth, time.Second)
+
+			// Hold the only slot until the job under test is done or refused
+			release := make(chan struct{})
+			running := make(chan struct{})
+			go func() {
+				_ = pool.Do(context.Background(), func() {
+					close(running)
+					<-release
+				})
+			}()
+			<-running
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			if !tt.wantBusy {
+				time.AfterFunc(20*time.Millisecond, func() { close(release) })
+			} else {
+				defer close(release)
+			}
+
+			ran := false
+			err := pool.Do(ctx, func() { ran = true })
+			if !tt.wantBusy {
+				if err != nil || !ran {
+					t.Fatalf("Do() = %v, ran = %v, want the job to run", err, ran)
+				}
+				return
+			}
+
+			var ce *utils.CustomError
+			if !errors.As(err, &ce) || ce.HTTPStatus() != http.StatusServiceUnavailable || ce.RetryAfter() != time.Second {
+				t.Fatalf("Do() = %v, want a 503 with a Retry-After of 1s", err)
+			}
+			if ran {
+				t.Fatal("the refused job ran")
+			}
+		})
+	}
+}
+
+// BenchmarkWorkerPoolDo measures the overhead of the pool around an empty job
+func BenchmarkWorkerPoolDo(b *testing.B) {
+	pool := utils.NewWorkerPool(runtime.GOMAXPROCS(0), 1024, utils.DefaultWorkerPoolRetryAfter)
+	ctx := context.Background()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = pool.Do(ctx, func() {})
+		}
+	})
+}
+
+// BenchmarkWorkerPoolHash hashes passwords from more goroutines than CPUs, inline and through the pool
+// sized like the server's default. refused/op is the share of hashes the pool turned away.
+func BenchmarkWorkerPoolHash(b *testing.B) {
+	for _, algorithm := range []string{passwordhash.AlgorithmArgon2id, passwordhash.AlgorithmBcrypt} {
+		hasher, err := passwordhash.NewFromConfig(passwordhash.Config{Algorithm: algorithm})
+		if err != nil {
+			b.Fatalf("configure %s: %v", algorithm, err)
+		}
+		concurrency := max(runtime.GOMAXPROCS(0)-1, 1)
+		pool := utils.NewWorkerPool(concurrency, 4*concurrency, utils.DefaultWorkerPoolRetryAfter)
+
+		modes := []struct {
+			name string
+			run  func(ctx context.Context, job func()) error
+		}{
+			{"inline", func(ctx context.Context, job func()) error { job(); return nil }},
+			{"pool", pool.Do},
+		}
+		for _, mode := range modes {
+			b.Run(algorithm+"/"+mode.name, func(b *testing.B) {
+				var refused atomic.Int64
+				ctx := context.Background()
+				b.SetParallelism(4)
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if err := mode.run(ctx, func() { _, _ = hasher.Hash("correct horse battery staple") }); err != nil {
+							refused.Add(1)
+						}
+					}
+				})
+				b.ReportMetric(float64(refused.Load())/float64(b.N), "refused/op")
+			})
+		}
+	}
+}